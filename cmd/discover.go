@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/Pjt727/classy/collection"
+	"github.com/Pjt727/classy/collection/services/banner"
+	"github.com/Pjt727/classy/data"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	discoverSchoolIDFlag   string
+	discoverSchoolNameFlag string
+	discoverSaveFlag       bool
+)
+
+var discoverCmd = &cobra.Command{
+	Use:   "discover [host]",
+	Short: "check a host is a banner 9 instance and make its school config",
+	Long: `probes the banner endpoints collections use, samples a term and prints
+the school registry config for the host
+with --save the school is added to the school registry`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if discoverSaveFlag && (discoverSchoolIDFlag == "" || discoverSchoolNameFlag == "") {
+			fmt.Println("--schoolid and --schoolname are needed to save")
+			os.Exit(1)
+		}
+		logger := slog.With(slog.String("job", "discover"))
+
+		ctx := context.Background()
+		discovery, err := banner.Discover(ctx, *logger, args[0])
+		if err != nil {
+			fmt.Printf("Could not discover banner %v\n", err)
+			os.Exit(1)
+		}
+		config, err := json.MarshalIndent(discovery.Config, "", "  ")
+		if err != nil {
+			fmt.Printf("Could not make the config %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Found banner 9 at %s\n", discovery.BaseURL)
+		fmt.Printf("Terms: %d\n", discovery.TermCount)
+		fmt.Printf(
+			"Sample term: %s %s with %d sections (%s)\n",
+			discovery.SampleTerm.ID,
+			discovery.SampleTerm.Name.String,
+			discovery.SectionCount,
+			discovery.ResponseTime,
+		)
+		fmt.Printf("Config:\n%s\n", config)
+		if !discoverSaveFlag {
+			return
+		}
+
+		entry := db.SchoolRegistry{
+			SchoolID:    discoverSchoolIDFlag,
+			SchoolName:  discoverSchoolNameFlag,
+			ServiceName: banner.SERVICE_NAME,
+			Config:      config,
+			Enabled:     true,
+		}
+		if err := collection.ValidateRegistryEntry(entry); err != nil {
+			fmt.Printf("Invalid registry entry %v\n", err)
+			os.Exit(1)
+		}
+		dbPool, err := data.NewPool(ctx, false)
+		if err != nil {
+			fmt.Printf("Could not connect to the database %v", err)
+			os.Exit(1)
+		}
+		q := db.New(dbPool)
+		err = q.UpsertSchoolRegistryEntry(ctx, db.UpsertSchoolRegistryEntryParams{
+			SchoolID:    entry.SchoolID,
+			SchoolName:  entry.SchoolName,
+			ServiceName: entry.ServiceName,
+			Config:      entry.Config,
+			Priority:    entry.Priority,
+			Enabled:     entry.Enabled,
+		})
+		if err != nil {
+			fmt.Printf("Could not save the school %v", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Saved %s to the school registry\n", entry.SchoolID)
+	},
+}
+
+func init() {
+	appCmd.AddCommand(discoverCmd)
+	discoverCmd.Flags().StringVar(&discoverSchoolIDFlag, "schoolid", "", "Id of the school when saving")
+	discoverCmd.Flags().StringVar(&discoverSchoolNameFlag, "schoolname", "", "Name of the school when saving")
+	discoverCmd.Flags().BoolVar(&discoverSaveFlag, "save", false, "Add the school to the school registry")
+}
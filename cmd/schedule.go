@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Pjt727/classy/collection"
+	"github.com/Pjt727/classy/data"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleSchoolFlag   string
+	scheduleTermFlag     string
+	scheduleCronFlag     string
+	scheduleTimezoneFlag string
+	scheduleServiceFlag  string
+	scheduleFullFlag     bool
+	scheduleDisabledFlag bool
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "manage recurring collection schedules",
+	Long: `schedules add collection jobs whenever their cron matches
+ex: a full collection of every still collecting term every sunday at 3am
+	classy schedule add --school marist --cron "0 3 * * SUN" --full
+the running app plans the jobs`,
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "add a recurring collection schedule",
+	Long: `the cron is minute hour day-of-month month day-of-week in the given timezone
+term defaults to every still collecting term of the school`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := collection.ValidateCollectionSchedule(scheduleCronFlag, scheduleTimezoneFlag); err != nil {
+			fmt.Printf("Invalid schedule %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		dbPool, err := data.NewPool(ctx, false)
+		if err != nil {
+			fmt.Printf("Could not connect to the database %v", err)
+			os.Exit(1)
+		}
+		q := db.New(dbPool)
+
+		schedule, err := q.InsertCollectionSchedule(ctx, db.InsertCollectionScheduleParams{
+			SchoolID:         scheduleSchoolFlag,
+			TermCollectionID: pgtype.Text{String: scheduleTermFlag, Valid: scheduleTermFlag != ""},
+			Cron:             scheduleCronFlag,
+			Timezone:         scheduleTimezoneFlag,
+			IsFullCollection: scheduleFullFlag,
+			ServiceName:      pgtype.Text{String: scheduleServiceFlag, Valid: scheduleServiceFlag != ""},
+			Enabled:          !scheduleDisabledFlag,
+		})
+		if err != nil {
+			fmt.Printf("Could not add the schedule %v", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Added schedule %d\n", schedule.ID)
+		next, err := collection.NextScheduledCollection(schedule, time.Now())
+		if err == nil && !next.IsZero() {
+			fmt.Printf("Next collection: %s\n", next.Format(time.RFC1123))
+		}
+	},
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the recurring collection schedules",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		dbPool, err := data.NewPool(ctx, false)
+		if err != nil {
+			fmt.Printf("Could not connect to the database %v", err)
+			os.Exit(1)
+		}
+		q := db.New(dbPool)
+
+		schedules, err := q.ListCollectionSchedules(ctx)
+		if err != nil {
+			fmt.Printf("Could not get the schedules %v", err)
+			os.Exit(1)
+		}
+		if len(schedules) == 0 {
+			fmt.Println("No collection schedules")
+			return
+		}
+		now := time.Now()
+		for _, schedule := range schedules {
+			term := "still collecting terms"
+			if schedule.TermCollectionID.Valid {
+				term = schedule.TermCollectionID.String
+			}
+			kind := "incremental"
+			if schedule.IsFullCollection {
+				kind = "full"
+			}
+			next := "disabled"
+			if schedule.Enabled {
+				nextTime, err := collection.NextScheduledCollection(schedule, now)
+				if err != nil {
+					next = err.Error()
+				} else {
+					next = nextTime.Format(time.RFC1123)
+				}
+			}
+			fmt.Printf(
+				"%d\t%s\t%s\t%s %s\t%s\tnext: %s\n",
+				schedule.ID,
+				schedule.SchoolID,
+				term,
+				schedule.Cron,
+				schedule.Timezone,
+				kind,
+				next,
+			)
+		}
+	},
+}
+
+var scheduleRemoveCmd = &cobra.Command{
+	Use:   "remove [schedule id]",
+	Short: "remove a recurring collection schedule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		scheduleID := parseScheduleID(args[0])
+		ctx := context.Background()
+		dbPool, err := data.NewPool(ctx, false)
+		if err != nil {
+			fmt.Printf("Could not connect to the database %v", err)
+			os.Exit(1)
+		}
+		q := db.New(dbPool)
+
+		removed, err := q.DeleteCollectionSchedule(ctx, scheduleID)
+		if err != nil {
+			fmt.Printf("Could not remove the schedule %v", err)
+			os.Exit(1)
+		}
+		if removed == 0 {
+			fmt.Printf("No schedule with id %d\n", scheduleID)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed schedule %d\n", scheduleID)
+	},
+}
+
+func setScheduleEnabledCmd(use string, short string, enabled bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " [schedule id]",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			scheduleID := parseScheduleID(args[0])
+			ctx := context.Background()
+			dbPool, err := data.NewPool(ctx, false)
+			if err != nil {
+				fmt.Printf("Could not connect to the database %v", err)
+				os.Exit(1)
+			}
+			q := db.New(dbPool)
+
+			changed, err := q.SetCollectionScheduleEnabled(ctx, db.SetCollectionScheduleEnabledParams{
+				Enabled: enabled,
+				ID:      scheduleID,
+			})
+			if err != nil {
+				fmt.Printf("Could not change the schedule %v", err)
+				os.Exit(1)
+			}
+			if changed == 0 {
+				fmt.Printf("No schedule with id %d\n", scheduleID)
+				os.Exit(1)
+			}
+			fmt.Printf("Schedule %d enabled: %v\n", scheduleID, enabled)
+		},
+	}
+}
+
+var schedulePlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "add the collection jobs of every schedule which is due",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		dbPool, err := data.NewPool(ctx, false)
+		if err != nil {
+			fmt.Printf("Could not connect to the database %v", err)
+			os.Exit(1)
+		}
+		planner := collection.NewPlanner(dbPool, slog.With(slog.String("job", "plan")))
+		jobCount, err := planner.PlanDueCollections(ctx, time.Now())
+		if err != nil {
+			fmt.Printf("Could not plan every schedule %v\n", err)
+		}
+		fmt.Printf("Added %d collection jobs\n", jobCount)
+		if err != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+func parseScheduleID(text string) int32 {
+	scheduleID, err := strconv.ParseInt(text, 10, 32)
+	if err != nil {
+		fmt.Printf("Invalid schedule id `%s`\n", text)
+		os.Exit(1)
+	}
+	return int32(scheduleID)
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(
+		scheduleAddCmd,
+		scheduleListCmd,
+		scheduleRemoveCmd,
+		setScheduleEnabledCmd("enable", "enable a recurring collection schedule", true),
+		setScheduleEnabledCmd("disable", "disable a recurring collection schedule", false),
+		schedulePlanCmd,
+	)
+	scheduleAddCmd.Flags().StringVar(&scheduleSchoolFlag, "school", "", "Id of the school to collect")
+	scheduleAddCmd.Flags().StringVar(&scheduleTermFlag, "term", "", "Only collect this term collection")
+	scheduleAddCmd.Flags().StringVar(&scheduleCronFlag, "cron", "", "When to collect ex: \"*/15 7-21 * * *\"")
+	scheduleAddCmd.Flags().StringVar(&scheduleTimezoneFlag, "timezone", "UTC", "Time zone the cron is in ex: America/New_York")
+	scheduleAddCmd.Flags().StringVar(&scheduleServiceFlag, "service", "", "Service to collect with instead of the school's default")
+	scheduleAddCmd.Flags().BoolVar(&scheduleFullFlag, "full", false, "Do full collections")
+	scheduleAddCmd.Flags().BoolVar(&scheduleDisabledFlag, "disabled", false, "Add the schedule without enabling it")
+	scheduleAddCmd.MarkFlagRequired("school")
+	scheduleAddCmd.MarkFlagRequired("cron")
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"log/slog"
@@ -48,12 +50,24 @@ facualty members, and internal collection tables`,
 			logger.Error("invalid school name", "err", err)
 			return
 		}
+		maxDeletedPercent, err := cmd.Flags().GetFloat64("maxdeletedpercent")
+		if err != nil {
+			logger.Error("invalid max deleted percent", "err", err)
+			return
+		}
+		isDryRun, err := cmd.Flags().GetBool("dryrun")
+		if err != nil {
+			logger.Error("invalid dry run", "err", err)
+			return
+		}
 		var termSeason db.SeasonEnum
 		if err := termSeason.Scan(termSeasonInput); err != nil {
 			logger.Error("Term season is invalid: ", "err", err)
 			return
 		}
-		ctx := context.Background()
+		// an interrupted collection is still finished as a failure
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 		dbPool, err := data.NewPool(ctx, false)
 		if err != nil {
 			logger.Error("Could not connect to db: ", "err", err)
@@ -118,11 +132,70 @@ facualty members, and internal collection tables`,
 		}
 
 		logger.Info("Starting update for school", "schoolid", schoolId)
-		orchestrator.UpdateAllSectionsOfSchool(ctx, termCollection, collection.DefualtUpdateSectionsConfig())
-		logger.Info("Finished update for school", "schoolid", schoolId)
+		deletionGuard := collection.DefaultDeletionGuard()
+		deletionGuard.MaxDeletedPercent = maxDeletedPercent
+		config := collection.DefualtUpdateSectionsConfig().
+			SetDeletionGuard(deletionGuard).
+			SetDryRun(isDryRun)
+		result, err := orchestrator.UpdateAllSectionsOfSchool(ctx, termCollection, config)
+		if err != nil {
+			logger.Error("Could not update school", "schoolid", schoolId, "err", err)
+			return
+		}
+		if result.Diff != nil {
+			printCollectionDiff(*result.Diff)
+		}
+		logger.Info("Finished update for school", "schoolid", schoolId, "warnings", result.Warnings)
 	},
 }
 
+func printCollectionDiff(diff collection.CollectionDiff) {
+	inserted, updated, deleted := diff.Counts()
+	fmt.Printf("Dry run would insert %d, update %d and delete %d records\n", inserted, updated, deleted)
+	if diff.WouldQuarantine {
+		fmt.Printf(
+			"It would be quarantined for deleting %d of %d sections\n",
+			diff.DeletedSections,
+			diff.LiveSections,
+		)
+	}
+	if diff.ValidationFailure != "" {
+		fmt.Printf("It would fail validation: %s\n", diff.ValidationFailure)
+	}
+	for _, violation := range diff.Violations {
+		fmt.Printf(
+			"  %s (%s) %s %s: %s\n",
+			violation.Rule,
+			violation.Severity,
+			violation.TableName,
+			violation.RecordKey,
+			violation.Message,
+		)
+	}
+	for _, table := range diff.Tables {
+		fmt.Printf(
+			"\n%s: %d inserted, %d updated, %d deleted\n",
+			table.Table,
+			table.Inserted,
+			table.Updated,
+			table.Deleted,
+		)
+		for _, record := range table.Records {
+			fmt.Printf("  %s %s\n", record.Action, record.KeyString())
+			for _, field := range record.Fields {
+				switch record.Action {
+				case db.SyncKindInsert:
+					fmt.Printf("      %s: %s\n", field.Field, field.New)
+				case db.SyncKindDelete:
+					fmt.Printf("      %s: %s\n", field.Field, field.Old)
+				default:
+					fmt.Printf("      %s: %s -> %s\n", field.Field, field.Old, field.New)
+				}
+			}
+		}
+	}
+}
+
 func init() {
 	collectCmd.AddCommand(schoolCmd)
 
@@ -148,6 +221,16 @@ func init() {
 		"marist",
 		"The school to be collected (none for all of them)",
 	)
+	schoolCmd.Flags().Float64(
+		"maxdeletedpercent",
+		collection.MAX_DELETED_SECTIONS_PERCENT,
+		"Quarantine the collection when it would delete more than this percent of the term's sections (100 to never)",
+	)
+	schoolCmd.Flags().Bool(
+		"dryrun",
+		false,
+		"Print what the collection would change without changing anything",
+	)
 	schoolCmd.Flags().String(
 		"schoolname",
 		"",
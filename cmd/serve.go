@@ -1,26 +1,37 @@
 package cmd
 
 import (
+	"context"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	logginghelpers "github.com/Pjt727/classy/data/logging-helpers"
 	"github.com/Pjt727/classy/server"
 	"github.com/spf13/cobra"
 )
 
+var serveWorkerFlag bool
+
 // serveapiCmd represents the serve command
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Runs the api service",
-	Long:  `Runs the api service`,
+	Long: `Runs the api service
+collections are ran in the same process unless --worker=false is given
+then they are left to ` + "`classy worker`" + ` processes`,
 	Run: func(cmd *cobra.Command, args []string) {
 		defaultLogger := slog.New(logginghelpers.NewHandler(os.Stdout, nil))
 		slog.SetDefault(defaultLogger)
-		api.Serve()
+		// running collections get to finish before the process exits
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		api.Serve(ctx, serveWorkerFlag)
 	},
 }
 
 func init() {
 	appCmd.AddCommand(serveCmd)
+	serveCmd.Flags().BoolVar(&serveWorkerFlag, "worker", true, "Also run collections and plan the recurring ones")
 }
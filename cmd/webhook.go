@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Pjt727/classy/collection/webhooks"
+	"github.com/Pjt727/classy/data"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/cobra"
+)
+
+var (
+	webhookURLFlag    string
+	webhookSchoolFlag string
+	webhookEventsFlag []string
+)
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "manage webhooks for collection events",
+	Long: `webhooks are sent when a collection starts, succeeds, fails or hits an incorrect assumption
+deliveries are signed with the secret given when the webhook is added`,
+}
+
+var webhookAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "add a collection webhook",
+	Long: `events default to all events and school defaults to every school
+events: ` + joinCollectionEventKinds(),
+	Run: func(cmd *cobra.Command, args []string) {
+		callbackURL, err := url.Parse(webhookURLFlag)
+		if err != nil || (callbackURL.Scheme != "http" && callbackURL.Scheme != "https") {
+			fmt.Println("The url must be an absolute http(s) url")
+			os.Exit(1)
+		}
+		for _, event := range webhookEventsFlag {
+			if !slices.Contains(webhooks.CollectionEventKinds, webhooks.CollectionEventKind(event)) {
+				fmt.Printf("Unknown event `%s` expected one of %s\n", event, joinCollectionEventKinds())
+				os.Exit(1)
+			}
+		}
+
+		ctx := context.Background()
+		dbPool, err := data.NewPool(ctx, false)
+		if err != nil {
+			fmt.Printf("Could not connect to the database %v", err)
+			os.Exit(1)
+		}
+		q := db.New(dbPool)
+
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			fmt.Printf("Could not make a secret %v", err)
+			os.Exit(1)
+		}
+		events := webhookEventsFlag
+		if events == nil {
+			events = []string{}
+		}
+		collectionWebhook, err := q.InsertCollectionWebhook(ctx, db.InsertCollectionWebhookParams{
+			CallbackUrl: callbackURL.String(),
+			Secret:      secret,
+			SchoolID:    pgtype.Text{String: webhookSchoolFlag, Valid: webhookSchoolFlag != ""},
+			Events:      events,
+		})
+		if err != nil {
+			fmt.Printf("Could not add the webhook %v", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Added webhook %d\n", collectionWebhook.ID)
+		fmt.Printf("Secret (will not be shown again): %s\n", secret)
+	},
+}
+
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the collection webhooks",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		dbPool, err := data.NewPool(ctx, false)
+		if err != nil {
+			fmt.Printf("Could not connect to the database %v", err)
+			os.Exit(1)
+		}
+		q := db.New(dbPool)
+
+		collectionWebhooks, err := q.ListCollectionWebhooks(ctx)
+		if err != nil {
+			fmt.Printf("Could not get the webhooks %v", err)
+			os.Exit(1)
+		}
+		if len(collectionWebhooks) == 0 {
+			fmt.Println("No collection webhooks")
+			return
+		}
+		for _, collectionWebhook := range collectionWebhooks {
+			school := "all schools"
+			if collectionWebhook.SchoolID.Valid {
+				school = collectionWebhook.SchoolID.String
+			}
+			events := "all events"
+			if len(collectionWebhook.Events) > 0 {
+				events = strings.Join(collectionWebhook.Events, ", ")
+			}
+			fmt.Printf("%d\t%s\t%s\t%s\n", collectionWebhook.ID, collectionWebhook.CallbackUrl, school, events)
+		}
+	},
+}
+
+var webhookRemoveCmd = &cobra.Command{
+	Use:   "remove [webhook id]",
+	Short: "remove a collection webhook",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		webhookID, err := strconv.ParseInt(args[0], 10, 32)
+		if err != nil {
+			fmt.Printf("Invalid webhook id `%s`\n", args[0])
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		dbPool, err := data.NewPool(ctx, false)
+		if err != nil {
+			fmt.Printf("Could not connect to the database %v", err)
+			os.Exit(1)
+		}
+		q := db.New(dbPool)
+
+		removed, err := q.DeleteCollectionWebhook(ctx, int32(webhookID))
+		if err != nil {
+			fmt.Printf("Could not remove the webhook %v", err)
+			os.Exit(1)
+		}
+		if removed == 0 {
+			fmt.Printf("No webhook with id %d\n", webhookID)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed webhook %d\n", webhookID)
+	},
+}
+
+func joinCollectionEventKinds() string {
+	kinds := make([]string, len(webhooks.CollectionEventKinds))
+	for i, kind := range webhooks.CollectionEventKinds {
+		kinds[i] = string(kind)
+	}
+	return strings.Join(kinds, ", ")
+}
+
+func init() {
+	appCmd.AddCommand(webhookCmd)
+	webhookCmd.AddCommand(webhookAddCmd, webhookListCmd, webhookRemoveCmd)
+	webhookAddCmd.Flags().StringVar(&webhookURLFlag, "url", "", "Url the events are posted to")
+	webhookAddCmd.Flags().StringVar(&webhookSchoolFlag, "school", "", "Only send events for this school")
+	webhookAddCmd.Flags().StringSliceVar(&webhookEventsFlag, "events", nil, "Comma separated events to send")
+	webhookAddCmd.MarkFlagRequired("url")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Pjt727/classy/collection"
+	"github.com/Pjt727/classy/data"
+	logginghelpers "github.com/Pjt727/classy/data/logging-helpers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	workerCountFlag       int
+	workerSchoolJobsFlag  int
+	workerServiceJobsFlag int
+)
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "runs collection jobs without the api",
+	Long: `runs collection jobs from the queue until interrupted
+any number of workers can run at once and one of them is elected to plan the recurring schedules
+ex: run the api and collections separately
+	classy app serve --worker=false
+	classy worker --workers 8`,
+	Run: func(cmd *cobra.Command, args []string) {
+		baseLogger := slog.New(logginghelpers.NewHandler(os.Stdout, nil))
+		slog.SetDefault(baseLogger)
+
+		config := collection.DefaultWorkerPoolConfig()
+		config.Workers = workerCountFlag
+		config.JobsPerSchool = workerSchoolJobsFlag
+		config.JobsPerService = workerServiceJobsFlag
+		if config.Workers < 1 || config.JobsPerSchool < 1 || config.JobsPerService < 1 {
+			fmt.Println("Every limit must be at least 1")
+			os.Exit(1)
+		}
+
+		// running jobs get a grace period when stopped
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		dbPool, err := data.NewPool(ctx, false)
+		if err != nil {
+			fmt.Printf("Could not connect to the database %v", err)
+			os.Exit(1)
+		}
+		defer dbPool.Close()
+		// a school which cannot be loaded would silently never be collected
+		if err := collection.ValidateSchoolRegistry(ctx, dbPool); err != nil {
+			fmt.Printf("Invalid school registry %v\n", err)
+			os.Exit(1)
+		}
+
+		collection.RunCollectionWorker(ctx, dbPool, config, baseLogger)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(workerCmd)
+	workerCmd.Flags().IntVar(&workerCountFlag, "workers", collection.WORKER_COUNT, "Collections to run at once")
+	workerCmd.Flags().IntVar(&workerSchoolJobsFlag, "jobs-per-school", collection.JOBS_PER_SCHOOL, "Collections of one school to run at once")
+	workerCmd.Flags().IntVar(&workerServiceJobsFlag, "jobs-per-service", collection.JOBS_PER_SERVICE, "Collections of one service to run at once")
+}
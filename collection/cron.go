@@ -0,0 +1,175 @@
+package collection
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how far ahead to look for a matching time before giving up ex: 0 0 30 2 *
+const CRON_SEARCH_LIMIT = 5 * 366 * 24 * time.Hour
+
+// a standard 5 field cron expression
+//
+//	minute hour day-of-month month day-of-week
+//	fields can be *, numbers, ranges, steps and lists ex: */15 7-21 * * MON-FRI
+type CronSchedule struct {
+	Expression  string
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	// cron matches either day field when both are restricted
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}},
+	// 7 is also sunday
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}},
+}
+
+func ParseCron(expression string) (CronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return CronSchedule{}, fmt.Errorf("Cron `%s` needs %d fields got %d", expression, len(cronFields), len(fields))
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		fieldBits, err := cronFields[i].parse(field)
+		if err != nil {
+			return CronSchedule{}, fmt.Errorf("Cron `%s`: %w", expression, err)
+		}
+		bits[i] = fieldBits
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return CronSchedule{
+		Expression:    strings.Join(fields, " "),
+		minutes:       bits[0],
+		hours:         bits[1],
+		daysOfMonth:   bits[2],
+		months:        bits[3],
+		daysOfWeek:    bits[4],
+		anyDayOfMonth: strings.HasPrefix(fields[2], "*"),
+		anyDayOfWeek:  strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid %s step `%s`", f.name, stepPart)
+			}
+		}
+
+		var start, end int
+		if rangePart == "*" {
+			start, end = f.min, f.max
+		} else {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = f.value(startPart); err != nil {
+				return 0, err
+			}
+			end = start
+			if isRange {
+				if end, err = f.value(endPart); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// 5/15 means starting at 5 every 15
+				end = f.max
+			}
+			if end < start {
+				return 0, fmt.Errorf("invalid %s range `%s`", f.name, rangePart)
+			}
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(text string) (int, error) {
+	if value, ok := f.names[strings.ToUpper(text)]; ok {
+		return value, nil
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("invalid %s `%s` expected %d-%d", f.name, text, f.min, f.max)
+	}
+	return value, nil
+}
+
+func (c CronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := c.daysOfMonth&(1<<t.Day()) != 0
+	dayOfWeek := c.daysOfWeek&(1<<int(t.Weekday())) != 0
+	switch {
+	case c.anyDayOfMonth && c.anyDayOfWeek:
+		return true
+	case c.anyDayOfMonth:
+		return dayOfWeek
+	case c.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
+
+// the first matching minute after the given time in the given time's location
+//
+//	returns the zero time when nothing matches ex: 0 0 30 2 *
+func (c CronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(CRON_SEARCH_LIMIT)
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case c.months&(1<<int(t.Month())) == 0:
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchesDay(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hours&(1<<t.Hour()) == 0:
+			// not made with time.Date since hours skipped by daylight saving would go back an hour
+			next = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+		case c.minutes&(1<<t.Minute()) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// midnights skipped by daylight saving can also go back
+		if !next.After(t) {
+			next = t.Add(time.Hour)
+		}
+		t = next
+	}
+	return time.Time{}
+}
@@ -0,0 +1,99 @@
+package collection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParseCron(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"*/15 7-21 * * *",
+		"0 3 * * SUN",
+		"0,30 9-17/2 1,15 JAN-MAR 1-5",
+		"5/20 * * * 7",
+	}
+	for _, expression := range valid {
+		if _, err := ParseCron(expression); err != nil {
+			t.Errorf("expected `%s` to parse got %v", expression, err)
+		}
+	}
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * FOO *",
+	}
+	for _, expression := range invalid {
+		if _, err := ParseCron(expression); err == nil {
+			t.Errorf("expected `%s` to not parse", expression)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone data", err)
+	}
+	// a saturday
+	saturday := time.Date(2025, time.March, 8, 22, 7, 30, 0, newYork)
+	tests := []struct {
+		expression string
+		after      time.Time
+		expected   time.Time
+	}{
+		{"*/15 7-21 * * *", saturday, time.Date(2025, time.March, 9, 7, 0, 0, 0, newYork)},
+		{"*/15 7-21 * * *", saturday.Add(-time.Hour), time.Date(2025, time.March, 8, 21, 15, 0, 0, newYork)},
+		{"0 3 * * SUN", saturday, time.Date(2025, time.March, 9, 3, 0, 0, 0, newYork)},
+		// daylight saving skips 2am on that sunday
+		{"30 2 * * *", saturday, time.Date(2025, time.March, 10, 2, 30, 0, 0, newYork)},
+		{"0 0 1 * *", saturday, time.Date(2025, time.April, 1, 0, 0, 0, 0, newYork)},
+		// either day field matches when both are restricted
+		{"0 12 15 * MON", saturday, time.Date(2025, time.March, 10, 12, 0, 0, 0, newYork)},
+		{"0 0 29 2 *", saturday, time.Date(2028, time.February, 29, 0, 0, 0, 0, newYork)},
+		{"0 0 30 2 *", saturday, time.Time{}},
+	}
+	for _, test := range tests {
+		cron, err := ParseCron(test.expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next := cron.Next(test.after); !next.Equal(test.expected) {
+			t.Errorf("`%s` after %s expected %s got %s", test.expression, test.after, test.expected, next)
+		}
+	}
+}
+
+func TestNextUnplannedCollection(t *testing.T) {
+	created := time.Date(2025, time.March, 3, 10, 0, 0, 0, time.UTC)
+	schedule := db.CollectionSchedule{
+		Cron:      "0 * * * *",
+		Timezone:  "UTC",
+		CreatedAt: pgtype.Timestamptz{Time: created, Valid: true},
+	}
+	due, err := nextUnplannedCollection(schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := created.Add(time.Hour); !due.Equal(expected) {
+		t.Errorf("expected a new schedule to be due at %s got %s", expected, due)
+	}
+
+	// planned late so the missed runs are only planned once
+	schedule.LastPlannedAt = pgtype.Timestamptz{Time: created.Add(5*time.Hour + 20*time.Minute), Valid: true}
+	due, err = nextUnplannedCollection(schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := created.Add(6 * time.Hour); !due.Equal(expected) {
+		t.Errorf("expected the schedule to be due at %s got %s", expected, due)
+	}
+}
@@ -0,0 +1,237 @@
+package collection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/Pjt727/classy/data/db"
+)
+
+// dry runs only keep this many of their violations since the rest are deleted with their history
+const MAX_DRY_RUN_VIOLATIONS = 200
+
+// what a collection would change if it was not a dry run
+type CollectionDiff struct {
+	Tables []TableDiff
+	// the deletion guard would have quarantined the collection
+	WouldQuarantine bool
+	LiveSections    int64
+	DeletedSections int64
+	// the staged records which broke validation rules
+	Violations []db.CollectionViolation
+	// why validation would fail the collection, there are no tables when it is set
+	ValidationFailure string
+}
+
+type TableDiff struct {
+	Table    string
+	Inserted int
+	Updated  int
+	Deleted  int
+	Records  []RecordDiff
+}
+
+type RecordDiff struct {
+	Action db.SyncKind
+	// primary key of the record without the school id
+	Key    []FieldDiff
+	Fields []FieldDiff
+}
+
+// values are json and empty when the record does not have them
+//
+//	inserted records have no old values and deleted records no new ones
+type FieldDiff struct {
+	Field string
+	Old   string
+	New   string
+}
+
+func (d CollectionDiff) Counts() (inserted int, updated int, deleted int) {
+	for _, table := range d.Tables {
+		inserted += table.Inserted
+		updated += table.Updated
+		deleted += table.Deleted
+	}
+	return inserted, updated, deleted
+}
+
+// ex: course_number="101", sequence="1", subject_code="CMPT"
+func (r RecordDiff) KeyString() string {
+	parts := make([]string, len(r.Key))
+	for i, key := range r.Key {
+		parts[i] = key.Field + "=" + key.New
+	}
+	return strings.Join(parts, ", ")
+}
+
+// stages the collection and moves it in a transaction which is rolled back to see what it would change
+//
+//	staged data which broke blocking validation rules is not moved so only its violations are reported
+func (o *Orchestrator) dryRunStagedCollection(
+	ctx context.Context,
+	logger *slog.Logger,
+	termCollection db.TermCollection,
+	termCollectionHistoryID int32,
+	guard DeletionGuard,
+	validationErr error,
+) (CollectionResult, error) {
+	q := db.New(o.dbPool)
+	counts, err := q.CountUnstagedSections(ctx, db.CountUnstagedSectionsParams{
+		TermCollectionHistoryID: termCollectionHistoryID,
+		TermCollectionID:        termCollection.ID,
+		SchoolID:                termCollection.SchoolID,
+	})
+	if err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not count the sections the collection would delete %w", err)
+	}
+	// read before the dry run's history and its violations are deleted
+	violations, err := q.ListCollectionViolations(ctx, db.ListCollectionViolationsParams{
+		TermCollectionHistoryID: termCollectionHistoryID,
+		LimitCount:              MAX_DRY_RUN_VIOLATIONS,
+	})
+	if err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not get the violations of the dry run %w", err)
+	}
+	if validationErr != nil {
+		logger.Warn("Dry run would fail validation", "error", validationErr)
+		return CollectionResult{
+			TermCollectionHistoryId: termCollectionHistoryID,
+			Diff: &CollectionDiff{
+				WouldQuarantine:   !guard.Allows(counts.LiveSections, counts.UnstagedSections),
+				LiveSections:      counts.LiveSections,
+				DeletedSections:   counts.UnstagedSections,
+				Violations:        violations,
+				ValidationFailure: validationErr.Error(),
+			},
+		}, nil
+	}
+
+	tx, err := o.dbPool.Begin(ctx)
+	if err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not begin dry run transcation %w", err)
+	}
+	// nothing the dry run does is ever committed
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL app.term_collection_history_id = '%d';", termCollectionHistoryID)); err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not set term_collection_history_id postgres variable %w", err)
+	}
+	q = q.WithTx(tx)
+	err = q.SnapshotDryRunPrevious(ctx, db.SnapshotDryRunPreviousParams{
+		SchoolID:                termCollection.SchoolID,
+		TermCollectionID:        termCollection.ID,
+		TermCollectionHistoryID: termCollectionHistoryID,
+	})
+	if err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not snapshot the records the collection could change %w", err)
+	}
+	if err := moveStagedTables(ctx, q, termCollection, termCollectionHistoryID); err != nil {
+		return CollectionResult{}, fmt.Errorf("Failed moving staged classes %w", err)
+	}
+	changes, err := q.GetDryRunChanges(ctx, termCollectionHistoryID)
+	if err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not get the changes of the dry run %w", err)
+	}
+
+	diff, err := buildCollectionDiff(changes)
+	if err != nil {
+		return CollectionResult{}, err
+	}
+	diff.LiveSections = counts.LiveSections
+	diff.DeletedSections = counts.UnstagedSections
+	diff.WouldQuarantine = !guard.Allows(counts.LiveSections, counts.UnstagedSections)
+	diff.Violations = violations
+
+	inserted, updated, deleted := diff.Counts()
+	logger.Info(
+		"Dry run finished without committing",
+		"inserted", inserted,
+		"updated", updated,
+		"deleted", deleted,
+		"wouldQuarantine", diff.WouldQuarantine,
+	)
+	return CollectionResult{
+		TermCollectionHistoryId: termCollectionHistoryID,
+		Inserted:                uint(inserted),
+		Updated:                 uint(updated),
+		Deleted:                 uint(deleted),
+		Diff:                    &diff,
+	}, nil
+}
+
+func buildCollectionDiff(changes []db.DryRunChangeRow) (CollectionDiff, error) {
+	var diff CollectionDiff
+	tableIndexes := map[string]int{}
+	for _, change := range changes {
+		var key, relevant, previous map[string]json.RawMessage
+		if err := json.Unmarshal(change.PkFields, &key); err != nil {
+			return CollectionDiff{}, fmt.Errorf("Could not read changed record's key %w", err)
+		}
+		if err := json.Unmarshal(change.RelevantFields, &relevant); err != nil {
+			return CollectionDiff{}, fmt.Errorf("Could not read changed record's fields %w", err)
+		}
+		if change.PreviousFields != nil {
+			if err := json.Unmarshal(change.PreviousFields, &previous); err != nil {
+				return CollectionDiff{}, fmt.Errorf("Could not read changed record's previous fields %w", err)
+			}
+		}
+
+		record := RecordDiff{Action: change.SyncAction}
+		for _, field := range sortedKeys(key) {
+			record.Key = append(record.Key, FieldDiff{Field: field, New: string(key[field])})
+		}
+		switch change.SyncAction {
+		case db.SyncKindInsert:
+			for _, field := range sortedKeys(relevant) {
+				record.Fields = append(record.Fields, FieldDiff{Field: field, New: string(relevant[field])})
+			}
+		case db.SyncKindUpdate:
+			for _, field := range sortedKeys(relevant) {
+				record.Fields = append(record.Fields, FieldDiff{
+					Field: field,
+					Old:   string(previous[field]),
+					New:   string(relevant[field]),
+				})
+			}
+		case db.SyncKindDelete:
+			for _, field := range sortedKeys(previous) {
+				if _, isKey := key[field]; isKey || field == "school_id" {
+					continue
+				}
+				record.Fields = append(record.Fields, FieldDiff{Field: field, Old: string(previous[field])})
+			}
+		}
+
+		index, ok := tableIndexes[change.TableName]
+		if !ok {
+			index = len(diff.Tables)
+			tableIndexes[change.TableName] = index
+			diff.Tables = append(diff.Tables, TableDiff{Table: change.TableName})
+		}
+		table := &diff.Tables[index]
+		switch change.SyncAction {
+		case db.SyncKindInsert:
+			table.Inserted++
+		case db.SyncKindUpdate:
+			table.Updated++
+		case db.SyncKindDelete:
+			table.Deleted++
+		}
+		table.Records = append(table.Records, record)
+	}
+	return diff, nil
+}
+
+func sortedKeys(fields map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,54 @@
+package collection
+
+import (
+	"testing"
+
+	"github.com/Pjt727/classy/data/db"
+)
+
+func TestBuildCollectionDiff(t *testing.T) {
+	diff, err := buildCollectionDiff([]db.DryRunChangeRow{
+		{
+			TableName:      "sections",
+			SyncAction:     db.SyncKindUpdate,
+			PkFields:       []byte(`{"sequence": "1", "term_collection_id": "2025SP"}`),
+			RelevantFields: []byte(`{"enrollment": 20}`),
+			PreviousFields: []byte(`{"sequence": "1", "term_collection_id": "2025SP", "school_id": "marist", "enrollment": 18, "max_enrollment": 30}`),
+		},
+		{
+			TableName:      "courses",
+			SyncAction:     db.SyncKindInsert,
+			PkFields:       []byte(`{"subject_code": "CMPT", "number": "101"}`),
+			RelevantFields: []byte(`{"title": "Intro"}`),
+		},
+		{
+			TableName:      "sections",
+			SyncAction:     db.SyncKindDelete,
+			PkFields:       []byte(`{"sequence": "2", "term_collection_id": "2025SP"}`),
+			RelevantFields: []byte(`{}`),
+			PreviousFields: []byte(`{"sequence": "2", "term_collection_id": "2025SP", "school_id": "marist", "enrollment": 5}`),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Tables) != 2 || diff.Tables[0].Table != "sections" || diff.Tables[1].Table != "courses" {
+		t.Fatalf("expected the tables in the order they were changed got %v", diff.Tables)
+	}
+	if inserted, updated, deleted := diff.Counts(); inserted != 1 || updated != 1 || deleted != 1 {
+		t.Errorf("expected one of each change got %d %d %d", inserted, updated, deleted)
+	}
+
+	updated := diff.Tables[0].Records[0]
+	if key := updated.KeyString(); key != `sequence="1", term_collection_id="2025SP"` {
+		t.Errorf("unexpected key %s", key)
+	}
+	if len(updated.Fields) != 1 || updated.Fields[0] != (FieldDiff{Field: "enrollment", Old: "18", New: "20"}) {
+		t.Errorf("unexpected updated fields %v", updated.Fields)
+	}
+
+	deleted := diff.Tables[0].Records[1]
+	if len(deleted.Fields) != 1 || deleted.Fields[0] != (FieldDiff{Field: "enrollment", Old: "5"}) {
+		t.Errorf("expected the deleted record's fields without its key got %v", deleted.Fields)
+	}
+}
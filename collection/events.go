@@ -0,0 +1,67 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Pjt727/classy/collection/webhooks"
+	"github.com/Pjt727/classy/data/db"
+)
+
+type collectionEvent struct {
+	kind                    webhooks.CollectionEventKind
+	termCollection          db.TermCollection
+	termCollectionHistoryID int32
+	config                  UpdateSectionsConfig
+	result                  CollectionResult
+	duration                time.Duration
+	err                     error
+}
+
+// queues the event for the configured collection webhooks
+//
+//	failing to queue is only logged because it should never fail the collection itself
+func (o *Orchestrator) emitCollectionEvent(ctx context.Context, logger *slog.Logger, event collectionEvent) {
+	payload := webhooks.CollectionEventPayload{
+		Event:                   event.kind,
+		SchoolID:                event.termCollection.SchoolID,
+		TermCollectionID:        event.termCollection.ID,
+		TermCollectionHistoryID: event.termCollectionHistoryID,
+		ServiceName:             event.config.serviceName,
+		IsFullCollection:        event.config.isFullCollection,
+		OccurredAt:              time.Now().UTC(),
+		DurationMilliseconds:    event.duration.Milliseconds(),
+		ErrorClass:              classifyCollectionError(event.err),
+	}
+	if event.kind == webhooks.CollectionSucceededEvent {
+		payload.Counts = &webhooks.CollectionEventCounts{
+			Inserted: event.result.Inserted,
+			Updated:  event.result.Updated,
+			Deleted:  event.result.Deleted,
+		}
+	}
+	if event.err != nil {
+		payload.Error = event.err.Error()
+	}
+
+	// the collection's context could be the reason it failed
+	ctx = context.WithoutCancel(ctx)
+	queued, err := webhooks.EnqueueCollectionEvent(ctx, db.New(o.dbPool), payload)
+	if err != nil {
+		logger.Error("Could not queue collection webhooks", "event", event.kind, "error", err)
+		return
+	}
+	if queued > 0 {
+		logger.Info("Queued collection webhooks", "event", event.kind, "count", queued)
+	}
+}
+
+// quarantined collections are not failures of the service so they get their own class
+func classifyCollectionError(err error) webhooks.ErrorClass {
+	if errors.Is(err, ErrMassDeletion) {
+		return webhooks.ErrorClassQuarantined
+	}
+	return webhooks.ClassifyError(err)
+}
@@ -0,0 +1,179 @@
+package collection
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+)
+
+// how many of the latest collections of a school's service are looked at
+const SERVICE_HEALTH_WINDOW = 20
+
+// failures in a row until a service is considered broken
+const FAILOVER_CONSECUTIVE_FAILURES = 3
+
+// broken services are tried again after this long so they can recover
+const FAILOVER_RETRY_INTERVAL = 6 * time.Hour
+
+// a lower priority service is only used over a higher one when its score is this much better
+const HEALTH_SCORE_TOLERANCE = 0.2
+
+// collections taking this long get the full latency penalty
+const SLOW_COLLECTION_DURATION = 30 * time.Minute
+
+// how the recent collections of a school's service went
+type ServiceHealth struct {
+	ServiceName          string
+	Successes            int
+	Failures             int
+	IncorrectAssumptions int
+	// failures since the last success
+	ConsecutiveFailures int
+	AverageDuration     time.Duration
+	LastFailure         time.Time
+}
+
+// from 0 to 1 where services without any collections are 0.5
+//
+//	incorrect assumptions are penalized more since they usually mean the school's site changed
+func (h ServiceHealth) Score() float64 {
+	total := float64(h.Successes + h.Failures)
+	// smoothed so one collection does not decide everything
+	score := (float64(h.Successes) + 1) / (total + 2)
+	if total > 0 {
+		score -= 0.5 * float64(h.IncorrectAssumptions) / total
+	}
+	score -= 0.1 * min(1, float64(h.AverageDuration)/float64(SLOW_COLLECTION_DURATION))
+	return max(0, score)
+}
+
+func (h ServiceHealth) IsBroken(now time.Time) bool {
+	return h.ConsecutiveFailures >= FAILOVER_CONSECUTIVE_FAILURES &&
+		now.Sub(h.LastFailure) < FAILOVER_RETRY_INTERVAL
+}
+
+// which service a school's collections should use and why
+type ServiceDecision struct {
+	Service Service
+	Reason  string
+	// in priority order
+	Health []ServiceHealth
+}
+
+// the highest priority service which is not broken unless a lower one is clearly healthier
+func (s *SchoolsServiceManager) Decide() ServiceDecision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.decide(time.Now())
+}
+
+func (s *SchoolsServiceManager) decide(now time.Time) ServiceDecision {
+	health := make([]ServiceHealth, len(s.services))
+	for i, service := range s.services {
+		health[i] = s.health[service.GetName()]
+		health[i].ServiceName = service.GetName()
+	}
+	decision := ServiceDecision{Health: health}
+
+	best := -1
+	for i, serviceHealth := range health {
+		if serviceHealth.IsBroken(now) {
+			continue
+		}
+		if best == -1 || serviceHealth.Score() > health[best].Score()+HEALTH_SCORE_TOLERANCE {
+			best = i
+		}
+	}
+
+	switch {
+	case best == -1:
+		// every service is broken so go with whichever has been doing the best
+		best = 0
+		for i, serviceHealth := range health {
+			if serviceHealth.Score() > health[best].Score() {
+				best = i
+			}
+		}
+		decision.Reason = fmt.Sprintf("Every service is failing so using the healthiest %s", health[best].ServiceName)
+	case best == 0:
+		decision.Reason = fmt.Sprintf("Using the highest priority service %s", health[best].ServiceName)
+	case health[0].IsBroken(now):
+		decision.Reason = fmt.Sprintf(
+			"Failing over to %s because %s failed %d times in a row",
+			health[best].ServiceName,
+			health[0].ServiceName,
+			health[0].ConsecutiveFailures,
+		)
+	default:
+		decision.Reason = fmt.Sprintf(
+			"Using %s because its health %.2f is better than %s's %.2f",
+			health[best].ServiceName,
+			health[best].Score(),
+			health[0].ServiceName,
+			health[0].Score(),
+		)
+	}
+	decision.Service = s.services[best]
+	return decision
+}
+
+func (s *SchoolsServiceManager) setHealth(health map[string]ServiceHealth) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health = health
+}
+
+// updates every service manager with how their latest collections went
+func (o *Orchestrator) RefreshServiceHealth(ctx context.Context) error {
+	q := db.New(o.dbPool)
+	rows, err := q.GetServiceHealth(ctx, SERVICE_HEALTH_WINDOW)
+	if err != nil {
+		return fmt.Errorf("Could not get service health %w", err)
+	}
+	schoolIdToHealth := make(map[string]map[string]ServiceHealth)
+	for _, row := range rows {
+		if schoolIdToHealth[row.SchoolID] == nil {
+			schoolIdToHealth[row.SchoolID] = make(map[string]ServiceHealth)
+		}
+		schoolIdToHealth[row.SchoolID][row.ServiceName] = ServiceHealth{
+			ServiceName:          row.ServiceName,
+			Successes:            int(row.SuccessCount),
+			Failures:             int(row.FailureCount),
+			IncorrectAssumptions: int(row.IncorrectAssumptionCount),
+			ConsecutiveFailures:  int(row.ConsecutiveFailureCount),
+			AverageDuration:      time.Duration(row.AverageSuccessSeconds * float64(time.Second)),
+			LastFailure:          row.LastFailureTime.Time,
+		}
+	}
+
+	o.mappingsMu.RLock()
+	defer o.mappingsMu.RUnlock()
+	for schoolID, serviceManager := range o.schoolIdToServiceManager {
+		serviceManager.setHealth(schoolIdToHealth[schoolID])
+	}
+	return nil
+}
+
+type SchoolServiceDecision struct {
+	School   db.School
+	Decision ServiceDecision
+}
+
+// what each school's service manager would pick right now
+func (o *Orchestrator) GetServiceDecisions() []SchoolServiceDecision {
+	o.mappingsMu.RLock()
+	defer o.mappingsMu.RUnlock()
+	schoolIDs := slices.Sorted(maps.Keys(o.schoolIdToServiceManager))
+	decisions := make([]SchoolServiceDecision, len(schoolIDs))
+	for i, schoolID := range schoolIDs {
+		decisions[i] = SchoolServiceDecision{
+			School:   o.schoolIdToSchool[schoolID],
+			Decision: o.schoolIdToServiceManager[schoolID].Decide(),
+		}
+	}
+	return decisions
+}
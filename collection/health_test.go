@@ -0,0 +1,104 @@
+package collection
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	classentry "github.com/Pjt727/classy/data/class-entry"
+)
+
+type namedService struct{ name string }
+
+func (n namedService) GetName() string { return n.name }
+
+func (n namedService) ListValidSchools(slog.Logger, context.Context) ([]classentry.School, error) {
+	return nil, nil
+}
+
+func (n namedService) StageAllClasses(
+	slog.Logger,
+	context.Context,
+	*classentry.EntryQueries,
+	string,
+	classentry.TermCollection,
+	bool,
+) error {
+	return nil
+}
+
+func (n namedService) GetTermCollections(
+	slog.Logger,
+	context.Context,
+	classentry.School,
+) ([]classentry.TermCollection, error) {
+	return nil, nil
+}
+
+func TestServiceFailover(t *testing.T) {
+	now := time.Now()
+	serviceManager, err := NewServiceManager([]Service{namedService{"primary"}, namedService{"backup"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		health   map[string]ServiceHealth
+		expected string
+	}{
+		{
+			name:     "no history uses priority",
+			health:   nil,
+			expected: "primary",
+		},
+		{
+			name: "slightly healthier backup is not used",
+			health: map[string]ServiceHealth{
+				"primary": {Successes: 8, Failures: 2},
+				"backup":  {Successes: 10},
+			},
+			expected: "primary",
+		},
+		{
+			name: "broken primary fails over",
+			health: map[string]ServiceHealth{
+				"primary": {Successes: 10, Failures: 3, ConsecutiveFailures: 3, LastFailure: now.Add(-time.Minute)},
+				"backup":  {Successes: 1},
+			},
+			expected: "backup",
+		},
+		{
+			name: "broken primary is tried again after a while",
+			health: map[string]ServiceHealth{
+				"primary": {Successes: 10, Failures: 3, ConsecutiveFailures: 3, LastFailure: now.Add(-FAILOVER_RETRY_INTERVAL)},
+				"backup":  {Successes: 1},
+			},
+			expected: "primary",
+		},
+		{
+			name: "incorrect assumptions are worse than failures",
+			health: map[string]ServiceHealth{
+				"primary": {Successes: 5, Failures: 5, IncorrectAssumptions: 5},
+				"backup":  {Successes: 10},
+			},
+			expected: "backup",
+		},
+		{
+			name: "everything broken uses the healthiest",
+			health: map[string]ServiceHealth{
+				"primary": {Failures: 5, ConsecutiveFailures: 5, LastFailure: now},
+				"backup":  {Successes: 5, Failures: 3, ConsecutiveFailures: 3, LastFailure: now},
+			},
+			expected: "backup",
+		},
+	}
+	for _, test := range tests {
+		serviceManager.setHealth(test.health)
+		decision := serviceManager.decide(now)
+		if decision.Service.GetName() != test.expected {
+			t.Errorf("%s: expected %s got %s (%s)", test.name, test.expected, decision.Service.GetName(), decision.Reason)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package collection
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// advisory lock keys of the work only one process should do at a time
+const (
+	PLANNER_LOCK_KEY int64 = 727_0001
+	WATCHER_LOCK_KEY int64 = 727_0002
+)
+
+// how often a follower tries to become the leader and the leader checks it still is
+const LEADER_ELECTION_INTERVAL = 15 * time.Second
+
+// runs lead whenever this process holds the advisory lock until the context is done
+//
+//	the lock belongs to a held connection so when the process dies postgres releases it
+//	and another process takes over. lead's context is cancelled when the connection is lost
+func RunAsLeader(ctx context.Context, pool *pgxpool.Pool, key int64, logger *slog.Logger, lead func(ctx context.Context)) {
+	logger = logger.With("leaderLock", key)
+	for ctx.Err() == nil {
+		if err := leadWhileLocked(ctx, pool, key, logger, lead); err != nil && ctx.Err() == nil {
+			logger.Error("Could not take part in leader election", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(LEADER_ELECTION_INTERVAL):
+		}
+	}
+}
+
+func leadWhileLocked(ctx context.Context, pool *pgxpool.Pool, key int64, logger *slog.Logger, lead func(ctx context.Context)) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	q := db.New(conn)
+
+	acquired, err := q.TryAdvisoryLock(ctx, key)
+	if err != nil || !acquired {
+		return err
+	}
+	logger.Info("Became the leader")
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+
+	ticker := time.NewTicker(LEADER_ELECTION_INTERVAL)
+	defer ticker.Stop()
+	var lostErr error
+leading:
+	for {
+		select {
+		case <-done:
+			break leading
+		case <-ctx.Done():
+			break leading
+		case <-ticker.C:
+			// when the session is gone so is the lock
+			if err := conn.Ping(ctx); err != nil && ctx.Err() == nil {
+				lostErr = err
+				logger.Warn("Lost the leader connection", "error", err)
+				break leading
+			}
+		}
+	}
+	cancel()
+	<-done
+
+	if lostErr != nil {
+		// do not give a broken connection back to the pool
+		conn.Conn().Close(context.WithoutCancel(ctx))
+		return lostErr
+	}
+	unlockCtx, cancelUnlock := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancelUnlock()
+	if _, err := q.AdvisoryUnlock(unlockCtx, key); err != nil {
+		conn.Conn().Close(unlockCtx)
+		return err
+	}
+	logger.Info("Stepped down as the leader")
+	return nil
+}
@@ -5,14 +5,24 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/Pjt727/classy/collection/services"
 	"github.com/Pjt727/classy/collection/services/banner"
+	"github.com/Pjt727/classy/collection/services/fileimport"
+	"github.com/Pjt727/classy/collection/services/jsoncatalog"
+	"github.com/Pjt727/classy/collection/services/peoplesoft"
+	"github.com/Pjt727/classy/collection/webhooks"
 
 	classentry "github.com/Pjt727/classy/data/class-entry"
 	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -26,7 +36,7 @@ type Service interface {
 	// get the name of the service
 	GetName() string
 
-	// get the schools for this service (only called once at the start of the program)
+	// get the schools for this service (called again when the school registry changes)
 	ListValidSchools(
 		logger slog.Logger,
 		ctx context.Context,
@@ -55,7 +65,10 @@ type Service interface {
 
 // object responsible for which service should be used for a collection if not explicity stated
 type SchoolsServiceManager struct {
+	mu       sync.Mutex
 	services []Service
+	// service name -> how its latest collections for the school went
+	health map[string]ServiceHealth
 }
 
 func NewServiceManager(services []Service) (*SchoolsServiceManager, error) {
@@ -68,16 +81,20 @@ func NewServiceManager(services []Service) (*SchoolsServiceManager, error) {
 	}, nil
 }
 
-// eventually might be more sohpicated and ingest data about how collections went and whether the were sucessfull or not
+// the healthiest service based on how the latest collections went
 func (s *SchoolsServiceManager) GetService() Service {
-	return s.services[0]
+	return s.Decide().Service
 }
 
 func (s *SchoolsServiceManager) GetServices() []Service {
-	return s.services
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.services)
 }
 
 func (s *SchoolsServiceManager) AddSerivce(service Service) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.services = append(s.services, service)
 }
 
@@ -87,15 +104,24 @@ type Orchestrator struct {
 	//    collected by multiple workers at the same time
 	schoolIdToServiceManager map[string]*SchoolsServiceManager
 	schoolIdToSchool         map[string]db.School
-	orchestrationLogger      slog.Logger
-	dbPool                   *pgxpool.Pool
+	// the school registry can change the mappings while the orchestrator is running
+	mappingsMu *sync.RWMutex
+	// service name -> school id -> the registry entry the school was configured with
+	registeredSchools   map[string]map[string]db.SchoolRegistry
+	orchestrationLogger slog.Logger
+	dbPool              *pgxpool.Pool
 }
 
 var DefaultEnabledServices []Service
 
 func init() {
 	// might change to be determined by env variables or accesible resources
-	DefaultEnabledServices = []Service{banner.GetDefaultService()}
+	DefaultEnabledServices = []Service{
+		banner.GetDefaultService(),
+		peoplesoft.GetDefaultService(),
+		jsoncatalog.GetDefaultService(),
+		fileimport.GetDefaultService(),
+	}
 }
 
 func GetDefaultOrchestrator(pool *pgxpool.Pool) Orchestrator {
@@ -111,11 +137,17 @@ func GetDefaultOrchestrator(pool *pgxpool.Pool) Orchestrator {
 		serviceEntries:           serviceEntries,
 		schoolIdToServiceManager: make(map[string]*SchoolsServiceManager),
 		schoolIdToSchool:         make(map[string]db.School),
+		mappingsMu:               &sync.RWMutex{},
+		registeredSchools:        make(map[string]map[string]db.SchoolRegistry),
 		orchestrationLogger:      *logger,
 		dbPool:                   pool,
 	}
 
-	orchestrator.initMappings(ctx)
+	// schools come from the school registry
+	//    long running processes keep them up to date with WatchSchoolRegistry
+	if err := orchestrator.ReloadSchoolRegistry(ctx); err != nil {
+		logger.Error("Could not load all of the school registry", "error", err)
+	}
 
 	return orchestrator
 }
@@ -155,6 +187,8 @@ func CreateOrchestrator(
 		serviceEntries:           serviceEntries,
 		schoolIdToServiceManager: make(map[string]*SchoolsServiceManager),
 		schoolIdToSchool:         make(map[string]db.School),
+		mappingsMu:               &sync.RWMutex{},
+		registeredSchools:        make(map[string]map[string]db.SchoolRegistry),
 		orchestrationLogger:      *logger,
 		dbPool:                   pool,
 	}
@@ -164,7 +198,17 @@ func CreateOrchestrator(
 	return orchestrator, nil
 }
 
+// the mappings lock must be held
 func (o *Orchestrator) initMappings(ctx context.Context) {
+	schoolIdToServiceManager, schoolIdToSchool := o.listSchoolMappings(ctx)
+	maps.Copy(o.schoolIdToServiceManager, schoolIdToServiceManager)
+	maps.Copy(o.schoolIdToSchool, schoolIdToSchool)
+}
+
+// asks every service for its schools which can go over the network
+func (o *Orchestrator) listSchoolMappings(ctx context.Context) (map[string]*SchoolsServiceManager, map[string]db.School) {
+	schoolIdToServiceManager := make(map[string]*SchoolsServiceManager)
+	schoolIdToSchool := make(map[string]db.School)
 	for _, service := range o.serviceEntries {
 		serviceLogger := o.orchestrationLogger.With(slog.String("service", service.GetName()))
 		schools, err := service.ListValidSchools(*serviceLogger, ctx)
@@ -180,7 +224,7 @@ func (o *Orchestrator) initMappings(ctx context.Context) {
 		}
 
 		for _, school := range schools {
-			serviceManager, ok := o.schoolIdToServiceManager[school.ID]
+			serviceManager, ok := schoolIdToServiceManager[school.ID]
 			if ok {
 				serviceManager.AddSerivce(service)
 			} else {
@@ -189,11 +233,12 @@ func (o *Orchestrator) initMappings(ctx context.Context) {
 					serviceLogger.Warn("Skipping school to service mapping", "error", err)
 					continue
 				}
-				o.schoolIdToServiceManager[school.ID] = serviceManager
+				schoolIdToServiceManager[school.ID] = serviceManager
 			}
-			o.schoolIdToSchool[school.ID] = school
+			schoolIdToSchool[school.ID] = school
 		}
 	}
+	return schoolIdToServiceManager, schoolIdToSchool
 }
 
 type SchoolWithService struct {
@@ -202,6 +247,8 @@ type SchoolWithService struct {
 }
 
 func (o *Orchestrator) GetSchoolsWithService() []SchoolWithService {
+	o.mappingsMu.RLock()
+	defer o.mappingsMu.RUnlock()
 	schools := make([]SchoolWithService, 0)
 	for schoolId, serviceManager := range o.schoolIdToServiceManager {
 		for _, service := range serviceManager.GetServices() {
@@ -215,10 +262,23 @@ func (o *Orchestrator) GetSchoolsWithService() []SchoolWithService {
 }
 
 func (o *Orchestrator) GetSchoolById(schoolId string) (db.School, bool) {
+	o.mappingsMu.RLock()
+	defer o.mappingsMu.RUnlock()
 	school, ok := o.schoolIdToSchool[schoolId]
 	return school, ok
 }
 
+// the name of the service the school's collections would use right now
+func (o *Orchestrator) GetSchoolServiceName(schoolId string) (string, bool) {
+	o.mappingsMu.RLock()
+	defer o.mappingsMu.RUnlock()
+	serviceManager, ok := o.schoolIdToServiceManager[schoolId]
+	if !ok {
+		return "", false
+	}
+	return serviceManager.GetService().GetName(), true
+}
+
 func (o *Orchestrator) UpsertAllSchools(ctx context.Context) error {
 	tx, err := o.dbPool.Begin(ctx)
 	if err != nil {
@@ -226,7 +286,10 @@ func (o *Orchestrator) UpsertAllSchools(ctx context.Context) error {
 		return err
 	}
 	q := db.New(o.dbPool).WithTx(tx)
-	for _, school := range o.schoolIdToSchool {
+	o.mappingsMu.RLock()
+	schools := slices.Collect(maps.Values(o.schoolIdToSchool))
+	o.mappingsMu.RUnlock()
+	for _, school := range schools {
 		err = q.UpsertSchool(
 			ctx,
 			db.UpsertSchoolParams{
@@ -249,11 +312,14 @@ func (o *Orchestrator) UpsertSchoolTerms(
 	logger *slog.Logger,
 	school db.School,
 ) error {
+	o.mappingsMu.RLock()
 	serviceManager, ok := o.schoolIdToServiceManager[school.ID]
 	if !ok {
+		o.mappingsMu.RUnlock()
 		return fmt.Errorf("Do not know how to scrape %s. No service was found.", school.ID)
 	}
 	service := serviceManager.GetService()
+	o.mappingsMu.RUnlock()
 	err := o.UpsertSchoolTermsWithService(ctx, logger, school, service.GetName())
 	if err != nil {
 		return err
@@ -344,18 +410,25 @@ func (o *Orchestrator) UpsertSchoolTermsWithService(
 
 func (o Orchestrator) UpsertAllTerms(ctx context.Context) error {
 	var eg errgroup.Group
-	numberOfWorkers := len(o.schoolIdToServiceManager)
+	// the registry could change the mappings while terms are upserted
+	o.mappingsMu.RLock()
+	schoolIdToService := make(map[string]Service, len(o.schoolIdToServiceManager))
+	for schoolID, serviceManager := range o.schoolIdToServiceManager {
+		schoolIdToService[schoolID] = serviceManager.GetService()
+	}
+	schoolIdToSchool := maps.Clone(o.schoolIdToSchool)
+	o.mappingsMu.RUnlock()
+	numberOfWorkers := len(schoolIdToService)
 	o.orchestrationLogger.Info("Starting to add school's terms", slog.Int("schools", numberOfWorkers))
 
-	for schoolID, serviceManager := range o.schoolIdToServiceManager {
+	for schoolID, s := range schoolIdToService {
 		eg.Go(func() error {
-			s := serviceManager.GetService()
-			school := o.schoolIdToSchool[schoolID]
+			school := schoolIdToSchool[schoolID]
 			termLogger := o.orchestrationLogger.With(
 				slog.String("school_id", schoolID),
 				slog.String("service", s.GetName()),
 			)
-			if err := o.UpsertSchoolTerms(ctx, termLogger, school); err != nil {
+			if err := o.UpsertSchoolTermsWithService(ctx, termLogger, school, s.GetName()); err != nil {
 				termLogger.Error("There was an error collecting terms", "error", err)
 				return fmt.Errorf("error upserting terms for school %s: %s", schoolID, err)
 			}
@@ -378,6 +451,9 @@ type UpdateSectionsConfig struct {
 	serviceName      string
 	service          Service
 	logger           *slog.Logger
+	deletionGuard    DeletionGuard
+	dryRun           bool
+	validationRules  []ValidationRule
 }
 
 func DefualtUpdateSectionsConfig() UpdateSectionsConfig {
@@ -386,6 +462,9 @@ func DefualtUpdateSectionsConfig() UpdateSectionsConfig {
 		serviceName:      "",
 		service:          nil,
 		logger:           nil,
+		deletionGuard:    DefaultDeletionGuard(),
+		dryRun:           false,
+		validationRules:  DefaultValidationRules(),
 	}
 }
 
@@ -404,9 +483,29 @@ func (u UpdateSectionsConfig) SetLogger(logger *slog.Logger) UpdateSectionsConfi
 	return u
 }
 
+func (u UpdateSectionsConfig) SetDeletionGuard(deletionGuard DeletionGuard) UpdateSectionsConfig {
+	u.deletionGuard = deletionGuard
+	return u
+}
+
+func (u UpdateSectionsConfig) SetValidationRules(validationRules []ValidationRule) UpdateSectionsConfig {
+	u.validationRules = validationRules
+	return u
+}
+
+// stages the collection and reports what it would change without committing anything
+func (u UpdateSectionsConfig) SetDryRun(dryRun bool) UpdateSectionsConfig {
+	u.dryRun = dryRun
+	return u
+}
+
 // change values so they can be used in a collection
 // e.i. "" serviceName to the default service
 func (u *UpdateSectionsConfig) normalize(termCollection db.TermCollection, o *Orchestrator) error {
+	if u.logger == nil {
+		u.logger = slog.Default()
+	}
+
 	if u.serviceName != "" {
 		service, ok := o.serviceEntries[u.serviceName]
 		if !ok {
@@ -415,6 +514,8 @@ func (u *UpdateSectionsConfig) normalize(termCollection db.TermCollection, o *Or
 		u.service = service
 		return nil
 	}
+	o.mappingsMu.RLock()
+	defer o.mappingsMu.RUnlock()
 	serviceManager, ok := o.schoolIdToServiceManager[termCollection.SchoolID]
 	if !ok {
 		return fmt.Errorf("Could not find service manager for shool id %s", termCollection.SchoolID)
@@ -423,10 +524,6 @@ func (u *UpdateSectionsConfig) normalize(termCollection db.TermCollection, o *Or
 	u.serviceName = service.GetName()
 	u.service = service
 
-	if u.logger == nil {
-		u.logger = slog.Default()
-	}
-
 	return nil
 }
 
@@ -436,6 +533,10 @@ type CollectionResult struct {
 	Updated                 uint
 	Deleted                 uint
 	Duration                time.Duration
+	// staged records which broke a warning validation rule
+	Warnings int64
+	// only set for dry runs
+	Diff *CollectionDiff
 }
 
 func (c *CollectionResult) AreChanges() bool {
@@ -446,8 +547,16 @@ func (o *Orchestrator) UpdateAllSectionsOfSchool(
 	ctx context.Context,
 	termCollection db.TermCollection,
 	config UpdateSectionsConfig,
-) (CollectionResult, error) {
-	config.normalize(termCollection, o)
+) (result CollectionResult, err error) {
+	if err := config.normalize(termCollection, o); err != nil {
+		return CollectionResult{}, err
+	}
+	startTime := time.Now()
+	// keeps the logs of the collection so they can be looked at once it is done
+	runLog := &runLogRecorder{}
+	if !config.dryRun {
+		config.logger = withRunLog(config.logger, runLog)
+	}
 	updateLogger := config.logger.With(
 		slog.String("school_id", termCollection.SchoolID),
 		slog.String("season", string(termCollection.Season)),
@@ -455,25 +564,103 @@ func (o *Orchestrator) UpdateAllSectionsOfSchool(
 		slog.String("service", config.serviceName),
 		slog.String("termCollectionID", termCollection.ID),
 		slog.Bool("isFullCollection", config.isFullCollection),
+		slog.Bool("isDryRun", config.dryRun),
 	)
 
-	school := o.schoolIdToSchool[termCollection.SchoolID]
-
 	// inserting the new term collection attempt in the history
 	priviledgedQueryObject := db.New(o.dbPool)
+	historyStatus := db.TermCollectionStatusEnumActive
+	if config.dryRun {
+		// the diff would be of data which is about to change
+		if running, err := o.isCollecting(ctx, priviledgedQueryObject, termCollection); err != nil {
+			return CollectionResult{}, err
+		} else if running {
+			return CollectionResult{}, ErrTermCollecting
+		}
+		// dry runs are not active so they do not block the term's scheduled collections
+		historyStatus = db.TermCollectionStatusEnumDryRun
+	}
 	termCollectionHistoryID, err := priviledgedQueryObject.InsertTermCollectionHistory(ctx, db.InsertTermCollectionHistoryParams{
 		TermCollectionID: termCollection.ID,
 		SchoolID:         termCollection.SchoolID,
 		IsFull:           config.isFullCollection,
+		ServiceName:      pgtype.Text{String: config.serviceName, Valid: true},
+		Status:           historyStatus,
 	})
 
 	if err != nil {
 		return CollectionResult{}, fmt.Errorf("Could not start collection %w", err)
 	}
 
+	// the collection must still be finished and cleaned up when it is cancelled
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	if !config.dryRun {
+		// runs last so the logs of the other deferred cleanups are kept too
+		defer o.storeRunLog(cleanupCtx, termCollection, termCollectionHistoryID, runLog)
+	}
+
+	if config.dryRun {
+		// dry runs do not leave any history behind (runs after the staging tables are cleaned up)
+		defer func() {
+			if err := priviledgedQueryObject.DeleteTermCollectionHistory(cleanupCtx, termCollectionHistoryID); err != nil {
+				updateLogger.Error("Could not delete dry run history", "error", err)
+			}
+		}()
+	}
+
+	// the next collection of the school should know how this one went
+	defer func() {
+		if config.dryRun {
+			return
+		}
+		if err := o.RefreshServiceHealth(cleanupCtx); err != nil {
+			updateLogger.Error("Could not refresh service health", "error", err)
+		}
+	}()
+
+	if !config.dryRun {
+		o.emitCollectionEvent(ctx, updateLogger, collectionEvent{
+			kind:                    webhooks.CollectionStartedEvent,
+			termCollection:          termCollection,
+			termCollectionHistoryID: termCollectionHistoryID,
+			config:                  config,
+		})
+	}
+
+	// let the webhooks know how the collection went once everything else is done
+	defer func() {
+		if config.dryRun {
+			return
+		}
+		event := collectionEvent{
+			kind:                    webhooks.CollectionSucceededEvent,
+			termCollection:          termCollection,
+			termCollectionHistoryID: termCollectionHistoryID,
+			config:                  config,
+			result:                  result,
+			duration:                result.Duration,
+			err:                     err,
+		}
+		if err != nil {
+			event.kind = webhooks.CollectionFailedEvent
+			if errors.Is(err, services.ErrIncorrectAssumption) {
+				event.kind = webhooks.CollectionIncorrectAssumptionEvent
+			} else if errors.Is(err, ErrMassDeletion) {
+				event.kind = webhooks.CollectionQuarantinedEvent
+			}
+			event.duration = time.Since(startTime)
+		}
+		o.emitCollectionEvent(cleanupCtx, updateLogger, event)
+	}()
+
 	// cleanup staging tables so they do not baloon in size
 	defer func() {
-		err = cleanupStagingTables(ctx, priviledgedQueryObject, termCollectionHistoryID)
+		// quarantined collections keep their staged data until they are approved or rejected
+		if errors.Is(err, ErrMassDeletion) {
+			return
+		}
+		err := cleanupStagingTables(cleanupCtx, priviledgedQueryObject, termCollectionHistoryID)
 		if err != nil {
 			updateLogger.Error(
 				"Could not clean up tables for collection",
@@ -488,17 +675,29 @@ func (o *Orchestrator) UpdateAllSectionsOfSchool(
 	defer func() {
 		// there were no errors so assume the collection was sucessful
 		collectionErr := err
-		if collectionErr == nil {
+		// dry runs do not leave any history behind to finish
+		if collectionErr == nil || config.dryRun {
 			return
 		}
 		q := db.New(o.dbPool)
 
-		err = q.FinishTermCollectionHistory(ctx, db.FinishTermCollectionHistoryParams{
-			NewFinishedStatus:       db.TermCollectionStatusEnumFailure,
+		status := db.TermCollectionStatusEnumFailure
+		failureReason := collectionErr.Error()
+		// the service is not at fault for shutdowns and timeouts
+		if errors.Is(collectionErr, ErrMassDeletion) {
+			status = db.TermCollectionStatusEnumQuarantined
+		} else if ctx.Err() != nil {
+			status = db.TermCollectionStatusEnumCancelled
+			failureReason = fmt.Sprintf("%v: %s", context.Cause(ctx), failureReason)
+		}
+		err := q.FinishTermCollectionHistory(cleanupCtx, db.FinishTermCollectionHistoryParams{
+			NewFinishedStatus:       status,
 			TermCollectionHistoryID: termCollectionHistoryID,
 			InsertedRecordsCount:    0,
 			UpdatedRecordsCount:     0,
 			DeletedRecordsCount:     0,
+			IsIncorrectAssumption:   errors.Is(collectionErr, services.ErrIncorrectAssumption),
+			FailureReason:           pgtype.Text{String: failureReason, Valid: true},
 		})
 		if err != nil {
 			updateLogger.Error(
@@ -510,6 +709,11 @@ func (o *Orchestrator) UpdateAllSectionsOfSchool(
 		}
 	}()
 
+	// lets the reaper know this collection is still running
+	heartbeatCtx, stopHeartbeat := context.WithCancel(cleanupCtx)
+	defer stopHeartbeat()
+	go o.beatUntilDone(heartbeatCtx, updateLogger, termCollectionHistoryID)
+
 	classEntryTermCollection := classentry.TermCollection{
 		ID: termCollection.ID,
 		Term: classentry.Term{
@@ -522,47 +726,109 @@ func (o *Orchestrator) UpdateAllSectionsOfSchool(
 
 	// do the actual collection with the service
 	entryQ := classentry.NewEntryQuery(o.dbPool, termCollection.SchoolID, &termCollection.ID, &termCollectionHistoryID)
-	if err := (config.service).StageAllClasses(
+	if err = (config.service).StageAllClasses(
 		*updateLogger,
 		ctx,
 		entryQ,
-		school.ID,
+		termCollection.SchoolID,
 		classEntryTermCollection,
 		config.isFullCollection,
 	); err != nil {
 		return CollectionResult{}, fmt.Errorf("Could not stage with service %w", err)
 	}
 
+	warnings, err := o.validateStagedCollection(ctx, updateLogger, termCollectionHistoryID, config.validationRules)
+	// dry runs report the blocking violations with the rest of their diff
+	if err != nil && !(config.dryRun && errors.Is(err, ErrInvalidStagedData)) {
+		return CollectionResult{}, err
+	}
+
+	if config.dryRun {
+		result, err = o.dryRunStagedCollection(ctx, updateLogger, termCollection, termCollectionHistoryID, config.deletionGuard, err)
+		result.Duration = time.Since(startTime)
+		result.Warnings = warnings
+		return result, err
+	}
+
+	// a truncated collection would wipe the term for every sync client
+	if err = o.guardDeletions(ctx, updateLogger, termCollection, termCollectionHistoryID, config.deletionGuard); err != nil {
+		return CollectionResult{}, err
+	}
+
 	tx, err := o.dbPool.Begin(ctx)
 	if err != nil {
 		return CollectionResult{}, fmt.Errorf("Could not begin move staging transcation %w", err)
 	}
-	// this transcation should be rollback by the above defer (cannot rollback here bc defers are stacks)
+	// the history row is locked by this transaction so it has to be rolled back before the
+	//    above defer can mark the collection as failed (does nothing once committed)
+	defer tx.Rollback(cleanupCtx)
+
+	collectionResult, err := publishStagedCollection(ctx, updateLogger, tx, termCollection, termCollectionHistoryID)
+	if err != nil {
+		return CollectionResult{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		updateLogger.Error("Failed commiting move class data transacation", "error", err)
+		return CollectionResult{}, fmt.Errorf("Failed commiting move class data transcation %w", err)
+	}
 
+	o.supersedeQuarantinedCollections(cleanupCtx, updateLogger, termCollection, termCollectionHistoryID)
+	collectionResult.Warnings = warnings
+	return collectionResult, nil
+}
+
+// moves the staged data of the collection into the live tables and finishes it as a success
+func publishStagedCollection(
+	ctx context.Context,
+	logger *slog.Logger,
+	tx pgx.Tx,
+	termCollection db.TermCollection,
+	termCollectionHistoryID int32,
+) (CollectionResult, error) {
 	// setting this variable so triggers are aware of the collection class information is coming from
 	// this did not work using sqlc for some reason
-	if _, err = tx.Exec(ctx, fmt.Sprintf("SET LOCAL app.term_collection_history_id = '%d';", termCollectionHistoryID)); err != nil {
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL app.term_collection_history_id = '%d';", termCollectionHistoryID)); err != nil {
 		return CollectionResult{}, fmt.Errorf("Could not set term_collection_history_id postgres variable %w", err)
 	}
 
-	q := db.New(o.dbPool)
-	q = q.WithTx(tx)
-	err = moveStagedTables(ctx, q, termCollection, termCollectionHistoryID)
+	q := db.New(tx)
+	err := moveStagedTables(ctx, q, termCollection, termCollectionHistoryID)
 	if err != nil {
 		return CollectionResult{}, fmt.Errorf("Failed moving staged classes %w", err)
 	}
 
+	// must happen before this collection's snapshot so openings compare against the last one
+	seatOpenings, err := webhooks.EnqueueSeatOpenings(ctx, q, termCollection, termCollectionHistoryID)
+	if err != nil {
+		return CollectionResult{}, fmt.Errorf("Failed queueing seat opening webhooks %w", err)
+	}
+	if seatOpenings > 0 {
+		logger.Info("Queued seat opening webhooks", "count", seatOpenings)
+	}
+
+	// keep the changed enrollment numbers of this collection so they can be looked at over time
+	err = q.SnapshotSectionEnrollment(ctx, db.SnapshotSectionEnrollmentParams{
+		TermCollectionHistoryID: termCollectionHistoryID,
+		SchoolID:                termCollection.SchoolID,
+		TermCollectionID:        termCollection.ID,
+	})
+	if err != nil {
+		return CollectionResult{}, fmt.Errorf("Failed snapshotting section enrollment %w", err)
+	}
+
 	// now that the transaction is committed the historic data table should be populated by the AFTER
 	// triggers telling us what information was changed
 	// knowing the updated, inserted, deleted data numbers might be helpful to inform automatic scheduling
 	//    of collections and it is also nice to have for logging
 	changeInformation, err := q.GetChangesFromMoveTermCollection(ctx, termCollectionHistoryID)
 	if err != nil {
-		updateLogger.Error("Could not query changed data", "error", err)
+		logger.Error("Could not query changed data", "error", err)
 		return CollectionResult{}, err
 	}
 	duration := time.Duration(changeInformation.ElapsedTime.Microseconds * time.Microsecond.Nanoseconds())
-	updateLogger.Info("duration", "micros", changeInformation.ElapsedTime.Microseconds)
+	logger.Info("duration", "micros", changeInformation.ElapsedTime.Microseconds)
 	collectionResult := CollectionResult{
 		Inserted:                uint(changeInformation.InsertRecords),
 		Updated:                 uint(changeInformation.UpdatedRecords),
@@ -584,14 +850,23 @@ func (o *Orchestrator) UpdateAllSectionsOfSchool(
 			err,
 		)
 	}
+	return collectionResult, nil
+}
 
-	err = tx.Commit(ctx)
-	if err != nil {
-		updateLogger.Error("Failed commiting move class data transacation", "error", err)
-		return CollectionResult{}, fmt.Errorf("Failed commiting move class data transcation %w", err)
+func (o *Orchestrator) beatUntilDone(ctx context.Context, logger *slog.Logger, termCollectionHistoryID int32) {
+	q := db.New(o.dbPool)
+	ticker := time.NewTicker(COLLECTION_HEARTBEAT_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.BeatTermCollectionHistory(ctx, termCollectionHistoryID); err != nil && ctx.Err() == nil {
+				logger.Warn("Could not beat term collection history", "error", err)
+			}
+		}
 	}
-
-	return collectionResult, nil
 }
 
 // these are all active collections not just ones for this orchestrator
@@ -675,15 +950,17 @@ func moveStagedTables(
 	termCollectionHistoryID int32,
 ) error {
 	err := q.RemoveUnstagedMeetings(ctx, db.RemoveUnstagedMeetingsParams{
-		TermCollectionID: termCollection.ID,
-		SchoolID:         termCollection.SchoolID,
+		TermCollectionID:        termCollection.ID,
+		SchoolID:                termCollection.SchoolID,
+		TermCollectionHistoryID: termCollectionHistoryID,
 	})
 	if err != nil {
 		return fmt.Errorf("error unstaging meeting %v", err)
 	}
 	err = q.RemoveUnstagedSections(ctx, db.RemoveUnstagedSectionsParams{
-		TermCollectionID: termCollection.ID,
-		SchoolID:         termCollection.SchoolID,
+		TermCollectionID:        termCollection.ID,
+		SchoolID:                termCollection.SchoolID,
+		TermCollectionHistoryID: termCollectionHistoryID,
 	})
 	if err != nil {
 		return fmt.Errorf("error unstaging sections %v", err)
@@ -0,0 +1,175 @@
+package collection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// how often the planner checks for schedules which are due
+const PLANNING_INTERVAL = time.Minute
+
+// turns the recurring collection schedules into collection jobs
+//
+//	runs which were missed while no planner was running are collected once
+type Planner struct {
+	dbPool *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewPlanner(pool *pgxpool.Pool, logger *slog.Logger) Planner {
+	return Planner{
+		dbPool: pool,
+		logger: logger,
+	}
+}
+
+// checks the cron and timezone of a schedule
+func ValidateCollectionSchedule(cron string, timezone string) error {
+	if _, err := ParseCron(cron); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("Unknown timezone `%s`", timezone)
+	}
+	return nil
+}
+
+// the first run of the schedule after the given time
+//
+//	the zero time when the cron never matches
+func NextScheduledCollection(schedule db.CollectionSchedule, after time.Time) (time.Time, error) {
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Unknown timezone `%s`", schedule.Timezone)
+	}
+	return cron.Next(after.In(loc)), nil
+}
+
+// the run of the schedule which has not been planned yet
+func nextUnplannedCollection(schedule db.CollectionSchedule) (time.Time, error) {
+	since := schedule.CreatedAt.Time
+	if schedule.LastPlannedAt.Valid {
+		since = schedule.LastPlannedAt.Time
+	}
+	return NextScheduledCollection(schedule, since)
+}
+
+// plans collections until the context is done
+func (p *Planner) Run(ctx context.Context) {
+	ticker := time.NewTicker(PLANNING_INTERVAL)
+	defer ticker.Stop()
+	for {
+		if _, err := p.PlanDueCollections(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			p.logger.Error("Could not plan scheduled collections", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// adds collection jobs for every schedule which is due
+//
+//	returns how many jobs were added
+func (p *Planner) PlanDueCollections(ctx context.Context, now time.Time) (int, error) {
+	q := db.New(p.dbPool)
+	schedules, err := q.GetEnabledCollectionSchedules(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("Could not get collection schedules %w", err)
+	}
+	jobCount := 0
+	var errs []error
+	for _, schedule := range schedules {
+		due, err := nextUnplannedCollection(schedule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %d: %w", schedule.ID, err))
+			continue
+		}
+		if due.IsZero() || due.After(now) {
+			continue
+		}
+		added, err := p.planSchedule(ctx, schedule, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %d: %w", schedule.ID, err))
+			continue
+		}
+		jobCount += added
+	}
+	return jobCount, errors.Join(errs...)
+}
+
+func (p *Planner) planSchedule(ctx context.Context, schedule db.CollectionSchedule, now time.Time) (int, error) {
+	logger := p.logger.With("schedule", schedule.ID, "school", schedule.SchoolID, "cron", schedule.Cron)
+	tx, err := p.dbPool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	q := db.New(p.dbPool).WithTx(tx)
+
+	claimed, err := q.ClaimCollectionSchedule(ctx, db.ClaimCollectionScheduleParams{
+		PlannedAt:         pgtype.Timestamptz{Time: now, Valid: true},
+		ID:                schedule.ID,
+		PreviousPlannedAt: schedule.LastPlannedAt,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Could not claim schedule %w", err)
+	}
+	if claimed == 0 {
+		logger.Debug("Schedule was planned by another planner")
+		return 0, nil
+	}
+
+	var termCollections []db.TermCollection
+	if schedule.TermCollectionID.Valid {
+		termCollections = append(termCollections, db.TermCollection{
+			ID:       schedule.TermCollectionID.String,
+			SchoolID: schedule.SchoolID,
+		})
+	} else {
+		termCollections, err = q.GetStillCollectingTermCollections(ctx, schedule.SchoolID)
+		if err != nil {
+			return 0, fmt.Errorf("Could not get still collecting terms %w", err)
+		}
+	}
+
+	for _, termCollection := range termCollections {
+		messageBytes, err := json.Marshal(CollectionMessage{
+			TermCollectionID: termCollection.ID,
+			SchoolID:         termCollection.SchoolID,
+			ServiceName:      schedule.ServiceName,
+			IsFullCollection: pgtype.Bool{Bool: schedule.IsFullCollection, Valid: true},
+			ScheduleID:       pgtype.Int4{Int32: schedule.ID, Valid: true},
+		})
+		if err != nil {
+			return 0, err
+		}
+		err = q.AddToQueue(ctx, db.AddToQueueParams{
+			QueueName:             SECTIONS_OF_TERM_COLLECTIONS,
+			Message:               messageBytes,
+			SecondsUntilAvailable: 0,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	logger.Info("Planned scheduled collections", "jobs", len(termCollections))
+	return len(termCollections), nil
+}
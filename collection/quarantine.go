@@ -0,0 +1,209 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5"
+)
+
+// moves which would delete more than this percent of a term's sections are quarantined
+const MAX_DELETED_SECTIONS_PERCENT = 20.0
+
+// terms with fewer sections than this are small enough for any amount of them to be deleted
+const MIN_GUARDED_SECTIONS = 25
+
+var ErrMassDeletion = errors.New("Collection would delete too many sections")
+
+var ErrQuarantineDecided = errors.New("Quarantined collection was already decided")
+
+var ErrTermCollecting = errors.New("The term is being collected right now try again once it finishes")
+
+// decides whether a collection deletes too much of its term to be moved without an operator
+type DeletionGuard struct {
+	// percent of the term's live sections a collection can delete
+	MaxDeletedPercent float64
+	// terms with fewer live sections than this are not guarded
+	MinLiveSections int64
+}
+
+func DefaultDeletionGuard() DeletionGuard {
+	return DeletionGuard{
+		MaxDeletedPercent: MAX_DELETED_SECTIONS_PERCENT,
+		MinLiveSections:   MIN_GUARDED_SECTIONS,
+	}
+}
+
+// lets every collection through
+var NoDeletionGuard = DeletionGuard{MaxDeletedPercent: 100}
+
+func (g DeletionGuard) Allows(liveSections int64, deletedSections int64) bool {
+	if liveSections == 0 || liveSections < g.MinLiveSections {
+		return true
+	}
+	return float64(deletedSections)/float64(liveSections)*100 <= g.MaxDeletedPercent
+}
+
+// quarantines the collection when moving it would delete more of the term than the guard allows
+func (o *Orchestrator) guardDeletions(
+	ctx context.Context,
+	logger *slog.Logger,
+	termCollection db.TermCollection,
+	termCollectionHistoryID int32,
+	guard DeletionGuard,
+) error {
+	q := db.New(o.dbPool)
+	counts, err := q.CountUnstagedSections(ctx, db.CountUnstagedSectionsParams{
+		TermCollectionHistoryID: termCollectionHistoryID,
+		TermCollectionID:        termCollection.ID,
+		SchoolID:                termCollection.SchoolID,
+	})
+	if err != nil {
+		return fmt.Errorf("Could not count the sections the collection would delete %w", err)
+	}
+	if guard.Allows(counts.LiveSections, counts.UnstagedSections) {
+		return nil
+	}
+
+	logger.Warn(
+		"Quarantining collection which would delete too many sections",
+		"liveSections", counts.LiveSections,
+		"deletedSections", counts.UnstagedSections,
+	)
+	err = q.InsertQuarantinedCollection(ctx, db.InsertQuarantinedCollectionParams{
+		TermCollectionHistoryID: termCollectionHistoryID,
+		TermCollectionID:        termCollection.ID,
+		SchoolID:                termCollection.SchoolID,
+		LiveSections:            int32(counts.LiveSections),
+		DeletedSections:         int32(counts.UnstagedSections),
+	})
+	if err != nil {
+		return fmt.Errorf("Could not quarantine collection %w", err)
+	}
+	// only the newest quarantined collection of a term can be approved
+	o.supersedeQuarantinedCollections(ctx, logger, termCollection, termCollectionHistoryID)
+
+	return fmt.Errorf(
+		"%w: %d of %d sections (over %.0f%%) it is quarantined until it is approved",
+		ErrMassDeletion,
+		counts.UnstagedSections,
+		counts.LiveSections,
+		guard.MaxDeletedPercent,
+	)
+}
+
+// quarantined collections from before the given collection would overwrite newer data
+func (o *Orchestrator) supersedeQuarantinedCollections(
+	ctx context.Context,
+	logger *slog.Logger,
+	termCollection db.TermCollection,
+	termCollectionHistoryID int32,
+) {
+	q := db.New(o.dbPool)
+	superseded, err := q.SupersedeQuarantinedCollections(ctx, db.SupersedeQuarantinedCollectionsParams{
+		SchoolID:                termCollection.SchoolID,
+		TermCollectionID:        termCollection.ID,
+		TermCollectionHistoryID: termCollectionHistoryID,
+	})
+	if err != nil {
+		logger.Error("Could not supersede quarantined collections", "error", err)
+		return
+	}
+	for _, supersededID := range superseded {
+		if err := cleanupStagingTables(ctx, q, supersededID); err != nil {
+			logger.Error("Could not clean up tables for superseded collection", "termCollectionHistoryID", supersededID, "error", err)
+		}
+	}
+}
+
+// moves the staged data of a quarantined collection as if it had passed the guard
+func (o *Orchestrator) ApproveQuarantinedCollection(ctx context.Context, termCollectionHistoryID int32) (CollectionResult, error) {
+	logger := o.orchestrationLogger.With("termCollectionHistoryID", termCollectionHistoryID)
+	tx, err := o.dbPool.Begin(ctx)
+	if err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not begin approval transaction %w", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+	q := db.New(tx)
+
+	// locked so the collection cannot be approved twice
+	quarantined, err := q.GetQuarantinedCollectionForUpdate(ctx, termCollectionHistoryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return CollectionResult{}, fmt.Errorf("There is no quarantined collection %d", termCollectionHistoryID)
+	} else if err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not get quarantined collection %w", err)
+	}
+	if quarantined.Status != db.QuarantineStatusEnumPending {
+		return CollectionResult{}, fmt.Errorf("%w: it is %s", ErrQuarantineDecided, quarantined.Status)
+	}
+	termCollection, err := q.GetTermCollection(ctx, db.GetTermCollectionParams{
+		ID:       quarantined.TermCollectionID,
+		SchoolID: quarantined.SchoolID,
+	})
+	if err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not get term collection %w", err)
+	}
+	if running, err := o.isCollecting(ctx, q, termCollection); err != nil {
+		return CollectionResult{}, err
+	} else if running {
+		return CollectionResult{}, ErrTermCollecting
+	}
+
+	_, err = q.DecideQuarantinedCollection(ctx, db.DecideQuarantinedCollectionParams{
+		Status:                  db.QuarantineStatusEnumApproved,
+		TermCollectionHistoryID: termCollectionHistoryID,
+	})
+	if err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not approve quarantined collection %w", err)
+	}
+	result, err := publishStagedCollection(ctx, logger, tx, termCollection, termCollectionHistoryID)
+	if err != nil {
+		return CollectionResult{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return CollectionResult{}, fmt.Errorf("Could not commit approved collection %w", err)
+	}
+	logger.Info("Approved quarantined collection", "deleted", result.Deleted)
+
+	cleanupCtx := context.WithoutCancel(ctx)
+	o.supersedeQuarantinedCollections(cleanupCtx, logger, termCollection, termCollectionHistoryID)
+	if err := cleanupStagingTables(cleanupCtx, db.New(o.dbPool), termCollectionHistoryID); err != nil {
+		logger.Error("Could not clean up tables for approved collection", "error", err)
+	}
+	return result, nil
+}
+
+// throws away the staged data of a quarantined collection
+func (o *Orchestrator) RejectQuarantinedCollection(ctx context.Context, termCollectionHistoryID int32) error {
+	q := db.New(o.dbPool)
+	rejected, err := q.DecideQuarantinedCollection(ctx, db.DecideQuarantinedCollectionParams{
+		Status:                  db.QuarantineStatusEnumRejected,
+		TermCollectionHistoryID: termCollectionHistoryID,
+	})
+	if err != nil {
+		return fmt.Errorf("Could not reject quarantined collection %w", err)
+	}
+	if rejected == 0 {
+		return ErrQuarantineDecided
+	}
+	if err := cleanupStagingTables(ctx, q, termCollectionHistoryID); err != nil {
+		return fmt.Errorf("Could not clean up tables for rejected collection %w", err)
+	}
+	return nil
+}
+
+func (o *Orchestrator) isCollecting(ctx context.Context, q *db.Queries, termCollection db.TermCollection) (bool, error) {
+	running, err := q.GetActiveTermCollections(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Could not get running collections %w", err)
+	}
+	for _, runningTermCollection := range running {
+		if runningTermCollection.ID == termCollection.ID && runningTermCollection.SchoolID == termCollection.SchoolID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
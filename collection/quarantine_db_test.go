@@ -0,0 +1,168 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/Pjt727/classy/data"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/Pjt727/classy/data/testdb"
+)
+
+// stages however many sections it is told to so a collection can be cut short
+type truncatingService struct {
+	sections int
+}
+
+func (s *truncatingService) GetName() string { return "Truncating" }
+
+func (s *truncatingService) ListValidSchools(logger slog.Logger, ctx context.Context) ([]classentry.School, error) {
+	return []classentry.School{{ID: "truncating", Name: "Truncating University"}}, nil
+}
+
+func (s *truncatingService) GetTermCollections(
+	logger slog.Logger,
+	ctx context.Context,
+	school classentry.School,
+) ([]classentry.TermCollection, error) {
+	return []classentry.TermCollection{{
+		ID:              "2025SP",
+		Term:            classentry.Term{Year: 2025, Season: classentry.SeasonEnumSpring},
+		StillCollecting: true,
+	}}, nil
+}
+
+func (s *truncatingService) StageAllClasses(
+	logger slog.Logger,
+	ctx context.Context,
+	q *classentry.EntryQueries,
+	schoolID string,
+	termCollection classentry.TermCollection,
+	fullCollection bool,
+) error {
+	classData := classentry.ClassData{
+		Courses: []classentry.Course{{SubjectCode: "CMPT", Number: "101", CreditHours: 3}},
+	}
+	for i := range s.sections {
+		classData.Sections = append(classData.Sections, classentry.Section{
+			Sequence:     fmt.Sprint(i),
+			SubjectCode:  "CMPT",
+			CourseNumber: "101",
+		})
+	}
+	return q.InsertClassData(&logger, ctx, classData)
+}
+
+func TestQuarantinedCollections(t *testing.T) {
+	if err := testdb.SetupTestDb(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	pool, err := data.NewPool(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.Default()
+	service := &truncatingService{sections: 30}
+	orchestrator, err := CreateOrchestrator([]Service{service}, logger, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	school := classentry.School{ID: "truncating", Name: "Truncating University"}
+	if err := orchestrator.UpsertSchoolTermsWithService(ctx, logger, school, service.GetName()); err != nil {
+		t.Fatal(err)
+	}
+	termCollection := db.TermCollection{
+		ID:              "2025SP",
+		SchoolID:        school.ID,
+		Year:            2025,
+		Season:          db.SeasonEnumSpring,
+		StillCollecting: true,
+	}
+	config := DefualtUpdateSectionsConfig().SetServiceName(service.GetName()).SetFullCollection(true)
+	q := db.New(pool)
+
+	if _, err := orchestrator.UpdateAllSectionsOfSchool(ctx, termCollection, config); err != nil {
+		t.Fatal(err)
+	}
+
+	quarantine := func() int32 {
+		t.Helper()
+		_, err := orchestrator.UpdateAllSectionsOfSchool(ctx, termCollection, config)
+		if !errors.Is(err, ErrMassDeletion) {
+			t.Fatalf("expected the truncated collection to be quarantined got %v", err)
+		}
+		pending, err := q.ListPendingQuarantinedCollections(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 || pending[0].DeletedSections != 25 || pending[0].LiveSections != 30 {
+			t.Fatalf("expected one pending quarantine deleting 25 of 30 sections got %+v", pending)
+		}
+		return pending[0].TermCollectionHistoryID
+	}
+	unstagedSections := func(termCollectionHistoryID int32) int64 {
+		t.Helper()
+		counts, err := q.CountUnstagedSections(ctx, db.CountUnstagedSectionsParams{
+			TermCollectionHistoryID: termCollectionHistoryID,
+			TermCollectionID:        termCollection.ID,
+			SchoolID:                termCollection.SchoolID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return counts.UnstagedSections
+	}
+
+	service.sections = 5
+	first := quarantine()
+	if unstaged := unstagedSections(first); unstaged != 25 {
+		t.Errorf("expected the quarantined collection to keep its staged sections got %d unstaged", unstaged)
+	}
+
+	second := quarantine()
+	if unstaged := unstagedSections(first); unstaged != 30 {
+		t.Errorf("expected the superseded collection's staged sections to be cleaned up got %d unstaged", unstaged)
+	}
+	if _, err := orchestrator.ApproveQuarantinedCollection(ctx, first); !errors.Is(err, ErrQuarantineDecided) {
+		t.Errorf("expected the superseded collection to not be approvable got %v", err)
+	}
+
+	if err := orchestrator.RejectQuarantinedCollection(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if err := orchestrator.RejectQuarantinedCollection(ctx, second); !errors.Is(err, ErrQuarantineDecided) {
+		t.Errorf("expected the rejected collection to not be rejected twice got %v", err)
+	}
+
+	third := quarantine()
+	result, err := orchestrator.ApproveQuarantinedCollection(ctx, third)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Deleted == 0 {
+		t.Error("expected the approved collection to delete the missing sections")
+	}
+	if _, err := orchestrator.ApproveQuarantinedCollection(ctx, third); !errors.Is(err, ErrQuarantineDecided) {
+		t.Errorf("expected the approved collection to not be approved twice got %v", err)
+	}
+	if unstaged := unstagedSections(third); unstaged != 5 {
+		t.Errorf("expected only the approved collection's sections to be left got %d", unstaged)
+	}
+
+	history, err := q.GetRecentTermCollectionHistory(ctx, db.GetRecentTermCollectionHistoryParams{
+		SchoolID:         termCollection.SchoolID,
+		TermCollectionID: termCollection.ID,
+		LimitCount:       1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Status != db.TermCollectionStatusEnumQuarantined {
+		t.Errorf("expected the quarantined collection's history to be quarantined got %+v", history)
+	}
+}
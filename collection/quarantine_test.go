@@ -0,0 +1,43 @@
+package collection
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Pjt727/classy/collection/webhooks"
+)
+
+func TestDeletionGuard(t *testing.T) {
+	guard := DefaultDeletionGuard()
+	tests := []struct {
+		live    int64
+		deleted int64
+		allowed bool
+	}{
+		{0, 0, true},
+		// small terms are not guarded
+		{MIN_GUARDED_SECTIONS - 1, MIN_GUARDED_SECTIONS - 1, true},
+		{100, 20, true},
+		{100, 21, false},
+		{1000, 1000, false},
+	}
+	for _, test := range tests {
+		if allowed := guard.Allows(test.live, test.deleted); allowed != test.allowed {
+			t.Errorf("deleting %d of %d sections expected allowed %v got %v", test.deleted, test.live, test.allowed, allowed)
+		}
+	}
+	if !NoDeletionGuard.Allows(1000, 1000) {
+		t.Error("expected no deletion guard to allow deleting every section")
+	}
+}
+
+func TestQuarantinedCollectionErrorClass(t *testing.T) {
+	err := fmt.Errorf("Could not move staged data %w", ErrMassDeletion)
+	if class := classifyCollectionError(err); class != webhooks.ErrorClassQuarantined {
+		t.Errorf("expected a quarantined collection to be classified as quarantined got %s", class)
+	}
+	if class := classifyCollectionError(context.Canceled); class != webhooks.ErrorClassCanceled {
+		t.Errorf("expected other errors to be classified by the webhooks got %s", class)
+	}
+}
@@ -0,0 +1,90 @@
+package collection
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// how often running collections mark that they are still alive
+const COLLECTION_HEARTBEAT_INTERVAL = 30 * time.Second
+
+// active collections which have not had a heartbeat for this long are assumed to be orphaned
+//
+//	long enough that a slow database does not fail collections which are still running
+const ORPHANED_COLLECTION_AGE = 10 * COLLECTION_HEARTBEAT_INTERVAL
+
+// how often the leader looks for orphaned collections and prunes old logs
+const REAPING_INTERVAL = 5 * time.Minute
+
+// enrollment snapshots older than this are pruned unless they are still a section's latest one
+//
+//	fill rates from before this only count the sections which have not changed since
+const ENROLLMENT_SNAPSHOT_RETENTION = 365 * 24 * time.Hour
+
+// fails the active collections whose process died without finishing them
+//
+//	they would otherwise block every future collection of their term
+func ReapOrphanedCollections(ctx context.Context, pool *pgxpool.Pool, logger *slog.Logger) (int, error) {
+	q := db.New(pool)
+	orphans, err := q.ReapOrphanedTermCollectionHistory(ctx, db.ReapOrphanedTermCollectionHistoryParams{
+		FailureReason: fmt.Sprintf("Orphaned: no heartbeat for over %s", ORPHANED_COLLECTION_AGE),
+		StaleSeconds:  int32(ORPHANED_COLLECTION_AGE.Seconds()),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Could not reap orphaned collections %w", err)
+	}
+	for _, orphan := range orphans {
+		logger.Warn(
+			"Released orphaned collection",
+			"termCollectionHistoryID", orphan.ID,
+			"school_id", orphan.SchoolID,
+			"termCollectionID", orphan.TermCollectionID,
+		)
+		if err := cleanupStagingTables(ctx, q, orphan.ID); err != nil {
+			logger.Error("Could not clean up tables for orphaned collection", "termCollectionHistoryID", orphan.ID, "error", err)
+		}
+	}
+	return len(orphans), nil
+}
+
+// deletes the enrollment snapshots which are past the retention limit
+func PruneEnrollmentSnapshots(ctx context.Context, pool *pgxpool.Pool, logger *slog.Logger) error {
+	expired, err := db.New(pool).DeleteExpiredEnrollmentSnapshots(ctx, int32(ENROLLMENT_SNAPSHOT_RETENTION.Seconds()))
+	if err != nil {
+		return fmt.Errorf("Could not delete expired enrollment snapshots %w", err)
+	}
+	if expired > 0 {
+		logger.Info("Pruned enrollment snapshots", "expired", expired)
+	}
+	return nil
+}
+
+// reaps orphaned collections and prunes logs until the context is done
+func RunReaper(ctx context.Context, pool *pgxpool.Pool, logger *slog.Logger) {
+	ticker := time.NewTicker(REAPING_INTERVAL)
+	defer ticker.Stop()
+	for {
+		if _, err := ReapOrphanedCollections(ctx, pool, logger); err != nil && ctx.Err() == nil {
+			logger.Error("Could not reap orphaned collections", "error", err)
+		}
+		if err := PruneRunLogs(ctx, pool, logger); err != nil && ctx.Err() == nil {
+			logger.Error("Could not prune collection logs", "error", err)
+		}
+		if err := PruneEnrollmentSnapshots(ctx, pool, logger); err != nil && ctx.Err() == nil {
+			logger.Error("Could not prune enrollment snapshots", "error", err)
+		}
+		if err := PruneCollectionViolations(ctx, pool, logger); err != nil && ctx.Err() == nil {
+			logger.Error("Could not prune collection violations", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package collection
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/Pjt727/classy/collection/services/banner"
+	"github.com/Pjt727/classy/collection/services/jsoncatalog"
+	"github.com/Pjt727/classy/collection/services/peoplesoft"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// the channel the school_registry table notifies on when it changes
+const SCHOOL_REGISTRY_CHANNEL = "school_registry"
+
+// how long to wait before listening again after losing the listening connection
+const REGISTRY_LISTEN_RETRY_INTERVAL = 5 * time.Second
+
+// services whose schools come from the school registry
+type RegistryService interface {
+	Service
+
+	// adds or replaces the school with the config from its registry entry
+	ConfigureSchool(school classentry.School, config []byte) error
+
+	RemoveSchool(schoolID string)
+}
+
+// fresh services for checking registry entries without changing running ones
+var registryServiceConstructors = []func() RegistryService{
+	func() RegistryService { return banner.GetDefaultService() },
+	func() RegistryService { return peoplesoft.GetDefaultService() },
+	func() RegistryService { return jsoncatalog.GetDefaultService() },
+}
+
+func RegistryServiceNames() []string {
+	serviceNames := make([]string, len(registryServiceConstructors))
+	for i, newService := range registryServiceConstructors {
+		serviceNames[i] = newService().GetName()
+	}
+	return serviceNames
+}
+
+// checks the entry could be loaded by its service
+func ValidateRegistryEntry(entry db.SchoolRegistry) error {
+	if entry.SchoolID == "" || entry.SchoolName == "" {
+		return errors.New("School id and name are required")
+	}
+	for _, newService := range registryServiceConstructors {
+		service := newService()
+		if service.GetName() != entry.ServiceName {
+			continue
+		}
+		school := db.School{ID: entry.SchoolID, Name: entry.SchoolName}
+		if err := service.ConfigureSchool(school, entry.Config); err != nil {
+			return fmt.Errorf("%s for %s: %w", entry.ServiceName, entry.SchoolID, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("Unknown registry service `%s`", entry.ServiceName)
+}
+
+// checks every enabled entry of the registry could be loaded
+func ValidateSchoolRegistry(ctx context.Context, pool *pgxpool.Pool) error {
+	q := db.New(pool)
+	entries, err := q.ListSchoolRegistry(ctx)
+	if err != nil {
+		return fmt.Errorf("Could not list school registry %w", err)
+	}
+	var errs []error
+	for _, entry := range entries {
+		if !entry.Enabled {
+			continue
+		}
+		if err := ValidateRegistryEntry(entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// configures the orchestrator's services with the registry and remaps its schools
+//
+//	entries which cannot be loaded are skipped and returned as errors
+func (o *Orchestrator) ReloadSchoolRegistry(ctx context.Context) error {
+	q := db.New(o.dbPool)
+	entries, err := q.ListSchoolRegistry(ctx)
+	if err != nil {
+		return fmt.Errorf("Could not list school registry %w", err)
+	}
+
+	err = o.loadSchoolRegistry(ctx, entries)
+	// the service managers were remade so they do not know how their services have been doing
+	if healthErr := o.RefreshServiceHealth(ctx); healthErr != nil {
+		o.orchestrationLogger.Error("Could not refresh service health", "error", healthErr)
+	}
+	return err
+}
+
+// reconfiguring a school replaces its limiters and clients so it is only done when its entry changed
+func (o *Orchestrator) loadSchoolRegistry(ctx context.Context, entries []db.SchoolRegistry) error {
+	var errs []error
+	registered := make(map[string]map[string]db.SchoolRegistry)
+	// school id -> service name -> priority
+	priorities := make(map[string]map[string]int32)
+	for _, entry := range entries {
+		if !entry.Enabled {
+			continue
+		}
+		service, ok := o.serviceEntries[entry.ServiceName]
+		if !ok {
+			o.orchestrationLogger.Warn(
+				"Skipping registry entry of a service the orchestrator does not have",
+				"school", entry.SchoolID,
+				"service", entry.ServiceName,
+			)
+			continue
+		}
+		registryService, ok := service.(RegistryService)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s cannot be configured by the registry", entry.ServiceName))
+			continue
+		}
+		o.mappingsMu.RLock()
+		configured, ok := o.registeredSchools[entry.ServiceName][entry.SchoolID]
+		o.mappingsMu.RUnlock()
+		if !ok || !sameSchoolConfig(configured, entry) {
+			school := db.School{ID: entry.SchoolID, Name: entry.SchoolName}
+			if err := registryService.ConfigureSchool(school, entry.Config); err != nil {
+				errs = append(errs, fmt.Errorf("%s for %s: %w", entry.ServiceName, entry.SchoolID, err))
+				continue
+			}
+		}
+		if registered[entry.ServiceName] == nil {
+			registered[entry.ServiceName] = make(map[string]db.SchoolRegistry)
+		}
+		registered[entry.ServiceName][entry.SchoolID] = entry
+		if priorities[entry.SchoolID] == nil {
+			priorities[entry.SchoolID] = make(map[string]int32)
+		}
+		priorities[entry.SchoolID][entry.ServiceName] = entry.Priority
+	}
+
+	// schools which were deleted or disabled
+	o.mappingsMu.RLock()
+	for serviceName, schoolIDs := range o.registeredSchools {
+		registryService := o.serviceEntries[serviceName].(RegistryService)
+		for schoolID := range schoolIDs {
+			if _, ok := registered[serviceName][schoolID]; !ok {
+				registryService.RemoveSchool(schoolID)
+			}
+		}
+	}
+	o.mappingsMu.RUnlock()
+
+	// services can go over the network to list their schools so the mappings are
+	//    built before taking the lock and collections are only blocked for the swap
+	schoolIdToServiceManager, schoolIdToSchool := o.listSchoolMappings(ctx)
+	for schoolID, serviceManager := range schoolIdToServiceManager {
+		slices.SortStableFunc(serviceManager.services, func(a Service, b Service) int {
+			return cmp.Compare(priorities[schoolID][a.GetName()], priorities[schoolID][b.GetName()])
+		})
+	}
+
+	o.mappingsMu.Lock()
+	// copies of the orchestrator share these maps so they are changed in place
+	clear(o.registeredSchools)
+	maps.Copy(o.registeredSchools, registered)
+	clear(o.schoolIdToServiceManager)
+	maps.Copy(o.schoolIdToServiceManager, schoolIdToServiceManager)
+	clear(o.schoolIdToSchool)
+	maps.Copy(o.schoolIdToSchool, schoolIdToSchool)
+	o.mappingsMu.Unlock()
+
+	o.orchestrationLogger.Info("Loaded school registry", "schools", len(schoolIdToSchool))
+	return errors.Join(errs...)
+}
+
+// priority and timestamps do not change how the service collects the school
+func sameSchoolConfig(a db.SchoolRegistry, b db.SchoolRegistry) bool {
+	return a.SchoolName == b.SchoolName && bytes.Equal(a.Config, b.Config)
+}
+
+// reloads the school registry whenever it changes
+//
+//	blocks until the context is done
+func (o *Orchestrator) WatchSchoolRegistry(ctx context.Context) {
+	for {
+		err := o.listenSchoolRegistry(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		o.orchestrationLogger.Error("Lost listening connection for the school registry", "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(REGISTRY_LISTEN_RETRY_INTERVAL):
+		}
+	}
+}
+
+func (o *Orchestrator) listenSchoolRegistry(ctx context.Context) error {
+	poolConn, err := o.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the connection has a LISTEN registered on it so it is not given back to the pool
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+SCHOOL_REGISTRY_CHANNEL)
+	if err != nil {
+		return err
+	}
+	for {
+		// changes could have been missed while not listening
+		if err := o.ReloadSchoolRegistry(ctx); err != nil {
+			o.orchestrationLogger.Error("Could not load all of the school registry", "error", err)
+		}
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package collection
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/Pjt727/classy/data/db"
+)
+
+// counts how often each school is configured
+type countingRegistryService struct {
+	configured map[string]int
+	removed    map[string]int
+}
+
+func (s *countingRegistryService) GetName() string { return "Counting" }
+
+func (s *countingRegistryService) ListValidSchools(logger slog.Logger, ctx context.Context) ([]classentry.School, error) {
+	return nil, nil
+}
+
+func (s *countingRegistryService) GetTermCollections(
+	logger slog.Logger,
+	ctx context.Context,
+	school classentry.School,
+) ([]classentry.TermCollection, error) {
+	return nil, nil
+}
+
+func (s *countingRegistryService) StageAllClasses(
+	logger slog.Logger,
+	ctx context.Context,
+	q *classentry.EntryQueries,
+	schoolID string,
+	termCollection classentry.TermCollection,
+	fullCollection bool,
+) error {
+	return nil
+}
+
+func (s *countingRegistryService) ConfigureSchool(school classentry.School, config []byte) error {
+	s.configured[school.ID]++
+	return nil
+}
+
+func (s *countingRegistryService) RemoveSchool(schoolID string) {
+	s.removed[schoolID]++
+}
+
+func TestSchoolRegistryOnlyReconfiguresChangedSchools(t *testing.T) {
+	ctx := context.Background()
+	service := &countingRegistryService{configured: make(map[string]int), removed: make(map[string]int)}
+	orchestrator, err := CreateOrchestrator([]Service{service}, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := func(schoolID string, config string, priority int32) db.SchoolRegistry {
+		return db.SchoolRegistry{
+			SchoolID:    schoolID,
+			SchoolName:  schoolID,
+			ServiceName: service.GetName(),
+			Config:      []byte(config),
+			Priority:    priority,
+			Enabled:     true,
+		}
+	}
+
+	loads := [][]db.SchoolRegistry{
+		{entry("marist", `{}`, 0), entry("temple", `{}`, 0)},
+		// only the priority changed
+		{entry("marist", `{}`, 1), entry("temple", `{}`, 0)},
+		{entry("marist", `{}`, 1), entry("temple", `{"rate": 2}`, 0)},
+		{entry("temple", `{"rate": 2}`, 0)},
+		{entry("marist", `{}`, 1), entry("temple", `{"rate": 2}`, 0)},
+	}
+	for _, entries := range loads {
+		if err := orchestrator.loadSchoolRegistry(ctx, entries); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if service.configured["marist"] != 2 || service.removed["marist"] != 1 {
+		t.Errorf("expected marist to be configured again only after being removed got %d configures %d removes",
+			service.configured["marist"], service.removed["marist"])
+	}
+	if service.configured["temple"] != 2 || service.removed["temple"] != 0 {
+		t.Errorf("expected temple to be configured again only when its config changed got %d configures %d removes",
+			service.configured["temple"], service.removed["temple"])
+	}
+}
@@ -0,0 +1,197 @@
+package collection
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+	logginghelpers "github.com/Pjt727/classy/data/logging-helpers"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// the lowest level of records kept in a collection's logs
+const RUN_LOG_LEVEL = slog.LevelInfo
+
+// logs past this size are cut off so a looping service cannot fill the database
+const MAX_RUN_LOG_BYTES = 4 << 20
+
+// logs older than this are pruned
+const RUN_LOG_RETENTION = 30 * 24 * time.Hour
+
+// only this many of the most recent logs of each term are kept
+const RUN_LOGS_PER_TERM = 50
+
+// searching has to decompress each log so only the most recent ones are searched
+const MAX_SEARCHED_RUN_LOGS = 100
+
+// lines past this are not returned for a single log
+const MAX_RUN_LOG_MATCHES = 25
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// keeps the formatted records of a single collection in memory until it is finished
+type runLogRecorder struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	lines     int
+	truncated bool
+}
+
+// the ansi handler writes one whole record per call
+func (r *runLogRecorder) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.buf.Len()+len(b) > MAX_RUN_LOG_BYTES {
+		r.truncated = true
+		return len(b), nil
+	}
+	r.lines += bytes.Count(b, []byte{'\n'})
+	return r.buf.Write(b)
+}
+
+// adds a handler writing to the recorder which keeps the attributes of the logger
+func withRunLog(logger *slog.Logger, recorder *runLogRecorder) *slog.Logger {
+	if _, ok := logger.Handler().(*logginghelpers.MultiHandler); !ok {
+		logger = slog.New(logginghelpers.NewMultiHandler(logger.Handler()))
+	}
+	return logginghelpers.WithHandler(logger, logginghelpers.NewHandler(recorder, &logginghelpers.Options{
+		AddSource: false,
+		Level:     RUN_LOG_LEVEL,
+		NoColor:   false,
+	}))
+}
+
+// compresses and stores the logs of a collection
+func (o *Orchestrator) storeRunLog(
+	ctx context.Context,
+	termCollection db.TermCollection,
+	termCollectionHistoryID int32,
+	recorder *runLogRecorder,
+) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(recorder.buf.Bytes()); err != nil {
+		o.orchestrationLogger.Error("Could not compress collection logs", "termCollectionHistoryID", termCollectionHistoryID, "error", err)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		o.orchestrationLogger.Error("Could not compress collection logs", "termCollectionHistoryID", termCollectionHistoryID, "error", err)
+		return
+	}
+
+	err := db.New(o.dbPool).InsertCollectionLog(ctx, db.InsertCollectionLogParams{
+		TermCollectionHistoryID: termCollectionHistoryID,
+		SchoolID:                termCollection.SchoolID,
+		TermCollectionID:        termCollection.ID,
+		CompressedLogs:          compressed.Bytes(),
+		UncompressedBytes:       int32(recorder.buf.Len()),
+		Lines:                   int32(recorder.lines),
+		Truncated:               recorder.truncated,
+	})
+	if err != nil {
+		o.orchestrationLogger.Error("Could not store collection logs", "termCollectionHistoryID", termCollectionHistoryID, "error", err)
+	}
+}
+
+// the ansi formatted logs of the collection
+func ReadRunLog(runLog db.CollectionLog) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(runLog.CompressedLogs))
+	if err != nil {
+		return nil, fmt.Errorf("Could not decompress collection logs %w", err)
+	}
+	defer zr.Close()
+	logs, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decompress collection logs %w", err)
+	}
+	return logs, nil
+}
+
+type SearchRunLogsParams struct {
+	// empty to search every school
+	SchoolID string
+	// empty to search every term
+	TermCollectionID string
+	// case insensitive text the lines have to contain
+	Text string
+}
+
+type RunLogMatch struct {
+	Log db.CollectionLog
+	// the matching ansi formatted lines
+	Lines []string
+	// there were more matching lines than MAX_RUN_LOG_MATCHES
+	MoreLines bool
+}
+
+// finds the lines of the most recent collection logs which contain the text
+func SearchRunLogs(ctx context.Context, q *db.Queries, params SearchRunLogsParams) ([]RunLogMatch, error) {
+	logs, err := q.SearchCollectionLogs(ctx, db.SearchCollectionLogsParams{
+		SchoolID:         pgtype.Text{String: params.SchoolID, Valid: params.SchoolID != ""},
+		TermCollectionID: pgtype.Text{String: params.TermCollectionID, Valid: params.TermCollectionID != ""},
+		LimitCount:       MAX_SEARCHED_RUN_LOGS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Could not get collection logs %w", err)
+	}
+
+	text := strings.ToLower(params.Text)
+	var matches []RunLogMatch
+	for _, runLog := range logs {
+		contents, err := ReadRunLog(runLog)
+		if err != nil {
+			return nil, err
+		}
+		match := RunLogMatch{Log: runLog}
+		scanner := bufio.NewScanner(bytes.NewReader(contents))
+		scanner.Buffer(make([]byte, 0, 64*1024), MAX_RUN_LOG_BYTES)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.Contains(strings.ToLower(ansiEscape.ReplaceAllString(line, "")), text) {
+				continue
+			}
+			if len(match.Lines) == MAX_RUN_LOG_MATCHES {
+				match.MoreLines = true
+				break
+			}
+			match.Lines = append(match.Lines, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("Could not read collection logs %w", err)
+		}
+		if len(match.Lines) > 0 {
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
+
+// deletes the logs which are past the retention limits
+func PruneRunLogs(ctx context.Context, pool *pgxpool.Pool, logger *slog.Logger) error {
+	q := db.New(pool)
+	expired, err := q.DeleteExpiredCollectionLogs(ctx, int32(RUN_LOG_RETENTION.Seconds()))
+	if err != nil {
+		return fmt.Errorf("Could not delete expired collection logs %w", err)
+	}
+	excess, err := q.DeleteExcessCollectionLogs(ctx, RUN_LOGS_PER_TERM)
+	if err != nil {
+		return fmt.Errorf("Could not delete excess collection logs %w", err)
+	}
+	if expired+excess > 0 {
+		logger.Info("Pruned collection logs", "expired", expired, "excess", excess)
+	}
+	return nil
+}
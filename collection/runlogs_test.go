@@ -0,0 +1,38 @@
+package collection
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	logginghelpers "github.com/Pjt727/classy/data/logging-helpers"
+)
+
+func TestRunLogRecorder(t *testing.T) {
+	recorder := &runLogRecorder{}
+	base := slog.New(logginghelpers.NewMultiHandler(
+		logginghelpers.NewHandler(io.Discard, &logginghelpers.Options{Level: slog.LevelDebug}),
+	))
+	logger := withRunLog(base, recorder).With("school_id", "marist")
+
+	logger.Debug("only for the other handler")
+	logger.Info("staged sections", "count", 12)
+	logger.Warn("slow service")
+
+	logs := ansiEscape.ReplaceAllString(recorder.buf.String(), "")
+	if recorder.lines != 2 {
+		t.Errorf("expected 2 lines got %d: %q", recorder.lines, logs)
+	}
+	if strings.Contains(logs, "only for the other handler") {
+		t.Error("expected records below the run log level to be dropped")
+	}
+	if !strings.Contains(logs, "staged sections") || !strings.Contains(logs, "school_id=marist") {
+		t.Errorf("expected the record with the logger's attributes got %q", logs)
+	}
+
+	logger.Info(strings.Repeat("x", MAX_RUN_LOG_BYTES))
+	if !recorder.truncated || recorder.lines != 2 {
+		t.Error("expected records past the size limit to be cut off")
+	}
+}
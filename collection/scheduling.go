@@ -6,41 +6,47 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"sync/atomic"
 	"time"
 
 	"github.com/Pjt727/classy/collection/services"
 	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"golang.org/x/sync/errgroup"
 )
 
 // queues
 const SECTIONS_OF_TERM_COLLECTIONS = "collection_jobs"
+const DEAD_SECTIONS_OF_TERM_COLLECTIONS = "collection_jobs_dead"
+
+// failed collections are retried with backoff until they are dead lettered
+const MAX_COLLECTION_ATTEMPTS = 6
+
+// jobs of a term with a quarantined collection check this often whether it was decided
+const QUARANTINE_RECHECK_INTERVAL = 15 * time.Minute
 
 const COLLECTION_TIMEOUT = 10 * time.Minute
 const COLLECITON_BATCH_SIZE = 10
 const POLLING_INTERVAL = 200 * time.Millisecond
 const POLLING_TIME = 5 * time.Second
 
-// intervals that dictate when to reschedule this collection
-// there will be a more dynamic algorithm used later
-const RESCHEDULE_UNCHANGED = 24 * time.Hour
-const RESCHEDULE_CHANGE = 5 * time.Minute
-const RESCHEDULE_ERROR = 10 * time.Minute
-
 type Scheduler struct {
 	orch   Orchestrator
 	dbPool *pgxpool.Pool
 	logger *slog.Logger
+	policy SchedulingPolicy
 }
 
 func NewScheduler(pool *pgxpool.Pool) Scheduler {
+	return NewSchedulerWithPolicy(pool, DefaultAdaptivePolicy())
+}
+
+func NewSchedulerWithPolicy(pool *pgxpool.Pool, policy SchedulingPolicy) Scheduler {
 	return Scheduler{
 		orch:   GetDefaultOrchestrator(pool),
 		dbPool: pool,
 		logger: slog.Default(),
+		policy: policy,
 	}
 }
 
@@ -50,48 +56,30 @@ type CollectionMessage struct {
 	Debug            bool        `json:"debug"`
 	ServiceName      pgtype.Text `json:"service"`
 	IsFullCollection pgtype.Bool `json:"is_full_collection"`
+	// jobs from a recurring schedule are not rescheduled since the planner adds the next one
+	ScheduleID pgtype.Int4 `json:"schedule_id"`
 }
 
-// this calls uses internal postgres polling to return practically instantly if there are messages
-func (s *Scheduler) PollForCollections(ctx context.Context) (uint32, error) {
-	q := db.New(s.dbPool)
-	readPollingRows, err := q.ReadPollingQueue(ctx, db.ReadPollingParams{
-		QueueName:                   SECTIONS_OF_TERM_COLLECTIONS,
-		SecondsUntilRescheduled:     int32(COLLECTION_TIMEOUT.Seconds()),
-		JobCount:                    COLLECITON_BATCH_SIZE,
-		SecondsPollingTime:          int32(POLLING_TIME.Seconds()),
-		MillisecondsPollingInterval: int32(POLLING_INTERVAL.Milliseconds()),
-	})
-	if err != nil {
-		return 0, err
-	}
-
-	var successfulTasks atomic.Uint32
-	var eg errgroup.Group
-	for _, row := range readPollingRows {
-		eg.Go(func() error {
-			var message CollectionMessage
-			err := json.Unmarshal(row.Message, &message)
-			if err != nil {
-				return err
-			}
-			err = s.executeCollection(ctx, row.MessageID, message)
-			if err != nil {
-				return err
-			}
-			successfulTasks.Add(1)
-			return nil
-		})
-	}
-
-	if err = eg.Wait(); err != nil {
-		return successfulTasks.Load(), nil
-	}
-
-	return successfulTasks.Load(), nil
+// a collection job which will not be tried again until it is requeued
+type DeadCollectionMessage struct {
+	Collection            CollectionMessage `json:"collection"`
+	Error                 string            `json:"error"`
+	Attempts              int               `json:"attempts"`
+	IsIncorrectAssumption bool              `json:"is_incorrect_assumption"`
+	DiedAt                time.Time         `json:"died_at"`
 }
 
-func (s *Scheduler) executeCollection(ctx context.Context, collectionJobId int32, collectionMessage CollectionMessage) error {
+// returns whether the collection succeeded
+//
+//	errors are only for when the job could not be managed
+//	stopKeepAlive is called before the job is rescheduled so its visibility is not overwritten
+func (s *Scheduler) executeCollection(
+	ctx context.Context,
+	collectionJobId int32,
+	attempts int,
+	collectionMessage CollectionMessage,
+	stopKeepAlive func(),
+) (bool, error) {
 	logger := s.logger.With(
 		"school",
 		collectionMessage.SchoolID,
@@ -103,27 +91,46 @@ func (s *Scheduler) executeCollection(ctx context.Context, collectionJobId int32
 		ID:       collectionMessage.TermCollectionID,
 		SchoolID: collectionMessage.SchoolID,
 	})
+	// retrying would never find the term
+	if errors.Is(err, pgx.ErrNoRows) {
+		stopKeepAlive()
+		return false, s.deadLetterCollectionJob(ctx, collectionJobId, DeadCollectionMessage{
+			Collection: collectionMessage,
+			Error:      fmt.Sprintf("Could not find term collection %s", err),
+			Attempts:   attempts,
+			DiedAt:     time.Now(),
+		})
+	} else if err != nil {
+		stopKeepAlive()
+		return s.failCollectionJob(ctx, logger, collectionJobId, attempts, collectionMessage, err)
+	}
+	// collecting again would stage the same data and supersede the quarantined collection
+	isQuarantined, err := q.HasPendingQuarantinedCollection(ctx, db.HasPendingQuarantinedCollectionParams{
+		SchoolID:         collectionMessage.SchoolID,
+		TermCollectionID: collectionMessage.TermCollectionID,
+	})
 	if err != nil {
-		return err
+		stopKeepAlive()
+		return s.failCollectionJob(ctx, logger, collectionJobId, attempts, collectionMessage, err)
+	}
+	if isQuarantined {
+		logger.Info("Parking collection until the term's quarantined collection is decided")
+		stopKeepAlive()
+		return false, s.parkCollectionJob(ctx, collectionJobId, collectionMessage)
 	}
 
 	config := DefualtUpdateSectionsConfig()
 	if collectionMessage.ServiceName.Valid {
-		config.SetServiceName(collectionMessage.ServiceName.String)
+		config = config.SetServiceName(collectionMessage.ServiceName.String)
 	}
 	if collectionMessage.IsFullCollection.Valid {
-		config.SetFullCollection(collectionMessage.IsFullCollection.Bool)
+		config = config.SetFullCollection(collectionMessage.IsFullCollection.Bool)
 	}
 
 	results, collectionError := s.orch.UpdateAllSectionsOfSchool(ctx, termCollection, config)
+	stopKeepAlive()
 	if collectionError != nil {
-		logger.Error("Failed collection", "error", err)
-		didReschedule, err := s.rescheduleTermCollectionJob(ctx, collectionJobId, collectionMessage, CollectionResult{}, collectionError)
-		if err != nil {
-			return fmt.Errorf("Failed to reschedule collection %w", err)
-		}
-		logger.Info("Managed collection's scheduling", "didReschedule", didReschedule)
-		return nil
+		return s.failCollectionJob(ctx, logger, collectionJobId, attempts, collectionMessage, collectionError)
 	}
 
 	logger.Info(
@@ -137,35 +144,81 @@ func (s *Scheduler) executeCollection(ctx context.Context, collectionJobId int32
 		"duration",
 		results.Duration,
 	)
-	didReschedule, err := s.rescheduleTermCollectionJob(ctx, collectionJobId, collectionMessage, results, nil)
+	didReschedule, err := s.rescheduleTermCollectionJob(ctx, collectionJobId, attempts, collectionMessage, results, nil)
 	if err != nil {
-		return fmt.Errorf("Failed to reschedule collection %w", err)
+		return true, fmt.Errorf("Failed to reschedule collection %w", err)
 	}
 	logger.Info("Managed collection's scheduling", "didReschedule", didReschedule)
 
-	return nil
+	return true, nil
+}
+
+// backs off the job like any failed collection so it is dead lettered once it runs out of attempts
+func (s *Scheduler) failCollectionJob(
+	ctx context.Context,
+	logger *slog.Logger,
+	collectionJobId int32,
+	attempts int,
+	collectionMessage CollectionMessage,
+	collectionError error,
+) (bool, error) {
+	if ctx.Err() != nil {
+		return false, fmt.Errorf("%w: %w", errCollectionInterrupted, collectionError)
+	}
+	logger.Error("Failed collection", "error", collectionError, "attempt", attempts)
+	didReschedule, err := s.rescheduleTermCollectionJob(
+		ctx,
+		collectionJobId,
+		attempts,
+		collectionMessage,
+		CollectionResult{},
+		collectionError,
+	)
+	if err != nil {
+		return false, fmt.Errorf("Failed to reschedule collection %w", err)
+	}
+	logger.Info("Managed collection's scheduling", "didReschedule", didReschedule)
+	return false, nil
 }
 
 // deletes the job from the queue and reschedules a new one only if needed
+//
+//	failed jobs keep their message so pgmq's read count keeps track of the attempts
+//	until they are moved to the dead queue
+//
 // return whether the job was rescheduled
 // TODO: ensure there are not active term collections requests
 func (s *Scheduler) rescheduleTermCollectionJob(
 	ctx context.Context,
 	collectionJobId int32,
+	attempts int,
 	oldCollectionMessage CollectionMessage,
 	collectionResult CollectionResult,
 	collectionError error,
 ) (bool, error) {
-	// might use the collection result later
-	_ = collectionResult
-
 	deleteParams := db.DeleteFromQueueParams{
 		QueueName: SECTIONS_OF_TERM_COLLECTIONS,
 		MessageID: collectionJobId,
 	}
 
-	// there might be some manual changes that need to be done before so do not reschedule
-	if errors.Is(collectionError, services.ErrIncorrectAssumption) {
+	// the collection waits on an operator so retrying would not change anything
+	if errors.Is(collectionError, ErrMassDeletion) {
+		return false, s.parkCollectionJob(ctx, collectionJobId, oldCollectionMessage)
+	}
+
+	isIncorrectAssumption := errors.Is(collectionError, services.ErrIncorrectAssumption)
+	// there might be some manual changes that need to be done before so do not retry
+	if isIncorrectAssumption || (collectionError != nil && attempts >= MAX_COLLECTION_ATTEMPTS) {
+		return false, s.deadLetterCollectionJob(ctx, collectionJobId, DeadCollectionMessage{
+			Collection:            oldCollectionMessage,
+			Error:                 collectionError.Error(),
+			Attempts:              attempts,
+			IsIncorrectAssumption: isIncorrectAssumption,
+			DiedAt:                time.Now(),
+		})
+	}
+
+	if collectionError == nil && oldCollectionMessage.ScheduleID.Valid {
 		q := db.New(s.dbPool)
 		err := q.DeleteFromQueue(ctx, deleteParams)
 		if err != nil {
@@ -173,19 +226,43 @@ func (s *Scheduler) rescheduleTermCollectionJob(
 		}
 		return false, nil
 	}
-	// currently any other error we will resechedule with the error timeout
-	var secondsToSchedule int32
-	var doDebug bool
-	if collectionError == nil {
-		if collectionResult.AreChanges() {
-			secondsToSchedule = int32(RESCHEDULE_CHANGE.Seconds())
-		} else {
-			secondsToSchedule = int32(RESCHEDULE_UNCHANGED.Seconds())
+
+	decision, err := s.decideNextCollection(ctx, attempts, oldCollectionMessage, collectionResult, collectionError)
+	if err != nil {
+		return false, err
+	}
+	s.logger.Info(
+		"Decided next collection",
+		"school",
+		oldCollectionMessage.SchoolID,
+		"term collection",
+		oldCollectionMessage.TermCollectionID,
+		"stop",
+		decision.Stop,
+		"delay",
+		decision.Delay,
+		"reason",
+		decision.Reason,
+	)
+	if decision.Stop {
+		q := db.New(s.dbPool)
+		err := q.DeleteFromQueue(ctx, deleteParams)
+		if err != nil {
+			return false, err
 		}
-		doDebug = false
-	} else {
-		secondsToSchedule = int32(RESCHEDULE_ERROR.Seconds())
-		doDebug = true
+		return false, nil
+	}
+	if collectionError != nil {
+		q := db.New(s.dbPool)
+		err := q.SetQueueVisibility(ctx, db.SetQueueVisibilityParams{
+			QueueName:             SECTIONS_OF_TERM_COLLECTIONS,
+			MessageID:             collectionJobId,
+			SecondsUntilAvailable: int(decision.Delay.Seconds()),
+		})
+		if err != nil {
+			return false, err
+		}
+		return true, nil
 	}
 
 	tx, err := s.dbPool.Begin(ctx)
@@ -201,7 +278,7 @@ func (s *Scheduler) rescheduleTermCollectionJob(
 	nextCollectionMessage := CollectionMessage{
 		TermCollectionID: oldCollectionMessage.TermCollectionID,
 		SchoolID:         oldCollectionMessage.SchoolID,
-		Debug:            doDebug,
+		Debug:            false,
 		ServiceName:      oldCollectionMessage.ServiceName,
 		IsFullCollection: pgtype.Bool{
 			Bool:  false,
@@ -215,7 +292,7 @@ func (s *Scheduler) rescheduleTermCollectionJob(
 	err = q.AddToQueue(ctx, db.AddToQueueParams{
 		QueueName:             SECTIONS_OF_TERM_COLLECTIONS,
 		Message:               messageBytes,
-		SecondsUntilAvailable: int(secondsToSchedule),
+		SecondsUntilAvailable: int(decision.Delay.Seconds()),
 	})
 	if err != nil {
 		return false, err
@@ -228,3 +305,179 @@ func (s *Scheduler) rescheduleTermCollectionJob(
 
 	return true, nil
 }
+
+// holds the job back until the term's quarantined collection is approved or rejected
+//
+//	the job is sent again so waiting does not count towards its attempts
+func (s *Scheduler) parkCollectionJob(
+	ctx context.Context,
+	collectionJobId int32,
+	collectionMessage CollectionMessage,
+) error {
+	deleteParams := db.DeleteFromQueueParams{
+		QueueName: SECTIONS_OF_TERM_COLLECTIONS,
+		MessageID: collectionJobId,
+	}
+	// the schedule's next job waits instead
+	if collectionMessage.ScheduleID.Valid {
+		return db.New(s.dbPool).DeleteFromQueue(ctx, deleteParams)
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	q := db.New(s.dbPool).WithTx(tx)
+	err = q.DeleteFromQueue(ctx, deleteParams)
+	if err != nil {
+		return err
+	}
+	messageBytes, err := json.Marshal(collectionMessage)
+	if err != nil {
+		return err
+	}
+	err = q.AddToQueue(ctx, db.AddToQueueParams{
+		QueueName:             SECTIONS_OF_TERM_COLLECTIONS,
+		Message:               messageBytes,
+		SecondsUntilAvailable: int(QUARANTINE_RECHECK_INTERVAL.Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// asks the scheduler's policy when the term collection should be collected again
+func (s *Scheduler) decideNextCollection(
+	ctx context.Context,
+	attempts int,
+	collectionMessage CollectionMessage,
+	collectionResult CollectionResult,
+	collectionError error,
+) (SchedulingDecision, error) {
+	q := db.New(s.dbPool)
+	termCollection, err := q.GetTermCollection(ctx, db.GetTermCollectionParams{
+		ID:       collectionMessage.TermCollectionID,
+		SchoolID: collectionMessage.SchoolID,
+	})
+	if err != nil {
+		return SchedulingDecision{}, fmt.Errorf("Could not get term collection %w", err)
+	}
+	history, err := q.GetRecentTermCollectionHistory(ctx, db.GetRecentTermCollectionHistoryParams{
+		SchoolID:         collectionMessage.SchoolID,
+		TermCollectionID: collectionMessage.TermCollectionID,
+		LimitCount:       SCHEDULING_HISTORY_SIZE,
+	})
+	if err != nil {
+		return SchedulingDecision{}, fmt.Errorf("Could not get term collection history %w", err)
+	}
+
+	var calendar RegistrationCalendar
+	dbCalendar, err := q.GetTermRegistrationCalendar(ctx, db.GetTermRegistrationCalendarParams{
+		SchoolID:         collectionMessage.SchoolID,
+		TermCollectionID: collectionMessage.TermCollectionID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		calendar = EstimateRegistrationCalendar(int(termCollection.Year), termCollection.Season)
+	} else if err != nil {
+		return SchedulingDecision{}, fmt.Errorf("Could not get registration calendar %w", err)
+	} else {
+		calendar = registrationCalendarFromDB(dbCalendar)
+	}
+
+	return s.policy.NextCollection(SchedulingInput{
+		Now:            time.Now(),
+		TermCollection: termCollection,
+		Calendar:       calendar,
+		History:        history,
+		Attempts:       attempts,
+		Result:         collectionResult,
+		Err:            collectionError,
+	}), nil
+}
+
+// moves the job to the dead queue where it stays until it is requeued
+func (s *Scheduler) deadLetterCollectionJob(
+	ctx context.Context,
+	collectionJobId int32,
+	deadMessage DeadCollectionMessage,
+) error {
+	s.logger.Warn(
+		"Moving collection to the dead queue",
+		"school",
+		deadMessage.Collection.SchoolID,
+		"term collection",
+		deadMessage.Collection.TermCollectionID,
+		"attempts",
+		deadMessage.Attempts,
+		"error",
+		deadMessage.Error,
+	)
+	messageBytes, err := json.Marshal(deadMessage)
+	if err != nil {
+		return err
+	}
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	q := db.New(s.dbPool).WithTx(tx)
+	err = q.DeleteFromQueue(ctx, db.DeleteFromQueueParams{
+		QueueName: SECTIONS_OF_TERM_COLLECTIONS,
+		MessageID: collectionJobId,
+	})
+	if err != nil {
+		return err
+	}
+	err = q.AddToQueue(ctx, db.AddToQueueParams{
+		QueueName:             DEAD_SECTIONS_OF_TERM_COLLECTIONS,
+		Message:               messageBytes,
+		SecondsUntilAvailable: 0,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// puts a dead collection job back on the collection queue to be collected right away
+//
+//	returns the requeued collection
+func RequeueDeadCollectionJob(ctx context.Context, pool *pgxpool.Pool, deadJobId int32) (CollectionMessage, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return CollectionMessage{}, err
+	}
+	defer tx.Rollback(ctx)
+	q := db.New(pool).WithTx(tx)
+	row, err := q.GetDeadQueueMessage(ctx, deadJobId)
+	if err != nil {
+		return CollectionMessage{}, fmt.Errorf("Could not get dead collection job %w", err)
+	}
+	var deadMessage DeadCollectionMessage
+	if err := json.Unmarshal(row.Message, &deadMessage); err != nil {
+		return CollectionMessage{}, fmt.Errorf("Could not read dead collection job %w", err)
+	}
+	messageBytes, err := json.Marshal(deadMessage.Collection)
+	if err != nil {
+		return CollectionMessage{}, err
+	}
+	err = q.AddToQueue(ctx, db.AddToQueueParams{
+		QueueName:             SECTIONS_OF_TERM_COLLECTIONS,
+		Message:               messageBytes,
+		SecondsUntilAvailable: 0,
+	})
+	if err != nil {
+		return CollectionMessage{}, err
+	}
+	err = q.DeleteFromQueue(ctx, db.DeleteFromQueueParams{
+		QueueName: DEAD_SECTIONS_OF_TERM_COLLECTIONS,
+		MessageID: deadJobId,
+	})
+	if err != nil {
+		return CollectionMessage{}, err
+	}
+	return deadMessage.Collection, tx.Commit(ctx)
+}
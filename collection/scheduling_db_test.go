@@ -0,0 +1,81 @@
+package collection
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Pjt727/classy/data"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/Pjt727/classy/data/testdb"
+)
+
+func TestMissingTermCollectionIsDeadLettered(t *testing.T) {
+	if err := testdb.SetupTestDb(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	pool, err := data.NewPool(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := db.New(pool)
+	scheduler := NewScheduler(pool)
+
+	message := CollectionMessage{TermCollectionID: "2025SP", SchoolID: "missing"}
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = q.AddToQueue(ctx, db.AddToQueueParams{
+		QueueName:             SECTIONS_OF_TERM_COLLECTIONS,
+		Message:               messageBytes,
+		SecondsUntilAvailable: 0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := q.ReadPollingQueue(ctx, db.ReadPollingParams{
+		QueueName:                   SECTIONS_OF_TERM_COLLECTIONS,
+		SecondsUntilRescheduled:     int32(COLLECTION_TIMEOUT.Seconds()),
+		JobCount:                    1,
+		SecondsPollingTime:          1,
+		MillisecondsPollingInterval: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected to read the job got %d jobs", len(rows))
+	}
+
+	succeeded, err := scheduler.executeCollection(ctx, rows[0].MessageID, 1, message, func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if succeeded {
+		t.Error("expected the collection of a missing term to fail")
+	}
+
+	queued, err := q.ViewQueue(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 0 {
+		t.Errorf("expected the job to be removed from the queue got %d jobs", len(queued))
+	}
+	dead, err := q.ViewDeadQueue(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("expected the job to be dead lettered got %d dead jobs", len(dead))
+	}
+	var deadMessage DeadCollectionMessage
+	if err := json.Unmarshal(dead[0].Message, &deadMessage); err != nil {
+		t.Fatal(err)
+	}
+	if deadMessage.Collection.SchoolID != message.SchoolID || deadMessage.Attempts != 1 {
+		t.Errorf("unexpected dead job %+v", deadMessage)
+	}
+}
@@ -0,0 +1,249 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Pjt727/classy/collection/services"
+	"github.com/Pjt727/classy/data/db"
+)
+
+// how many of a term collection's latest collections the policy gets to look at
+const SCHEDULING_HISTORY_SIZE = 10
+
+// decides when a term collection should be collected again after a collection
+type SchedulingPolicy interface {
+	NextCollection(input SchedulingInput) SchedulingDecision
+}
+
+type SchedulingInput struct {
+	Now            time.Time
+	TermCollection db.TermCollection
+	Calendar       RegistrationCalendar
+	// finished collections of the term collection latest first
+	//  including the one that just ran
+	History []db.TermCollectionHistory
+	// times the job has been tried including this one
+	Attempts int
+	Result   CollectionResult
+	Err      error
+}
+
+type SchedulingDecision struct {
+	// the term collection should not be collected again until someone requeues it
+	Stop   bool
+	Delay  time.Duration
+	Reason string
+}
+
+// the dates of a term that change how often its sections change
+type RegistrationCalendar struct {
+	RegistrationStart time.Time
+	ClassesStart      time.Time
+	AddDropEnd        time.Time
+	TermEnd           time.Time
+	// the dates are a guess from the season rather than from the school
+	IsEstimated bool
+}
+
+func registrationCalendarFromDB(calendar db.TermRegistrationCalendar) RegistrationCalendar {
+	return RegistrationCalendar{
+		RegistrationStart: calendar.RegistrationStart.Time,
+		ClassesStart:      calendar.ClassesStart.Time,
+		AddDropEnd:        calendar.AddDropEnd.Time,
+		TermEnd:           calendar.TermEnd.Time,
+	}
+}
+
+// a typical us calendar for the term's season when the school's is not known
+func EstimateRegistrationCalendar(year int, season db.SeasonEnum) RegistrationCalendar {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	calendar := RegistrationCalendar{IsEstimated: true}
+	switch season {
+	case db.SeasonEnumSpring:
+		calendar.RegistrationStart = date(year-1, time.November, 1)
+		calendar.ClassesStart = date(year, time.January, 20)
+		calendar.AddDropEnd = date(year, time.February, 3)
+		calendar.TermEnd = date(year, time.May, 15)
+	case db.SeasonEnumSummer:
+		calendar.RegistrationStart = date(year, time.March, 15)
+		calendar.ClassesStart = date(year, time.June, 1)
+		calendar.AddDropEnd = date(year, time.June, 8)
+		calendar.TermEnd = date(year, time.August, 15)
+	case db.SeasonEnumWinter:
+		calendar.RegistrationStart = date(year-1, time.November, 1)
+		calendar.ClassesStart = date(year, time.January, 2)
+		calendar.AddDropEnd = date(year, time.January, 6)
+		calendar.TermEnd = date(year, time.January, 20)
+	default:
+		calendar.RegistrationStart = date(year, time.April, 1)
+		calendar.ClassesStart = date(year, time.September, 1)
+		calendar.AddDropEnd = date(year, time.September, 15)
+		calendar.TermEnd = date(year, time.December, 20)
+	}
+	return calendar
+}
+
+type TermPhase string
+
+const (
+	TermPhaseUpcoming     TermPhase = "upcoming"
+	TermPhaseRegistration TermPhase = "registration"
+	TermPhaseAddDrop      TermPhase = "add/drop"
+	TermPhaseInSession    TermPhase = "in session"
+	TermPhaseFinished     TermPhase = "finished"
+)
+
+func (c RegistrationCalendar) Phase(now time.Time) TermPhase {
+	switch {
+	case now.Before(c.RegistrationStart):
+		return TermPhaseUpcoming
+	case now.Before(c.ClassesStart):
+		return TermPhaseRegistration
+	case now.Before(c.AddDropEnd):
+		return TermPhaseAddDrop
+	case now.Before(c.TermEnd):
+		return TermPhaseInSession
+	default:
+		return TermPhaseFinished
+	}
+}
+
+// the old behavior of rescheduling with the same intervals no matter the term
+type FixedIntervalPolicy struct {
+	Unchanged time.Duration
+	Changed   time.Duration
+	Error     time.Duration
+}
+
+func DefaultFixedIntervalPolicy() FixedIntervalPolicy {
+	return FixedIntervalPolicy{
+		Unchanged: 24 * time.Hour,
+		Changed:   5 * time.Minute,
+		Error:     10 * time.Minute,
+	}
+}
+
+func (p FixedIntervalPolicy) NextCollection(input SchedulingInput) SchedulingDecision {
+	switch {
+	case errors.Is(input.Err, services.ErrIncorrectAssumption):
+		// there might be some manual changes that need to be done before so do not reschedule
+		return SchedulingDecision{Stop: true, Reason: "The service made an incorrect assumption"}
+	case input.Err != nil:
+		return SchedulingDecision{Delay: p.Error, Reason: "The collection failed"}
+	case input.Result.AreChanges():
+		return SchedulingDecision{Delay: p.Changed, Reason: "The collection had changes"}
+	default:
+		return SchedulingDecision{Delay: p.Unchanged, Reason: "The collection had no changes"}
+	}
+}
+
+// how often a term collection is collected during a phase of its term
+type PhaseInterval struct {
+	// the delay after a collection with changes
+	Base time.Duration
+	Min  time.Duration
+	Max  time.Duration
+}
+
+// collects often when sections are changing and around registration
+//
+//	every collection in a row without changes doubles the delay up to the phase's max
+type AdaptivePolicy struct {
+	Phases map[TermPhase]PhaseInterval
+	// the delay after the first failure which doubles with each failure in a row
+	ErrorBase time.Duration
+	ErrorMax  time.Duration
+	// most doublings from collections without changes
+	MaxBackoffSteps int
+	// changes in one collection to be collected again as soon as the phase allows
+	ManyChanges uint
+}
+
+func DefaultAdaptivePolicy() AdaptivePolicy {
+	return AdaptivePolicy{
+		Phases: map[TermPhase]PhaseInterval{
+			TermPhaseUpcoming:     {Base: 12 * time.Hour, Min: time.Hour, Max: 48 * time.Hour},
+			TermPhaseRegistration: {Base: 30 * time.Minute, Min: 5 * time.Minute, Max: 6 * time.Hour},
+			TermPhaseAddDrop:      {Base: 10 * time.Minute, Min: 5 * time.Minute, Max: 2 * time.Hour},
+			TermPhaseInSession:    {Base: 6 * time.Hour, Min: time.Hour, Max: 48 * time.Hour},
+			TermPhaseFinished:     {Base: 24 * time.Hour, Min: 24 * time.Hour, Max: 7 * 24 * time.Hour},
+		},
+		ErrorBase:       10 * time.Minute,
+		ErrorMax:        6 * time.Hour,
+		MaxBackoffSteps: 4,
+		ManyChanges:     100,
+	}
+}
+
+func (p AdaptivePolicy) NextCollection(input SchedulingInput) SchedulingDecision {
+	if errors.Is(input.Err, services.ErrIncorrectAssumption) {
+		return SchedulingDecision{Stop: true, Reason: "The service made an incorrect assumption"}
+	}
+	if input.Err != nil {
+		failures := max(1, input.Attempts, consecutiveFailures(input.History))
+		delay := min(p.ErrorMax, p.ErrorBase<<min(failures-1, 16))
+		return SchedulingDecision{
+			Delay:  delay,
+			Reason: fmt.Sprintf("The collection failed %d times in a row", failures),
+		}
+	}
+
+	phase := input.Calendar.Phase(input.Now)
+	if phase == TermPhaseFinished && !input.TermCollection.StillCollecting {
+		return SchedulingDecision{Stop: true, Reason: "The term is over and no longer collecting"}
+	}
+	interval := p.Phases[phase]
+
+	if input.Result.Inserted+input.Result.Updated+input.Result.Deleted >= p.ManyChanges {
+		return SchedulingDecision{
+			Delay:  interval.Min,
+			Reason: fmt.Sprintf("Many sections changed during %s", phase),
+		}
+	}
+	unchanged := 0
+	if !input.Result.AreChanges() {
+		// the history may not have the collection that just ran
+		unchanged = max(1, unchangedStreak(input.History))
+	}
+	delay := interval.Base << min(unchanged, p.MaxBackoffSteps)
+	delay = max(interval.Min, min(interval.Max, delay))
+	return SchedulingDecision{
+		Delay:  delay,
+		Reason: fmt.Sprintf("%d collections without changes during %s", unchanged, phase),
+	}
+}
+
+// failed collections in a row, cancelled and quarantined collections are skipped
+func consecutiveFailures(history []db.TermCollectionHistory) int {
+	failures := 0
+	for _, collection := range history {
+		if collection.Status == db.TermCollectionStatusEnumCancelled ||
+			collection.Status == db.TermCollectionStatusEnumQuarantined {
+			continue
+		}
+		if collection.Status != db.TermCollectionStatusEnumFailure {
+			break
+		}
+		failures++
+	}
+	return failures
+}
+
+// successful collections in a row without changes, failures are skipped
+func unchangedStreak(history []db.TermCollectionHistory) int {
+	streak := 0
+	for _, collection := range history {
+		if collection.Status != db.TermCollectionStatusEnumSuccess {
+			continue
+		}
+		if collection.InsertedRecordsCount+collection.UpdatedRecordsCount+collection.DeletedRecordsCount > 0 {
+			break
+		}
+		streak++
+	}
+	return streak
+}
@@ -0,0 +1,212 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Pjt727/classy/collection/services"
+	"github.com/Pjt727/classy/data/db"
+)
+
+func historyOf(collections ...db.TermCollectionHistory) []db.TermCollectionHistory {
+	return collections
+}
+
+func success(changes int32) db.TermCollectionHistory {
+	return db.TermCollectionHistory{Status: db.TermCollectionStatusEnumSuccess, UpdatedRecordsCount: changes}
+}
+
+func failure() db.TermCollectionHistory {
+	return db.TermCollectionHistory{Status: db.TermCollectionStatusEnumFailure}
+}
+
+func cancelled() db.TermCollectionHistory {
+	return db.TermCollectionHistory{Status: db.TermCollectionStatusEnumCancelled}
+}
+
+func TestAdaptivePolicy(t *testing.T) {
+	policy := DefaultAdaptivePolicy()
+	calendar := EstimateRegistrationCalendar(2025, db.SeasonEnumFall)
+	registration := calendar.RegistrationStart.Add(24 * time.Hour)
+	inSession := calendar.AddDropEnd.Add(24 * time.Hour)
+	termCollection := db.TermCollection{Year: 2025, Season: db.SeasonEnumFall, StillCollecting: true}
+
+	tests := []struct {
+		name     string
+		input    SchedulingInput
+		stop     bool
+		expected time.Duration
+	}{
+		{
+			name:  "incorrect assumptions stop",
+			input: SchedulingInput{Now: registration, Err: fmt.Errorf("bad html %w", services.ErrIncorrectAssumption)},
+			stop:  true,
+		},
+		{
+			name:     "first failure",
+			input:    SchedulingInput{Now: registration, Err: errors.New("timeout"), History: historyOf(failure())},
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "failures back off",
+			input:    SchedulingInput{Now: registration, Err: errors.New("timeout"), History: historyOf(failure(), failure(), failure())},
+			expected: 40 * time.Minute,
+		},
+		{
+			name:     "cancelled collections do not break failures",
+			input:    SchedulingInput{Now: registration, Err: errors.New("timeout"), History: historyOf(failure(), cancelled(), failure(), failure())},
+			expected: 40 * time.Minute,
+		},
+		{
+			name:     "failures back off with the job's attempts",
+			input:    SchedulingInput{Now: registration, Err: errors.New("timeout"), Attempts: 4},
+			expected: 80 * time.Minute,
+		},
+		{
+			name: "failures are capped",
+			input: SchedulingInput{
+				Now:     registration,
+				Err:     errors.New("timeout"),
+				History: slices.Repeat(historyOf(failure()), 10),
+			},
+			expected: 6 * time.Hour,
+		},
+		{
+			name: "changes during registration",
+			input: SchedulingInput{
+				Now:     registration,
+				Result:  CollectionResult{Updated: 3},
+				History: historyOf(success(3), success(0), success(0)),
+			},
+			expected: 30 * time.Minute,
+		},
+		{
+			name: "many changes use the phase's minimum",
+			input: SchedulingInput{
+				Now:    registration,
+				Result: CollectionResult{Inserted: 500},
+			},
+			expected: 5 * time.Minute,
+		},
+		{
+			name: "unchanged during registration backs off",
+			input: SchedulingInput{
+				Now:     registration,
+				History: historyOf(success(0), failure(), success(0), success(4)),
+			},
+			expected: 2 * time.Hour,
+		},
+		{
+			name: "unchanged during registration is capped",
+			input: SchedulingInput{
+				Now:     registration,
+				History: slices.Repeat(historyOf(success(0)), 10),
+			},
+			expected: 6 * time.Hour,
+		},
+		{
+			name: "unchanged in session",
+			input: SchedulingInput{
+				Now:     inSession,
+				History: historyOf(success(0)),
+			},
+			expected: 12 * time.Hour,
+		},
+		{
+			name: "finished term that is not collecting stops",
+			input: SchedulingInput{
+				Now:            calendar.TermEnd.Add(time.Hour),
+				TermCollection: db.TermCollection{Year: 2025, Season: db.SeasonEnumFall, StillCollecting: false},
+			},
+			stop: true,
+		},
+	}
+	for _, test := range tests {
+		if test.input.TermCollection.Year == 0 {
+			test.input.TermCollection = termCollection
+		}
+		test.input.Calendar = calendar
+		decision := policy.NextCollection(test.input)
+		if decision.Stop != test.stop {
+			t.Errorf("%s: expected stop %v got %v (%s)", test.name, test.stop, decision.Stop, decision.Reason)
+			continue
+		}
+		if !test.stop && decision.Delay != test.expected {
+			t.Errorf("%s: expected %s got %s (%s)", test.name, test.expected, decision.Delay, decision.Reason)
+		}
+	}
+}
+
+func TestFixedIntervalPolicy(t *testing.T) {
+	policy := DefaultFixedIntervalPolicy()
+	if decision := policy.NextCollection(SchedulingInput{}); decision.Delay != 24*time.Hour {
+		t.Errorf("expected unchanged collections to wait a day got %s", decision.Delay)
+	}
+	if decision := policy.NextCollection(SchedulingInput{Result: CollectionResult{Deleted: 1}}); decision.Delay != 5*time.Minute {
+		t.Errorf("expected changed collections to wait 5 minutes got %s", decision.Delay)
+	}
+	if decision := policy.NextCollection(SchedulingInput{Err: services.ErrIncorrectAssumption}); !decision.Stop {
+		t.Errorf("expected incorrect assumptions to stop")
+	}
+}
+
+// runs the policy over a whole fall term on a simulated clock
+//
+//	sections change every collection during registration and add/drop and never otherwise
+func TestAdaptivePolicyTimeline(t *testing.T) {
+	policy := DefaultAdaptivePolicy()
+	calendar := EstimateRegistrationCalendar(2025, db.SeasonEnumFall)
+	termCollection := db.TermCollection{Year: 2025, Season: db.SeasonEnumFall, StillCollecting: false}
+
+	now := calendar.RegistrationStart.Add(-30 * 24 * time.Hour)
+	var history []db.TermCollectionHistory
+	collections := make(map[TermPhase]int)
+	stopped := false
+	for range 10_000 {
+		phase := calendar.Phase(now)
+		collections[phase]++
+		var result CollectionResult
+		if phase == TermPhaseRegistration || phase == TermPhaseAddDrop {
+			result.Updated = 10
+		}
+		history = slices.Insert(history, 0, success(int32(result.Updated)))
+		history = history[:min(len(history), SCHEDULING_HISTORY_SIZE)]
+
+		decision := policy.NextCollection(SchedulingInput{
+			Now:            now,
+			TermCollection: termCollection,
+			Calendar:       calendar,
+			History:        history,
+			Result:         result,
+		})
+		if decision.Stop {
+			stopped = true
+			break
+		}
+		if decision.Delay <= 0 {
+			t.Fatalf("non positive delay %s at %s", decision.Delay, now)
+		}
+		now = now.Add(decision.Delay)
+	}
+
+	if !stopped {
+		t.Fatal("expected the policy to stop once the term was over")
+	}
+	if calendar.Phase(now) != TermPhaseFinished {
+		t.Errorf("expected to stop after the term ended but stopped at %s", now)
+	}
+	registrationDays := calendar.ClassesStart.Sub(calendar.RegistrationStart).Hours() / 24
+	if perDay := float64(collections[TermPhaseRegistration]) / registrationDays; perDay < 40 {
+		t.Errorf("expected changing registration to be collected often got %.1f a day", perDay)
+	}
+	sessionDays := calendar.TermEnd.Sub(calendar.AddDropEnd).Hours() / 24
+	if perDay := float64(collections[TermPhaseInSession]) / sessionDays; perDay > 1 {
+		t.Errorf("expected an unchanging term to be collected rarely got %.1f a day", perDay)
+	}
+	if collections[TermPhaseUpcoming] > 30 {
+		t.Errorf("expected few collections before registration got %d", collections[TermPhaseUpcoming])
+	}
+}
@@ -18,7 +18,6 @@ import (
 	"time"
 
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/time/rate"
 
 	"github.com/Pjt727/classy/collection/services"
 	classentry "github.com/Pjt727/classy/data/class-entry"
@@ -26,6 +25,8 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const SERVICE_NAME = "Banner"
+
 type bannerSchool struct {
 	school  classentry.School
 	baseURL string
@@ -41,43 +42,107 @@ type bannerSchool struct {
 }
 
 type banner struct {
+	// schools can be changed by the school registry while collections are running
+	mu      sync.RWMutex
 	schools map[string]*bannerSchool
 }
 
+// what the school registry can set for a banner school
+//
+//	everything but the base url has a default
+type SchoolConfig struct {
+	BaseURL                           string                   `json:"base_url"`
+	RegularCollectionSectionSemaphore int                      `json:"regular_collection_section_semaphore"`
+	FullCollectionSectionSemaphore    int                      `json:"full_collection_section_semaphore"`
+	FullCollectionCourseSemaphore     int                      `json:"full_collection_course_semaphore"`
+	RequestRetryCount                 int                      `json:"request_retry_count"`
+	MaxTermCount                      int                      `json:"max_term_count"`
+	MaxSectionPageCount               int                      `json:"max_section_page_count"`
+	RateLimit                         services.RateLimitConfig `json:"rate_limit"`
+}
+
+var defaultRateLimit = services.RateLimitConfig{
+	Interval:    services.Duration(250 * time.Millisecond),
+	Burst:       5,
+	MaxIncrease: services.Duration(500 * time.Millisecond),
+}
+
+// schools come from the school registry
 func GetDefaultService() *banner {
-	marist := classentry.School{ID: "marist", Name: "Marist University"}
-	temple := classentry.School{ID: "temple", Name: "Temple University"}
-	schools := map[string]*bannerSchool{
-		marist.ID: {
-			school:                            marist,
-			baseURL:                           "https://ssb1-reg.banner.marist.edu",
-			FullCollectionSectionSemaphore:    3,
-			FullCollectionCourseSemaphore:     35,
-			RegularCollectionSectionSemaphore: 5,
-			MaxTermCount:                      100,
-			MaxSectionPageCount:               200,
-			RequestRetryCount:                 3,
-			rateLimiter:                       services.NewAdaptiveRateLimiter(rate.Every(250*time.Millisecond), 5, rate.Every(500*time.Millisecond)),
-		},
-		temple.ID: {
-			school:                            temple,
-			baseURL:                           "https://prd-xereg.temple.edu",
-			FullCollectionSectionSemaphore:    2,
-			FullCollectionCourseSemaphore:     20,
-			RegularCollectionSectionSemaphore: 5,
-			MaxTermCount:                      100,
-			MaxSectionPageCount:               200,
-			RequestRetryCount:                 3,
-			rateLimiter:                       services.NewAdaptiveRateLimiter(rate.Every(25*time.Millisecond), 5, rate.Every(50*time.Millisecond)),
-		},
-	}
-	return &banner{schools: schools}
+	return &banner{schools: make(map[string]*bannerSchool)}
+}
+
+// replaces the school if it was already added
+func (b *banner) AddSchool(school classentry.School, config SchoolConfig) error {
+	bannerSchool, err := newBannerSchool(school, config)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.schools[school.ID] = bannerSchool
+	return nil
+}
+
+// fills in the defaults of the config
+func newBannerSchool(school classentry.School, config SchoolConfig) (*bannerSchool, error) {
+	if err := services.ValidateBaseURL(config.BaseURL); err != nil {
+		return nil, err
+	}
+	withDefault := func(value int, defaultValue int) int {
+		if value == 0 {
+			return defaultValue
+		}
+		return value
+	}
+	bannerSchool := &bannerSchool{
+		school:                            school,
+		baseURL:                           strings.TrimSuffix(config.BaseURL, "/"),
+		FullCollectionSectionSemaphore:    withDefault(config.FullCollectionSectionSemaphore, 3),
+		FullCollectionCourseSemaphore:     withDefault(config.FullCollectionCourseSemaphore, 35),
+		RegularCollectionSectionSemaphore: withDefault(config.RegularCollectionSectionSemaphore, 5),
+		MaxTermCount:                      withDefault(config.MaxTermCount, 100),
+		MaxSectionPageCount:               withDefault(config.MaxSectionPageCount, 200),
+		RequestRetryCount:                 withDefault(config.RequestRetryCount, 3),
+	}
+	if bannerSchool.FullCollectionSectionSemaphore < 0 ||
+		bannerSchool.FullCollectionCourseSemaphore < 0 ||
+		bannerSchool.RegularCollectionSectionSemaphore < 0 ||
+		bannerSchool.MaxTermCount < 0 ||
+		bannerSchool.MaxSectionPageCount < 0 ||
+		bannerSchool.RequestRetryCount < 0 {
+		return nil, errors.New("Semaphores, counts and retries cannot be negative")
+	}
+	rateLimit := config.RateLimit.WithDefaults(defaultRateLimit)
+	if err := rateLimit.Validate(); err != nil {
+		return nil, err
+	}
+	bannerSchool.rateLimiter = rateLimit.NewRateLimiter()
+	return bannerSchool, nil
+}
+
+func (b *banner) ConfigureSchool(school classentry.School, config []byte) error {
+	var schoolConfig SchoolConfig
+	if err := services.DecodeSchoolConfig(config, &schoolConfig); err != nil {
+		return err
+	}
+	return b.AddSchool(school, schoolConfig)
+}
+
+// collections already running keep going with the school
+func (b *banner) RemoveSchool(schoolID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.schools, schoolID)
 }
 
 // sets a the respective hostname of the school
 // mainly just used for testing purposes
 // returns true if the hostname was set else false
 func (b *banner) SetHostname(schoolID string, newHostName string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	bannerSchool, ok := b.schools[schoolID]
 	if !ok {
 		return false
@@ -90,6 +155,8 @@ func (b *banner) ListValidSchools(
 	logger slog.Logger,
 	ctx context.Context,
 ) ([]classentry.School, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 	schools := make([]classentry.School, len(b.schools))
 	i := 0
 	for _, schoolEntry := range b.schools {
@@ -104,7 +171,7 @@ type bannerTerm struct {
 	Description string `json:"description"`
 }
 
-func (b *banner) GetName() string { return "Banner" }
+func (b *banner) GetName() string { return SERVICE_NAME }
 
 func (b *banner) GetTermCollections(
 	logger slog.Logger,
@@ -153,6 +220,8 @@ func (b *banner) StageAllClasses(
 }
 
 func (b *banner) getBannerSchool(schoolID string) (*bannerSchool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 	schoolEntry, ok := b.schools[schoolID]
 	if !ok {
 		err := fmt.Errorf(
@@ -429,26 +498,10 @@ func (b *bannerSchool) stageAllClasses(
 	if err != nil {
 		return fmt.Errorf("%w failed making class search probe, %v", services.ErrIncorrectAssumption, err)
 	}
-	queryParams := url.Values{
-		"txt_term":    {termStr},
-		"pageOffset":  {"0"},
-		"pageMaxSize": {"1"},
-	}
-	req.URL.RawQuery = queryParams.Encode()
-	resp, err := client.Do(req)
-	err = services.RespOrStatusErr(resp, err)
+	count, err := b.getSectionCount(ctx, client, termStr)
 	if err != nil {
-		return fmt.Errorf("%w failed doing class search probe", err)
-	}
-	defer resp.Body.Close()
-	type Sectioncount struct {
-		Count int32 `json:"totalCount"`
-	}
-	var sectionCount Sectioncount
-	if err := json.NewDecoder(resp.Body).Decode(&sectionCount); err != nil {
-		return fmt.Errorf("%w failed parsing class search probe %v", services.ErrIncorrectAssumption, err)
+		return err
 	}
-	count := sectionCount.Count
 
 	var actualTotalSectionCount int32
 	var semaphore chan struct{}
@@ -557,6 +610,43 @@ func (b *bannerSchool) stageAllClasses(
 	return nil
 }
 
+// the client must already have cookies for the term
+func (b *bannerSchool) getSectionCount(
+	ctx context.Context,
+	client *http.Client,
+	bannerTerm string,
+) (int32, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"GET",
+		b.baseURL+"/StudentRegistrationSsb/ssb/searchResults/searchResults",
+		nil,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%w failed making class search probe, %v", services.ErrIncorrectAssumption, err)
+	}
+	queryParams := url.Values{
+		"txt_term":    {bannerTerm},
+		"pageOffset":  {"0"},
+		"pageMaxSize": {"1"},
+	}
+	req.URL.RawQuery = queryParams.Encode()
+	resp, err := client.Do(req)
+	err = services.RespOrStatusErr(resp, err)
+	if err != nil {
+		return 0, fmt.Errorf("%w failed doing class search probe", err)
+	}
+	defer resp.Body.Close()
+	type Sectioncount struct {
+		Count int32 `json:"totalCount"`
+	}
+	var sectionCount Sectioncount
+	if err := json.NewDecoder(resp.Body).Decode(&sectionCount); err != nil {
+		return 0, fmt.Errorf("%w failed parsing class search probe %v", services.ErrIncorrectAssumption, err)
+	}
+	return sectionCount.Count, nil
+}
+
 func (b *bannerSchool) insertGroupOfSections(
 	logger *slog.Logger,
 	sectionReq *http.Request,
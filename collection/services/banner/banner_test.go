@@ -3,12 +3,16 @@ package banner_test
 import (
 	"context"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
 	"github.com/Pjt727/classy/collection/services/banner"
 	"github.com/Pjt727/classy/collection/services/banner/testbanner"
 	"github.com/Pjt727/classy/collection/services/testservice"
+	"github.com/Pjt727/classy/data"
+	"github.com/Pjt727/classy/data/db"
 	"github.com/Pjt727/classy/data/logging-helpers"
 	"github.com/Pjt727/classy/data/testdb"
 )
@@ -78,6 +82,28 @@ func TestBannerDryRun(t *testing.T) {
 	})
 	logger := slog.New(frameHandler)
 	service := banner.GetDefaultService()
+	// the real schools are configured by the registry the migrations seed
+	ctx := context.Background()
+	testPool, err := data.NewPool(ctx, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	entries, err := db.New(testPool).ListSchoolRegistry(ctx)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.ServiceName != service.GetName() {
+			continue
+		}
+		school := db.School{ID: entry.SchoolID, Name: entry.SchoolName}
+		if err := service.ConfigureSchool(school, entry.Config); err != nil {
+			t.Error(err)
+			return
+		}
+	}
 	err = testservice.RunServiceThroughTestOrchestrator(*logger, service, true)
 
 	if err != nil {
@@ -85,3 +111,63 @@ func TestBannerDryRun(t *testing.T) {
 		return
 	}
 }
+
+func TestDiscover(t *testing.T) {
+	logger := slog.New(logginghelpers.NewHandler(os.Stdout, &logginghelpers.Options{
+		AddSource: true,
+		Level:     slog.LevelInfo,
+		NoColor:   false,
+	}))
+	// only the endpoints discovery goes through
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /StudentRegistrationSsb/ssb/classSearch/getTerms", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"code": "202520", "description": "Spring 2025"}, {"code": "202510", "description": "Winter 2025 (View Only)"}]`))
+	})
+	mux.HandleFunc("GET /StudentRegistrationSsb/ssb/term/termSelection", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "session", Path: "/"})
+	})
+	mux.HandleFunc("POST /StudentRegistrationSsb/ssb/term/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("GET /StudentRegistrationSsb/ssb/searchResults/searchResults", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("JSESSIONID"); err != nil || cookie.Value != "session" {
+			http.Error(w, "no session", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"totalCount": 1234, "data": []}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// links to any banner page of the school should work
+	discovery, err := banner.Discover(
+		context.Background(),
+		*logger,
+		server.URL+"/StudentRegistrationSsb/ssb/registration",
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if discovery.BaseURL != server.URL {
+		t.Errorf("expected base url %s got %s", server.URL, discovery.BaseURL)
+	}
+	if discovery.TermCount != 2 || discovery.SampleTerm.ID != "202520" || discovery.SectionCount != 1234 {
+		t.Errorf(
+			"expected 2 terms, sample term 202520 and 1234 sections got %d, %s and %d",
+			discovery.TermCount,
+			discovery.SampleTerm.ID,
+			discovery.SectionCount,
+		)
+	}
+	// the config should be ready to be saved
+	if err := banner.GetDefaultService().AddSchool(db.School{ID: "example", Name: "Example"}, discovery.Config); err != nil {
+		t.Error(err)
+	}
+
+	notBanner := httptest.NewServer(http.NotFoundHandler())
+	defer notBanner.Close()
+	if _, err := banner.Discover(context.Background(), *logger, notBanner.URL); err == nil {
+		t.Error("expected a host which is not banner to fail")
+	}
+}
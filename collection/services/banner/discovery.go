@@ -0,0 +1,131 @@
+package banner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Pjt727/classy/collection/services"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+)
+
+// what probing a candidate host found
+type Discovery struct {
+	BaseURL    string
+	TermCount  int
+	SampleTerm classentry.TermCollection
+	// sections in the sample term
+	SectionCount int32
+	// how long the sample term's section count took to come back
+	ResponseTime time.Duration
+	// ready to be saved to the school registry
+	Config SchoolConfig
+}
+
+// checks the host is a working banner 9 instance by going through the same
+// endpoints collections use
+//
+//	host can be a bare hostname or a link to any banner page of the school
+func Discover(ctx context.Context, logger slog.Logger, host string) (Discovery, error) {
+	baseURL, err := discoveryBaseURL(host)
+	if err != nil {
+		return Discovery{}, err
+	}
+	config := SchoolConfig{
+		BaseURL:                           baseURL,
+		RegularCollectionSectionSemaphore: 5,
+		FullCollectionSectionSemaphore:    3,
+		FullCollectionCourseSemaphore:     35,
+		RequestRetryCount:                 3,
+		MaxTermCount:                      100,
+		MaxSectionPageCount:               200,
+		RateLimit:                         defaultRateLimit,
+	}
+	school, err := newBannerSchool(classentry.School{ID: "discovery", Name: baseURL}, config)
+	if err != nil {
+		return Discovery{}, err
+	}
+
+	logger.Info("Getting terms", "baseURL", baseURL)
+	terms, err := school.getTerms(ctx, logger)
+	if err != nil {
+		return Discovery{}, fmt.Errorf("%s does not look like banner 9 because the terms could not be found %w", baseURL, err)
+	}
+	if len(terms) == 0 {
+		return Discovery{}, fmt.Errorf("%s is banner 9 but does not list any terms", baseURL)
+	}
+	// terms come newest first so this is usually the term being registered for
+	sampleTerm := terms[0]
+	for _, term := range terms {
+		if term.StillCollecting {
+			sampleTerm = term
+			break
+		}
+	}
+
+	logger.Info("Counting sections of sample term", "term", sampleTerm.ID)
+	client := &http.Client{}
+	services.AddRateLimiter(client, &school.rateLimiter)
+	services.AddHttpReporting(client, logger)
+	startTime := time.Now()
+	if err := school.refreshTermAssociatedCookies(ctx, client, sampleTerm.ID); err != nil {
+		return Discovery{}, fmt.Errorf("%s lists terms but class search could not be started %w", baseURL, err)
+	}
+	sectionCount, err := school.getSectionCount(ctx, client, sampleTerm.ID)
+	if err != nil {
+		return Discovery{}, fmt.Errorf("%s lists terms but sections could not be counted %w", baseURL, err)
+	}
+	responseTime := time.Since(startTime)
+
+	// larger schools take more workers but a slow host should not be pushed
+	if sectionCount > 5_000 && responseTime < 2*time.Second {
+		config.FullCollectionSectionSemaphore = 5
+		config.RegularCollectionSectionSemaphore = 8
+	}
+	if responseTime > 5*time.Second {
+		config.FullCollectionSectionSemaphore = 2
+		config.FullCollectionCourseSemaphore = 20
+		config.RateLimit.Interval = services.Duration(500 * time.Millisecond)
+		config.RateLimit.MaxIncrease = services.Duration(time.Second)
+	}
+
+	return Discovery{
+		BaseURL:      baseURL,
+		TermCount:    len(terms),
+		SampleTerm:   sampleTerm,
+		SectionCount: sectionCount,
+		ResponseTime: responseTime,
+		Config:       config,
+	}, nil
+}
+
+// ex: prd-xereg.temple.edu -> https://prd-xereg.temple.edu
+// ex: https://ssb1-reg.banner.marist.edu/StudentRegistrationSsb/ssb/registration -> https://ssb1-reg.banner.marist.edu
+func discoveryBaseURL(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return "", errors.New("A host is required")
+	}
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	parsed, err := url.Parse(host)
+	if err != nil {
+		return "", fmt.Errorf("Invalid host %w", err)
+	}
+	// banner can be hosted under a path so only what comes before banner's own path is kept
+	path, _, _ := strings.Cut(parsed.Path, "/StudentRegistrationSsb")
+	parsed.Path = strings.TrimSuffix(path, "/")
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	baseURL := parsed.String()
+	if err := services.ValidateBaseURL(baseURL); err != nil {
+		return "", err
+	}
+	return baseURL, nil
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/Pjt727/classy/collection"
 	"github.com/Pjt727/classy/collection/services/banner"
+	classentry "github.com/Pjt727/classy/data/class-entry"
 	"github.com/google/uuid"
 )
 
@@ -234,15 +235,15 @@ func GetMockTestingService(logger slog.Logger, ctx context.Context) (collection.
 	mockServer := NewMockServer(logger, ctx)
 
 	bannerService := banner.GetDefaultService()
-	schools, err := bannerService.ListValidSchools(logger, ctx)
-	if err != nil {
-		return nil, err
+	schools := []classentry.School{
+		{ID: "marist", Name: "Marist University"},
+		{ID: "temple", Name: "Temple University"},
 	}
 	for _, school := range schools {
 		// for now just make all schools point to the same mockServer
-		didSet := bannerService.SetHostname(school.ID, mockServer.URL)
-		if !didSet {
-			return nil, fmt.Errorf("Could not set hostname for %s", school.ID)
+		err := bannerService.AddSchool(school, banner.SchoolConfig{BaseURL: mockServer.URL})
+		if err != nil {
+			return nil, fmt.Errorf("Could not add %s %w", school.ID, err)
 		}
 	}
 	return bannerService, nil
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// school configs come from the school registry as json
+
+// a duration written like `250ms` in configs
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err != nil {
+		return fmt.Errorf("durations are written like `250ms` %w", err)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// limits are written as the time between requests
+//
+//	ex: {"interval": "250ms", "burst": 5, "max_increase": "500ms"}
+type RateLimitConfig struct {
+	Interval Duration `json:"interval"`
+	Burst    int      `json:"burst"`
+	// the most the limit can go up by after successful requests
+	MaxIncrease Duration `json:"max_increase"`
+}
+
+// fills in the parts of the config which were not given
+func (c RateLimitConfig) WithDefaults(defaults RateLimitConfig) RateLimitConfig {
+	if c.Interval == 0 {
+		c.Interval = defaults.Interval
+	}
+	if c.Burst == 0 {
+		c.Burst = defaults.Burst
+	}
+	if c.MaxIncrease == 0 {
+		c.MaxIncrease = defaults.MaxIncrease
+	}
+	return c
+}
+
+func (c RateLimitConfig) Validate() error {
+	if c.Interval <= 0 || c.MaxIncrease <= 0 {
+		return errors.New("rate limit intervals must be positive")
+	}
+	if c.Burst <= 0 {
+		return errors.New("rate limit burst must be positive")
+	}
+	return nil
+}
+
+func (c RateLimitConfig) NewRateLimiter() *AdaptiveRateLimiter {
+	return NewAdaptiveRateLimiter(
+		rate.Every(time.Duration(c.Interval)),
+		c.Burst,
+		rate.Every(time.Duration(c.MaxIncrease)),
+	)
+}
+
+// rejects fields the service does not know about so typos are not silently ignored
+func DecodeSchoolConfig(config []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(config))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("Invalid school config %w", err)
+	}
+	return nil
+}
+
+func ValidateBaseURL(baseURL string) error {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("Invalid base url %w", err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("Base url `%s` must be an absolute http(s) url", baseURL)
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package fileimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Pjt727/classy/collection/projectpath"
+	"github.com/Pjt727/classy/collection/services"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// schools which cannot be scraped send their schedules as spreadsheets
+//
+//	the import directory is laid out as
+//	  <import dir>/<school id>/mapping.json
+//	  <import dir>/<school id>/<term collection id>/*.csv | *.xlsx
+//
+//	every file in a term's directory is part of that term's collection
+const SERVICE_NAME = "FileImport"
+const MAPPING_FILE_NAME = "mapping.json"
+
+// files starting with this are still being written
+const PARTIAL_FILE_PREFIX = "."
+
+var supportedExtensions = []string{".csv", ".xlsx"}
+
+type MappedTerm struct {
+	ID              string                `json:"id"`
+	Year            int32                 `json:"year"`
+	Season          classentry.SeasonEnum `json:"season"`
+	Name            string                `json:"name"`
+	StillCollecting bool                  `json:"still_collecting"`
+}
+
+// the column headers of the school's files for each part of the class data
+//
+//	each row is one meeting of a section so sections which meet at different
+//	times are on multiple rows with the same subject, course number and section
+type ColumnMapping struct {
+	SubjectCode        string `json:"subject_code"`
+	SubjectDescription string `json:"subject_description"`
+	CourseNumber       string `json:"course_number"`
+	Section            string `json:"section"`
+	Title              string `json:"title"`
+	Description        string `json:"description"`
+	CreditHours        string `json:"credit_hours"`
+	Campus             string `json:"campus"`
+	InstructionMethod  string `json:"instruction_method"`
+	Enrollment         string `json:"enrollment"`
+	MaxEnrollment      string `json:"max_enrollment"`
+	// multiple instructors are separated by the list separator with the primary first
+	Instructors      string `json:"instructors"`
+	InstructorEmails string `json:"instructor_emails"`
+	MeetingType      string `json:"meeting_type"`
+	// ex: MWF, TR, MoWeFr, M W F
+	Days      string `json:"days"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+type SchoolMapping struct {
+	Name    string        `json:"name"`
+	Terms   []MappedTerm  `json:"terms"`
+	Columns ColumnMapping `json:"columns"`
+	// the sheet of xlsx files to read, the first sheet is used when empty
+	Sheet string `json:"sheet"`
+	// tried before the default layouts
+	TimeLayouts   []string `json:"time_layouts"`
+	DateLayouts   []string `json:"date_layouts"`
+	ListSeparator string   `json:"list_separator"`
+}
+
+type fileImport struct {
+	dir string
+}
+
+// uses the IMPORT_DIR env variable falling back to `imports` in the project
+func GetDefaultService() *fileImport {
+	dir := os.Getenv("IMPORT_DIR")
+	if dir == "" {
+		dir = filepath.Join(projectpath.Root, "imports")
+	}
+	return NewService(dir)
+}
+
+// mappings are read from the directory on every use so they can be changed while running
+func NewService(dir string) *fileImport {
+	return &fileImport{dir: dir}
+}
+
+func (f *fileImport) GetName() string { return SERVICE_NAME }
+
+func (f *fileImport) Dir() string { return f.dir }
+
+func (f *fileImport) ListValidSchools(
+	logger slog.Logger,
+	ctx context.Context,
+) ([]classentry.School, error) {
+	mappings, err := f.SchoolMappings()
+	if err != nil {
+		return nil, err
+	}
+	schools := make([]classentry.School, 0, len(mappings))
+	for schoolID, mapping := range mappings {
+		schools = append(schools, classentry.School{ID: schoolID, Name: mapping.Name})
+	}
+	return schools, nil
+}
+
+// every school in the import directory with a valid mapping
+func (f *fileImport) SchoolMappings() (map[string]SchoolMapping, error) {
+	mappings := make(map[string]SchoolMapping)
+	entries, err := os.ReadDir(f.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return mappings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not read import directory %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		mapping, err := f.getSchoolMapping(entry.Name())
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		mappings[entry.Name()] = mapping
+	}
+	return mappings, nil
+}
+
+func (f *fileImport) GetTermCollections(
+	logger slog.Logger,
+	ctx context.Context,
+	school classentry.School,
+) ([]classentry.TermCollection, error) {
+	mapping, err := f.getSchoolMapping(school.ID)
+	if err != nil {
+		return nil, err
+	}
+	termCollections := make([]classentry.TermCollection, len(mapping.Terms))
+	for i, term := range mapping.Terms {
+		termCollections[i] = classentry.TermCollection{
+			ID:              term.ID,
+			Term:            classentry.Term{Year: term.Year, Season: term.Season},
+			Name:            pgtype.Text{String: term.Name, Valid: term.Name != ""},
+			StillCollecting: term.StillCollecting,
+		}
+	}
+	return termCollections, nil
+}
+
+func (f *fileImport) StageAllClasses(
+	logger slog.Logger,
+	ctx context.Context,
+	q *classentry.EntryQueries,
+	schoolID string,
+	termCollection classentry.TermCollection,
+	fullCollection bool,
+) error {
+	logger.Info("Starting class collection")
+	mapping, err := f.getSchoolMapping(schoolID)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(mapping.Terms, func(t MappedTerm) bool { return t.ID == termCollection.ID }) {
+		return fmt.Errorf("%w term `%s` is not in the mapping of %s", services.ErrIncorrectAssumption, termCollection.ID, schoolID)
+	}
+
+	termDir := filepath.Join(f.dir, schoolID, termCollection.ID)
+	filePaths, err := importFiles(termDir)
+	if err != nil {
+		return fmt.Errorf("%w could not read term directory %v", services.ErrIncorrectAssumption, err)
+	}
+	if len(filePaths) == 0 {
+		return fmt.Errorf("%w no files to import in %s", services.ErrIncorrectAssumption, termDir)
+	}
+
+	// all files are put through one builder so a section can be split across files
+	builder := newClassDataBuilder(mapping)
+	for _, filePath := range filePaths {
+		if err := builder.addFile(filePath); err != nil {
+			return err
+		}
+		logger.Info("Read import file", "file", filepath.Base(filePath))
+	}
+	classData := builder.ToEntry()
+
+	if err := q.InsertClassData(&logger, ctx, classData); err != nil {
+		return err
+	}
+	logger.Info("sections finished getting", "sections", len(classData.Sections), "files", len(filePaths))
+	return nil
+}
+
+// checks the file against the school's mapping before putting it in the term's directory
+//
+//	returns the amount of sections in the file
+func (f *fileImport) SaveUpload(schoolID string, termCollectionID string, fileName string, file io.Reader) (int, error) {
+	// the school id comes from the upload form so it cannot be allowed to leave the service's directory
+	schoolID = filepath.Base(schoolID)
+	mapping, err := f.getSchoolMapping(schoolID)
+	if err != nil {
+		return 0, err
+	}
+	if !slices.ContainsFunc(mapping.Terms, func(t MappedTerm) bool { return t.ID == termCollectionID }) {
+		return 0, fmt.Errorf("Term `%s` is not in the mapping of %s", termCollectionID, schoolID)
+	}
+	fileName = filepath.Base(fileName)
+	if !isImportFile(fileName) {
+		return 0, fmt.Errorf("Only %s files can be imported", strings.Join(supportedExtensions, ", "))
+	}
+
+	termDir := filepath.Join(f.dir, schoolID, termCollectionID)
+	if err := os.MkdirAll(termDir, 0o755); err != nil {
+		return 0, fmt.Errorf("Could not make term directory %w", err)
+	}
+	// the extension is kept so the file is read as the right type
+	partialFile, err := os.CreateTemp(termDir, PARTIAL_FILE_PREFIX+"upload-*"+filepath.Ext(fileName))
+	if err != nil {
+		return 0, fmt.Errorf("Could not make upload file %w", err)
+	}
+	defer os.Remove(partialFile.Name())
+	_, err = io.Copy(partialFile, file)
+	partialFile.Close()
+	if err != nil {
+		return 0, fmt.Errorf("Could not write upload file %w", err)
+	}
+
+	builder := newClassDataBuilder(mapping)
+	if err := builder.addFile(partialFile.Name()); err != nil {
+		return 0, err
+	}
+
+	// renaming means the watcher never sees a half written file
+	if err := os.Rename(partialFile.Name(), filepath.Join(termDir, fileName)); err != nil {
+		return 0, fmt.Errorf("Could not save upload file %w", err)
+	}
+	return len(builder.sections), nil
+}
+
+func (f *fileImport) getSchoolMapping(schoolID string) (SchoolMapping, error) {
+	mappingFile, err := os.Open(filepath.Join(f.dir, filepath.Base(schoolID), MAPPING_FILE_NAME))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return SchoolMapping{}, fmt.Errorf(
+				"%w school not known for this service: %s %w",
+				services.ErrIncorrectAssumption,
+				schoolID,
+				err,
+			)
+		}
+		return SchoolMapping{}, err
+	}
+	defer mappingFile.Close()
+
+	var mapping SchoolMapping
+	decoder := json.NewDecoder(mappingFile)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&mapping); err != nil {
+		return SchoolMapping{}, fmt.Errorf("Could not decode mapping of %s %w", schoolID, err)
+	}
+	if err := mapping.validate(); err != nil {
+		return SchoolMapping{}, fmt.Errorf("Invalid mapping of %s %w", schoolID, err)
+	}
+	return mapping, nil
+}
+
+func (m SchoolMapping) validate() error {
+	if m.Name == "" {
+		return errors.New("school name is required")
+	}
+	if m.Columns.SubjectCode == "" || m.Columns.CourseNumber == "" || m.Columns.Section == "" {
+		return errors.New("subject_code, course_number and section columns are required")
+	}
+	for _, term := range m.Terms {
+		if term.ID == "" || term.ID != filepath.Base(term.ID) || strings.HasPrefix(term.ID, ".") {
+			return fmt.Errorf("term id `%s` cannot be used as a directory", term.ID)
+		}
+		switch term.Season {
+		case classentry.SeasonEnumSpring, classentry.SeasonEnumSummer, classentry.SeasonEnumFall, classentry.SeasonEnumWinter:
+		default:
+			return fmt.Errorf("term `%s` has unknown season `%s`", term.ID, term.Season)
+		}
+	}
+	return nil
+}
+
+func isImportFile(fileName string) bool {
+	return !strings.HasPrefix(fileName, PARTIAL_FILE_PREFIX) &&
+		slices.Contains(supportedExtensions, strings.ToLower(filepath.Ext(fileName)))
+}
+
+// sorted so files are always read in the same order
+func importFiles(termDir string) ([]string, error) {
+	entries, err := os.ReadDir(termDir)
+	if err != nil {
+		return nil, err
+	}
+	var filePaths []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && isImportFile(entry.Name()) {
+			filePaths = append(filePaths, filepath.Join(termDir, entry.Name()))
+		}
+	}
+	return filePaths, nil
+}
@@ -0,0 +1,124 @@
+package fileimport_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pjt727/classy/collection/projectpath"
+	"github.com/Pjt727/classy/collection/services/fileimport"
+	"github.com/Pjt727/classy/collection/services/testservice"
+)
+
+var TESTING_ASSETS_BASE_DIR = filepath.Join(
+	projectpath.Root,
+	"collection",
+	"services",
+	"fileimport",
+	"test-assets",
+)
+
+// a copy of the example school's mapping without any files
+func newImportDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	mapping, err := os.ReadFile(filepath.Join(TESTING_ASSETS_BASE_DIR, "example", fileimport.MAPPING_FILE_NAME))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "example"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "example", fileimport.MAPPING_FILE_NAME), mapping, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFileImportData(t *testing.T) {
+	logger := testservice.NewTestLogger()
+	service := fileimport.NewService(TESTING_ASSETS_BASE_DIR)
+	err := testservice.RunServiceThroughTestOrchestrator(*logger, service, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestSaveUpload(t *testing.T) {
+	dir := newImportDir(t)
+	service := fileimport.NewService(dir)
+
+	badFile := "Subject,Catalog Nbr\nACCT,101\n"
+	if _, err := service.SaveUpload("example", "2025SP", "bad.csv", strings.NewReader(badFile)); err == nil {
+		t.Error("expected a file missing mapped columns to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "example", "2025SP", "bad.csv")); !os.IsNotExist(err) {
+		t.Error("expected the rejected file to not be saved")
+	}
+	if _, err := service.SaveUpload("example", "1999SP", "sections.csv", strings.NewReader(badFile)); err == nil {
+		t.Error("expected a term not in the mapping to be rejected")
+	}
+
+	goodFile, err := os.Open(filepath.Join(TESTING_ASSETS_BASE_DIR, "example", "2025SP", "sections.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer goodFile.Close()
+	sectionCount, err := service.SaveUpload("example", "2025SP", "../sections.csv", goodFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sectionCount != 4 {
+		t.Errorf("expected 4 sections got %d", sectionCount)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "example", "2025SP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "sections.csv" {
+		t.Errorf("expected only sections.csv to be in the term directory got %v", entries)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := newImportDir(t)
+	service := fileimport.NewService(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan string, 10)
+	go service.Watch(ctx, testservice.NewTestLogger(), func(schoolID string, termCollectionID string) {
+		changes <- schoolID + "/" + termCollectionID
+	})
+	// give the watcher time to start
+	time.Sleep(100 * time.Millisecond)
+
+	termDir := filepath.Join(dir, "example", "2025SP")
+	if err := os.MkdirAll(termDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	for i := range 3 {
+		if err := os.WriteFile(filepath.Join(termDir, "sections.csv"), []byte(strings.Repeat("a", i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case change := <-changes:
+		if change != "example/2025SP" {
+			t.Errorf("expected example/2025SP got %s", change)
+		}
+	case <-time.After(fileimport.WATCH_DEBOUNCE + 3*time.Second):
+		t.Fatal("no change was reported")
+	}
+	select {
+	case change := <-changes:
+		t.Errorf("expected the writes to be reported once got another %s", change)
+	case <-time.After(fileimport.WATCH_DEBOUNCE):
+	}
+}
@@ -0,0 +1,521 @@
+package fileimport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pjt727/classy/collection/services"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var defaultTimeLayouts = []string{"3:04 PM", "3:04PM", "3:04 pm", "3:04pm", "15:04", "1504"}
+var defaultDateLayouts = []string{"01/02/2006", "1/2/2006", "2006-01-02"}
+
+const DEFAULT_LIST_SEPARATOR = ";"
+
+// spreadsheets store dates as days since this
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+type column int
+
+const (
+	subjectCodeColumn column = iota
+	subjectDescriptionColumn
+	courseNumberColumn
+	sectionColumn
+	titleColumn
+	descriptionColumn
+	creditHoursColumn
+	campusColumn
+	instructionMethodColumn
+	enrollmentColumn
+	maxEnrollmentColumn
+	instructorsColumn
+	instructorEmailsColumn
+	meetingTypeColumn
+	daysColumn
+	startTimeColumn
+	endTimeColumn
+	startDateColumn
+	endDateColumn
+	columnCount
+)
+
+func (c ColumnMapping) headers() [columnCount]string {
+	return [columnCount]string{
+		c.SubjectCode,
+		c.SubjectDescription,
+		c.CourseNumber,
+		c.Section,
+		c.Title,
+		c.Description,
+		c.CreditHours,
+		c.Campus,
+		c.InstructionMethod,
+		c.Enrollment,
+		c.MaxEnrollment,
+		c.Instructors,
+		c.InstructorEmails,
+		c.MeetingType,
+		c.Days,
+		c.StartTime,
+		c.EndTime,
+		c.StartDate,
+		c.EndDate,
+	}
+}
+
+// where each mapped column is in a file, -1 when it is not mapped
+type columnIndexes [columnCount]int
+
+func (c ColumnMapping) indexesOf(header []string) (columnIndexes, error) {
+	var indexes columnIndexes
+	for col, name := range c.headers() {
+		indexes[col] = -1
+		if name == "" {
+			continue
+		}
+		for i, headerName := range header {
+			if strings.EqualFold(strings.TrimSpace(headerName), name) {
+				indexes[col] = i
+				break
+			}
+		}
+		if indexes[col] == -1 {
+			return indexes, fmt.Errorf("%w mapped column `%s` is not in the header", services.ErrIncorrectAssumption, name)
+		}
+	}
+	return indexes, nil
+}
+
+type row struct {
+	number  int
+	values  []string
+	indexes columnIndexes
+}
+
+func (r row) get(col column) string {
+	i := r.indexes[col]
+	if i < 0 || i >= len(r.values) {
+		return ""
+	}
+	return strings.TrimSpace(r.values[i])
+}
+
+func (r row) errorf(col column, format string, args ...any) error {
+	return fmt.Errorf(
+		"%w row %d column %d: %s",
+		services.ErrIncorrectAssumption,
+		r.number,
+		r.indexes[col]+1,
+		fmt.Sprintf(format, args...),
+	)
+}
+
+// builds the class data rows are converted into keeping courses, sections and professors unique
+type classDataBuilder struct {
+	mapping SchoolMapping
+
+	sections     map[string]*classentry.Section
+	sectionOrder []string
+	meetingTimes []classentry.MeetingTime
+	// the next meeting sequence of each section
+	meetingCounts map[string]int32
+	professors    map[string]classentry.Professor
+	courses       map[string]classentry.Course
+}
+
+func newClassDataBuilder(mapping SchoolMapping) classDataBuilder {
+	if mapping.ListSeparator == "" {
+		mapping.ListSeparator = DEFAULT_LIST_SEPARATOR
+	}
+	return classDataBuilder{
+		mapping:       mapping,
+		sections:      make(map[string]*classentry.Section),
+		meetingCounts: make(map[string]int32),
+		professors:    make(map[string]classentry.Professor),
+		courses:       make(map[string]classentry.Course),
+	}
+}
+
+func (b *classDataBuilder) addFile(filePath string) error {
+	var records [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".csv":
+		records, err = readCSV(filePath)
+	case ".xlsx":
+		records, err = readXLSX(filePath, b.mapping.Sheet)
+	default:
+		return fmt.Errorf("%w cannot import file type of %s", services.ErrIncorrectAssumption, filePath)
+	}
+	if err != nil {
+		return fmt.Errorf("%w could not read %s %v", services.ErrIncorrectAssumption, filepath.Base(filePath), err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%w %s has no header", services.ErrIncorrectAssumption, filepath.Base(filePath))
+	}
+
+	indexes, err := b.mapping.Columns.indexesOf(records[0])
+	if err != nil {
+		return fmt.Errorf("%s %w", filepath.Base(filePath), err)
+	}
+	for i, values := range records[1:] {
+		r := row{number: i + 2, values: values, indexes: indexes}
+		if isBlank(values) {
+			continue
+		}
+		if err := b.addRow(r); err != nil {
+			return fmt.Errorf("%s %w", filepath.Base(filePath), err)
+		}
+	}
+	return nil
+}
+
+func isBlank(values []string) bool {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *classDataBuilder) addRow(r row) error {
+	subjectCode := r.get(subjectCodeColumn)
+	courseNumber := r.get(courseNumberColumn)
+	sequence := r.get(sectionColumn)
+	if subjectCode == "" || courseNumber == "" || sequence == "" {
+		return fmt.Errorf("%w row %d is missing its subject, course number or section", services.ErrIncorrectAssumption, r.number)
+	}
+	sectionKey := subjectCode + "," + courseNumber + "," + sequence
+
+	// the first row of a section has its section and course information
+	if _, ok := b.sections[sectionKey]; !ok {
+		section, err := b.sectionFromRow(r)
+		if err != nil {
+			return err
+		}
+		b.sections[sectionKey] = &section
+		b.sectionOrder = append(b.sectionOrder, sectionKey)
+
+		course, err := courseFromRow(r)
+		if err != nil {
+			return err
+		}
+		b.courses[course.SubjectCode+","+course.Number] = course
+	}
+
+	meetingTime, hasMeeting, err := b.meetingTimeFromRow(r)
+	if err != nil {
+		return err
+	}
+	if hasMeeting {
+		meetingTime.Sequence = b.meetingCounts[sectionKey]
+		b.meetingCounts[sectionKey]++
+		b.meetingTimes = append(b.meetingTimes, meetingTime)
+	}
+	return nil
+}
+
+func (b *classDataBuilder) sectionFromRow(r row) (classentry.Section, error) {
+	section := classentry.Section{
+		Sequence:          r.get(sectionColumn),
+		SubjectCode:       r.get(subjectCodeColumn),
+		CourseNumber:      r.get(courseNumberColumn),
+		Campus:            optionalText(r.get(campusColumn)),
+		InstructionMethod: optionalText(r.get(instructionMethodColumn)),
+	}
+	var err error
+	if section.Enrollment, err = optionalInt(r, enrollmentColumn); err != nil {
+		return section, err
+	}
+	if section.MaxEnrollment, err = optionalInt(r, maxEnrollmentColumn); err != nil {
+		return section, err
+	}
+
+	emails := b.splitList(r.get(instructorEmailsColumn))
+	for i, name := range b.splitList(r.get(instructorsColumn)) {
+		if name == "" {
+			continue
+		}
+		email := ""
+		if i < len(emails) {
+			email = emails[i]
+		}
+		professor := professorFromName(name, email)
+		// the first instructor listed is the primary one
+		if !section.PrimaryProfessorID.Valid {
+			section.PrimaryProfessorID = pgtype.Text{String: professor.ID, Valid: true}
+		}
+		b.professors[professor.ID] = professor
+	}
+	return section, nil
+}
+
+func courseFromRow(r row) (classentry.Course, error) {
+	course := classentry.Course{
+		SubjectCode:        r.get(subjectCodeColumn),
+		Number:             r.get(courseNumberColumn),
+		SubjectDescription: optionalText(r.get(subjectDescriptionColumn)),
+		Title:              optionalText(r.get(titleColumn)),
+		Description:        optionalText(r.get(descriptionColumn)),
+	}
+	// ex: 3
+	// ex: 1-4 uses the lowest amount
+	credits := r.get(creditHoursColumn)
+	if credits != "" {
+		lowest, _, _ := strings.Cut(credits, "-")
+		creditHours, err := strconv.ParseFloat(strings.TrimSpace(lowest), 32)
+		if err != nil {
+			return course, r.errorf(creditHoursColumn, "invalid credit hours `%s`", credits)
+		}
+		course.CreditHours = float32(creditHours)
+	}
+	return course, nil
+}
+
+// rows without days, times or dates are sections without a meeting
+func (b *classDataBuilder) meetingTimeFromRow(r row) (classentry.MeetingTime, bool, error) {
+	meetingTime := classentry.MeetingTime{
+		SectionSequence: r.get(sectionColumn),
+		SubjectCode:     r.get(subjectCodeColumn),
+		CourseNumber:    r.get(courseNumberColumn),
+		MeetingType:     optionalText(r.get(meetingTypeColumn)),
+	}
+	days := r.get(daysColumn)
+	if days == "" && r.get(startTimeColumn) == "" && r.get(startDateColumn) == "" {
+		return meetingTime, false, nil
+	}
+
+	weekdays, ok := parseDays(days)
+	if !ok {
+		return meetingTime, false, r.errorf(daysColumn, "unknown days `%s`", days)
+	}
+	meetingTime.IsMonday = weekdays[time.Monday]
+	meetingTime.IsTuesday = weekdays[time.Tuesday]
+	meetingTime.IsWednesday = weekdays[time.Wednesday]
+	meetingTime.IsThursday = weekdays[time.Thursday]
+	meetingTime.IsFriday = weekdays[time.Friday]
+	meetingTime.IsSaturday = weekdays[time.Saturday]
+	meetingTime.IsSunday = weekdays[time.Sunday]
+
+	var err error
+	if meetingTime.StartMinutes, err = b.parseTime(r, startTimeColumn); err != nil {
+		return meetingTime, false, err
+	}
+	if meetingTime.EndMinutes, err = b.parseTime(r, endTimeColumn); err != nil {
+		return meetingTime, false, err
+	}
+	if meetingTime.StartDate, err = b.parseDate(r, startDateColumn); err != nil {
+		return meetingTime, false, err
+	}
+	if meetingTime.EndDate, err = b.parseDate(r, endDateColumn); err != nil {
+		return meetingTime, false, err
+	}
+	return meetingTime, true, nil
+}
+
+func (b *classDataBuilder) splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, b.mapping.ListSeparator) {
+		values = append(values, strings.TrimSpace(value))
+	}
+	return values
+}
+
+func (b *classDataBuilder) ToEntry() classentry.ClassData {
+	sections := make([]classentry.Section, len(b.sectionOrder))
+	for i, sectionKey := range b.sectionOrder {
+		sections[i] = *b.sections[sectionKey]
+	}
+	professors := make([]classentry.Professor, 0, len(b.professors))
+	for _, professor := range b.professors {
+		professors = append(professors, professor)
+	}
+	courses := make([]classentry.Course, 0, len(b.courses))
+	for _, course := range b.courses {
+		courses = append(courses, course)
+	}
+	return classentry.ClassData{
+		MeetingTimes: b.meetingTimes,
+		Sections:     sections,
+		Professors:   professors,
+		Courses:      courses,
+	}
+}
+
+func readCSV(filePath string) ([][]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	// rows of spreadsheet exports are not always the same length
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	// excel puts a byte order mark at the start of utf-8 exports
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+	}
+	return records, nil
+}
+
+// professors without an email get an id from their name
+func professorFromName(name string, email string) classentry.Professor {
+	name = strings.Join(strings.Fields(name), " ")
+	professor := classentry.Professor{
+		ID:           strings.ToLower(strings.ReplaceAll(name, " ", "-")),
+		Name:         name,
+		EmailAddress: pgtype.Text{String: email, Valid: email != ""},
+	}
+	if email != "" {
+		professor.ID = email
+	}
+	// ex: Kirk, James
+	if last, first, ok := strings.Cut(name, ","); ok {
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+		professor.Name = first + " " + last
+		professor.FirstName = pgtype.Text{String: first, Valid: true}
+		professor.LastName = pgtype.Text{String: last, Valid: true}
+		if email == "" {
+			professor.ID = strings.ToLower(strings.ReplaceAll(professor.Name, " ", "-"))
+		}
+	} else if first, last, ok := strings.Cut(name, " "); ok {
+		professor.FirstName = pgtype.Text{String: first, Valid: true}
+		professor.LastName = pgtype.Text{String: last, Valid: true}
+	}
+	return professor
+}
+
+func optionalText(text string) pgtype.Text {
+	return pgtype.Text{String: text, Valid: text != ""}
+}
+
+func optionalInt(r row, col column) (pgtype.Int4, error) {
+	number := r.get(col)
+	if number == "" {
+		return pgtype.Int4{}, nil
+	}
+	// spreadsheets may store whole numbers as decimals
+	parsed, err := strconv.ParseFloat(number, 64)
+	if err != nil || parsed != math.Trunc(parsed) {
+		return pgtype.Int4{}, r.errorf(col, "invalid whole number `%s`", number)
+	}
+	return pgtype.Int4{Int32: int32(parsed), Valid: true}, nil
+}
+
+var twoLetterDays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+var oneLetterDays = map[rune]time.Weekday{
+	'M': time.Monday,
+	'T': time.Tuesday,
+	'W': time.Wednesday,
+	'R': time.Thursday,
+	'F': time.Friday,
+	'S': time.Saturday,
+	'U': time.Sunday,
+}
+
+// ex: MWF
+// ex: MoWeFr
+// ex: T R
+// ex: TBA
+func parseDays(days string) (map[time.Weekday]bool, bool) {
+	weekdays := make(map[time.Weekday]bool)
+	letters := strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == ' ' || r == ',' || r == '/' {
+			return -1
+		}
+		return r
+	}, days))
+	if letters == "" || letters == "TBA" {
+		return weekdays, true
+	}
+
+	if len(letters)%2 == 0 {
+		isTwoLetter := true
+		for i := 0; i < len(letters); i += 2 {
+			day, ok := twoLetterDays[letters[i:i+2]]
+			if !ok {
+				isTwoLetter = false
+				break
+			}
+			weekdays[day] = true
+		}
+		if isTwoLetter {
+			return weekdays, true
+		}
+		clear(weekdays)
+	}
+
+	for _, letter := range letters {
+		day, ok := oneLetterDays[letter]
+		if !ok {
+			return weekdays, false
+		}
+		weekdays[day] = true
+	}
+	return weekdays, true
+}
+
+// spreadsheet times are stored as the fraction of the day which has passed
+func (b *classDataBuilder) parseTime(r row, col column) (pgtype.Time, error) {
+	const minuteToMicro int64 = 60_000_000
+	clock := r.get(col)
+	if clock == "" {
+		return pgtype.Time{}, nil
+	}
+	for _, layout := range slices.Concat(b.mapping.TimeLayouts, defaultTimeLayouts) {
+		parsed, err := time.Parse(layout, clock)
+		if err != nil {
+			continue
+		}
+		return pgtype.Time{Microseconds: int64(parsed.Hour()*60+parsed.Minute()) * minuteToMicro, Valid: true}, nil
+	}
+	if dayFraction, err := strconv.ParseFloat(clock, 64); err == nil && dayFraction >= 0 {
+		_, dayFraction = math.Modf(dayFraction)
+		minutes := int64(math.Round(dayFraction * 24 * 60))
+		return pgtype.Time{Microseconds: minutes * minuteToMicro, Valid: true}, nil
+	}
+	return pgtype.Time{}, r.errorf(col, "unknown time `%s`", clock)
+}
+
+// spreadsheet dates are stored as days since the excel epoch
+func (b *classDataBuilder) parseDate(r row, col column) (pgtype.Timestamp, error) {
+	date := r.get(col)
+	if date == "" {
+		return pgtype.Timestamp{}, nil
+	}
+	for _, layout := range slices.Concat(b.mapping.DateLayouts, defaultDateLayouts) {
+		parsed, err := time.Parse(layout, date)
+		if err != nil {
+			continue
+		}
+		return pgtype.Timestamp{Time: parsed, Valid: true}, nil
+	}
+	if serial, err := strconv.ParseFloat(date, 64); err == nil && serial >= 1 {
+		return pgtype.Timestamp{Time: excelEpoch.AddDate(0, 0, int(serial)), Valid: true}, nil
+	}
+	return pgtype.Timestamp{}, r.errorf(col, "unknown date `%s`", date)
+}
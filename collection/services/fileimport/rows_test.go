@@ -0,0 +1,149 @@
+package fileimport
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Pjt727/classy/collection/projectpath"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+)
+
+var testingSchoolDir = filepath.Join(
+	projectpath.Root,
+	"collection",
+	"services",
+	"fileimport",
+	"test-assets",
+	"example",
+)
+
+func readTestingFile(t *testing.T, termID string, fileName string) classentry.ClassData {
+	t.Helper()
+	service := NewService(filepath.Dir(testingSchoolDir))
+	mapping, err := service.getSchoolMapping("example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := newClassDataBuilder(mapping)
+	if err := builder.addFile(filepath.Join(testingSchoolDir, termID, fileName)); err != nil {
+		t.Fatal(err)
+	}
+	return builder.ToEntry()
+}
+
+func findSection(classData classentry.ClassData, subjectCode string, courseNumber string, sequence string) (classentry.Section, bool) {
+	for _, section := range classData.Sections {
+		if section.SubjectCode == subjectCode && section.CourseNumber == courseNumber && section.Sequence == sequence {
+			return section, true
+		}
+	}
+	return classentry.Section{}, false
+}
+
+func TestCSVImport(t *testing.T) {
+	classData := readTestingFile(t, "2025SP", "sections.csv")
+	if len(classData.Sections) != 4 || len(classData.MeetingTimes) != 4 || len(classData.Courses) != 3 {
+		t.Fatalf(
+			"expected 4 sections, 4 meeting times and 3 courses got %d, %d and %d",
+			len(classData.Sections),
+			len(classData.MeetingTimes),
+			len(classData.Courses),
+		)
+	}
+	if len(classData.Professors) != 3 {
+		t.Errorf("expected 3 professors got %d", len(classData.Professors))
+	}
+
+	section, ok := findSection(classData, "CMPT", "220", "111")
+	if !ok {
+		t.Fatal("CMPT 220 111 was not imported")
+	}
+	if section.PrimaryProfessorID.String != "james.kirk@example.edu" {
+		t.Errorf("expected the first instructor as primary got %v", section.PrimaryProfessorID)
+	}
+	if section.Enrollment.Int32 != 18 || section.MaxEnrollment.Int32 != 24 {
+		t.Errorf("expected enrollment of 18/24 got %v/%v", section.Enrollment, section.MaxEnrollment)
+	}
+
+	var lab classentry.MeetingTime
+	for _, meetingTime := range classData.MeetingTimes {
+		if meetingTime.CourseNumber == "220" && meetingTime.MeetingType.String == "Lab" {
+			lab = meetingTime
+		}
+	}
+	if lab.Sequence != 1 || !lab.IsFriday || lab.IsMonday {
+		t.Errorf("expected the lab as the second meeting on fridays got %+v", lab)
+	}
+	if lab.StartMinutes.Microseconds != (13*60)*60_000_000 {
+		t.Errorf("expected the lab to start at 13:00 got %v", lab.StartMinutes)
+	}
+
+	for _, professor := range classData.Professors {
+		if professor.ID == "james.kirk@example.edu" && professor.Name != "James Kirk" {
+			t.Errorf("expected last, first names to be flipped got %s", professor.Name)
+		}
+	}
+}
+
+func TestXLSXImport(t *testing.T) {
+	classData := readTestingFile(t, "2024FA", "sections.xlsx")
+	if len(classData.Sections) != 2 || len(classData.MeetingTimes) != 3 || len(classData.Courses) != 2 {
+		t.Fatalf(
+			"expected 2 sections, 3 meeting times and 2 courses got %d, %d and %d",
+			len(classData.Sections),
+			len(classData.MeetingTimes),
+			len(classData.Courses),
+		)
+	}
+	for _, course := range classData.Courses {
+		if course.SubjectCode == "ACCT" && course.Title.String != "Principles of Accounting I" {
+			t.Errorf("expected the inline title got %v", course.Title)
+		}
+	}
+	for _, meetingTime := range classData.MeetingTimes {
+		if meetingTime.SubjectCode != "ACCT" {
+			continue
+		}
+		if meetingTime.StartMinutes.Microseconds != (9*60)*60_000_000 || meetingTime.EndMinutes.Microseconds != (9*60+50)*60_000_000 {
+			t.Errorf("expected 9:00 to 9:50 got %v to %v", meetingTime.StartMinutes, meetingTime.EndMinutes)
+		}
+		if !meetingTime.StartDate.Time.Equal(time.Date(2024, time.August, 26, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected the start date of 2024-08-26 got %v", meetingTime.StartDate.Time)
+		}
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	cases := []struct {
+		days     string
+		expected []time.Weekday
+		ok       bool
+	}{
+		{"MWF", []time.Weekday{time.Monday, time.Wednesday, time.Friday}, true},
+		{"TR", []time.Weekday{time.Tuesday, time.Thursday}, true},
+		{"MoWeFr", []time.Weekday{time.Monday, time.Wednesday, time.Friday}, true},
+		{"Tu/Th", []time.Weekday{time.Tuesday, time.Thursday}, true},
+		{"M W", []time.Weekday{time.Monday, time.Wednesday}, true},
+		{"TBA", nil, true},
+		{"MX", nil, false},
+	}
+	for _, c := range cases {
+		weekdays, ok := parseDays(c.days)
+		if ok != c.ok {
+			t.Errorf("%s expected ok to be %v", c.days, c.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if len(weekdays) != len(c.expected) {
+			t.Errorf("%s expected %v got %v", c.days, c.expected, weekdays)
+		}
+		for _, day := range c.expected {
+			if !weekdays[day] {
+				t.Errorf("%s expected %s", c.days, day)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package fileimport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// files are usually written in many small writes so changes are only reported
+//
+//	once the term's directory has been quiet for this long
+const WATCH_DEBOUNCE = 2 * time.Second
+
+// calls onChange with the school and term of import files which are written
+//
+//	blocks until the context is done
+func (f *fileImport) Watch(
+	ctx context.Context,
+	logger *slog.Logger,
+	onChange func(schoolID string, termCollectionID string),
+) error {
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return fmt.Errorf("Could not make import directory %w", err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("Could not start import watcher %w", err)
+	}
+	defer watcher.Close()
+
+	// fsnotify is not recursive so every school and term directory is watched
+	watchTree := func(dir string) {
+		filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil || !entry.IsDir() {
+				return nil
+			}
+			if depth := f.depth(path); depth > 2 {
+				return filepath.SkipDir
+			}
+			if err := watcher.Add(path); err != nil {
+				logger.Warn("Could not watch import directory", "dir", path, "error", err)
+			}
+			return nil
+		})
+	}
+	watchTree(f.dir)
+	logger.Info("Watching import directory", "dir", f.dir)
+
+	var timersMu sync.Mutex
+	timers := make(map[string]*time.Timer)
+	defer func() {
+		timersMu.Lock()
+		defer timersMu.Unlock()
+		for _, timer := range timers {
+			timer.Stop()
+		}
+	}()
+	debounceChange := func(schoolID string, termCollectionID string) {
+		key := schoolID + "/" + termCollectionID
+		timersMu.Lock()
+		defer timersMu.Unlock()
+		if timer, ok := timers[key]; ok {
+			timer.Reset(WATCH_DEBOUNCE)
+			return
+		}
+		timers[key] = time.AfterFunc(WATCH_DEBOUNCE, func() {
+			timersMu.Lock()
+			delete(timers, key)
+			timersMu.Unlock()
+			logger.Info("Import files changed", "school", schoolID, "termCollection", termCollectionID)
+			onChange(schoolID, termCollectionID)
+		})
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Warn("Import watcher error", "error", err)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					watchTree(event.Name)
+					// files may have been put in the directory before it was watched
+					if f.depth(event.Name) == 2 {
+						if filePaths, err := importFiles(event.Name); err == nil && len(filePaths) > 0 {
+							schoolID, termCollectionID, _ := f.termOf(filePaths[0])
+							debounceChange(schoolID, termCollectionID)
+						}
+					}
+					continue
+				}
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			schoolID, termCollectionID, ok := f.termOf(event.Name)
+			if !ok || !isImportFile(filepath.Base(event.Name)) {
+				continue
+			}
+			debounceChange(schoolID, termCollectionID)
+		}
+	}
+}
+
+// how many directories down the path is from the import directory
+func (f *fileImport) depth(path string) int {
+	relativePath, err := filepath.Rel(f.dir, path)
+	if err != nil || relativePath == "." {
+		return 0
+	}
+	return len(strings.Split(relativePath, string(filepath.Separator)))
+}
+
+// ex: <import dir>/example/2025SP/sections.csv -> example, 2025SP
+func (f *fileImport) termOf(filePath string) (string, string, bool) {
+	relativePath, err := filepath.Rel(f.dir, filePath)
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.Split(relativePath, string(filepath.Separator))
+	if len(parts) != 3 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
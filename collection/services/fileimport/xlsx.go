@@ -0,0 +1,193 @@
+package fileimport
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// only what is needed to read cell values of an xlsx file
+//
+//	styles are not read so dates and times come out as the numbers excel stores them as
+const XLSX_WORKBOOK_PATH = "xl/workbook.xml"
+const XLSX_RELATIONSHIPS_PATH = "xl/_rels/workbook.xml.rels"
+const XLSX_SHARED_STRINGS_PATH = "xl/sharedStrings.xml"
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// rich text is split into runs which are joined back together
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var text strings.Builder
+	for _, run := range t.Runs {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxCell struct {
+	// ex: B12
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func decodeZipXML(archive *zip.ReadCloser, name string, v any) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return xml.NewDecoder(file).Decode(v)
+}
+
+// reads the rows of the sheet with the name or the first sheet when the name is empty
+func readXLSX(filePath string, sheetName string) ([][]string, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var workbook xlsxWorkbook
+	if err := decodeZipXML(archive, XLSX_WORKBOOK_PATH, &workbook); err != nil {
+		return nil, fmt.Errorf("could not read workbook %w", err)
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New("workbook has no sheets")
+	}
+	sheetRID := workbook.Sheets[0].RID
+	if sheetName != "" {
+		sheetRID = ""
+		for _, sheet := range workbook.Sheets {
+			if sheet.Name == sheetName {
+				sheetRID = sheet.RID
+			}
+		}
+		if sheetRID == "" {
+			return nil, fmt.Errorf("workbook has no sheet `%s`", sheetName)
+		}
+	}
+
+	var relationships xlsxRelationships
+	if err := decodeZipXML(archive, XLSX_RELATIONSHIPS_PATH, &relationships); err != nil {
+		return nil, fmt.Errorf("could not read workbook relationships %w", err)
+	}
+	sheetPath := ""
+	for _, relationship := range relationships.Relationships {
+		if relationship.ID != sheetRID {
+			continue
+		}
+		// targets are relative to the workbook unless they start from the root
+		if strings.HasPrefix(relationship.Target, "/") {
+			sheetPath = strings.TrimPrefix(relationship.Target, "/")
+		} else {
+			sheetPath = path.Join(path.Dir(XLSX_WORKBOOK_PATH), relationship.Target)
+		}
+	}
+	if sheetPath == "" {
+		return nil, fmt.Errorf("sheet `%s` has no file", sheetRID)
+	}
+
+	// workbooks without any text do not have shared strings
+	var sharedStrings xlsxSharedStrings
+	err = decodeZipXML(archive, XLSX_SHARED_STRINGS_PATH, &sharedStrings)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read shared strings %w", err)
+	}
+
+	var sheet xlsxSheet
+	if err := decodeZipXML(archive, sheetPath, &sheet); err != nil {
+		return nil, fmt.Errorf("could not read sheet %w", err)
+	}
+
+	records := make([][]string, len(sheet.Rows))
+	for i, sheetRow := range sheet.Rows {
+		var values []string
+		for j, cell := range sheetRow.Cells {
+			// empty cells are left out so the reference says where the cell is
+			columnIndex := j
+			if cell.Ref != "" {
+				columnIndex = xlsxColumnIndex(cell.Ref)
+			}
+			for len(values) <= columnIndex {
+				values = append(values, "")
+			}
+			values[columnIndex], err = cell.value(sharedStrings)
+			if err != nil {
+				return nil, fmt.Errorf("cell %s %w", cell.Ref, err)
+			}
+		}
+		records[i] = values
+	}
+	return records, nil
+}
+
+func (c xlsxCell) value(sharedStrings xlsxSharedStrings) (string, error) {
+	switch c.Type {
+	case "s":
+		var index int
+		if _, err := fmt.Sscan(c.Value, &index); err != nil || index < 0 || index >= len(sharedStrings.Items) {
+			return "", fmt.Errorf("has an invalid shared string `%s`", c.Value)
+		}
+		return sharedStrings.Items[index].String(), nil
+	case "inlineStr":
+		return c.Inline.String(), nil
+	case "b":
+		if c.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	default:
+		return c.Value, nil
+	}
+}
+
+// ex: A1 -> 0
+// ex: AB7 -> 27
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, letter := range ref {
+		if letter < 'A' || letter > 'Z' {
+			break
+		}
+		index = index*26 + int(letter-'A'+1)
+	}
+	return index - 1
+}
@@ -0,0 +1,180 @@
+package jsoncatalog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type coursedogAPI struct{}
+
+type coursedogTerm struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type coursedogInstructor struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	IsPrimary bool   `json:"isPrimary"`
+}
+
+type coursedogMeeting struct {
+	// ex: ["M", "W"] using R for thursday and U for sunday
+	Days        []string `json:"days"`
+	StartTime   string   `json:"startTime"`
+	EndTime     string   `json:"endTime"`
+	StartDate   string   `json:"startDate"`
+	EndDate     string   `json:"endDate"`
+	MeetingType string   `json:"meetingType"`
+}
+
+type coursedogSection struct {
+	SubjectCode       string                `json:"subjectCode"`
+	SubjectName       string                `json:"subjectName"`
+	CourseNumber      string                `json:"courseNumber"`
+	SectionNumber     string                `json:"sectionNumber"`
+	CourseTitle       string                `json:"courseTitle"`
+	CourseDescription string                `json:"courseDescription"`
+	Credits           float32               `json:"credits"`
+	Campus            string                `json:"campus"`
+	InstructionMode   string                `json:"instructionMode"`
+	EnrolledSeats     *int32                `json:"enrolledSeats"`
+	MaxEnrollment     *int32                `json:"maxEnrollment"`
+	Instructors       []coursedogInstructor `json:"instructors"`
+	Meetings          []coursedogMeeting    `json:"meetings"`
+}
+
+type CoursedogSectionPage struct {
+	Data []coursedogSection `json:"data"`
+}
+
+func (coursedogAPI) termsRequest(ctx context.Context, baseURL string, tenant string) (*http.Request, error) {
+	return http.NewRequestWithContext(
+		ctx,
+		"GET",
+		baseURL+"/api/v1/"+url.PathEscape(tenant)+"/general/terms",
+		nil,
+	)
+}
+
+func (coursedogAPI) parseTerms(body io.Reader) ([]apiTerm, error) {
+	var coursedogTerms []coursedogTerm
+	if err := json.NewDecoder(body).Decode(&coursedogTerms); err != nil {
+		return nil, err
+	}
+	terms := make([]apiTerm, len(coursedogTerms))
+	for i, term := range coursedogTerms {
+		terms[i] = apiTerm{ID: term.ID, Name: term.Name}
+	}
+	return terms, nil
+}
+
+func (coursedogAPI) sectionsRequest(
+	ctx context.Context,
+	baseURL string,
+	tenant string,
+	termID string,
+	offset int,
+	limit int,
+) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"GET",
+		baseURL+"/api/v1/"+url.PathEscape(tenant)+"/sections/"+url.PathEscape(termID),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = url.Values{
+		"skip":  {strconv.Itoa(offset)},
+		"limit": {strconv.Itoa(limit)},
+	}.Encode()
+	return req, nil
+}
+
+func (coursedogAPI) parseSections(body io.Reader) (classentry.ClassData, int, error) {
+	var page CoursedogSectionPage
+	if err := json.NewDecoder(body).Decode(&page); err != nil {
+		return classentry.ClassData{}, 0, err
+	}
+	return ProcessCoursedogSections(page), len(page.Data), nil
+}
+
+var coursedogDays = map[string]int{"M": 0, "T": 1, "W": 2, "R": 3, "F": 4, "S": 5, "U": 6}
+
+func ProcessCoursedogSections(page CoursedogSectionPage) classentry.ClassData {
+	builder := newClassDataBuilder()
+	for _, s := range page.Data {
+		primaryProf := pgtype.Text{}
+		for _, instructor := range s.Instructors {
+			professor := professorFromName(instructor.FirstName+" "+instructor.LastName, instructor.Email)
+			if instructor.IsPrimary || !primaryProf.Valid {
+				primaryProf = pgtype.Text{String: professor.ID, Valid: true}
+			}
+			builder.addProfessor(professor)
+		}
+
+		for i, meeting := range s.Meetings {
+			var days [7]bool
+			for _, day := range meeting.Days {
+				if dayIndex, ok := coursedogDays[strings.ToUpper(day)]; ok {
+					days[dayIndex] = true
+				}
+			}
+			builder.meetingTimes = append(builder.meetingTimes, classentry.MeetingTime{
+				Sequence:        int32(i),
+				SectionSequence: s.SectionNumber,
+				SubjectCode:     s.SubjectCode,
+				CourseNumber:    s.CourseNumber,
+				StartDate:       parseDate("2006-01-02", meeting.StartDate),
+				EndDate:         parseDate("2006-01-02", meeting.EndDate),
+				MeetingType:     optionalText(meeting.MeetingType),
+				StartMinutes:    parseClockTime(meeting.StartTime),
+				EndMinutes:      parseClockTime(meeting.EndTime),
+				IsMonday:        days[0],
+				IsTuesday:       days[1],
+				IsWednesday:     days[2],
+				IsThursday:      days[3],
+				IsFriday:        days[4],
+				IsSaturday:      days[5],
+				IsSunday:        days[6],
+			})
+		}
+
+		section := classentry.Section{
+			Sequence:           s.SectionNumber,
+			SubjectCode:        s.SubjectCode,
+			CourseNumber:       s.CourseNumber,
+			Campus:             optionalText(s.Campus),
+			InstructionMethod:  optionalText(s.InstructionMode),
+			PrimaryProfessorID: primaryProf,
+		}
+		if s.EnrolledSeats != nil {
+			section.Enrollment = pgtype.Int4{Int32: *s.EnrolledSeats, Valid: true}
+		}
+		if s.MaxEnrollment != nil {
+			section.MaxEnrollment = pgtype.Int4{Int32: *s.MaxEnrollment, Valid: true}
+		}
+		builder.sections = append(builder.sections, section)
+
+		builder.addCourse(classentry.Course{
+			SubjectCode:        s.SubjectCode,
+			Number:             s.CourseNumber,
+			SubjectDescription: optionalText(s.SubjectName),
+			Title:              optionalText(s.CourseTitle),
+			Description:        optionalText(s.CourseDescription),
+			CreditHours:        s.Credits,
+		})
+	}
+	return builder.ToEntry()
+}
@@ -0,0 +1,492 @@
+package jsoncatalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pjt727/classy/collection/services"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// the json catalog apis which are known to this service
+type APIKind string
+
+const (
+	Coursedog APIKind = "coursedog"
+	Workday   APIKind = "workday"
+)
+
+const DEFAULT_PAGE_SIZE = 500
+
+// the shape of a json catalog api
+//
+//	sections are paged through until a page is not full
+type catalogAPI interface {
+	termsRequest(ctx context.Context, baseURL string, tenant string) (*http.Request, error)
+	parseTerms(body io.Reader) ([]apiTerm, error)
+	sectionsRequest(ctx context.Context, baseURL string, tenant string, termID string, offset int, limit int) (*http.Request, error)
+	// returns the amount of sections on the page so paging knows when to stop
+	parseSections(body io.Reader) (classentry.ClassData, int, error)
+}
+
+var catalogAPIs = map[APIKind]catalogAPI{
+	Coursedog: coursedogAPI{},
+	Workday:   workdayAPI{},
+}
+
+type apiTerm struct {
+	ID   string
+	Name string
+}
+
+// how the term ids of a school become terms
+//
+//	IDPattern must have `year` and `season` named groups which are looked up in Seasons
+//	without a pattern the year and season are found in the term's name
+type TermMapping struct {
+	IDPattern *regexp.Regexp                   `json:"id_pattern"`
+	Seasons   map[string]classentry.SeasonEnum `json:"seasons"`
+	// terms starting before this year are marked as no longer collecting
+	StillCollectingFromYear int32 `json:"still_collecting_from_year"`
+}
+
+type SchoolConfig struct {
+	API     APIKind `json:"api"`
+	BaseURL string  `json:"base_url"`
+	// the coursedog school id or the workday tenant
+	Tenant            string                   `json:"tenant"`
+	TermMapping       TermMapping              `json:"term_mapping"`
+	PageSize          int                      `json:"page_size"`
+	RequestRetryCount int                      `json:"request_retry_count"`
+	RateLimit         services.RateLimitConfig `json:"rate_limit"`
+}
+
+var defaultRateLimit = services.RateLimitConfig{
+	Interval:    services.Duration(100 * time.Millisecond),
+	Burst:       5,
+	MaxIncrease: services.Duration(200 * time.Millisecond),
+}
+
+type catalogSchool struct {
+	school      classentry.School
+	api         catalogAPI
+	baseURL     string
+	tenant      string
+	termMapping TermMapping
+	pageSize    int
+
+	RequestRetryCount int
+	rateLimiter       services.RateLimiter
+}
+
+type jsonCatalog struct {
+	// schools can be changed by the school registry while collections are running
+	mu      sync.RWMutex
+	schools map[string]*catalogSchool
+}
+
+// schools are added once their apis have been checked to be public
+func GetDefaultService() *jsonCatalog {
+	return &jsonCatalog{schools: make(map[string]*catalogSchool)}
+}
+
+// replaces the school if it was already added
+func (j *jsonCatalog) AddSchool(school classentry.School, config SchoolConfig) error {
+	if err := services.ValidateBaseURL(config.BaseURL); err != nil {
+		return err
+	}
+	api, ok := catalogAPIs[config.API]
+	if !ok {
+		return fmt.Errorf("Unknown catalog api `%s`", config.API)
+	}
+	if config.TermMapping.IDPattern != nil {
+		groups := config.TermMapping.IDPattern.SubexpNames()
+		if !slices.Contains(groups, "year") || !slices.Contains(groups, "season") {
+			return fmt.Errorf("Term id pattern for %s must have `year` and `season` groups", school.ID)
+		}
+	}
+	pageSize := config.PageSize
+	if pageSize <= 0 {
+		pageSize = DEFAULT_PAGE_SIZE
+	}
+	requestRetryCount := config.RequestRetryCount
+	if requestRetryCount < 0 {
+		return errors.New("Retries cannot be negative")
+	}
+	if requestRetryCount == 0 {
+		requestRetryCount = 3
+	}
+	rateLimit := config.RateLimit.WithDefaults(defaultRateLimit)
+	if err := rateLimit.Validate(); err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.schools[school.ID] = &catalogSchool{
+		school:            school,
+		api:               api,
+		baseURL:           strings.TrimSuffix(config.BaseURL, "/"),
+		tenant:            config.Tenant,
+		termMapping:       config.TermMapping,
+		pageSize:          pageSize,
+		RequestRetryCount: requestRetryCount,
+		rateLimiter:       rateLimit.NewRateLimiter(),
+	}
+	return nil
+}
+
+func (j *jsonCatalog) ConfigureSchool(school classentry.School, config []byte) error {
+	var schoolConfig SchoolConfig
+	if err := services.DecodeSchoolConfig(config, &schoolConfig); err != nil {
+		return err
+	}
+	return j.AddSchool(school, schoolConfig)
+}
+
+// collections already running keep going with the school
+func (j *jsonCatalog) RemoveSchool(schoolID string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.schools, schoolID)
+}
+
+// sets a the respective hostname of the school
+// mainly just used for testing purposes
+// returns true if the hostname was set else false
+func (j *jsonCatalog) SetHostname(schoolID string, newHostName string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	catalogSchool, ok := j.schools[schoolID]
+	if !ok {
+		return false
+	}
+	catalogSchool.baseURL = newHostName
+	return true
+}
+
+func (j *jsonCatalog) GetName() string { return "JsonCatalog" }
+
+func (j *jsonCatalog) ListValidSchools(
+	logger slog.Logger,
+	ctx context.Context,
+) ([]classentry.School, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	schools := make([]classentry.School, 0, len(j.schools))
+	for _, schoolEntry := range j.schools {
+		schools = append(schools, schoolEntry.school)
+	}
+	return schools, nil
+}
+
+func (j *jsonCatalog) GetTermCollections(
+	logger slog.Logger,
+	ctx context.Context,
+	school classentry.School,
+) ([]classentry.TermCollection, error) {
+	catalogSchool, err := j.getCatalogSchool(school.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := catalogSchool.api.termsRequest(ctx, catalogSchool.baseURL, catalogSchool.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("%w failed making term request %v", services.ErrIncorrectAssumption, err)
+	}
+	resp, err := catalogSchool.newClient(logger).Do(req)
+	err = services.RespOrStatusErr(resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("%w failed doing term request", err)
+	}
+	defer resp.Body.Close()
+	terms, err := catalogSchool.api.parseTerms(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w failed parsing term request %v", services.ErrIncorrectAssumption, err)
+	}
+
+	var termCollections []classentry.TermCollection
+	for _, term := range terms {
+		t, err := catalogSchool.termMapping.toTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		termCollections = append(termCollections, classentry.TermCollection{
+			ID:              term.ID,
+			Term:            t,
+			Name:            pgtype.Text{String: term.Name, Valid: term.Name != ""},
+			StillCollecting: t.Year >= catalogSchool.termMapping.StillCollectingFromYear,
+		})
+	}
+	return termCollections, nil
+}
+
+func (j *jsonCatalog) StageAllClasses(
+	logger slog.Logger,
+	ctx context.Context,
+	q *classentry.EntryQueries,
+	schoolID string,
+	termCollection classentry.TermCollection,
+	fullCollection bool,
+) error {
+	logger.Info("Starting class collection")
+	catalogSchool, err := j.getCatalogSchool(schoolID)
+	if err != nil {
+		return err
+	}
+
+	// the apis give everything in one go so full collections are no different
+	client := catalogSchool.newClient(logger)
+	totalSectionCount := 0
+	for offset := 0; ; offset += catalogSchool.pageSize {
+		pageLogger := logger.With(slog.Int("offset", offset), slog.Int("limit", catalogSchool.pageSize))
+		var sectionCount int
+		for i := range catalogSchool.RequestRetryCount {
+			sectionCount, err = catalogSchool.insertPage(pageLogger, ctx, q, client, termCollection, offset)
+			if err == nil {
+				break
+			}
+			pageLogger.Info("Retrying page after failures", "failures", i+1, "err", err)
+		}
+		if err != nil {
+			return err
+		}
+		totalSectionCount += sectionCount
+		if sectionCount < catalogSchool.pageSize {
+			break
+		}
+	}
+
+	logger.Info("sections finished getting", "sections", totalSectionCount)
+	return nil
+}
+
+func (j *jsonCatalog) getCatalogSchool(schoolID string) (*catalogSchool, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	schoolEntry, ok := j.schools[schoolID]
+	if !ok {
+		err := fmt.Errorf(
+			"%w school not known for this service: %s",
+			services.ErrIncorrectAssumption,
+			schoolID,
+		)
+		return nil, err
+	}
+	return schoolEntry, nil
+}
+
+func (c *catalogSchool) newClient(logger slog.Logger) *http.Client {
+	client := &http.Client{}
+	services.AddRateLimiter(client, &c.rateLimiter)
+	services.AddHttpReporting(client, logger)
+	return client
+}
+
+func (c *catalogSchool) insertPage(
+	logger *slog.Logger,
+	ctx context.Context,
+	q *classentry.EntryQueries,
+	client *http.Client,
+	termCollection classentry.TermCollection,
+	offset int,
+) (int, error) {
+	req, err := c.api.sectionsRequest(ctx, c.baseURL, c.tenant, termCollection.ID, offset, c.pageSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w failed making sections request %v", services.ErrIncorrectAssumption, err)
+	}
+	resp, err := client.Do(req)
+	err = services.RespOrStatusErr(resp, err)
+	if err != nil {
+		return 0, fmt.Errorf("%w failed doing sections request", err)
+	}
+	defer resp.Body.Close()
+
+	classData, sectionCount, err := c.api.parseSections(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("%w failed parsing sections request %v", services.ErrIncorrectAssumption, err)
+	}
+
+	err = q.InsertClassData(logger, ctx, classData)
+	if err != nil {
+		return 0, err
+	}
+	logger.Info(
+		"Successfully added sections and their related information",
+		"sections", len(classData.Sections),
+	)
+	return sectionCount, nil
+}
+
+func (m TermMapping) toTerm(term apiTerm) (classentry.Term, error) {
+	if m.IDPattern == nil {
+		return termFromName(term.Name)
+	}
+
+	match := m.IDPattern.FindStringSubmatch(term.ID)
+	if match == nil {
+		return classentry.Term{}, fmt.Errorf(
+			"%w term id `%s` does not match the term pattern",
+			services.ErrIncorrectAssumption,
+			term.ID,
+		)
+	}
+	yearSection := match[m.IDPattern.SubexpIndex("year")]
+	year, err := strconv.Atoi(yearSection)
+	// year sanity check
+	if err != nil || year > (time.Now().Year()+5) || year < 1850 {
+		return classentry.Term{}, fmt.Errorf(
+			"%w year section `%s` is a invalid/ infeasible year",
+			services.ErrIncorrectAssumption,
+			yearSection,
+		)
+	}
+	seasonSection := match[m.IDPattern.SubexpIndex("season")]
+	season, ok := m.Seasons[seasonSection]
+	if !ok {
+		return classentry.Term{}, fmt.Errorf(
+			"%w season section `%s` has no season mapping",
+			services.ErrIncorrectAssumption,
+			seasonSection,
+		)
+	}
+	return classentry.Term{Year: int32(year), Season: season}, nil
+}
+
+// ex: Spring 2025
+// ex: 2025 Fall Semester
+func termFromName(name string) (classentry.Term, error) {
+	desc := strings.ToLower(name)
+	year := 0
+	for _, field := range strings.Fields(desc) {
+		if parsedYear, err := strconv.Atoi(field); err == nil && len(field) == 4 {
+			year = parsedYear
+			break
+		}
+	}
+	// year sanity check
+	if year > (time.Now().Year()+5) || year < 1850 {
+		return classentry.Term{}, fmt.Errorf(
+			"%w term `%s` has no valid/ feasible year",
+			services.ErrIncorrectAssumption,
+			name,
+		)
+	}
+
+	var season classentry.SeasonEnum
+	if strings.Contains(desc, "winter") {
+		season = classentry.SeasonEnumWinter
+	} else if strings.Contains(desc, "spring") {
+		season = classentry.SeasonEnumSpring
+	} else if strings.Contains(desc, "summer") {
+		season = classentry.SeasonEnumSummer
+	} else if strings.Contains(desc, "fall") {
+		season = classentry.SeasonEnumFall
+	} else {
+		return classentry.Term{}, fmt.Errorf(
+			"%w term `%s` has no season match in it",
+			services.ErrIncorrectAssumption,
+			name,
+		)
+	}
+	return classentry.Term{Year: int32(year), Season: season}, nil
+}
+
+// builds the class data sections are converted into keeping courses and professors unique
+type classDataBuilder struct {
+	sections     []classentry.Section
+	meetingTimes []classentry.MeetingTime
+	professors   map[string]classentry.Professor
+	courses      map[string]classentry.Course
+}
+
+func newClassDataBuilder() classDataBuilder {
+	return classDataBuilder{
+		professors: make(map[string]classentry.Professor),
+		courses:    make(map[string]classentry.Course),
+	}
+}
+
+func (b *classDataBuilder) addCourse(course classentry.Course) {
+	b.courses[course.SubjectCode+","+course.Number] = course
+}
+
+func (b *classDataBuilder) addProfessor(professor classentry.Professor) {
+	b.professors[professor.ID] = professor
+}
+
+func (b *classDataBuilder) ToEntry() classentry.ClassData {
+	professors := make([]classentry.Professor, 0, len(b.professors))
+	for _, professor := range b.professors {
+		professors = append(professors, professor)
+	}
+	courses := make([]classentry.Course, 0, len(b.courses))
+	for _, course := range b.courses {
+		courses = append(courses, course)
+	}
+	return classentry.ClassData{
+		MeetingTimes: b.meetingTimes,
+		Sections:     b.sections,
+		Professors:   professors,
+		Courses:      courses,
+	}
+}
+
+// professors without an email get an id from their name
+func professorFromName(name string, email string) classentry.Professor {
+	name = strings.Join(strings.Fields(name), " ")
+	professor := classentry.Professor{
+		ID:           strings.ToLower(strings.ReplaceAll(name, " ", "-")),
+		Name:         name,
+		EmailAddress: pgtype.Text{String: email, Valid: email != ""},
+	}
+	if email != "" {
+		professor.ID = email
+	}
+	if first, last, ok := strings.Cut(name, " "); ok {
+		professor.FirstName = pgtype.Text{String: first, Valid: true}
+		professor.LastName = pgtype.Text{String: last, Valid: true}
+	}
+	return professor
+}
+
+func optionalText(text string) pgtype.Text {
+	text = strings.TrimSpace(text)
+	return pgtype.Text{String: text, Valid: text != ""}
+}
+
+// accepts 24 hour `15:04`, `1504` and 12 hour `3:04 PM` times
+func parseClockTime(clock string) pgtype.Time {
+	clock = strings.TrimSpace(clock)
+	for _, layout := range []string{"15:04", "1504", "3:04 PM", "3:04PM"} {
+		parsed, err := time.Parse(layout, clock)
+		if err != nil {
+			continue
+		}
+		const minuteToMicro int64 = 60_000_000
+		return pgtype.Time{
+			Microseconds: int64(parsed.Hour()*60+parsed.Minute()) * minuteToMicro,
+			Valid:        true,
+		}
+	}
+	return pgtype.Time{}
+}
+
+func parseDate(layout string, date string) pgtype.Timestamp {
+	parsed, err := time.Parse(layout, strings.TrimSpace(date))
+	if err != nil {
+		return pgtype.Timestamp{}
+	}
+	return pgtype.Timestamp{Time: parsed, Valid: true}
+}
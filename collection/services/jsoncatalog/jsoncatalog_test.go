@@ -0,0 +1,174 @@
+package jsoncatalog_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Pjt727/classy/collection/services/jsoncatalog"
+	"github.com/Pjt727/classy/collection/services/jsoncatalog/testjsoncatalog"
+	"github.com/Pjt727/classy/collection/services/testservice"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/Pjt727/classy/data/testdb"
+)
+
+func TestJsonCatalogData(t *testing.T) {
+	err := testdb.SetupTestDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	fileTestsCatalog, err := testjsoncatalog.GetFileTestingService()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = fileTestsCatalog.RunThroughOrchestrator()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestCoursedogMockServer(t *testing.T) {
+	err := testdb.SetupTestDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	logger := testservice.NewTestLogger()
+	serverContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testingMockService, err := testjsoncatalog.GetMockTestingService(*logger, serverContext, jsoncatalog.Coursedog)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = testservice.RunServiceThroughTestOrchestrator(*logger, testingMockService, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestWorkdayMockServer(t *testing.T) {
+	err := testdb.SetupTestDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	logger := testservice.NewTestLogger()
+	serverContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testingMockService, err := testjsoncatalog.GetMockTestingService(*logger, serverContext, jsoncatalog.Workday)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = testservice.RunServiceThroughTestOrchestrator(*logger, testingMockService, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestTermMapping(t *testing.T) {
+	logger := testservice.NewTestLogger()
+	serverContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, api := range []jsoncatalog.APIKind{jsoncatalog.Coursedog, jsoncatalog.Workday} {
+		testingMockService, err := testjsoncatalog.GetMockTestingService(*logger, serverContext, api)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schools, err := testingMockService.ListValidSchools(*logger, serverContext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		termCollections, err := testingMockService.GetTermCollections(*logger, serverContext, schools[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := termCollections[0]
+		if first.Term != (classentry.Term{Year: 2025, Season: classentry.SeasonEnumSpring}) {
+			t.Errorf("%s expected spring 2025 first got %v", api, first.Term)
+		}
+		second := termCollections[1]
+		if second.Term != (classentry.Term{Year: 2024, Season: classentry.SeasonEnumFall}) {
+			t.Errorf("%s expected fall 2024 second got %v", api, second.Term)
+		}
+	}
+}
+
+func TestFixturesConvert(t *testing.T) {
+	fixtureDir := filepath.Join(testjsoncatalog.TESTING_ASSETS_BASE_DIR, "example", "mock-server")
+
+	coursedogFile, err := os.Open(filepath.Join(fixtureDir, "coursedog-sections.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer coursedogFile.Close()
+	var coursedogPage jsoncatalog.CoursedogSectionPage
+	if err := json.NewDecoder(coursedogFile).Decode(&coursedogPage); err != nil {
+		t.Fatal(err)
+	}
+	coursedogData := jsoncatalog.ProcessCoursedogSections(coursedogPage)
+	checkClassData(t, "coursedog", coursedogData, 5, 5, 3)
+
+	workdayFile, err := os.Open(filepath.Join(fixtureDir, "workday-sections.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer workdayFile.Close()
+	var workdayPage jsoncatalog.WorkdaySectionPage
+	if err := json.NewDecoder(workdayFile).Decode(&workdayPage); err != nil {
+		t.Fatal(err)
+	}
+	workdayData := jsoncatalog.ProcessWorkdaySections(workdayPage)
+	checkClassData(t, "workday", workdayData, 4, 4, 3)
+
+	for _, section := range workdayData.Sections {
+		if section.CourseNumber == "220" && section.Sequence == "111" && section.PrimaryProfessorID.String != "james.kirk@example.edu" {
+			t.Errorf("workday expected the first instructor as primary got %v", section.PrimaryProfessorID)
+		}
+	}
+	for _, section := range coursedogData.Sections {
+		if section.CourseNumber == "220" && section.Sequence == "111" && section.PrimaryProfessorID.String != "james.kirk@example.edu" {
+			t.Errorf("coursedog expected the primary instructor got %v", section.PrimaryProfessorID)
+		}
+	}
+}
+
+func checkClassData(
+	t *testing.T,
+	api string,
+	classData classentry.ClassData,
+	sections int,
+	meetingTimes int,
+	courses int,
+) {
+	t.Helper()
+	if len(classData.Sections) != sections {
+		t.Errorf("%s expected %d sections got %d", api, sections, len(classData.Sections))
+	}
+	if len(classData.MeetingTimes) != meetingTimes {
+		t.Errorf("%s expected %d meeting times got %d", api, meetingTimes, len(classData.MeetingTimes))
+	}
+	if len(classData.Courses) != courses {
+		t.Errorf("%s expected %d courses got %d", api, courses, len(classData.Courses))
+	}
+	for _, meetingTime := range classData.MeetingTimes {
+		if !meetingTime.StartMinutes.Valid || !meetingTime.StartDate.Valid {
+			t.Errorf("%s meeting time missing its times %+v", api, meetingTime)
+		}
+	}
+}
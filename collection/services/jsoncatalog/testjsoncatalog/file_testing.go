@@ -0,0 +1,47 @@
+package testjsoncatalog
+
+import (
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/Pjt727/classy/collection/projectpath"
+	"github.com/Pjt727/classy/collection/services/jsoncatalog"
+	"github.com/Pjt727/classy/collection/services/testservice"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+)
+
+var TESTING_ASSETS_BASE_DIR = filepath.Join(
+	projectpath.Root,
+	"collection",
+	"services",
+	"jsoncatalog",
+	"test-assets",
+)
+
+func jsonToClassData(logger slog.Logger, data []byte) (classentry.ClassData, error) {
+	var page jsoncatalog.CoursedogSectionPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		logger.Error("Error decoding sections", "error", err)
+		return classentry.ClassData{}, err
+	}
+	return jsoncatalog.ProcessCoursedogSections(page), nil
+}
+
+// cycles through coursedog pages of the same term so changes between them are collected
+func GetFileTestingService() (*testservice.FileTestService, error) {
+	fileTestsCatalog, err := testservice.NewService(
+		[]testservice.TermDirectoryEntry{
+			{
+				SchoolID:       "example",
+				TermCollection: testservice.NewTermCollection("2025SP", classentry.SeasonEnumSpring, 2025),
+				FilePath:       filepath.Join(TESTING_ASSETS_BASE_DIR, "example", "spring-2025"),
+			},
+		},
+		jsonToClassData,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &fileTestsCatalog, nil
+}
@@ -0,0 +1,187 @@
+package testjsoncatalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/Pjt727/classy/collection"
+	"github.com/Pjt727/classy/collection/services/jsoncatalog"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+)
+
+// small enough that the fixtures take multiple pages
+const MOCK_PAGE_SIZE = 2
+
+const (
+	COURSEDOG_SCHOOL_ID = "example"
+	WORKDAY_SCHOOL_ID   = "example-workday"
+	TENANT              = "example"
+)
+
+var CoursedogTermMapping = jsoncatalog.TermMapping{
+	IDPattern: regexp.MustCompile(`^(?P<year>\d{4})(?P<season>SP|SU|FA|WI)$`),
+	Seasons: map[string]classentry.SeasonEnum{
+		"SP": classentry.SeasonEnumSpring,
+		"SU": classentry.SeasonEnumSummer,
+		"FA": classentry.SeasonEnumFall,
+		"WI": classentry.SeasonEnumWinter,
+	},
+	StillCollectingFromYear: 2025,
+}
+
+type mockServerState struct {
+	logger    slog.Logger
+	assetsDir string
+}
+
+func (m *mockServerState) serveFixture(w http.ResponseWriter, r *http.Request, fileName string) {
+	http.ServeFile(w, r, filepath.Join(m.assetsDir, fileName))
+}
+
+// serves the part of the fixture's list the offset and limit ask for
+func (m *mockServerState) servePage(
+	w http.ResponseWriter,
+	fileName string,
+	listKey string,
+	offsetStr string,
+	limitStr string,
+) {
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		m.logger.Error("invalid offset", "offset", offsetStr)
+		http.Error(w, fmt.Sprintf("Invalid offset `%s`", offsetStr), http.StatusBadRequest)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		m.logger.Error("invalid limit", "limit", limitStr)
+		http.Error(w, fmt.Sprintf("Invalid limit `%s`", limitStr), http.StatusBadRequest)
+		return
+	}
+
+	fixturePath := filepath.Join(m.assetsDir, fileName)
+	jsonData, err := os.ReadFile(fixturePath)
+	if err != nil {
+		m.logger.Error("could not read fixture", "path", fixturePath)
+		http.Error(w, fmt.Sprintf("Could not find fixture %s", fileName), http.StatusInternalServerError)
+		return
+	}
+	var fixture map[string][]json.RawMessage
+	if err := json.Unmarshal(jsonData, &fixture); err != nil {
+		m.logger.Error("could not parse fixture", "err", err)
+		http.Error(w, fmt.Sprintf("Could not parse fixture %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	entries := fixture[listKey]
+	start := min(offset, len(entries))
+	end := min(len(entries), offset+limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]json.RawMessage{listKey: entries[start:end]})
+}
+
+func (m *mockServerState) handleCoursedogTerms(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("tenant") != TENANT {
+		http.NotFound(w, r)
+		return
+	}
+	m.serveFixture(w, r, "coursedog-terms.json")
+}
+
+func (m *mockServerState) handleCoursedogSections(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("tenant") != TENANT {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	m.servePage(w, "coursedog-sections.json", "data", query.Get("skip"), query.Get("limit"))
+}
+
+func (m *mockServerState) handleWorkdayReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if r.PathValue("tenant") != TENANT || query.Get("format") != "json" {
+		m.logger.Error("report must be for the tenant as json", "tenant", r.PathValue("tenant"), "format", query.Get("format"))
+		http.Error(w, "Bad Request: invalid report request", http.StatusBadRequest)
+		return
+	}
+
+	switch r.PathValue("report") {
+	case jsoncatalog.WORKDAY_TERMS_REPORT:
+		m.serveFixture(w, r, "workday-terms.json")
+	case jsoncatalog.WORKDAY_SECTIONS_REPORT:
+		if query.Get("Academic_Period") == "" {
+			m.logger.Error("academic period must be set")
+			http.Error(w, "Bad Request: 'Academic_Period' is required", http.StatusBadRequest)
+			return
+		}
+		m.servePage(w, "workday-sections.json", "Report_Entry", query.Get("Offset"), query.Get("Limit"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// returns a new server which will be closed once the context ends
+func NewMockServer(logger slog.Logger, ctx context.Context) *httptest.Server {
+	serverState := mockServerState{
+		logger:    logger,
+		assetsDir: filepath.Join(TESTING_ASSETS_BASE_DIR, "example", "mock-server"),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/{tenant}/general/terms", serverState.handleCoursedogTerms)
+	mux.HandleFunc("GET /api/v1/{tenant}/sections/{termID}", serverState.handleCoursedogSections)
+	mux.HandleFunc("GET /ccx/service/customreport2/{tenant}/{report}", serverState.handleWorkdayReport)
+
+	server := httptest.NewServer(mux)
+	// close server once the context finishes
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	return server
+}
+
+// this context is tied to the server and once the context closes the server will too
+func GetMockTestingService(logger slog.Logger, ctx context.Context, api jsoncatalog.APIKind) (collection.Service, error) {
+	mockServer := NewMockServer(logger, ctx)
+
+	catalogService := jsoncatalog.GetDefaultService()
+	var err error
+	switch api {
+	case jsoncatalog.Coursedog:
+		err = catalogService.AddSchool(
+			classentry.School{ID: COURSEDOG_SCHOOL_ID, Name: "Example University"},
+			jsoncatalog.SchoolConfig{
+				API:         jsoncatalog.Coursedog,
+				BaseURL:     mockServer.URL,
+				Tenant:      TENANT,
+				TermMapping: CoursedogTermMapping,
+				PageSize:    MOCK_PAGE_SIZE,
+			},
+		)
+	case jsoncatalog.Workday:
+		err = catalogService.AddSchool(
+			classentry.School{ID: WORKDAY_SCHOOL_ID, Name: "Example Workday University"},
+			jsoncatalog.SchoolConfig{
+				API:      jsoncatalog.Workday,
+				BaseURL:  mockServer.URL,
+				Tenant:   TENANT,
+				PageSize: MOCK_PAGE_SIZE,
+			},
+		)
+	default:
+		err = fmt.Errorf("Unknown catalog api `%s`", api)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return catalogService, nil
+}
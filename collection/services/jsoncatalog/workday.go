@@ -0,0 +1,212 @@
+package jsoncatalog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// workday student data is read through custom reports (RaaS) the school publishes
+const (
+	WORKDAY_TERMS_REPORT    = "Classy_Academic_Periods"
+	WORKDAY_SECTIONS_REPORT = "Classy_Course_Sections"
+)
+
+type workdayAPI struct{}
+
+type workdayTerm struct {
+	ID   string `json:"Academic_Period_ID"`
+	Name string `json:"Academic_Period"`
+}
+
+type workdayTerms struct {
+	ReportEntry []workdayTerm `json:"Report_Entry"`
+}
+
+// reports give every value as text and join lists with `; `
+type workdaySection struct {
+	Subject             string `json:"Subject"`
+	SubjectDescription  string `json:"Subject_Description"`
+	CourseNumber        string `json:"Course_Number"`
+	SectionNumber       string `json:"Section_Number"`
+	CourseTitle         string `json:"Course_Title"`
+	CourseDescription   string `json:"Course_Description"`
+	Credits             string `json:"Credits"`
+	Campus              string `json:"Campus"`
+	DeliveryMode        string `json:"Delivery_Mode"`
+	InstructionalFormat string `json:"Instructional_Format"`
+	Enrolled            string `json:"Enrolled"`
+	Capacity            string `json:"Capacity"`
+	Instructors         string `json:"Instructors"`
+	InstructorEmails    string `json:"Instructor_Emails"`
+	// ex: MWF | 11:00 AM - 11:50 AM | 01/21/2025 - 05/09/2025
+	MeetingPatterns string `json:"Meeting_Patterns"`
+}
+
+type WorkdaySectionPage struct {
+	ReportEntry []workdaySection `json:"Report_Entry"`
+}
+
+func workdayReportURL(baseURL string, tenant string, report string) string {
+	return baseURL + "/ccx/service/customreport2/" + url.PathEscape(tenant) + "/" + report
+}
+
+func (workdayAPI) termsRequest(ctx context.Context, baseURL string, tenant string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", workdayReportURL(baseURL, tenant, WORKDAY_TERMS_REPORT), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = url.Values{"format": {"json"}}.Encode()
+	return req, nil
+}
+
+func (workdayAPI) parseTerms(body io.Reader) ([]apiTerm, error) {
+	var report workdayTerms
+	if err := json.NewDecoder(body).Decode(&report); err != nil {
+		return nil, err
+	}
+	terms := make([]apiTerm, len(report.ReportEntry))
+	for i, term := range report.ReportEntry {
+		terms[i] = apiTerm{ID: term.ID, Name: term.Name}
+	}
+	return terms, nil
+}
+
+func (workdayAPI) sectionsRequest(
+	ctx context.Context,
+	baseURL string,
+	tenant string,
+	termID string,
+	offset int,
+	limit int,
+) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", workdayReportURL(baseURL, tenant, WORKDAY_SECTIONS_REPORT), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = url.Values{
+		"format":          {"json"},
+		"Academic_Period": {termID},
+		"Offset":          {strconv.Itoa(offset)},
+		"Limit":           {strconv.Itoa(limit)},
+	}.Encode()
+	return req, nil
+}
+
+func (workdayAPI) parseSections(body io.Reader) (classentry.ClassData, int, error) {
+	var page WorkdaySectionPage
+	if err := json.NewDecoder(body).Decode(&page); err != nil {
+		return classentry.ClassData{}, 0, err
+	}
+	return ProcessWorkdaySections(page), len(page.ReportEntry), nil
+}
+
+var workdayDays = map[rune]int{'M': 0, 'T': 1, 'W': 2, 'R': 3, 'F': 4, 'S': 5, 'U': 6}
+
+func splitWorkdayList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ";") {
+		values = append(values, strings.TrimSpace(value))
+	}
+	return values
+}
+
+func optionalWorkdayInt(number string) pgtype.Int4 {
+	parsed, err := strconv.Atoi(strings.TrimSpace(number))
+	return pgtype.Int4{Int32: int32(parsed), Valid: err == nil}
+}
+
+func ProcessWorkdaySections(page WorkdaySectionPage) classentry.ClassData {
+	builder := newClassDataBuilder()
+	for _, s := range page.ReportEntry {
+		primaryProf := pgtype.Text{}
+		emails := splitWorkdayList(s.InstructorEmails)
+		for i, name := range splitWorkdayList(s.Instructors) {
+			if name == "" {
+				continue
+			}
+			email := ""
+			if i < len(emails) {
+				email = emails[i]
+			}
+			professor := professorFromName(name, email)
+			// the first instructor listed is the primary one
+			if !primaryProf.Valid {
+				primaryProf = pgtype.Text{String: professor.ID, Valid: true}
+			}
+			builder.addProfessor(professor)
+		}
+
+		meetingSequence := int32(0)
+		for _, pattern := range splitWorkdayList(s.MeetingPatterns) {
+			if pattern == "" {
+				continue
+			}
+			meetingTime := classentry.MeetingTime{
+				Sequence:        meetingSequence,
+				SectionSequence: s.SectionNumber,
+				SubjectCode:     s.Subject,
+				CourseNumber:    s.CourseNumber,
+				MeetingType:     optionalText(s.InstructionalFormat),
+			}
+			meetingSequence++
+
+			parts := strings.Split(pattern, "|")
+			var days [7]bool
+			for _, day := range strings.TrimSpace(parts[0]) {
+				if dayIndex, ok := workdayDays[day]; ok {
+					days[dayIndex] = true
+				}
+			}
+			meetingTime.IsMonday = days[0]
+			meetingTime.IsTuesday = days[1]
+			meetingTime.IsWednesday = days[2]
+			meetingTime.IsThursday = days[3]
+			meetingTime.IsFriday = days[4]
+			meetingTime.IsSaturday = days[5]
+			meetingTime.IsSunday = days[6]
+			if len(parts) > 1 {
+				if start, end, ok := strings.Cut(parts[1], "-"); ok {
+					meetingTime.StartMinutes = parseClockTime(start)
+					meetingTime.EndMinutes = parseClockTime(end)
+				}
+			}
+			if len(parts) > 2 {
+				if start, end, ok := strings.Cut(parts[2], "-"); ok {
+					meetingTime.StartDate = parseDate("01/02/2006", start)
+					meetingTime.EndDate = parseDate("01/02/2006", end)
+				}
+			}
+			builder.meetingTimes = append(builder.meetingTimes, meetingTime)
+		}
+
+		builder.sections = append(builder.sections, classentry.Section{
+			Sequence:           s.SectionNumber,
+			SubjectCode:        s.Subject,
+			CourseNumber:       s.CourseNumber,
+			Campus:             optionalText(s.Campus),
+			InstructionMethod:  optionalText(s.DeliveryMode),
+			Enrollment:         optionalWorkdayInt(s.Enrolled),
+			MaxEnrollment:      optionalWorkdayInt(s.Capacity),
+			PrimaryProfessorID: primaryProf,
+		})
+
+		credits, _ := strconv.ParseFloat(strings.TrimSpace(s.Credits), 32)
+		builder.addCourse(classentry.Course{
+			SubjectCode:        s.Subject,
+			Number:             s.CourseNumber,
+			SubjectDescription: optionalText(s.SubjectDescription),
+			Title:              optionalText(s.CourseTitle),
+			Description:        optionalText(s.CourseDescription),
+			CreditHours:        float32(credits),
+		})
+	}
+	return builder.ToEntry()
+}
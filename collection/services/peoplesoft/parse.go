@@ -0,0 +1,332 @@
+package peoplesoft
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pjt727/classy/collection/services"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// every response has the session id and a state number which must be posted back
+//
+//	posting an old state number makes peoplesoft reject the action
+type pageState struct {
+	sid      string
+	stateNum string
+}
+
+type selectOption struct {
+	value string
+	text  string
+}
+
+type searchPage struct {
+	state    pageState
+	terms    []selectOption
+	subjects []selectOption
+}
+
+type searchMeeting struct {
+	days        map[time.Weekday]bool
+	startTime   pgtype.Time
+	endTime     pgtype.Time
+	startDate   pgtype.Timestamp
+	endDate     pgtype.Timestamp
+	instructors []string
+}
+
+type searchClass struct {
+	classNumber string
+	sequence    string
+	component   string
+	meetings    []searchMeeting
+}
+
+type searchCourse struct {
+	subjectCode  string
+	courseNumber string
+	title        string
+	classes      []searchClass
+}
+
+type classDetail struct {
+	units             float32
+	instructionMethod pgtype.Text
+	campus            pgtype.Text
+	enrollment        pgtype.Int4
+	maxEnrollment     pgtype.Int4
+	description       pgtype.Text
+}
+
+// the instructor shown for classes which do not have one yet
+var unassignedInstructors = map[string]bool{
+	"staff":           true,
+	"tba":             true,
+	"to be announced": true,
+}
+
+var peoplesoftDays = map[string]time.Weekday{
+	"Mo": time.Monday,
+	"Tu": time.Tuesday,
+	"We": time.Wednesday,
+	"Th": time.Thursday,
+	"Fr": time.Friday,
+	"Sa": time.Saturday,
+	"Su": time.Sunday,
+}
+
+// ex: ACCT  101 - Principles of Accounting I
+var courseHeaderRegex = regexp.MustCompile(`^(\S+)\s+(\S+)\s+-\s+(.+)$`)
+
+// ex: 01-LEC
+var classNameRegex = regexp.MustCompile(`^(\S+)-(\S+)`)
+
+// selectors with ids need quoting because peoplesoft ids are full of `$`
+func byID(id string) string {
+	return fmt.Sprintf("[id='%s']", id)
+}
+
+// peoplesoft pads everything with non breaking spaces which fields also splits on
+func cleanText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// multiple meetings of a class are put in the same cell separated by line breaks
+func cellLines(selection *goquery.Selection) []string {
+	selection = selection.Clone()
+	selection.Find("br").ReplaceWithHtml("\n")
+	var lines []string
+	for _, line := range strings.Split(selection.Text(), "\n") {
+		lines = append(lines, cleanText(line))
+	}
+	return lines
+}
+
+func parsePageState(doc *goquery.Document) (pageState, error) {
+	sid, sidOk := doc.Find(byID("ICSID")).Attr("value")
+	stateNum, stateOk := doc.Find(byID("ICStateNum")).Attr("value")
+	if !sidOk || !stateOk {
+		return pageState{}, fmt.Errorf("%w page is missing its session state", services.ErrIncorrectAssumption)
+	}
+	return pageState{sid: sid, stateNum: stateNum}, nil
+}
+
+func parseSelectOptions(doc *goquery.Document, id string) []selectOption {
+	var options []selectOption
+	doc.Find(byID(id)).Find("option").Each(func(_ int, option *goquery.Selection) {
+		value, _ := option.Attr("value")
+		if value == "" {
+			return
+		}
+		options = append(options, selectOption{value: value, text: cleanText(option.Text())})
+	})
+	return options
+}
+
+func parseSearchPage(doc *goquery.Document) (searchPage, error) {
+	state, err := parsePageState(doc)
+	if err != nil {
+		return searchPage{}, err
+	}
+	page := searchPage{
+		state:    state,
+		terms:    parseSelectOptions(doc, termField),
+		subjects: parseSelectOptions(doc, subjectField),
+	}
+	if len(page.terms) == 0 {
+		return page, fmt.Errorf("%w class search page has no terms", services.ErrIncorrectAssumption)
+	}
+	return page, nil
+}
+
+// large searches ask if the user wants to keep going before showing results
+func needsConfirmation(doc *goquery.Document) bool {
+	message := strings.ToLower(doc.Find(byID("DERIVED_SSE_DSP_SSR_MSG_TEXT")).Text())
+	return strings.Contains(message, "would you like to continue")
+}
+
+func parseClassSearch(doc *goquery.Document) ([]searchCourse, error) {
+	errorText := cleanText(doc.Find(byID("DERIVED_CLSMSG_ERROR_TEXT")).Text())
+	if strings.Contains(strings.ToLower(errorText), "no results") {
+		return nil, nil
+	}
+	if errorText != "" {
+		return nil, fmt.Errorf("%w class search error `%s`", services.ErrIncorrectAssumption, errorText)
+	}
+
+	var courses []searchCourse
+	var err error
+	doc.Find("div[id^='win0divSSR_CLSRSLT_WRK_GROUPBOX2$']").EachWithBreak(func(_ int, group *goquery.Selection) bool {
+		header := cleanText(group.Find("div[id^='win0divSSR_CLSRSLT_WRK_GROUPBOX2GP$']").Text())
+		match := courseHeaderRegex.FindStringSubmatch(header)
+		if match == nil {
+			err = fmt.Errorf("%w course header `%s` not in the expected format", services.ErrIncorrectAssumption, header)
+			return false
+		}
+		course := searchCourse{subjectCode: match[1], courseNumber: match[2], title: match[3]}
+
+		group.Find("a[id^='MTG_CLASS_NBR$']").EachWithBreak(func(_ int, classLink *goquery.Selection) bool {
+			id, _ := classLink.Attr("id")
+			rowIndex := strings.TrimPrefix(id, "MTG_CLASS_NBR$")
+			var class searchClass
+			class, err = parseSearchClass(doc, rowIndex, cleanText(classLink.Text()))
+			if err != nil {
+				return false
+			}
+			course.classes = append(course.classes, class)
+			return true
+		})
+		if err != nil {
+			return false
+		}
+		courses = append(courses, course)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return courses, nil
+}
+
+func parseSearchClass(doc *goquery.Document, rowIndex string, classNumber string) (searchClass, error) {
+	// ex: 01-LEC<br />Regular
+	className := cellLines(doc.Find(byID("MTG_CLASSNAME$" + rowIndex)))
+	match := classNameRegex.FindStringSubmatch(className[0])
+	if match == nil {
+		return searchClass{}, fmt.Errorf("%w class name `%s` not in the expected format", services.ErrIncorrectAssumption, className[0])
+	}
+	class := searchClass{
+		classNumber: classNumber,
+		sequence:    match[1],
+		component:   match[2],
+	}
+
+	dayTimes := cellLines(doc.Find(byID("MTG_DAYTIME$" + rowIndex)))
+	instructors := cellLines(doc.Find(byID("MTG_INSTR$" + rowIndex)))
+	dates := cellLines(doc.Find(byID("MTG_DATES$" + rowIndex)))
+
+	// multiple instructors of one meeting are on their own lines ending with a comma
+	var meetingInstructors [][]string
+	var current []string
+	for _, line := range instructors {
+		for _, name := range strings.Split(line, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" && !unassignedInstructors[strings.ToLower(name)] {
+				current = append(current, name)
+			}
+		}
+		if !strings.HasSuffix(line, ",") {
+			meetingInstructors = append(meetingInstructors, current)
+			current = nil
+		}
+	}
+
+	for i, dayTime := range dayTimes {
+		meeting, err := parseDayTime(dayTime)
+		if err != nil {
+			return class, err
+		}
+		if i < len(dates) {
+			meeting.startDate, meeting.endDate = parseDates(dates[i])
+		}
+		if i < len(meetingInstructors) {
+			meeting.instructors = meetingInstructors[i]
+		}
+		class.meetings = append(class.meetings, meeting)
+	}
+	return class, nil
+}
+
+// ex: MoWeFr 11:00AM - 11:50AM
+// ex: TBA
+func parseDayTime(dayTime string) (searchMeeting, error) {
+	meeting := searchMeeting{days: make(map[time.Weekday]bool)}
+	if dayTime == "" || strings.EqualFold(dayTime, "TBA") {
+		return meeting, nil
+	}
+
+	days, times, ok := strings.Cut(dayTime, " ")
+	if !ok || len(days)%2 != 0 {
+		return meeting, fmt.Errorf("%w day time `%s` not in the expected format", services.ErrIncorrectAssumption, dayTime)
+	}
+	for i := 0; i < len(days); i += 2 {
+		day, ok := peoplesoftDays[days[i:i+2]]
+		if !ok {
+			return meeting, fmt.Errorf("%w day `%s` not known", services.ErrIncorrectAssumption, days[i:i+2])
+		}
+		meeting.days[day] = true
+	}
+
+	start, end, ok := strings.Cut(times, "-")
+	if !ok {
+		return meeting, fmt.Errorf("%w times `%s` not in the expected format", services.ErrIncorrectAssumption, times)
+	}
+	meeting.startTime = toPeoplesoftTime(start)
+	meeting.endTime = toPeoplesoftTime(end)
+	return meeting, nil
+}
+
+// campus solutions can be configured for 12 or 24 hour times
+func toPeoplesoftTime(timeText string) pgtype.Time {
+	timeText = strings.TrimSpace(timeText)
+	for _, layout := range []string{"3:04PM", "15:04", "15.04"} {
+		parsed, err := time.Parse(layout, timeText)
+		if err != nil {
+			continue
+		}
+		const minuteToMicro int64 = 60_000_000
+		minutes := int64(parsed.Hour()*60 + parsed.Minute())
+		return pgtype.Time{Microseconds: minutes * minuteToMicro, Valid: true}
+	}
+	return pgtype.Time{}
+}
+
+// ex: 01/21/2025 - 05/09/2025
+func parseDates(dates string) (pgtype.Timestamp, pgtype.Timestamp) {
+	const dateLayout = "01/02/2006"
+	startDate, endDate := pgtype.Timestamp{}, pgtype.Timestamp{}
+	start, end, ok := strings.Cut(dates, "-")
+	if !ok {
+		return startDate, endDate
+	}
+	startDateTime, err1 := time.Parse(dateLayout, strings.TrimSpace(start))
+	endDateTime, err2 := time.Parse(dateLayout, strings.TrimSpace(end))
+	if err1 == nil && err2 == nil {
+		startDate = pgtype.Timestamp{Time: startDateTime, Valid: true}
+		endDate = pgtype.Timestamp{Time: endDateTime, Valid: true}
+	}
+	return startDate, endDate
+}
+
+func parseClassDetail(doc *goquery.Document) classDetail {
+	optionalText := func(id string) pgtype.Text {
+		text := cleanText(doc.Find(byID(id)).Text())
+		return pgtype.Text{String: text, Valid: text != ""}
+	}
+	optionalInt := func(id string) pgtype.Int4 {
+		number, err := strconv.Atoi(cleanText(doc.Find(byID(id)).Text()))
+		return pgtype.Int4{Int32: int32(number), Valid: err == nil}
+	}
+
+	detail := classDetail{
+		instructionMethod: optionalText("INSTRUCT_MODE_DESCR"),
+		campus:            optionalText("CAMPUS_TBL_DESCR"),
+		enrollment:        optionalInt("SSR_CLS_DTL_WRK_ENRL_TOT"),
+		maxEnrollment:     optionalInt("SSR_CLS_DTL_WRK_ENRL_CAP"),
+		description:       optionalText("DERIVED_CLSRCH_DESCRLONG"),
+	}
+	// ex: 3 units
+	// ex: 1 - 4 units
+	units := strings.Fields(cleanText(doc.Find(byID("SSR_CLS_DTL_WRK_UNITS_RANGE")).Text()))
+	if len(units) > 0 {
+		if credits, err := strconv.ParseFloat(units[0], 32); err == nil {
+			detail.units = float32(credits)
+		}
+	}
+	return detail
+}
@@ -0,0 +1,113 @@
+package peoplesoft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Pjt727/classy/collection/projectpath"
+	"github.com/PuerkitoBio/goquery"
+)
+
+func loadRecordedPage(t *testing.T, fileName string) *goquery.Document {
+	t.Helper()
+	file, err := os.Open(filepath.Join(
+		projectpath.Root, "collection", "services", "peoplesoft", "test-assets", "example", "mock-server", fileName,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	doc, err := goquery.NewDocumentFromReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestParseClassSearch(t *testing.T) {
+	results, err := parseClassSearch(loadRecordedPage(t, "search-CMPT.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 courses got %d", len(results))
+	}
+
+	softwareDevelopment := results[0]
+	if softwareDevelopment.subjectCode != "CMPT" ||
+		softwareDevelopment.courseNumber != "220" ||
+		softwareDevelopment.title != "Software Development I" {
+		t.Errorf("unexpected course %+v", softwareDevelopment)
+	}
+	if len(softwareDevelopment.classes) != 2 {
+		t.Fatalf("expected 2 classes got %d", len(softwareDevelopment.classes))
+	}
+
+	inPerson := softwareDevelopment.classes[0]
+	if inPerson.classNumber != "2001" || inPerson.sequence != "111" || inPerson.component != "LEC" {
+		t.Errorf("unexpected class %+v", inPerson)
+	}
+	if len(inPerson.meetings) != 2 {
+		t.Fatalf("expected 2 meetings got %d", len(inPerson.meetings))
+	}
+	lab := inPerson.meetings[1]
+	if !lab.days[time.Friday] || lab.days[time.Tuesday] {
+		t.Errorf("expected the second meeting only on friday got %v", lab.days)
+	}
+	if lab.startTime.Microseconds != 10*60*60_000_000 || lab.endTime.Microseconds != (11*60+50)*60_000_000 {
+		t.Errorf("unexpected meeting times %v %v", lab.startTime, lab.endTime)
+	}
+	if lab.startDate.Time.Format("01/02/2006") != "01/24/2025" {
+		t.Errorf("unexpected start date %v", lab.startDate)
+	}
+	if len(lab.instructors) != 1 || lab.instructors[0] != "James Kirk" {
+		t.Errorf("unexpected instructors %v", lab.instructors)
+	}
+
+	online := softwareDevelopment.classes[1]
+	if len(online.meetings) != 1 || online.meetings[0].startTime.Valid || len(online.meetings[0].days) != 0 {
+		t.Errorf("expected one unscheduled meeting got %+v", online.meetings)
+	}
+}
+
+func TestParseMultipleInstructors(t *testing.T) {
+	results, err := parseClassSearch(loadRecordedPage(t, "search-ACCT.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	staffed := results[0].classes[1].meetings[0]
+	if len(staffed.instructors) != 0 {
+		t.Errorf("expected staff to not be an instructor got %v", staffed.instructors)
+	}
+	shared := results[1].classes[0].meetings[0]
+	if len(shared.instructors) != 2 || shared.instructors[1] != "Brian Ortiz" {
+		t.Errorf("unexpected instructors %v", shared.instructors)
+	}
+}
+
+func TestParseNoResults(t *testing.T) {
+	results, err := parseClassSearch(loadRecordedPage(t, "search-HIST.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected no results got %d", len(results))
+	}
+}
+
+func TestParseClassDetail(t *testing.T) {
+	detail := parseClassDetail(loadRecordedPage(t, "detail-1003.html"))
+	if detail.units != 4 ||
+		detail.enrollment.Int32 != 12 ||
+		detail.maxEnrollment.Int32 != 25 ||
+		detail.campus.String != "Main Campus" ||
+		!detail.description.Valid {
+		t.Errorf("unexpected detail %+v", detail)
+	}
+	noDescription := parseClassDetail(loadRecordedPage(t, "detail-2003.html"))
+	if noDescription.description.Valid {
+		t.Errorf("expected no description got %v", noDescription.description)
+	}
+}
@@ -0,0 +1,649 @@
+package peoplesoft
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Pjt727/classy/collection/services"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// the public (guest) class search component most campus solutions installs expose
+const CLASS_SEARCH_PATH = "/c/COMMUNITY_ACCESS.CLASS_SEARCH.GBL"
+
+// form fields of the class search page
+const (
+	institutionField = "CLASS_SRCH_WRK2_INSTITUTION$31$"
+	termField        = "CLASS_SRCH_WRK2_STRM$35$"
+	subjectField     = "SSR_CLSRCH_WRK_SUBJECT_SRCH$0"
+	openOnlyField    = "SSR_CLSRCH_WRK_SSR_OPEN_ONLY$chk$3"
+	searchAction     = "CLASS_SRCH_WRK2_SSR_PB_CLASS_SRCH"
+	// accepts the "your search will return over 50 classes" prompt
+	confirmAction = "#ICSave"
+)
+
+type peoplesoftSchool struct {
+	school classentry.School
+	// everything before `/c/` e.g. https://cs.example.edu/psc/CSPRD/EMPLOYEE/SA
+	baseURL     string
+	institution string
+
+	// to limit the max amount of requests out that go without having an answer
+	RegularCollectionSubjectSemaphore int
+	FullCollectionSubjectSemaphore    int
+	ClassDetailSemaphore              int // keep in mind this is per subject search
+	RequestRetryCount                 int
+	rateLimiter                       services.RateLimiter
+}
+
+type SchoolConfig struct {
+	BaseURL string `json:"base_url"`
+	// the institution code the school has in campus solutions
+	Institution                       string                   `json:"institution"`
+	RegularCollectionSubjectSemaphore int                      `json:"regular_collection_subject_semaphore"`
+	FullCollectionSubjectSemaphore    int                      `json:"full_collection_subject_semaphore"`
+	ClassDetailSemaphore              int                      `json:"class_detail_semaphore"`
+	RequestRetryCount                 int                      `json:"request_retry_count"`
+	RateLimit                         services.RateLimitConfig `json:"rate_limit"`
+}
+
+var defaultRateLimit = services.RateLimitConfig{
+	Interval:    services.Duration(250 * time.Millisecond),
+	Burst:       5,
+	MaxIncrease: services.Duration(500 * time.Millisecond),
+}
+
+type peoplesoft struct {
+	// schools can be changed by the school registry while collections are running
+	mu      sync.RWMutex
+	schools map[string]*peoplesoftSchool
+}
+
+// schools are added once their class search pages have been checked to be public
+func GetDefaultService() *peoplesoft {
+	return &peoplesoft{schools: make(map[string]*peoplesoftSchool)}
+}
+
+// replaces the school if it was already added
+func (p *peoplesoft) AddSchool(school classentry.School, config SchoolConfig) error {
+	if err := services.ValidateBaseURL(config.BaseURL); err != nil {
+		return err
+	}
+	if config.Institution == "" {
+		return errors.New("PeopleSoft schools need an institution")
+	}
+	withDefault := func(value int, defaultValue int) int {
+		if value == 0 {
+			return defaultValue
+		}
+		return value
+	}
+	peoplesoftSchool := &peoplesoftSchool{
+		school:                            school,
+		baseURL:                           strings.TrimSuffix(config.BaseURL, "/"),
+		institution:                       config.Institution,
+		RegularCollectionSubjectSemaphore: withDefault(config.RegularCollectionSubjectSemaphore, 4),
+		FullCollectionSubjectSemaphore:    withDefault(config.FullCollectionSubjectSemaphore, 2),
+		ClassDetailSemaphore:              withDefault(config.ClassDetailSemaphore, 5),
+		RequestRetryCount:                 withDefault(config.RequestRetryCount, 3),
+	}
+	if peoplesoftSchool.RegularCollectionSubjectSemaphore < 0 ||
+		peoplesoftSchool.FullCollectionSubjectSemaphore < 0 ||
+		peoplesoftSchool.ClassDetailSemaphore < 0 ||
+		peoplesoftSchool.RequestRetryCount < 0 {
+		return errors.New("Semaphores and retries cannot be negative")
+	}
+	rateLimit := config.RateLimit.WithDefaults(defaultRateLimit)
+	if err := rateLimit.Validate(); err != nil {
+		return err
+	}
+	peoplesoftSchool.rateLimiter = rateLimit.NewRateLimiter()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.schools[school.ID] = peoplesoftSchool
+	return nil
+}
+
+func (p *peoplesoft) ConfigureSchool(school classentry.School, config []byte) error {
+	var schoolConfig SchoolConfig
+	if err := services.DecodeSchoolConfig(config, &schoolConfig); err != nil {
+		return err
+	}
+	return p.AddSchool(school, schoolConfig)
+}
+
+// collections already running keep going with the school
+func (p *peoplesoft) RemoveSchool(schoolID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.schools, schoolID)
+}
+
+// sets a the respective hostname of the school
+// mainly just used for testing purposes
+// returns true if the hostname was set else false
+func (p *peoplesoft) SetHostname(schoolID string, newHostName string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	peoplesoftSchool, ok := p.schools[schoolID]
+	if !ok {
+		return false
+	}
+	peoplesoftSchool.baseURL = newHostName
+	return true
+}
+
+func (p *peoplesoft) GetName() string { return "PeopleSoft" }
+
+func (p *peoplesoft) ListValidSchools(
+	logger slog.Logger,
+	ctx context.Context,
+) ([]classentry.School, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	schools := make([]classentry.School, 0, len(p.schools))
+	for _, schoolEntry := range p.schools {
+		schools = append(schools, schoolEntry.school)
+	}
+	return schools, nil
+}
+
+func (p *peoplesoft) GetTermCollections(
+	logger slog.Logger,
+	ctx context.Context,
+	school classentry.School,
+) ([]classentry.TermCollection, error) {
+	peoplesoftSchool, err := p.getPeoplesoftSchool(school.ID)
+	if err != nil {
+		return nil, err
+	}
+	client := peoplesoftSchool.newClient(logger)
+	searchPage, err := peoplesoftSchool.openClassSearch(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	var termCollections []classentry.TermCollection
+	for _, option := range searchPage.terms {
+		term, err := termConversion(option.text)
+		if err != nil {
+			return nil, err
+		}
+		termCollections = append(termCollections, classentry.TermCollection{
+			ID:   option.value,
+			Term: term,
+			Name: pgtype.Text{String: option.text, Valid: true},
+			// class search only lists the terms that are open for searching
+			StillCollecting: true,
+		})
+	}
+	return termCollections, nil
+}
+
+func (p *peoplesoft) StageAllClasses(
+	logger slog.Logger,
+	ctx context.Context,
+	q *classentry.EntryQueries,
+	schoolID string,
+	termCollection classentry.TermCollection,
+	fullCollection bool,
+) error {
+	logger.Info("Starting class collection")
+	peoplesoftSchool, err := p.getPeoplesoftSchool(schoolID)
+	if err != nil {
+		return err
+	}
+	return peoplesoftSchool.stageAllClasses(logger, ctx, q, termCollection, fullCollection)
+}
+
+func (p *peoplesoft) getPeoplesoftSchool(schoolID string) (*peoplesoftSchool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	schoolEntry, ok := p.schools[schoolID]
+	if !ok {
+		err := fmt.Errorf(
+			"%w school not known for this service: %s",
+			services.ErrIncorrectAssumption,
+			schoolID,
+		)
+		return nil, err
+	}
+	return schoolEntry, nil
+}
+
+// class search is stateful so every client gets its own session
+func (p *peoplesoftSchool) newClient(logger slog.Logger) *http.Client {
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	services.AddRateLimiter(client, &p.rateLimiter)
+	services.AddHttpReporting(client, logger)
+	return client
+}
+
+func (p *peoplesoftSchool) classSearchURL() string {
+	return p.baseURL + CLASS_SEARCH_PATH
+}
+
+// starts a new session on the class search page
+func (p *peoplesoftSchool) openClassSearch(ctx context.Context, client *http.Client) (searchPage, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.classSearchURL(), nil)
+	if err != nil {
+		return searchPage{}, fmt.Errorf("%w failed making class search request %v", services.ErrIncorrectAssumption, err)
+	}
+	req.URL.RawQuery = url.Values{
+		"Page":   {"SSR_CLSRCH_ENTRY"},
+		"Action": {"U"},
+	}.Encode()
+
+	doc, err := doPage(client, req)
+	if err != nil {
+		return searchPage{}, fmt.Errorf("%w failed doing class search request", err)
+	}
+	return parseSearchPage(doc)
+}
+
+func (p *peoplesoftSchool) postAction(
+	ctx context.Context,
+	client *http.Client,
+	state pageState,
+	action string,
+	fields url.Values,
+) (*goquery.Document, error) {
+	formData := url.Values{
+		"ICAction":     {action},
+		"ICSID":        {state.sid},
+		"ICStateNum":   {state.stateNum},
+		"ICType":       {"Panel"},
+		"ICElementNum": {"0"},
+	}
+	for key, values := range fields {
+		formData[key] = values
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		p.classSearchURL(),
+		bytes.NewBufferString(formData.Encode()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w failed making `%s` request %v", services.ErrIncorrectAssumption, action, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	doc, err := doPage(client, req)
+	if err != nil {
+		return nil, fmt.Errorf("%w failed doing `%s` request", err, action)
+	}
+	return doc, nil
+}
+
+func doPage(client *http.Client, req *http.Request) (*goquery.Document, error) {
+	resp, err := client.Do(req)
+	err = services.RespOrStatusErr(resp, err)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w failed parsing page %v", services.ErrIncorrectAssumption, err)
+	}
+	return doc, nil
+}
+
+func (p *peoplesoftSchool) stageAllClasses(
+	logger slog.Logger,
+	ctx context.Context,
+	q *classentry.EntryQueries,
+	termCollection classentry.TermCollection,
+	fullCollection bool,
+) error {
+	// the subjects are only listed on the search page
+	searchPage, err := p.openClassSearch(ctx, p.newClient(logger))
+	if err != nil {
+		return err
+	}
+	if len(searchPage.subjects) == 0 {
+		return fmt.Errorf("%w class search has no subjects", services.ErrIncorrectAssumption)
+	}
+
+	// peoplesoft only shows enrollment on the class details so every collection gets
+	//    them and a full collection just goes slower
+	subjectLimit := p.RegularCollectionSubjectSemaphore
+	if fullCollection {
+		subjectLimit = p.FullCollectionSubjectSemaphore
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(subjectLimit)
+
+	var totalSectionCount int32
+	for _, subject := range searchPage.subjects {
+		eg.Go(func() error {
+			subjectLogger := logger.With(slog.String("subject", subject.value))
+			var sectionCount int
+			var err error
+			for i := range p.RequestRetryCount {
+				sectionCount, err = p.insertSubject(subjectLogger, ctx, q, termCollection, subject)
+				if err == nil {
+					break
+				}
+				subjectLogger.Info("Retrying subject after failures", "failures", i+1, "err", err)
+			}
+			if err != nil {
+				return err
+			}
+			atomic.AddInt32(&totalSectionCount, int32(sectionCount))
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	logger.Info("sections finished getting", "sections", totalSectionCount, "subjects", len(searchPage.subjects))
+	return nil
+}
+
+// searches for every class in the subject with a new session
+func (p *peoplesoftSchool) insertSubject(
+	logger *slog.Logger,
+	ctx context.Context,
+	q *classentry.EntryQueries,
+	termCollection classentry.TermCollection,
+	subject selectOption,
+) (int, error) {
+	client := p.newClient(*logger)
+	searchPage, err := p.openClassSearch(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+
+	doc, err := p.postAction(ctx, client, searchPage.state, searchAction, url.Values{
+		institutionField: {p.institution},
+		termField:        {termCollection.ID},
+		subjectField:     {subject.value},
+		openOnlyField:    {"N"},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if needsConfirmation(doc) {
+		state, err := parsePageState(doc)
+		if err != nil {
+			return 0, err
+		}
+		doc, err = p.postAction(ctx, client, state, confirmAction, nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	results, err := parseClassSearch(doc)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		logger.Debug("No classes for subject")
+		return 0, nil
+	}
+
+	details, err := p.getClassDetails(ctx, client, termCollection, results)
+	if err != nil {
+		return 0, err
+	}
+	classData := processClassSearch(results, details, subject)
+
+	err = q.InsertClassData(logger, ctx, classData.ToEntry())
+	if err != nil {
+		return 0, err
+	}
+	logger.Info(
+		"Successfully added sections and their related information",
+		"sections", len(classData.Sections),
+	)
+	return len(classData.Sections), nil
+}
+
+// gets the details of every class concurrently keyed by the class number
+func (p *peoplesoftSchool) getClassDetails(
+	ctx context.Context,
+	client *http.Client,
+	termCollection classentry.TermCollection,
+	results []searchCourse,
+) (map[string]classDetail, error) {
+	var mu sync.Mutex
+	details := make(map[string]classDetail)
+
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(p.ClassDetailSemaphore)
+	for _, course := range results {
+		for _, class := range course.classes {
+			eg.Go(func() error {
+				detail, err := p.getClassDetail(ctx, client, termCollection, class.classNumber)
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				details[class.classNumber] = detail
+				return nil
+			})
+		}
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+func (p *peoplesoftSchool) getClassDetail(
+	ctx context.Context,
+	client *http.Client,
+	termCollection classentry.TermCollection,
+	classNumber string,
+) (classDetail, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.classSearchURL(), nil)
+	if err != nil {
+		return classDetail{}, fmt.Errorf("%w failed making class detail request %v", services.ErrIncorrectAssumption, err)
+	}
+	req.URL.RawQuery = url.Values{
+		"Page":        {"SSR_CLSRCH_DTL"},
+		"Action":      {"U"},
+		"INSTITUTION": {p.institution},
+		"STRM":        {termCollection.ID},
+		"CLASS_NBR":   {classNumber},
+	}.Encode()
+
+	doc, err := doPage(client, req)
+	if err != nil {
+		return classDetail{}, fmt.Errorf("%w failed doing class detail request", err)
+	}
+	return parseClassDetail(doc), nil
+}
+
+type classData struct {
+	Sections     []classentry.Section
+	MeetingTimes []classentry.MeetingTime
+	Professors   map[string]classentry.Professor
+	Courses      map[string]classentry.Course
+}
+
+func (c *classData) ToEntry() classentry.ClassData {
+	professors := make([]classentry.Professor, 0, len(c.Professors))
+	for _, professor := range c.Professors {
+		professors = append(professors, professor)
+	}
+	courses := make([]classentry.Course, 0, len(c.Courses))
+	for _, course := range c.Courses {
+		courses = append(courses, course)
+	}
+	return classentry.ClassData{
+		MeetingTimes: c.MeetingTimes,
+		Sections:     c.Sections,
+		Professors:   professors,
+		Courses:      courses,
+	}
+}
+
+func processClassSearch(
+	results []searchCourse,
+	details map[string]classDetail,
+	subject selectOption,
+) classData {
+	var sections []classentry.Section
+	var meetingTimes []classentry.MeetingTime
+	professors := make(map[string]classentry.Professor)
+	courses := make(map[string]classentry.Course)
+
+	subjectDescription := pgtype.Text{}
+	if _, description, ok := strings.Cut(subject.text, " - "); ok {
+		subjectDescription = pgtype.Text{String: strings.TrimSpace(description), Valid: true}
+	}
+
+	for _, result := range results {
+		course := classentry.Course{
+			SubjectCode:        result.subjectCode,
+			Number:             result.courseNumber,
+			SubjectDescription: subjectDescription,
+			Title:              pgtype.Text{String: result.title, Valid: true},
+		}
+
+		for _, class := range result.classes {
+			detail := details[class.classNumber]
+			// the details are per class but the courses are the same for all of them
+			if detail.description.Valid {
+				course.Description = detail.description
+			}
+			if detail.units > course.CreditHours {
+				course.CreditHours = detail.units
+			}
+
+			primaryProf := pgtype.Text{}
+			for i, meeting := range class.meetings {
+				for _, instructor := range meeting.instructors {
+					// peoplesoft only gives names so they are the best there is for an id
+					professorID := professorID(instructor)
+					if !primaryProf.Valid {
+						primaryProf = pgtype.Text{String: professorID, Valid: true}
+					}
+					firstName, lastName := pgtype.Text{}, pgtype.Text{}
+					if first, last, ok := strings.Cut(instructor, " "); ok {
+						firstName = pgtype.Text{String: first, Valid: true}
+						lastName = pgtype.Text{String: strings.TrimSpace(last), Valid: true}
+					}
+					professors[professorID] = classentry.Professor{
+						ID:        professorID,
+						Name:      instructor,
+						FirstName: firstName,
+						LastName:  lastName,
+					}
+				}
+
+				meetingTimes = append(meetingTimes, classentry.MeetingTime{
+					Sequence:        int32(i),
+					SectionSequence: class.sequence,
+					SubjectCode:     result.subjectCode,
+					CourseNumber:    result.courseNumber,
+					StartDate:       meeting.startDate,
+					EndDate:         meeting.endDate,
+					MeetingType:     pgtype.Text{String: class.component, Valid: class.component != ""},
+					StartMinutes:    meeting.startTime,
+					EndMinutes:      meeting.endTime,
+					IsMonday:        meeting.days[time.Monday],
+					IsTuesday:       meeting.days[time.Tuesday],
+					IsWednesday:     meeting.days[time.Wednesday],
+					IsThursday:      meeting.days[time.Thursday],
+					IsFriday:        meeting.days[time.Friday],
+					IsSaturday:      meeting.days[time.Saturday],
+					IsSunday:        meeting.days[time.Sunday],
+				})
+			}
+
+			sections = append(sections, classentry.Section{
+				Sequence:           class.sequence,
+				SubjectCode:        result.subjectCode,
+				CourseNumber:       result.courseNumber,
+				Campus:             detail.campus,
+				Enrollment:         detail.enrollment,
+				MaxEnrollment:      detail.maxEnrollment,
+				InstructionMethod:  detail.instructionMethod,
+				PrimaryProfessorID: primaryProf,
+			})
+		}
+		courses[result.subjectCode+","+result.courseNumber] = course
+	}
+
+	return classData{
+		Sections:     sections,
+		MeetingTimes: meetingTimes,
+		Professors:   professors,
+		Courses:      courses,
+	}
+}
+
+func professorID(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// example terms:
+// 2025 Spring Term
+// Fall 2024
+func termConversion(description string) (classentry.Term, error) {
+	desc := strings.ToLower(description)
+	var dbTerm classentry.Term
+
+	year := 0
+	for _, field := range strings.Fields(desc) {
+		if len(field) != 4 {
+			continue
+		}
+		if parsedYear, err := strconv.Atoi(field); err == nil {
+			year = parsedYear
+			break
+		}
+	}
+	// year sanity check
+	if year > (time.Now().Year()+5) || year < 1850 {
+		err := fmt.Errorf(
+			"%w term `%s` has no valid/ feasible year",
+			services.ErrIncorrectAssumption,
+			description,
+		)
+		return dbTerm, err
+	}
+
+	var season classentry.SeasonEnum
+	if strings.Contains(desc, "winter") {
+		season = classentry.SeasonEnumWinter
+	} else if strings.Contains(desc, "spring") {
+		season = classentry.SeasonEnumSpring
+	} else if strings.Contains(desc, "summer") {
+		season = classentry.SeasonEnumSummer
+	} else if strings.Contains(desc, "fall") {
+		season = classentry.SeasonEnumFall
+	} else {
+		err := fmt.Errorf(
+			"%w term description `%s` has no season match in it",
+			services.ErrIncorrectAssumption,
+			description,
+		)
+		return dbTerm, err
+	}
+	dbTerm = classentry.Term{Year: int32(year), Season: season}
+	return dbTerm, nil
+}
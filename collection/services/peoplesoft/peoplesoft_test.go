@@ -0,0 +1,67 @@
+package peoplesoft_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Pjt727/classy/collection/services/peoplesoft/testpeoplesoft"
+	"github.com/Pjt727/classy/collection/services/testservice"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/Pjt727/classy/data/testdb"
+)
+
+func TestPeopleSoftMockTerms(t *testing.T) {
+	logger := testservice.NewTestLogger()
+	serverContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testingMockService, err := testpeoplesoft.GetMockTestingService(*logger, serverContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schools, err := testingMockService.ListValidSchools(*logger, serverContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	termCollections, err := testingMockService.GetTermCollections(*logger, serverContext, schools[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []classentry.TermCollection{
+		{ID: "2251", Term: classentry.Term{Year: 2025, Season: classentry.SeasonEnumSpring}},
+		{ID: "2249", Term: classentry.Term{Year: 2024, Season: classentry.SeasonEnumFall}},
+		{ID: "2246", Term: classentry.Term{Year: 2024, Season: classentry.SeasonEnumSummer}},
+	}
+	if len(termCollections) != len(expected) {
+		t.Fatalf("expected %d terms got %d", len(expected), len(termCollections))
+	}
+	for i, termCollection := range termCollections {
+		if termCollection.ID != expected[i].ID || termCollection.Term != expected[i].Term {
+			t.Errorf("expected term %v got %v", expected[i], termCollection)
+		}
+	}
+}
+
+func TestPeopleSoftMockServer(t *testing.T) {
+	err := testdb.SetupTestDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	logger := testservice.NewTestLogger()
+	serverContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testingMockService, err := testpeoplesoft.GetMockTestingService(*logger, serverContext)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = testservice.RunServiceThroughTestOrchestrator(*logger, testingMockService, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
@@ -0,0 +1,224 @@
+package testpeoplesoft
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Pjt727/classy/collection"
+	"github.com/Pjt727/classy/collection/projectpath"
+	"github.com/Pjt727/classy/collection/services/peoplesoft"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/google/uuid"
+)
+
+var TESTING_ASSETS_BASE_DIR = filepath.Join(
+	projectpath.Root,
+	"collection",
+	"services",
+	"peoplesoft",
+	"test-assets",
+)
+
+// the site path of the recorded school
+const SITE_PATH = "/psc/CSPRD/EMPLOYEE/SA"
+
+// real class search asks for confirmation over 50 classes
+//
+//	this is lowered so the recorded subjects go through the confirmation
+const CONFIRM_SECTION_COUNT = 2
+
+var stateNumRegex = regexp.MustCompile(`(id='ICStateNum' value=')\d+(')`)
+
+type session struct {
+	stateNum int
+	// the subject waiting on a confirmation
+	pendingSubject string
+}
+
+type mockServerState struct {
+	logger        slog.Logger
+	assetsDir     string
+	sessions      map[string]*session
+	sessionsMutex sync.Mutex
+}
+
+func (m *mockServerState) getSession(r *http.Request) (*session, bool) {
+	cookie, err := r.Cookie("PSJSESSIONID")
+	if err != nil || cookie.Value == "" {
+		return nil, false
+	}
+	m.sessionsMutex.Lock()
+	defer m.sessionsMutex.Unlock()
+	s, ok := m.sessions[cookie.Value]
+	return s, ok
+}
+
+// serves the recorded page with the session's state number
+func (m *mockServerState) servePage(w http.ResponseWriter, fileName string, stateNum int) {
+	pagePath := filepath.Join(m.assetsDir, fileName)
+	page, err := os.ReadFile(pagePath)
+	if err != nil {
+		m.logger.Error("could not find page", "path", pagePath)
+		http.Error(w, fmt.Sprintf("Could not find page %s", fileName), http.StatusNotFound)
+		return
+	}
+	page = stateNumRegex.ReplaceAll(page, []byte("${1}"+strconv.Itoa(stateNum)+"${2}"))
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Write(page)
+}
+
+func (m *mockServerState) handleClassSearch(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("Page") {
+	case "SSR_CLSRCH_ENTRY":
+		m.handleEntry(w, r)
+	case "SSR_CLSRCH_DTL":
+		m.handleDetail(w, r)
+	default:
+		m.logger.Error("unknown page", "page", r.URL.Query().Get("Page"))
+		http.Error(w, "Bad Request: unknown page", http.StatusBadRequest)
+	}
+}
+
+// this route starts a session
+func (m *mockServerState) handleEntry(w http.ResponseWriter, r *http.Request) {
+	s, ok := m.getSession(r)
+	if !ok {
+		sessionID := uuid.New().String()
+		s = &session{}
+		m.sessionsMutex.Lock()
+		m.sessions[sessionID] = s
+		m.sessionsMutex.Unlock()
+		http.SetCookie(w, &http.Cookie{Name: "PSJSESSIONID", Value: sessionID, Path: "/", HttpOnly: true})
+	}
+	m.sessionsMutex.Lock()
+	s.stateNum = 1
+	stateNum := s.stateNum
+	m.sessionsMutex.Unlock()
+	m.servePage(w, "entry.html", stateNum)
+}
+
+// this route gets the details of one class
+func (m *mockServerState) handleDetail(w http.ResponseWriter, r *http.Request) {
+	if _, ok := m.getSession(r); !ok {
+		m.logger.Error("detail requested without a session")
+		http.Error(w, "Session cookie not found or invalid", http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("INSTITUTION") == "" || query.Get("STRM") == "" {
+		m.logger.Error("institution and term must be set")
+		http.Error(w, "Bad Request: no null values allowed", http.StatusBadRequest)
+		return
+	}
+	classNumber, err := strconv.Atoi(query.Get("CLASS_NBR"))
+	if err != nil {
+		m.logger.Error("invalid class number", "classNumber", query.Get("CLASS_NBR"))
+		http.Error(w, "Bad Request: invalid class number", http.StatusBadRequest)
+		return
+	}
+	m.servePage(w, fmt.Sprintf("detail-%d.html", classNumber), 1)
+}
+
+// this route does the actions of the search page
+func (m *mockServerState) handleAction(w http.ResponseWriter, r *http.Request) {
+	s, ok := m.getSession(r)
+	if !ok {
+		m.logger.Error("action without a session")
+		http.Error(w, "Session cookie not found or invalid", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		m.logger.Error("errors parsing form", "err", err)
+		http.Error(w, "Bad Request: Could not parse form", http.StatusBadRequest)
+		return
+	}
+
+	m.sessionsMutex.Lock()
+	defer m.sessionsMutex.Unlock()
+	if r.FormValue("ICStateNum") != strconv.Itoa(s.stateNum) {
+		m.logger.Error("stale state number", "given", r.FormValue("ICStateNum"), "expected", s.stateNum)
+		http.Error(w, "Page data is inconsistent with database", http.StatusConflict)
+		return
+	}
+	s.stateNum++
+
+	switch r.FormValue("ICAction") {
+	case "CLASS_SRCH_WRK2_SSR_PB_CLASS_SRCH":
+		subject := r.FormValue("SSR_CLSRCH_WRK_SUBJECT_SRCH$0")
+		if subject == "" || r.FormValue("CLASS_SRCH_WRK2_STRM$35$") == "" || r.FormValue("CLASS_SRCH_WRK2_INSTITUTION$31$") == "" {
+			m.logger.Error("search is missing values")
+			http.Error(w, "Bad Request: no null values allowed", http.StatusBadRequest)
+			return
+		}
+		resultsPage, err := os.ReadFile(filepath.Join(m.assetsDir, "search-"+subject+".html"))
+		if err != nil {
+			m.logger.Error("no recorded search for subject", "subject", subject)
+			http.Error(w, fmt.Sprintf("No recorded search for %s", subject), http.StatusNotFound)
+			return
+		}
+		if strings.Count(string(resultsPage), "id='MTG_CLASS_NBR$") > CONFIRM_SECTION_COUNT {
+			s.pendingSubject = subject
+			m.servePage(w, "confirm.html", s.stateNum)
+			return
+		}
+		m.servePage(w, "search-"+subject+".html", s.stateNum)
+	case "#ICSave":
+		if s.pendingSubject == "" {
+			m.logger.Error("confirmed without a pending search")
+			http.Error(w, "Bad Request: nothing to confirm", http.StatusBadRequest)
+			return
+		}
+		subject := s.pendingSubject
+		s.pendingSubject = ""
+		m.servePage(w, "search-"+subject+".html", s.stateNum)
+	default:
+		m.logger.Error("unknown action", "action", r.FormValue("ICAction"))
+		http.Error(w, "Bad Request: unknown action", http.StatusBadRequest)
+	}
+}
+
+// returns a new server which will be closed once the context ends
+func NewMockServer(logger slog.Logger, ctx context.Context) *httptest.Server {
+	serverState := mockServerState{
+		logger:    logger,
+		assetsDir: filepath.Join(TESTING_ASSETS_BASE_DIR, "example", "mock-server"),
+		sessions:  make(map[string]*session),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+SITE_PATH+peoplesoft.CLASS_SEARCH_PATH, serverState.handleClassSearch)
+	mux.HandleFunc("POST "+SITE_PATH+peoplesoft.CLASS_SEARCH_PATH, serverState.handleAction)
+
+	server := httptest.NewServer(mux)
+	// close server once the context finishes
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	return server
+}
+
+// this context is tied to the server and once the context closes the server will too
+func GetMockTestingService(logger slog.Logger, ctx context.Context) (collection.Service, error) {
+	mockServer := NewMockServer(logger, ctx)
+
+	peoplesoftService := peoplesoft.GetDefaultService()
+	school := classentry.School{ID: "example", Name: "Example University"}
+	err := peoplesoftService.AddSchool(school, peoplesoft.SchoolConfig{
+		BaseURL:     mockServer.URL + SITE_PATH,
+		Institution: "EXMPL",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return peoplesoftService, nil
+}
@@ -0,0 +1,17 @@
+package testservice
+
+import (
+	"log/slog"
+	"os"
+
+	logginghelpers "github.com/Pjt727/classy/data/logging-helpers"
+)
+
+// the logger service tests write to stdout with
+func NewTestLogger() *slog.Logger {
+	return slog.New(logginghelpers.NewHandler(os.Stdout, &logginghelpers.Options{
+		AddSource: true,
+		Level:     slog.LevelInfo,
+		NoColor:   false,
+	}))
+}
@@ -268,7 +268,9 @@ func (t *FileTestService) RunThroughOrchestrator() error {
 				StillCollecting: true,
 			}
 			for range termDirectory.filesPaths {
-				results, err := orch.UpdateAllSectionsOfSchool(context.Background(), dbTermCollection, collection.DefualtUpdateSectionsConfig())
+				// the files can remove any amount of a term's sections
+				config := collection.DefualtUpdateSectionsConfig().SetDeletionGuard(collection.NoDeletionGuard)
+				results, err := orch.UpdateAllSectionsOfSchool(context.Background(), dbTermCollection, config)
 				if err != nil {
 					return err
 				}
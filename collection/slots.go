@@ -0,0 +1,151 @@
+package collection
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// the per school and per service limits are shared by every worker pool through advisory locks
+//
+//	a limit of n is n lock keys and a job holds one free key of its school and of its service
+//	the locks belong to one held connection so when the process dies postgres frees its slots
+type jobSlots struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	conn *pgxpool.Conn
+	held map[int32][]int64
+}
+
+func newJobSlots(pool *pgxpool.Pool, logger *slog.Logger) *jobSlots {
+	return &jobSlots{
+		pool:   pool,
+		logger: logger,
+		held:   make(map[int32][]int64),
+	}
+}
+
+// ex: school:marist:0
+func slotKey(kind string, name string, slot int) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s:%s:%d", kind, name, slot)
+	return int64(h.Sum64())
+}
+
+// takes a slot of the school and of the service for the job
+//
+//	false when every slot of either is taken by another job
+func (s *jobSlots) acquire(
+	ctx context.Context,
+	jobID int32,
+	schoolID string,
+	schoolLimit int,
+	serviceName string,
+	serviceLimit int,
+) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		conn, err := s.pool.Acquire(ctx)
+		if err != nil {
+			return false, err
+		}
+		s.conn = conn
+	}
+	q := db.New(s.conn)
+	// advisory locks are reentrant so the session would take its own jobs' slots again
+	taken := make(map[int64]bool)
+	for _, keys := range s.held {
+		for _, key := range keys {
+			taken[key] = true
+		}
+	}
+
+	var keys []int64
+	for _, limit := range []struct {
+		kind  string
+		name  string
+		slots int
+	}{{"school", schoolID, schoolLimit}, {"service", serviceName, serviceLimit}} {
+		acquired := false
+		for slot := range limit.slots {
+			key := slotKey(limit.kind, limit.name, slot)
+			if taken[key] {
+				continue
+			}
+			ok, err := q.TryAdvisoryLock(ctx, key)
+			if err != nil {
+				s.dropConnLocked(ctx, err)
+				return false, err
+			}
+			if ok {
+				keys = append(keys, key)
+				acquired = true
+				break
+			}
+		}
+		if !acquired {
+			s.unlockLocked(ctx, keys)
+			return false, nil
+		}
+	}
+	s.held[jobID] = keys
+	return true, nil
+}
+
+// frees the slots of the job
+func (s *jobSlots) release(ctx context.Context, jobID int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, ok := s.held[jobID]
+	if !ok {
+		return
+	}
+	delete(s.held, jobID)
+	s.unlockLocked(ctx, keys)
+}
+
+func (s *jobSlots) unlockLocked(ctx context.Context, keys []int64) {
+	if s.conn == nil {
+		return
+	}
+	q := db.New(s.conn)
+	for _, key := range keys {
+		if _, err := q.AdvisoryUnlock(ctx, key); err != nil {
+			s.dropConnLocked(ctx, err)
+			return
+		}
+	}
+}
+
+// the session's locks go with the connection so the slots of running jobs are lost too
+func (s *jobSlots) dropConnLocked(ctx context.Context, err error) {
+	s.logger.Error("Lost the job slots connection", "running", len(s.held), "error", err)
+	s.conn.Conn().Close(context.WithoutCancel(ctx))
+	s.conn.Release()
+	s.conn = nil
+	clear(s.held)
+}
+
+func (s *jobSlots) close(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return
+	}
+	for _, keys := range s.held {
+		s.unlockLocked(ctx, keys)
+	}
+	clear(s.held)
+	if s.conn != nil {
+		s.conn.Release()
+		s.conn = nil
+	}
+}
@@ -0,0 +1,53 @@
+package collection
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/Pjt727/classy/data"
+	"github.com/Pjt727/classy/data/testdb"
+)
+
+func TestJobSlotsAreSharedAcrossWorkerPools(t *testing.T) {
+	if err := testdb.SetupTestDb(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	pool, err := data.NewPool(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := newJobSlots(pool, slog.Default())
+	defer first.close(ctx)
+	second := newJobSlots(pool, slog.Default())
+	defer second.close(ctx)
+
+	acquired, err := first.acquire(ctx, 1, "marist", 1, "Banner", 2)
+	if err != nil || !acquired {
+		t.Fatalf("expected the first pool to take the school's slot got %v %v", acquired, err)
+	}
+	acquired, err = second.acquire(ctx, 2, "marist", 1, "Banner", 2)
+	if err != nil || acquired {
+		t.Fatalf("expected the second pool to be capped by the school got %v %v", acquired, err)
+	}
+	acquired, err = second.acquire(ctx, 3, "temple", 1, "Banner", 2)
+	if err != nil || !acquired {
+		t.Fatalf("expected the second pool to take the service's other slot got %v %v", acquired, err)
+	}
+	acquired, err = first.acquire(ctx, 4, "vassar", 1, "Banner", 2)
+	if err != nil || acquired {
+		t.Fatalf("expected the first pool to be capped by the service got %v %v", acquired, err)
+	}
+
+	acquired, err = first.acquire(ctx, 5, "marist", 1, "Ellucian", 1)
+	if err != nil || acquired {
+		t.Fatalf("expected the first pool to not take its own job's slot again got %v %v", acquired, err)
+	}
+
+	first.release(ctx, 1)
+	acquired, err = second.acquire(ctx, 2, "marist", 1, "Banner", 2)
+	if err != nil || !acquired {
+		t.Fatalf("expected the released slots to be taken by the second pool got %v %v", acquired, err)
+	}
+}
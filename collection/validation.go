@@ -0,0 +1,168 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// sections with more than this many times their seats enrolled are probably wrong
+const MAX_ENROLLMENT_OVERFLOW_RATIO = 3.0
+
+const MIN_CREDIT_HOURS = 0.0
+
+const MAX_CREDIT_HOURS = 24.0
+
+// violations older than this are pruned
+const COLLECTION_VIOLATION_RETENTION = 30 * 24 * time.Hour
+
+var ErrInvalidStagedData = errors.New("Staged data broke blocking validation rules")
+
+// checks the staged data of a collection before it is moved
+//
+//	check records every violating staged record in collection_violations and returns how many there were
+type ValidationRule struct {
+	Name     string
+	Severity db.ViolationSeverityEnum
+	Check    func(ctx context.Context, q *db.Queries, rule ValidationRule, termCollectionHistoryID int32) (int64, error)
+}
+
+func DefaultValidationRules() []ValidationRule {
+	return []ValidationRule{
+		// sections without a course cannot be moved anyways
+		MissingSectionCoursesRule(),
+		BackwardsMeetingTimesRule(),
+		DuplicateMeetingSequencesRule(),
+		EnrollmentOverflowRule(MAX_ENROLLMENT_OVERFLOW_RATIO),
+		CreditHoursRangeRule(MIN_CREDIT_HOURS, MAX_CREDIT_HOURS),
+	}
+}
+
+func BackwardsMeetingTimesRule() ValidationRule {
+	return ValidationRule{
+		Name:     "backwards meeting times",
+		Severity: db.ViolationSeverityEnumWarning,
+		Check: func(ctx context.Context, q *db.Queries, rule ValidationRule, termCollectionHistoryID int32) (int64, error) {
+			return q.RecordBackwardsMeetingTimes(ctx, db.RecordBackwardsMeetingTimesParams{
+				Rule:                    rule.Name,
+				Severity:                rule.Severity,
+				TermCollectionHistoryID: termCollectionHistoryID,
+			})
+		},
+	}
+}
+
+func DuplicateMeetingSequencesRule() ValidationRule {
+	return ValidationRule{
+		Name:     "duplicate meeting sequences",
+		Severity: db.ViolationSeverityEnumWarning,
+		Check: func(ctx context.Context, q *db.Queries, rule ValidationRule, termCollectionHistoryID int32) (int64, error) {
+			return q.RecordDuplicateMeetingSequences(ctx, db.RecordDuplicateMeetingSequencesParams{
+				Rule:                    rule.Name,
+				Severity:                rule.Severity,
+				TermCollectionHistoryID: termCollectionHistoryID,
+			})
+		},
+	}
+}
+
+func MissingSectionCoursesRule() ValidationRule {
+	return ValidationRule{
+		Name:     "sections missing courses",
+		Severity: db.ViolationSeverityEnumBlocking,
+		Check: func(ctx context.Context, q *db.Queries, rule ValidationRule, termCollectionHistoryID int32) (int64, error) {
+			return q.RecordMissingSectionCourses(ctx, db.RecordMissingSectionCoursesParams{
+				Rule:                    rule.Name,
+				Severity:                rule.Severity,
+				TermCollectionHistoryID: termCollectionHistoryID,
+			})
+		},
+	}
+}
+
+func EnrollmentOverflowRule(maxOverflowRatio float32) ValidationRule {
+	return ValidationRule{
+		Name:     "enrollment overflow",
+		Severity: db.ViolationSeverityEnumWarning,
+		Check: func(ctx context.Context, q *db.Queries, rule ValidationRule, termCollectionHistoryID int32) (int64, error) {
+			return q.RecordEnrollmentOverflows(ctx, db.RecordEnrollmentOverflowsParams{
+				Rule:                    rule.Name,
+				Severity:                rule.Severity,
+				TermCollectionHistoryID: termCollectionHistoryID,
+				MaxOverflowRatio:        maxOverflowRatio,
+			})
+		},
+	}
+}
+
+func CreditHoursRangeRule(minCreditHours float32, maxCreditHours float32) ValidationRule {
+	return ValidationRule{
+		Name:     "credit hours out of range",
+		Severity: db.ViolationSeverityEnumWarning,
+		Check: func(ctx context.Context, q *db.Queries, rule ValidationRule, termCollectionHistoryID int32) (int64, error) {
+			return q.RecordCreditHoursOutOfRange(ctx, db.RecordCreditHoursOutOfRangeParams{
+				Rule:                    rule.Name,
+				Severity:                rule.Severity,
+				TermCollectionHistoryID: termCollectionHistoryID,
+				MinCreditHours:          minCreditHours,
+				MaxCreditHours:          maxCreditHours,
+			})
+		},
+	}
+}
+
+// runs every rule against the staged data of the collection and returns how many warnings there were
+//
+//	the collection fails with ErrInvalidStagedData when any blocking rule was broken
+func (o *Orchestrator) validateStagedCollection(
+	ctx context.Context,
+	logger *slog.Logger,
+	termCollectionHistoryID int32,
+	rules []ValidationRule,
+) (int64, error) {
+	q := db.New(o.dbPool)
+	var warnings int64
+	var blocking []string
+	for _, rule := range rules {
+		violations, err := rule.Check(ctx, q, rule, termCollectionHistoryID)
+		if err != nil {
+			return 0, fmt.Errorf("Could not validate staged data with rule `%s` %w", rule.Name, err)
+		}
+		if violations == 0 {
+			continue
+		}
+		logger.Warn(
+			"Staged data broke validation rule",
+			"rule", rule.Name,
+			"severity", rule.Severity,
+			"violations", violations,
+		)
+		if rule.Severity == db.ViolationSeverityEnumBlocking {
+			blocking = append(blocking, fmt.Sprintf("%s (%d)", rule.Name, violations))
+		} else {
+			warnings += violations
+		}
+	}
+	if len(blocking) > 0 {
+		return warnings, fmt.Errorf("%w: %s", ErrInvalidStagedData, strings.Join(blocking, ", "))
+	}
+	return warnings, nil
+}
+
+// deletes the violations which are past the retention limit
+func PruneCollectionViolations(ctx context.Context, pool *pgxpool.Pool, logger *slog.Logger) error {
+	expired, err := db.New(pool).DeleteExpiredCollectionViolations(ctx, int32(COLLECTION_VIOLATION_RETENTION.Seconds()))
+	if err != nil {
+		return fmt.Errorf("Could not delete expired collection violations %w", err)
+	}
+	if expired > 0 {
+		logger.Info("Pruned collection violations", "expired", expired)
+	}
+	return nil
+}
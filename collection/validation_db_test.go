@@ -0,0 +1,198 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Pjt727/classy/data"
+	classentry "github.com/Pjt727/classy/data/class-entry"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/Pjt727/classy/data/testdb"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// stages whatever class data it is given so each validation rule can be broken
+type stagingService struct {
+	classData classentry.ClassData
+}
+
+func (s *stagingService) GetName() string { return "Staging" }
+
+func (s *stagingService) ListValidSchools(logger slog.Logger, ctx context.Context) ([]classentry.School, error) {
+	return []classentry.School{{ID: "staging", Name: "Staging University"}}, nil
+}
+
+func (s *stagingService) GetTermCollections(
+	logger slog.Logger,
+	ctx context.Context,
+	school classentry.School,
+) ([]classentry.TermCollection, error) {
+	return []classentry.TermCollection{{
+		ID:              "2025SP",
+		Term:            classentry.Term{Year: 2025, Season: classentry.SeasonEnumSpring},
+		StillCollecting: true,
+	}}, nil
+}
+
+func (s *stagingService) StageAllClasses(
+	logger slog.Logger,
+	ctx context.Context,
+	q *classentry.EntryQueries,
+	schoolID string,
+	termCollection classentry.TermCollection,
+	fullCollection bool,
+) error {
+	return q.InsertClassData(&logger, ctx, s.classData)
+}
+
+func meetingTime(sequence int32, start time.Duration, end time.Duration) classentry.MeetingTime {
+	return classentry.MeetingTime{
+		Sequence:        sequence,
+		SectionSequence: "1",
+		SubjectCode:     "CMPT",
+		CourseNumber:    "101",
+		StartMinutes:    pgtype.Time{Microseconds: start.Microseconds(), Valid: true},
+		EndMinutes:      pgtype.Time{Microseconds: end.Microseconds(), Valid: true},
+		IsMonday:        true,
+	}
+}
+
+func TestValidationRules(t *testing.T) {
+	if err := testdb.SetupTestDb(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	pool, err := data.NewPool(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.Default()
+	service := &stagingService{}
+	orchestrator, err := CreateOrchestrator([]Service{service}, logger, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	school := classentry.School{ID: "staging", Name: "Staging University"}
+	if err := orchestrator.UpsertSchoolTermsWithService(ctx, logger, school, service.GetName()); err != nil {
+		t.Fatal(err)
+	}
+	termCollection := db.TermCollection{
+		ID:              "2025SP",
+		SchoolID:        school.ID,
+		Year:            2025,
+		Season:          db.SeasonEnumSpring,
+		StillCollecting: true,
+	}
+	config := DefualtUpdateSectionsConfig().SetServiceName(service.GetName()).SetFullCollection(true)
+	q := db.New(pool)
+
+	violationsByRule := func(termCollectionHistoryID int32) map[string]db.ViolationSeverityEnum {
+		t.Helper()
+		violations, err := q.ListCollectionViolations(ctx, db.ListCollectionViolationsParams{
+			TermCollectionHistoryID: termCollectionHistoryID,
+			LimitCount:              100,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		byRule := make(map[string]db.ViolationSeverityEnum)
+		for _, violation := range violations {
+			if _, ok := byRule[violation.Rule]; ok {
+				t.Errorf("expected one violation of rule `%s`", violation.Rule)
+			}
+			byRule[violation.Rule] = violation.Severity
+		}
+		return byRule
+	}
+	liveSections := func() []db.GetSectionsForCoursesRow {
+		t.Helper()
+		sections, err := q.GetSectionsForCourses(ctx, db.GetSectionsForCoursesParams{
+			SubjectCodes:     []string{"CMPT", "CMPT"},
+			CourseNumbers:    []string{"101", "999"},
+			SchoolID:         termCollection.SchoolID,
+			TermCollectionID: termCollection.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sections
+	}
+
+	// each record breaks one of the warning rules
+	service.classData = classentry.ClassData{
+		Courses: []classentry.Course{{SubjectCode: "CMPT", Number: "101", CreditHours: MAX_CREDIT_HOURS + 1}},
+		Sections: []classentry.Section{{
+			Sequence:      "1",
+			SubjectCode:   "CMPT",
+			CourseNumber:  "101",
+			Enrollment:    pgtype.Int4{Int32: 100, Valid: true},
+			MaxEnrollment: pgtype.Int4{Int32: 20, Valid: true},
+		}},
+		MeetingTimes: []classentry.MeetingTime{
+			meetingTime(1, 9*time.Hour, 8*time.Hour),
+			meetingTime(2, 8*time.Hour, 9*time.Hour),
+			meetingTime(2, 8*time.Hour, 9*time.Hour),
+		},
+	}
+	result, err := orchestrator.UpdateAllSectionsOfSchool(ctx, termCollection, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Warnings != 4 {
+		t.Errorf("expected 4 warnings got %d", result.Warnings)
+	}
+	warned := violationsByRule(result.TermCollectionHistoryId)
+	for _, rule := range []ValidationRule{
+		BackwardsMeetingTimesRule(),
+		DuplicateMeetingSequencesRule(),
+		EnrollmentOverflowRule(MAX_ENROLLMENT_OVERFLOW_RATIO),
+		CreditHoursRangeRule(MIN_CREDIT_HOURS, MAX_CREDIT_HOURS),
+	} {
+		if severity, ok := warned[rule.Name]; !ok || severity != db.ViolationSeverityEnumWarning {
+			t.Errorf("expected a warning of rule `%s` got %+v", rule.Name, warned)
+		}
+	}
+	if len(warned) != 4 {
+		t.Errorf("expected only the warning rules to be broken got %+v", warned)
+	}
+	if sections := liveSections(); len(sections) != 1 || sections[0].Section.Enrollment.Int32 != 100 {
+		t.Fatalf("expected the warned collection to be moved got %+v", sections)
+	}
+
+	// a section of a course which was never staged blocks the whole collection
+	service.classData.Sections[0].Enrollment = pgtype.Int4{Int32: 200, Valid: true}
+	service.classData.Sections = append(service.classData.Sections, classentry.Section{
+		Sequence:     "1",
+		SubjectCode:  "CMPT",
+		CourseNumber: "999",
+	})
+	_, err = orchestrator.UpdateAllSectionsOfSchool(ctx, termCollection, config)
+	if !errors.Is(err, ErrInvalidStagedData) {
+		t.Fatalf("expected the collection to be blocked got %v", err)
+	}
+	history, err := q.GetRecentTermCollectionHistory(ctx, db.GetRecentTermCollectionHistoryParams{
+		SchoolID:         termCollection.SchoolID,
+		TermCollectionID: termCollection.ID,
+		LimitCount:       1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Status != db.TermCollectionStatusEnumFailure {
+		t.Fatalf("expected the blocked collection to fail got %+v", history)
+	}
+	blocked := violationsByRule(history[0].ID)
+	missingCourses := MissingSectionCoursesRule()
+	if severity, ok := blocked[missingCourses.Name]; !ok || severity != db.ViolationSeverityEnumBlocking {
+		t.Errorf("expected a blocking violation of rule `%s` got %+v", missingCourses.Name, blocked)
+	}
+	if len(blocked) != 5 {
+		t.Errorf("expected every rule to be broken got %+v", blocked)
+	}
+	if sections := liveSections(); len(sections) != 1 || sections[0].Section.Enrollment.Int32 != 100 {
+		t.Errorf("expected the blocked collection to leave the sections unchanged got %+v", sections)
+	}
+}
@@ -0,0 +1,103 @@
+package collection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/sync/errgroup"
+)
+
+// services which get told about new data instead of having to go get it
+// ex: files put in the import directory
+type WatchingService interface {
+	Service
+
+	// should block until the context is done calling onChange for every term with new data
+	Watch(
+		ctx context.Context,
+		logger *slog.Logger,
+		onChange func(schoolID string, termCollectionID string),
+	) error
+}
+
+// schedules a collection of every term a watching service says has new data
+//
+//	blocks until the context is done
+func (o *Orchestrator) WatchServices(ctx context.Context) error {
+	var eg errgroup.Group
+	for _, service := range o.serviceEntries {
+		watchingService, ok := service.(WatchingService)
+		if !ok {
+			continue
+		}
+		logger := o.orchestrationLogger.With(slog.String("service", service.GetName()))
+		eg.Go(func() error {
+			return watchingService.Watch(ctx, logger, func(schoolID string, termCollectionID string) {
+				err := o.scheduleWatchedCollection(ctx, logger, service, schoolID, termCollectionID)
+				if err != nil {
+					logger.Error(
+						"Could not schedule collection of new data",
+						"school", schoolID,
+						"termCollection", termCollectionID,
+						"error", err,
+					)
+				}
+			})
+		})
+	}
+	return eg.Wait()
+}
+
+func (o *Orchestrator) scheduleWatchedCollection(
+	ctx context.Context,
+	logger *slog.Logger,
+	service Service,
+	schoolID string,
+	termCollectionID string,
+) error {
+	// the school may have been added after the orchestrator started
+	schools, err := service.ListValidSchools(*logger, ctx)
+	if err != nil {
+		return err
+	}
+	var school db.School
+	for _, s := range schools {
+		if s.ID == schoolID {
+			school = s
+		}
+	}
+	if school.ID == "" {
+		return fmt.Errorf("School %s is not valid for %s", schoolID, service.GetName())
+	}
+
+	// the term has to exist before it can be collected
+	if err := o.UpsertSchoolTermsWithService(ctx, logger, school, service.GetName()); err != nil {
+		return err
+	}
+
+	message := CollectionMessage{
+		TermCollectionID: termCollectionID,
+		SchoolID:         schoolID,
+		ServiceName:      pgtype.Text{String: service.GetName(), Valid: true},
+		IsFullCollection: pgtype.Bool{Bool: true, Valid: true},
+	}
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	q := db.New(o.dbPool)
+	err = q.AddToQueue(ctx, db.AddToQueueParams{
+		QueueName:             SECTIONS_OF_TERM_COLLECTIONS,
+		Message:               messageBytes,
+		SecondsUntilAvailable: 0,
+	})
+	if err != nil {
+		return fmt.Errorf("Could not add collection message %w", err)
+	}
+	logger.Info("Scheduled collection of new data", "school", schoolID, "termCollection", termCollectionID)
+	return nil
+}
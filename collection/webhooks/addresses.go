@@ -0,0 +1,83 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// webhook urls are given by anyone so deliveries are only made to public addresses
+//
+//	otherwise subscribers could make the server call its internal network or cloud metadata endpoints
+var ErrNonPublicAddress = errors.New("webhook address is not public")
+
+// 100.64.0.0/10 is used by carrier grade nats and some cloud providers
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// checks the address after dns resolution right before connecting so a host
+// cannot resolve to a public address when subscribing and a private one when delivering
+func publicAddressControl(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+	return nil
+}
+
+func newDeliveryClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: DELIVERY_TIMEOUT,
+		Control: publicAddressControl,
+	}
+	return &http.Client{
+		Timeout: DELIVERY_TIMEOUT,
+		Transport: &http.Transport{
+			// a proxy would be the only address the dialer checks
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        DELIVERY_BATCH_SIZE,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// parses a callback url which has to be an absolute http or https url whose host resolves to public addresses
+//
+//	deliveries check the address again when connecting since what the host resolves to can change
+func ValidateCallbackURL(ctx context.Context, rawURL string) (*url.URL, error) {
+	callbackURL, err := url.Parse(rawURL)
+	if err != nil || (callbackURL.Scheme != "https" && callbackURL.Scheme != "http") || callbackURL.Hostname() == "" {
+		return nil, errors.New("Invalid url (expected an absolute http or https url)")
+	}
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, callbackURL.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("Could not resolve url host `%s`", callbackURL.Hostname())
+	}
+	for _, address := range addresses {
+		if !isPublicIP(address.IP) {
+			return nil, fmt.Errorf("Invalid url (%w)", ErrNonPublicAddress)
+		}
+	}
+	return callbackURL, nil
+}
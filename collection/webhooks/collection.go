@@ -0,0 +1,112 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Pjt727/classy/collection/services"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type CollectionEventKind string
+
+const (
+	CollectionStartedEvent             CollectionEventKind = "collection.started"
+	CollectionSucceededEvent           CollectionEventKind = "collection.succeeded"
+	CollectionFailedEvent              CollectionEventKind = "collection.failed"
+	CollectionIncorrectAssumptionEvent CollectionEventKind = "collection.incorrect_assumption"
+	// the collection would have deleted too many sections so it is waiting to be approved
+	CollectionQuarantinedEvent CollectionEventKind = "collection.quarantined"
+)
+
+var CollectionEventKinds = []CollectionEventKind{
+	CollectionStartedEvent,
+	CollectionSucceededEvent,
+	CollectionFailedEvent,
+	CollectionIncorrectAssumptionEvent,
+	CollectionQuarantinedEvent,
+}
+
+// broad reasons a collection failed so subscribers can decide what to page on
+type ErrorClass string
+
+const (
+	ErrorClassIncorrectAssumption     ErrorClass = "incorrect_assumption"
+	ErrorClassTemporaryNetworkFailure ErrorClass = "temporary_network_failure"
+	ErrorClassCanceled                ErrorClass = "canceled"
+	ErrorClassTimeout                 ErrorClass = "timeout"
+	// set by the collection package since the quarantine error is its own
+	ErrorClassQuarantined ErrorClass = "quarantined"
+	ErrorClassUnknown     ErrorClass = "unknown"
+)
+
+// incorrect assumptions are checked first to match how the scheduler treats them
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, services.ErrIncorrectAssumption):
+		return ErrorClassIncorrectAssumption
+	case errors.Is(err, services.ErrTemporaryNetworkFailure):
+		return ErrorClassTemporaryNetworkFailure
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	}
+	return ErrorClassUnknown
+}
+
+type CollectionEventCounts struct {
+	Inserted uint `json:"inserted"`
+	Updated  uint `json:"updated"`
+	Deleted  uint `json:"deleted"`
+}
+
+type CollectionEventPayload struct {
+	Event                   CollectionEventKind    `json:"event"`
+	SchoolID                string                 `json:"school_id"`
+	TermCollectionID        string                 `json:"term_collection_id"`
+	TermCollectionHistoryID int32                  `json:"term_collection_history_id"`
+	ServiceName             string                 `json:"service_name"`
+	IsFullCollection        bool                   `json:"is_full_collection"`
+	OccurredAt              time.Time              `json:"occurred_at"`
+	Counts                  *CollectionEventCounts `json:"counts,omitempty"`
+	DurationMilliseconds    int64                  `json:"duration_ms"`
+	ErrorClass              ErrorClass             `json:"error_class,omitempty"`
+	Error                   string                 `json:"error,omitempty"`
+}
+
+// queues a delivery for every webhook which wants this event
+func EnqueueCollectionEvent(ctx context.Context, q *db.Queries, payload CollectionEventPayload) (int, error) {
+	collectionWebhooks, err := q.GetCollectionWebhooksForEvent(ctx, db.GetCollectionWebhooksForEventParams{
+		SchoolID: pgtype.Text{String: payload.SchoolID, Valid: true},
+		Event:    string(payload.Event),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(collectionWebhooks) == 0 {
+		return 0, nil
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	for _, collectionWebhook := range collectionWebhooks {
+		err = EnqueueDelivery(ctx, q, DeliveryMessage{
+			Kind:           CollectionEventDelivery,
+			Event:          string(payload.Event),
+			SubscriptionID: collectionWebhook.ID,
+			Payload:        payloadBytes,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(collectionWebhooks), nil
+}
@@ -0,0 +1,272 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// queues
+const WEBHOOK_DELIVERIES = "webhook_deliveries"
+
+const DELIVERY_TIMEOUT = 15 * time.Second
+const DELIVERY_BATCH_SIZE = 10
+const POLLING_INTERVAL = 200 * time.Millisecond
+const POLLING_TIME = 5 * time.Second
+
+// failed deliveries are retried with exponential backoff until they are archived
+const MAX_DELIVERY_ATTEMPTS = 8
+const BASE_RETRY_BACKOFF = 30 * time.Second
+const MAX_RETRY_BACKOFF = 6 * time.Hour
+
+// headers sent with every delivery
+const SIGNATURE_HEADER = "X-Classy-Signature"
+const TIMESTAMP_HEADER = "X-Classy-Timestamp"
+const EVENT_HEADER = "X-Classy-Event"
+const DELIVERY_HEADER = "X-Classy-Delivery"
+
+type DeliveryKind string
+
+const (
+	SeatOpenedDelivery      DeliveryKind = "seat_opened"
+	CollectionEventDelivery DeliveryKind = "collection_event"
+)
+
+// what is put on the queue
+//
+//	the payload is built when the event happens but the url and secret are looked up on
+//	delivery so deleted subscriptions stop getting deliveries
+type DeliveryMessage struct {
+	Kind DeliveryKind `json:"kind"`
+	// the more specific event when a kind covers many
+	Event          string          `json:"event,omitempty"`
+	SubscriptionID int32           `json:"subscription_id"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+type deliveryTarget struct {
+	url    string
+	secret string
+}
+
+// the subscription was removed after the delivery was queued
+var errNoTarget = errors.New("delivery target no longer exists")
+
+func EnqueueDelivery(ctx context.Context, q *db.Queries, message DeliveryMessage) error {
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return q.AddToQueue(ctx, db.AddToQueueParams{
+		QueueName:             WEBHOOK_DELIVERIES,
+		Message:               messageBytes,
+		SecondsUntilAvailable: 0,
+	})
+}
+
+// secrets are shared with the subscriber to verify deliveries
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// the signature is a hex hmac sha256 of `<timestamp>.<body>`
+//
+//	including the timestamp lets subscribers reject replayed deliveries
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// the delay before trying a delivery again after it failed for the given attempt
+func RetryBackoff(attempt int) time.Duration {
+	backoff := float64(BASE_RETRY_BACKOFF) * math.Pow(2, float64(max(attempt-1, 0)))
+	return time.Duration(min(backoff, float64(MAX_RETRY_BACKOFF)))
+}
+
+type Deliverer struct {
+	dbPool *pgxpool.Pool
+	client *http.Client
+	logger *slog.Logger
+}
+
+func NewDeliverer(pool *pgxpool.Pool, logger *slog.Logger) Deliverer {
+	return Deliverer{
+		dbPool: pool,
+		client: newDeliveryClient(),
+		logger: logger,
+	}
+}
+
+// delivers webhooks until the context is done
+func (d *Deliverer) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		// the poll function takes care of waiting to not overwhelm the db
+		if err := d.PollForDeliveries(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Error("Webhook delivery error", "err", err)
+			time.Sleep(POLLING_TIME)
+		}
+	}
+}
+
+// this calls uses internal postgres polling to return practically instantly if there are messages
+func (d *Deliverer) PollForDeliveries(ctx context.Context) error {
+	q := db.New(d.dbPool)
+	rows, err := q.ReadPollingQueue(ctx, db.ReadPollingParams{
+		QueueName:                   WEBHOOK_DELIVERIES,
+		SecondsUntilRescheduled:     int32((DELIVERY_TIMEOUT + 5*time.Second).Seconds()),
+		JobCount:                    DELIVERY_BATCH_SIZE,
+		SecondsPollingTime:          int32(POLLING_TIME.Seconds()),
+		MillisecondsPollingInterval: int32(POLLING_INTERVAL.Milliseconds()),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		d.handleDelivery(ctx, q, row)
+	}
+	return nil
+}
+
+func (d *Deliverer) handleDelivery(ctx context.Context, q *db.Queries, row db.QueueRow) {
+	logger := d.logger.With("deliveryID", row.MessageID)
+	attempt, err := strconv.Atoi(row.ReadAmount)
+	if err != nil {
+		attempt = 1
+	}
+
+	var message DeliveryMessage
+	if err := json.Unmarshal(row.Message, &message); err != nil {
+		logger.Error("Archiving unreadable webhook delivery", "err", err)
+		d.archive(ctx, q, row.MessageID)
+		return
+	}
+	logger = logger.With("kind", message.Kind, "subscriptionID", message.SubscriptionID, "attempt", attempt)
+
+	err = d.deliver(ctx, q, row.MessageID, message)
+	if errors.Is(err, errNoTarget) {
+		logger.Info("Dropping webhook delivery for removed subscription")
+		d.delete(ctx, q, row.MessageID)
+		return
+	}
+	if err == nil {
+		logger.Info("Delivered webhook")
+		d.delete(ctx, q, row.MessageID)
+		return
+	}
+
+	if attempt >= MAX_DELIVERY_ATTEMPTS {
+		logger.Error("Giving up on webhook delivery", "err", err)
+		d.archive(ctx, q, row.MessageID)
+		return
+	}
+	backoff := RetryBackoff(attempt)
+	logger.Warn("Webhook delivery failed, retrying", "err", err, "retryIn", backoff)
+	err = q.SetQueueVisibility(ctx, db.SetQueueVisibilityParams{
+		QueueName:             WEBHOOK_DELIVERIES,
+		MessageID:             row.MessageID,
+		SecondsUntilAvailable: int(backoff.Seconds()),
+	})
+	if err != nil {
+		logger.Error("Could not reschedule webhook delivery", "err", err)
+	}
+}
+
+func (d *Deliverer) deliver(ctx context.Context, q *db.Queries, deliveryID int32, message DeliveryMessage) error {
+	target, err := resolveTarget(ctx, q, message)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.url, bytes.NewReader(message.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "classy-webhooks")
+	event := message.Event
+	if event == "" {
+		event = string(message.Kind)
+	}
+	req.Header.Set(EVENT_HEADER, event)
+	req.Header.Set(DELIVERY_HEADER, strconv.Itoa(int(deliveryID)))
+	req.Header.Set(TIMESTAMP_HEADER, timestamp)
+	req.Header.Set(SIGNATURE_HEADER, Sign(target.secret, timestamp, message.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func resolveTarget(ctx context.Context, q *db.Queries, message DeliveryMessage) (deliveryTarget, error) {
+	switch message.Kind {
+	case SeatOpenedDelivery:
+		subscription, err := q.GetSeatSubscription(ctx, message.SubscriptionID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return deliveryTarget{}, errNoTarget
+		}
+		if err != nil {
+			return deliveryTarget{}, err
+		}
+		return deliveryTarget{url: subscription.CallbackUrl, secret: subscription.Secret}, nil
+	case CollectionEventDelivery:
+		collectionWebhook, err := q.GetCollectionWebhook(ctx, message.SubscriptionID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return deliveryTarget{}, errNoTarget
+		}
+		if err != nil {
+			return deliveryTarget{}, err
+		}
+		return deliveryTarget{url: collectionWebhook.CallbackUrl, secret: collectionWebhook.Secret}, nil
+	}
+	return deliveryTarget{}, fmt.Errorf("%w: unknown delivery kind `%s`", errNoTarget, message.Kind)
+}
+
+func (d *Deliverer) delete(ctx context.Context, q *db.Queries, deliveryID int32) {
+	err := q.DeleteFromQueue(ctx, db.DeleteFromQueueParams{
+		QueueName: WEBHOOK_DELIVERIES,
+		MessageID: deliveryID,
+	})
+	if err != nil {
+		d.logger.Error("Could not delete webhook delivery", "deliveryID", deliveryID, "err", err)
+	}
+}
+
+func (d *Deliverer) archive(ctx context.Context, q *db.Queries, deliveryID int32) {
+	err := q.ArchiveFromQueue(ctx, db.ArchiveFromQueueParams{
+		QueueName: WEBHOOK_DELIVERIES,
+		MessageID: deliveryID,
+	})
+	if err != nil {
+		d.logger.Error("Could not archive webhook delivery", "deliveryID", deliveryID, "err", err)
+	}
+}
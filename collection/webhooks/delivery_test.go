@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"seat_opened"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", "1700000000", body); got != expected {
+		t.Fatalf("expected signature %s got %s", expected, got)
+	}
+	if Sign("other secret", "1700000000", body) == expected {
+		t.Fatal("signature did not depend on the secret")
+	}
+	if Sign("secret", "1700000001", body) == expected {
+		t.Fatal("signature did not depend on the timestamp")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	expected := []time.Duration{
+		BASE_RETRY_BACKOFF,
+		2 * BASE_RETRY_BACKOFF,
+		4 * BASE_RETRY_BACKOFF,
+	}
+	for i, backoff := range expected {
+		if got := RetryBackoff(i + 1); got != backoff {
+			t.Errorf("attempt %d expected backoff %s got %s", i+1, backoff, got)
+		}
+	}
+	if got := RetryBackoff(100); got != MAX_RETRY_BACKOFF {
+		t.Errorf("expected backoff to be capped at %s got %s", MAX_RETRY_BACKOFF, got)
+	}
+}
+
+func TestPublicAddressControl(t *testing.T) {
+	nonPublic := []string{
+		"127.0.0.1:80",
+		"10.1.2.3:443",
+		"172.16.0.1:443",
+		"192.168.1.1:80",
+		"169.254.169.254:80",
+		"100.64.0.1:80",
+		"0.0.0.0:80",
+		"[::1]:80",
+		"[fe80::1]:80",
+		"[fd00::1]:443",
+		"[::ffff:127.0.0.1]:80",
+	}
+	for _, address := range nonPublic {
+		if err := publicAddressControl("tcp", address, nil); !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("expected %s to be rejected got %v", address, err)
+		}
+	}
+	public := []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:443"}
+	for _, address := range public {
+		if err := publicAddressControl("tcp", address, nil); err != nil {
+			t.Errorf("expected %s to be allowed got %v", address, err)
+		}
+	}
+}
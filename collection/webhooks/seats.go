@@ -0,0 +1,73 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+)
+
+type SeatOpenedPayload struct {
+	Event                   DeliveryKind `json:"event"`
+	SubscriptionID          int32        `json:"subscription_id"`
+	SchoolID                string       `json:"school_id"`
+	TermCollectionID        string       `json:"term_collection_id"`
+	SubjectCode             string       `json:"subject_code"`
+	CourseNumber            string       `json:"course_number"`
+	Sequence                string       `json:"sequence"`
+	Enrollment              int32        `json:"enrollment"`
+	MaxEnrollment           int32        `json:"max_enrollment"`
+	TermCollectionHistoryID int32        `json:"term_collection_history_id"`
+	OpenedAt                time.Time    `json:"opened_at"`
+}
+
+// queues a delivery for every subscribed section which opened up in this collection
+//
+// should be done in the same transaction that moves the staged sections so the
+// deliveries are only queued if the collection commits
+func EnqueueSeatOpenings(
+	ctx context.Context,
+	q *db.Queries,
+	termCollection db.TermCollection,
+	termCollectionHistoryID int32,
+) (int, error) {
+	openings, err := q.GetSeatOpenings(ctx, db.GetSeatOpeningsParams{
+		TermCollectionHistoryID: termCollectionHistoryID,
+		SchoolID:                termCollection.SchoolID,
+		TermCollectionID:        termCollection.ID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	openedAt := time.Now().UTC()
+	for _, opening := range openings {
+		payload, err := json.Marshal(SeatOpenedPayload{
+			Event:                   SeatOpenedDelivery,
+			SubscriptionID:          opening.SubscriptionID,
+			SchoolID:                opening.SchoolID,
+			TermCollectionID:        opening.TermCollectionID,
+			SubjectCode:             opening.SubjectCode,
+			CourseNumber:            opening.CourseNumber,
+			Sequence:                opening.Sequence,
+			Enrollment:              opening.Enrollment.Int32,
+			MaxEnrollment:           opening.MaxEnrollment.Int32,
+			TermCollectionHistoryID: termCollectionHistoryID,
+			OpenedAt:                openedAt,
+		})
+		if err != nil {
+			return 0, err
+		}
+		err = EnqueueDelivery(ctx, q, DeliveryMessage{
+			Kind:           SeatOpenedDelivery,
+			SubscriptionID: opening.SubscriptionID,
+			Payload:        payload,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(openings), nil
+}
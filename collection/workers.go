@@ -0,0 +1,532 @@
+package collection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// collections a worker pool runs at once
+const WORKER_COUNT = 4
+
+// collections of one school at once so the school's site is not overwhelmed
+const JOBS_PER_SCHOOL = 1
+
+// collections of one service at once
+const JOBS_PER_SERVICE = 3
+
+// jobs of a school or service which is at its limit are given back to the queue for this long
+const CAPPED_JOB_DELAY = 30 * time.Second
+
+// running jobs get this long to finish when shutting down before they are interrupted
+const WORKER_SHUTDOWN_GRACE_PERIOD = 30 * time.Second
+
+// how often the worker pool reports its metrics
+const WORKER_METRICS_INTERVAL = 30 * time.Second
+
+var errCollectionInterrupted = errors.New("Collection was interrupted")
+
+// given to the collections which are still running once the grace period is over
+var errWorkerPoolStopped = errors.New("Worker pool stopped before the collection finished")
+
+type WorkerPoolConfig struct {
+	Workers        int
+	JobsPerSchool  int
+	JobsPerService int
+	// service name -> limit for services which need a different limit than JobsPerService
+	ServiceLimits map[string]int
+}
+
+func DefaultWorkerPoolConfig() WorkerPoolConfig {
+	return WorkerPoolConfig{
+		Workers:        WORKER_COUNT,
+		JobsPerSchool:  JOBS_PER_SCHOOL,
+		JobsPerService: JOBS_PER_SERVICE,
+		ServiceLimits:  map[string]int{},
+	}
+}
+
+func (c WorkerPoolConfig) serviceLimit(serviceName string) int {
+	if limit, ok := c.ServiceLimits[serviceName]; ok {
+		return limit
+	}
+	return c.JobsPerService
+}
+
+type WorkerPoolMetrics struct {
+	Workers int
+	Busy    int
+	// fraction of the workers' time spent running jobs since the last report
+	Utilization      float64
+	Completed        uint64
+	Failed           uint64
+	Deferred         uint64
+	RunningBySchool  map[string]int
+	RunningByService map[string]int
+}
+
+type runningJob struct {
+	schoolID    string
+	serviceName string
+	startedAt   time.Time
+}
+
+// runs collection jobs from the queue without going over its limits
+type WorkerPool struct {
+	id        string
+	hostname  string
+	scheduler *Scheduler
+	config    WorkerPoolConfig
+	logger    *slog.Logger
+	startedAt time.Time
+
+	mu               sync.Mutex
+	running          map[int32]runningJob
+	runningBySchool  map[string]int
+	runningByService map[string]int
+	completed        uint64
+	failed           uint64
+	deferred         uint64
+	// busy time of jobs which finished since the window started
+	windowStart  time.Time
+	finishedBusy time.Duration
+	// a worker might have been freed
+	freed chan struct{}
+	// the school and service limits across every worker pool
+	slots *jobSlots
+}
+
+func NewWorkerPool(scheduler *Scheduler, config WorkerPoolConfig, logger *slog.Logger) *WorkerPool {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	now := time.Now()
+	return &WorkerPool{
+		id:               uuid.NewString(),
+		hostname:         hostname,
+		scheduler:        scheduler,
+		config:           config,
+		logger:           logger.With("workerPool", hostname),
+		startedAt:        now,
+		running:          make(map[int32]runningJob),
+		runningBySchool:  make(map[string]int),
+		runningByService: make(map[string]int),
+		windowStart:      now,
+		freed:            make(chan struct{}, 1),
+	}
+}
+
+// runs collection jobs and, while this process is the leader, plans the recurring schedules
+// and releases orphaned collections. one process also watches the services for new data
+//
+//	any number of these can run at once since the queue gives each job to one worker pool
+//	and the per school and per service limits are held as advisory locks shared by every pool
+func RunCollectionWorker(ctx context.Context, pool *pgxpool.Pool, config WorkerPoolConfig, logger *slog.Logger) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		RunAsLeader(ctx, pool, PLANNER_LOCK_KEY, logger, func(ctx context.Context) {
+			reaperDone := make(chan struct{})
+			go func() {
+				defer close(reaperDone)
+				RunReaper(ctx, pool, logger)
+			}()
+			planner := NewPlanner(pool, logger)
+			planner.Run(ctx)
+			<-reaperDone
+		})
+	}()
+
+	scheduler := NewScheduler(pool)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.orch.WatchSchoolRegistry(ctx)
+	}()
+	// every watcher would schedule the same collection of new data
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		RunAsLeader(ctx, pool, WATCHER_LOCK_KEY, logger, func(ctx context.Context) {
+			if err := scheduler.orch.WatchServices(ctx); err != nil && ctx.Err() == nil {
+				logger.Error("Stopped watching services", "error", err)
+			}
+		})
+	}()
+	workerPool := NewWorkerPool(&scheduler, config, logger)
+	workerPool.Run(ctx)
+	wg.Wait()
+}
+
+// reserves a worker for the job unless a limit has been reached
+func (p *WorkerPool) tryStart(jobID int32, schoolID string, serviceName string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.running) >= p.config.Workers ||
+		p.runningBySchool[schoolID] >= p.config.JobsPerSchool ||
+		p.runningByService[serviceName] >= p.config.serviceLimit(serviceName) {
+		return false
+	}
+	p.running[jobID] = runningJob{schoolID: schoolID, serviceName: serviceName, startedAt: now}
+	p.runningBySchool[schoolID]++
+	p.runningByService[serviceName]++
+	return true
+}
+
+func (p *WorkerPool) finish(jobID int32, succeeded bool, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	job, ok := p.removeRunningLocked(jobID)
+	if !ok {
+		return
+	}
+	p.finishedBusy += now.Sub(later(job.startedAt, p.windowStart))
+	if succeeded {
+		p.completed++
+	} else {
+		p.failed++
+	}
+
+	select {
+	case p.freed <- struct{}{}:
+	default:
+	}
+}
+
+// gives back a job which was started but could not run so it does not count as finished
+func (p *WorkerPool) abandon(jobID int32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.removeRunningLocked(jobID); !ok {
+		return
+	}
+	select {
+	case p.freed <- struct{}{}:
+	default:
+	}
+}
+
+func (p *WorkerPool) removeRunningLocked(jobID int32) (runningJob, bool) {
+	job, ok := p.running[jobID]
+	if !ok {
+		return runningJob{}, false
+	}
+	delete(p.running, jobID)
+	p.runningBySchool[job.schoolID]--
+	if p.runningBySchool[job.schoolID] <= 0 {
+		delete(p.runningBySchool, job.schoolID)
+	}
+	p.runningByService[job.serviceName]--
+	if p.runningByService[job.serviceName] <= 0 {
+		delete(p.runningByService, job.serviceName)
+	}
+	return job, true
+}
+
+func later(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func (p *WorkerPool) freeWorkers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.config.Workers - len(p.running)
+}
+
+func (p *WorkerPool) Metrics() WorkerPoolMetrics {
+	return p.metrics(time.Now(), false)
+}
+
+// a new utilization window is started when reset
+func (p *WorkerPool) metrics(now time.Time, reset bool) WorkerPoolMetrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	busy := p.finishedBusy
+	for _, job := range p.running {
+		busy += now.Sub(later(job.startedAt, p.windowStart))
+	}
+	var utilization float64
+	if window := now.Sub(p.windowStart); window > 0 && p.config.Workers > 0 {
+		utilization = float64(busy) / (float64(window) * float64(p.config.Workers))
+	}
+	metrics := WorkerPoolMetrics{
+		Workers:          p.config.Workers,
+		Busy:             len(p.running),
+		Utilization:      utilization,
+		Completed:        p.completed,
+		Failed:           p.failed,
+		Deferred:         p.deferred,
+		RunningBySchool:  maps.Clone(p.runningBySchool),
+		RunningByService: maps.Clone(p.runningByService),
+	}
+	if reset {
+		p.windowStart = now
+		p.finishedBusy = 0
+	}
+	return metrics
+}
+
+// runs jobs until the context is done
+//
+//	running jobs then get a grace period before they are interrupted and every job
+//	which did not finish is made visible in the queue again
+func (p *WorkerPool) Run(ctx context.Context) {
+	// jobs outlive the context for the grace period
+	jobsCtx, cancelJobs := context.WithCancelCause(context.WithoutCancel(ctx))
+	defer cancelJobs(nil)
+	var wg sync.WaitGroup
+	p.slots = newJobSlots(p.scheduler.dbPool, p.logger)
+
+	metricsDone := make(chan struct{})
+	go func() {
+		defer close(metricsDone)
+		p.reportMetricsUntilDone(ctx)
+	}()
+
+	q := db.New(p.scheduler.dbPool)
+	for ctx.Err() == nil {
+		free := p.freeWorkers()
+		if free <= 0 {
+			select {
+			case <-ctx.Done():
+			case <-p.freed:
+			}
+			continue
+		}
+		// this uses internal postgres polling to return practically instantly if there are messages
+		rows, err := q.ReadPollingQueue(ctx, db.ReadPollingParams{
+			QueueName:                   SECTIONS_OF_TERM_COLLECTIONS,
+			SecondsUntilRescheduled:     int32(COLLECTION_TIMEOUT.Seconds()),
+			JobCount:                    int32(min(free, COLLECITON_BATCH_SIZE)),
+			SecondsPollingTime:          int32(POLLING_TIME.Seconds()),
+			MillisecondsPollingInterval: int32(POLLING_INTERVAL.Milliseconds()),
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				p.logger.Error("Could not read collection jobs", "error", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(POLLING_TIME):
+				}
+			}
+			continue
+		}
+		for _, row := range rows {
+			p.dispatch(ctx, jobsCtx, &wg, row)
+		}
+	}
+
+	p.logger.Info("Stopping worker pool", "running", p.Metrics().Busy)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(WORKER_SHUTDOWN_GRACE_PERIOD):
+		p.logger.Warn("Interrupting running collections", "running", p.Metrics().Busy)
+		cancelJobs(errWorkerPoolStopped)
+		<-done
+	}
+	<-metricsDone
+
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	p.slots.close(cleanupCtx)
+	if err := q.DeleteWorkerPoolMetrics(cleanupCtx, p.id); err != nil {
+		p.logger.Error("Could not remove worker pool metrics", "error", err)
+	}
+	p.logger.Info("Stopped worker pool")
+}
+
+func (p *WorkerPool) dispatch(ctx context.Context, jobsCtx context.Context, wg *sync.WaitGroup, row db.QueueRow) {
+	logger := p.logger.With("collectionJob", row.MessageID)
+	q := db.New(p.scheduler.dbPool)
+	var message CollectionMessage
+	if err := json.Unmarshal(row.Message, &message); err != nil {
+		logger.Error("Archiving unreadable collection job", "error", err)
+		err := q.ArchiveFromQueue(jobsCtx, db.ArchiveFromQueueParams{
+			QueueName: SECTIONS_OF_TERM_COLLECTIONS,
+			MessageID: row.MessageID,
+		})
+		if err != nil {
+			logger.Error("Could not archive collection job", "error", err)
+		}
+		return
+	}
+	// stopped between reading and starting
+	if ctx.Err() != nil {
+		p.release(row.MessageID, 0)
+		return
+	}
+
+	serviceName := message.ServiceName.String
+	if !message.ServiceName.Valid {
+		serviceName, _ = p.scheduler.orch.GetSchoolServiceName(message.SchoolID)
+	}
+	deferJob := func() {
+		logger.Debug("Deferring collection job", "school", message.SchoolID, "service", serviceName)
+		p.mu.Lock()
+		p.deferred++
+		p.mu.Unlock()
+		p.release(row.MessageID, CAPPED_JOB_DELAY)
+	}
+	if !p.tryStart(row.MessageID, message.SchoolID, serviceName, time.Now()) {
+		deferJob()
+		return
+	}
+	// other worker pools might be running jobs of the school or service
+	acquired, err := p.slots.acquire(
+		jobsCtx,
+		row.MessageID,
+		message.SchoolID,
+		p.config.JobsPerSchool,
+		serviceName,
+		p.config.serviceLimit(serviceName),
+	)
+	if err != nil {
+		logger.Error("Could not take the collection job's slots", "error", err)
+	}
+	if !acquired {
+		p.abandon(row.MessageID)
+		deferJob()
+		return
+	}
+
+	// pgmq counts every read so this is the attempt of the job
+	attempts, err := strconv.Atoi(row.ReadAmount)
+	if err != nil {
+		attempts = 1
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		keepAliveCtx, cancelKeepAlive := context.WithCancel(jobsCtx)
+		keepAliveDone := make(chan struct{})
+		go func() {
+			defer close(keepAliveDone)
+			p.keepJobHidden(keepAliveCtx, row.MessageID)
+		}()
+		// waits for the keep alive to exit so a late tick cannot hide the rescheduled job again
+		stopKeepAlive := sync.OnceFunc(func() {
+			cancelKeepAlive()
+			<-keepAliveDone
+		})
+
+		succeeded, err := p.scheduler.executeCollection(jobsCtx, row.MessageID, attempts, message, stopKeepAlive)
+		stopKeepAlive()
+		if errors.Is(err, errCollectionInterrupted) || (err != nil && jobsCtx.Err() != nil) {
+			logger.Warn("Giving back interrupted collection job", "error", err)
+			p.release(row.MessageID, 0)
+		} else if err != nil {
+			logger.Error("Could not run collection job", "error", err)
+		}
+		p.slots.release(context.WithoutCancel(jobsCtx), row.MessageID)
+		p.finish(row.MessageID, err == nil && succeeded, time.Now())
+	}()
+}
+
+// long collections would become visible to other workers while they are still running
+func (p *WorkerPool) keepJobHidden(ctx context.Context, jobID int32) {
+	ticker := time.NewTicker(COLLECTION_TIMEOUT / 2)
+	defer ticker.Stop()
+	q := db.New(p.scheduler.dbPool)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := q.SetQueueVisibility(ctx, db.SetQueueVisibilityParams{
+			QueueName:             SECTIONS_OF_TERM_COLLECTIONS,
+			MessageID:             jobID,
+			SecondsUntilAvailable: int(COLLECTION_TIMEOUT.Seconds()),
+		})
+		if err != nil && ctx.Err() == nil {
+			p.logger.Error("Could not keep collection job hidden", "collectionJob", jobID, "error", err)
+		}
+	}
+}
+
+// the job is given back without it counting as an attempt
+func (p *WorkerPool) release(jobID int32, delay time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	q := db.New(p.scheduler.dbPool)
+	err := q.ReleaseCollectionJob(ctx, db.ReleaseCollectionJobParams{
+		MessageID:             jobID,
+		SecondsUntilAvailable: int(delay.Seconds()),
+	})
+	if err != nil {
+		p.logger.Error("Could not give back collection job", "collectionJob", jobID, "error", err)
+	}
+}
+
+func (p *WorkerPool) reportMetricsUntilDone(ctx context.Context) {
+	ticker := time.NewTicker(WORKER_METRICS_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := p.reportMetrics(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error("Could not report worker pool metrics", "error", err)
+		}
+	}
+}
+
+func (p *WorkerPool) reportMetrics(ctx context.Context) error {
+	metrics := p.metrics(time.Now(), true)
+	q := db.New(p.scheduler.dbPool)
+	depth, err := q.GetCollectionQueueDepth(ctx)
+	if err != nil {
+		return err
+	}
+	p.logger.Info(
+		"Worker pool metrics",
+		"busy",
+		metrics.Busy,
+		"workers",
+		metrics.Workers,
+		"utilization",
+		metrics.Utilization,
+		"queued",
+		depth.Visible,
+		"scheduled",
+		depth.Total,
+		"dead",
+		depth.Dead,
+		"runningBySchool",
+		metrics.RunningBySchool,
+	)
+	return q.UpsertWorkerPoolMetrics(ctx, db.UpsertWorkerPoolMetricsParams{
+		WorkerPoolID:  p.id,
+		Hostname:      p.hostname,
+		Workers:       int32(metrics.Workers),
+		BusyWorkers:   int32(metrics.Busy),
+		Utilization:   float32(metrics.Utilization),
+		CompletedJobs: int64(metrics.Completed),
+		FailedJobs:    int64(metrics.Failed),
+		DeferredJobs:  int64(metrics.Deferred),
+		StartedAt:     pgtype.Timestamptz{Time: p.startedAt, Valid: true},
+	})
+}
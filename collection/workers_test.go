@@ -0,0 +1,85 @@
+package collection
+
+import (
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolLimits(t *testing.T) {
+	now := time.Date(2025, time.March, 3, 10, 0, 0, 0, time.UTC)
+	pool := NewWorkerPool(nil, WorkerPoolConfig{
+		Workers:        3,
+		JobsPerSchool:  1,
+		JobsPerService: 2,
+		ServiceLimits:  map[string]int{"slow": 1},
+	}, slog.Default())
+
+	if !pool.tryStart(1, "marist", "banner", now) {
+		t.Fatal("expected the first job to start")
+	}
+	if pool.tryStart(2, "marist", "banner", now) {
+		t.Error("expected a second job of the same school to be capped")
+	}
+	if !pool.tryStart(3, "vassar", "banner", now) {
+		t.Fatal("expected a job of another school to start")
+	}
+	if pool.tryStart(4, "bard", "banner", now) {
+		t.Error("expected a third job of the same service to be capped")
+	}
+	if !pool.tryStart(5, "bard", "slow", now) {
+		t.Fatal("expected a job of another service to start")
+	}
+	if pool.tryStart(6, "nyu", "other", now) {
+		t.Error("expected jobs to be capped when every worker is busy")
+	}
+	if free := pool.freeWorkers(); free != 0 {
+		t.Errorf("expected no free workers got %d", free)
+	}
+
+	pool.finish(5, true, now)
+	if !pool.tryStart(6, "nyu", "slow", now) {
+		t.Error("expected the freed worker to run the next job")
+	}
+	if pool.tryStart(7, "pace", "slow", now) {
+		t.Error("expected the service limit override to cap the job")
+	}
+
+	pool.finish(1, false, now)
+	if !pool.tryStart(2, "marist", "banner", now) {
+		t.Error("expected the school to be able to run a job after its last one finished")
+	}
+	metrics := pool.metrics(now, false)
+	if metrics.Completed != 1 || metrics.Failed != 1 {
+		t.Errorf("expected 1 completed and 1 failed got %d and %d", metrics.Completed, metrics.Failed)
+	}
+	if metrics.RunningBySchool["marist"] != 1 || metrics.RunningByService["banner"] != 2 {
+		t.Errorf("unexpected running counts %v %v", metrics.RunningBySchool, metrics.RunningByService)
+	}
+}
+
+func TestWorkerPoolUtilization(t *testing.T) {
+	start := time.Date(2025, time.March, 3, 10, 0, 0, 0, time.UTC)
+	pool := NewWorkerPool(nil, WorkerPoolConfig{Workers: 2, JobsPerSchool: 1, JobsPerService: 2}, slog.Default())
+	pool.windowStart = start
+
+	// one worker busy the whole window and the other for half of it
+	pool.tryStart(1, "marist", "banner", start)
+	pool.tryStart(2, "vassar", "banner", start)
+	pool.finish(2, true, start.Add(30*time.Second))
+
+	metrics := pool.metrics(start.Add(time.Minute), true)
+	if math.Abs(metrics.Utilization-0.75) > 0.0001 {
+		t.Errorf("expected 75%% utilization got %f", metrics.Utilization)
+	}
+	if metrics.Busy != 1 {
+		t.Errorf("expected 1 busy worker got %d", metrics.Busy)
+	}
+
+	// the next window only counts the job which is still running
+	metrics = pool.metrics(start.Add(2*time.Minute), false)
+	if math.Abs(metrics.Utilization-0.5) > 0.0001 {
+		t.Errorf("expected 50%% utilization after the reset got %f", metrics.Utilization)
+	}
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+)
+
+// sqlc does not know about temporary tables so these queries have to be written manually
+// the previous values only exist for the transaction they were snapshot in
+const createDryRunPrevious = `
+CREATE TEMPORARY TABLE dry_run_previous (
+    table_name TEXT NOT NULL,
+    fields JSONB NOT NULL
+) ON COMMIT DROP
+`
+
+// courses and professors are never deleted so only the staged ones can change
+const snapshotDryRunPrevious = `
+INSERT INTO dry_run_previous (table_name, fields)
+SELECT 'sections', to_jsonb(s)
+FROM sections s
+WHERE s.school_id = $1::text AND s.term_collection_id = $2::text
+UNION ALL
+SELECT 'meeting_times', to_jsonb(mt)
+FROM meeting_times mt
+WHERE mt.school_id = $1::text AND mt.term_collection_id = $2::text
+UNION ALL
+SELECT 'courses', to_jsonb(c)
+FROM courses c
+WHERE c.school_id = $1::text AND EXISTS (
+    SELECT 1
+    FROM staging_courses sc
+    WHERE sc.term_collection_history_id = $3::int
+      AND sc.school_id = c.school_id
+      AND sc.subject_code = c.subject_code
+      AND sc.number = c.number
+)
+UNION ALL
+SELECT 'professors', to_jsonb(p)
+FROM professors p
+WHERE p.school_id = $1::text AND EXISTS (
+    SELECT 1
+    FROM staging_professors sp
+    WHERE sp.term_collection_history_id = $3::int
+      AND sp.school_id = p.school_id
+      AND sp.id = p.id
+)
+`
+
+const indexDryRunPrevious = `CREATE INDEX ON dry_run_previous USING GIN (fields jsonb_path_ops)`
+
+// the changes the triggers logged for the collection with the values the records had before
+const getDryRunChanges = `
+SELECT h.table_name, h.sync_action, h.pk_fields, h.relevant_fields, p.fields
+FROM historic_class_information h
+LEFT JOIN LATERAL (
+    SELECT dp.fields
+    FROM dry_run_previous dp
+    WHERE dp.table_name = h.table_name
+      AND dp.fields @> h.pk_fields || jsonb_build_object('school_id', h.school_id)
+    LIMIT 1
+) p ON TRUE
+WHERE h.term_collection_history_id = $1::int
+ORDER BY h.sequence
+`
+
+type SnapshotDryRunPreviousParams struct {
+	SchoolID                string `json:"school_id"`
+	TermCollectionID        string `json:"term_collection_id"`
+	TermCollectionHistoryID int32  `json:"term_collection_history_id"`
+}
+
+// keeps the values of every record the collection could change for GetDryRunChanges
+func (q *Queries) SnapshotDryRunPrevious(ctx context.Context, arg SnapshotDryRunPreviousParams) error {
+	if _, err := q.db.Exec(ctx, createDryRunPrevious); err != nil {
+		return err
+	}
+	_, err := q.db.Exec(ctx, snapshotDryRunPrevious, arg.SchoolID, arg.TermCollectionID, arg.TermCollectionHistoryID)
+	if err != nil {
+		return err
+	}
+	_, err = q.db.Exec(ctx, indexDryRunPrevious)
+	return err
+}
+
+type DryRunChangeRow struct {
+	TableName      string   `json:"table_name"`
+	SyncAction     SyncKind `json:"sync_action"`
+	PkFields       []byte   `json:"pk_fields"`
+	RelevantFields []byte   `json:"relevant_fields"`
+	// null for inserted records
+	PreviousFields []byte `json:"previous_fields"`
+}
+
+func (q *Queries) GetDryRunChanges(ctx context.Context, termCollectionHistoryID int32) ([]DryRunChangeRow, error) {
+	rows, err := q.db.Query(ctx, getDryRunChanges, termCollectionHistoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DryRunChangeRow
+	for rows.Next() {
+		var i DryRunChangeRow
+		if err := rows.Scan(
+			&i.TableName,
+			&i.SyncAction,
+			&i.PkFields,
+			&i.RelevantFields,
+			&i.PreviousFields,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
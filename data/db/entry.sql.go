@@ -11,6 +11,130 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const beatTermCollectionHistory = `-- name: BeatTermCollectionHistory :exec
+UPDATE term_collection_history SET heartbeat_at = now()
+WHERE id = $1 AND status IN ('Active', 'DryRun')
+`
+
+func (q *Queries) BeatTermCollectionHistory(ctx context.Context, termCollectionHistoryID int32) error {
+	_, err := q.db.Exec(ctx, beatTermCollectionHistory, termCollectionHistoryID)
+	return err
+}
+
+const countUnstagedSections = `-- name: CountUnstagedSections :one
+SELECT
+    COUNT(*) AS live_sections,
+    COUNT(*) FILTER (WHERE NOT EXISTS (
+        SELECT 1
+        FROM staging_sections ss
+        WHERE ss.sequence = s.sequence
+          AND ss.term_collection_id = s.term_collection_id
+          AND ss.subject_code = s.subject_code
+          AND ss.course_number = s.course_number
+          AND ss.school_id = s.school_id
+          AND ss.term_collection_history_id = $1
+    )) AS unstaged_sections
+FROM sections s
+WHERE s.term_collection_id = $2
+  AND s.school_id = $3
+`
+
+type CountUnstagedSectionsParams struct {
+	TermCollectionHistoryID int32  `json:"term_collection_history_id"`
+	TermCollectionID        string `json:"term_collection_id"`
+	SchoolID                string `json:"school_id"`
+}
+
+type CountUnstagedSectionsRow struct {
+	LiveSections     int64 `json:"live_sections"`
+	UnstagedSections int64 `json:"unstaged_sections"`
+}
+
+// how many of the term's sections moving the collection's staged sections would delete
+func (q *Queries) CountUnstagedSections(ctx context.Context, arg CountUnstagedSectionsParams) (CountUnstagedSectionsRow, error) {
+	row := q.db.QueryRow(ctx, countUnstagedSections, arg.TermCollectionHistoryID, arg.TermCollectionID, arg.SchoolID)
+	var i CountUnstagedSectionsRow
+	err := row.Scan(&i.LiveSections, &i.UnstagedSections)
+	return i, err
+}
+
+const deleteExcessCollectionLogs = `-- name: DeleteExcessCollectionLogs :execrows
+DELETE FROM collection_logs
+WHERE term_collection_history_id IN (
+    SELECT ranked.term_collection_history_id
+    FROM (
+        SELECT term_collection_history_id,
+            row_number() OVER (
+                PARTITION BY school_id, term_collection_id
+                ORDER BY term_collection_history_id DESC
+            ) AS recency
+        FROM collection_logs
+    ) ranked
+    WHERE ranked.recency > $1::int
+)
+`
+
+// only the most recent logs of each term are kept
+func (q *Queries) DeleteExcessCollectionLogs(ctx context.Context, keepCount int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExcessCollectionLogs, keepCount)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredCollectionLogs = `-- name: DeleteExpiredCollectionLogs :execrows
+DELETE FROM collection_logs
+WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => $1::int)
+`
+
+func (q *Queries) DeleteExpiredCollectionLogs(ctx context.Context, maxAgeSeconds int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredCollectionLogs, maxAgeSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredCollectionViolations = `-- name: DeleteExpiredCollectionViolations :execrows
+DELETE FROM collection_violations
+WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => $1::int)
+`
+
+func (q *Queries) DeleteExpiredCollectionViolations(ctx context.Context, maxAgeSeconds int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredCollectionViolations, maxAgeSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredEnrollmentSnapshots = `-- name: DeleteExpiredEnrollmentSnapshots :execrows
+DELETE FROM section_enrollment_snapshots snap
+USING term_collection_history h
+WHERE h.id = snap.term_collection_history_id
+      AND h.start_time < CURRENT_TIMESTAMP - make_interval(secs => $1::int)
+      AND (snap.is_removed OR EXISTS (
+          SELECT 1
+          FROM section_enrollment_snapshots newer
+          WHERE newer.school_id = snap.school_id
+                AND newer.term_collection_id = snap.term_collection_id
+                AND newer.subject_code = snap.subject_code
+                AND newer.course_number = snap.course_number
+                AND newer.section_sequence = snap.section_sequence
+                AND newer.term_collection_history_id > snap.term_collection_history_id
+      ))
+`
+
+// the latest snapshot of a section is kept while the section exists since it is still its enrollment
+func (q *Queries) DeleteExpiredEnrollmentSnapshots(ctx context.Context, maxAgeSeconds int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredEnrollmentSnapshots, maxAgeSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteStagingCourses = `-- name: DeleteStagingCourses :exec
 DELETE FROM staging_courses
 WHERE term_collection_history_id = $1
@@ -51,14 +175,27 @@ func (q *Queries) DeleteStagingSections(ctx context.Context, termCollectionHisto
 	return err
 }
 
+const deleteTermCollectionHistory = `-- name: DeleteTermCollectionHistory :exec
+DELETE FROM term_collection_history
+WHERE id = $1
+`
+
+// dry runs do not leave any history behind
+func (q *Queries) DeleteTermCollectionHistory(ctx context.Context, termCollectionHistoryID int32) error {
+	_, err := q.db.Exec(ctx, deleteTermCollectionHistory, termCollectionHistoryID)
+	return err
+}
+
 const finishTermCollectionHistory = `-- name: FinishTermCollectionHistory :exec
 UPDATE term_collection_history SET
     status = $1,
     deleted_records_count = $2,
     updated_records_count = $3,
     inserted_records_count = $4,
+    is_incorrect_assumption = $5,
+    failure_reason = $6,
     end_time = now()
-WHERE id = $5
+WHERE id = $7
 `
 
 type FinishTermCollectionHistoryParams struct {
@@ -66,6 +203,8 @@ type FinishTermCollectionHistoryParams struct {
 	DeletedRecordsCount     int32                    `json:"deleted_records_count"`
 	UpdatedRecordsCount     int32                    `json:"updated_records_count"`
 	InsertedRecordsCount    int32                    `json:"inserted_records_count"`
+	IsIncorrectAssumption   bool                     `json:"is_incorrect_assumption"`
+	FailureReason           pgtype.Text              `json:"failure_reason"`
 	TermCollectionHistoryID int32                    `json:"term_collection_history_id"`
 }
 
@@ -75,6 +214,8 @@ func (q *Queries) FinishTermCollectionHistory(ctx context.Context, arg FinishTer
 		arg.DeletedRecordsCount,
 		arg.UpdatedRecordsCount,
 		arg.InsertedRecordsCount,
+		arg.IsIncorrectAssumption,
+		arg.FailureReason,
 		arg.TermCollectionHistoryID,
 	)
 	return err
@@ -150,21 +291,255 @@ func (q *Queries) GetChangesFromMoveTermCollection(ctx context.Context, termColl
 	return i, err
 }
 
+const getRecentTermCollectionHistory = `-- name: GetRecentTermCollectionHistory :many
+SELECT id, status, term_collection_id, school_id, start_time, is_full, end_time, deleted_records_count, updated_records_count, inserted_records_count, service_name, is_incorrect_assumption, failure_reason, heartbeat_at FROM term_collection_history
+WHERE school_id = $1
+      AND term_collection_id = $2
+      AND status NOT IN ('Active', 'DryRun')
+ORDER BY start_time DESC
+LIMIT $3
+`
+
+type GetRecentTermCollectionHistoryParams struct {
+	SchoolID         string `json:"school_id"`
+	TermCollectionID string `json:"term_collection_id"`
+	LimitCount       int32  `json:"limit_count"`
+}
+
+func (q *Queries) GetRecentTermCollectionHistory(ctx context.Context, arg GetRecentTermCollectionHistoryParams) ([]TermCollectionHistory, error) {
+	rows, err := q.db.Query(ctx, getRecentTermCollectionHistory, arg.SchoolID, arg.TermCollectionID, arg.LimitCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TermCollectionHistory
+	for rows.Next() {
+		var i TermCollectionHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.TermCollectionID,
+			&i.SchoolID,
+			&i.StartTime,
+			&i.IsFull,
+			&i.EndTime,
+			&i.DeletedRecordsCount,
+			&i.UpdatedRecordsCount,
+			&i.InsertedRecordsCount,
+			&i.ServiceName,
+			&i.IsIncorrectAssumption,
+			&i.FailureReason,
+			&i.HeartbeatAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getServiceHealth = `-- name: GetServiceHealth :many
+WITH recent AS (
+    -- walks term_collection_history_service_health for each service so only the window is read
+    SELECT r.school_id, r.service_name, h.status, h.is_incorrect_assumption, h.start_time, h.end_time,
+        ROW_NUMBER() OVER (PARTITION BY r.school_id, r.service_name ORDER BY h.start_time DESC) AS recency
+    FROM school_registry r
+    CROSS JOIN LATERAL (
+        SELECT status, is_incorrect_assumption, start_time, end_time
+        FROM term_collection_history
+        WHERE school_id = r.school_id AND service_name = r.service_name
+            -- cancelled collections say nothing about the service
+            AND status IN ('Success', 'Failure')
+        ORDER BY start_time DESC
+        LIMIT $1::INTEGER
+    ) h
+)
+SELECT school_id, service_name::TEXT AS service_name,
+    COUNT(*) FILTER (WHERE status = 'Success')::INTEGER AS success_count,
+    COUNT(*) FILTER (WHERE status = 'Failure')::INTEGER AS failure_count,
+    COUNT(*) FILTER (WHERE is_incorrect_assumption)::INTEGER AS incorrect_assumption_count,
+    -- failures since the last success
+    COALESCE(MIN(recency) FILTER (WHERE status = 'Success') - 1, COUNT(*))::INTEGER AS consecutive_failure_count,
+    COALESCE(
+        AVG(EXTRACT(EPOCH FROM end_time - start_time)) FILTER (WHERE status = 'Success'),
+        0
+    )::FLOAT8 AS average_success_seconds,
+    MAX(end_time) FILTER (WHERE status = 'Failure')::TIMESTAMPTZ AS last_failure_time
+FROM recent
+GROUP BY school_id, service_name
+`
+
+type GetServiceHealthRow struct {
+	SchoolID                 string             `json:"school_id"`
+	ServiceName              string             `json:"service_name"`
+	SuccessCount             int32              `json:"success_count"`
+	FailureCount             int32              `json:"failure_count"`
+	IncorrectAssumptionCount int32              `json:"incorrect_assumption_count"`
+	ConsecutiveFailureCount  int32              `json:"consecutive_failure_count"`
+	AverageSuccessSeconds    float64            `json:"average_success_seconds"`
+	LastFailureTime          pgtype.Timestamptz `json:"last_failure_time"`
+}
+
+// how the most recent finished collections of each school's services went
+func (q *Queries) GetServiceHealth(ctx context.Context, windowSize int32) ([]GetServiceHealthRow, error) {
+	rows, err := q.db.Query(ctx, getServiceHealth, windowSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetServiceHealthRow
+	for rows.Next() {
+		var i GetServiceHealthRow
+		if err := rows.Scan(
+			&i.SchoolID,
+			&i.ServiceName,
+			&i.SuccessCount,
+			&i.FailureCount,
+			&i.IncorrectAssumptionCount,
+			&i.ConsecutiveFailureCount,
+			&i.AverageSuccessSeconds,
+			&i.LastFailureTime,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTermRegistrationCalendar = `-- name: GetTermRegistrationCalendar :one
+SELECT term_collection_id, school_id, registration_start, classes_start, add_drop_end, term_end FROM term_registration_calendars
+WHERE school_id = $1 AND term_collection_id = $2
+`
+
+type GetTermRegistrationCalendarParams struct {
+	SchoolID         string `json:"school_id"`
+	TermCollectionID string `json:"term_collection_id"`
+}
+
+func (q *Queries) GetTermRegistrationCalendar(ctx context.Context, arg GetTermRegistrationCalendarParams) (TermRegistrationCalendar, error) {
+	row := q.db.QueryRow(ctx, getTermRegistrationCalendar, arg.SchoolID, arg.TermCollectionID)
+	var i TermRegistrationCalendar
+	err := row.Scan(
+		&i.TermCollectionID,
+		&i.SchoolID,
+		&i.RegistrationStart,
+		&i.ClassesStart,
+		&i.AddDropEnd,
+		&i.TermEnd,
+	)
+	return i, err
+}
+
+const hasPendingQuarantinedCollection = `-- name: HasPendingQuarantinedCollection :one
+SELECT EXISTS (
+    SELECT 1 FROM quarantined_collections
+    WHERE school_id = $1
+          AND term_collection_id = $2
+          AND status = 'Pending'
+)::BOOL AS is_pending
+`
+
+type HasPendingQuarantinedCollectionParams struct {
+	SchoolID         string `json:"school_id"`
+	TermCollectionID string `json:"term_collection_id"`
+}
+
+// scheduled collections wait for the term's quarantined collection to be decided
+func (q *Queries) HasPendingQuarantinedCollection(ctx context.Context, arg HasPendingQuarantinedCollectionParams) (bool, error) {
+	row := q.db.QueryRow(ctx, hasPendingQuarantinedCollection, arg.SchoolID, arg.TermCollectionID)
+	var is_pending bool
+	err := row.Scan(&is_pending)
+	return is_pending, err
+}
+
+const insertCollectionLog = `-- name: InsertCollectionLog :exec
+INSERT INTO collection_logs
+    (term_collection_history_id, school_id, term_collection_id,
+        compressed_logs, uncompressed_bytes, lines, truncated)
+VALUES
+    ($1, $2, $3,
+        $4, $5, $6, $7)
+`
+
+type InsertCollectionLogParams struct {
+	TermCollectionHistoryID int32  `json:"term_collection_history_id"`
+	SchoolID                string `json:"school_id"`
+	TermCollectionID        string `json:"term_collection_id"`
+	CompressedLogs          []byte `json:"compressed_logs"`
+	UncompressedBytes       int32  `json:"uncompressed_bytes"`
+	Lines                   int32  `json:"lines"`
+	Truncated               bool   `json:"truncated"`
+}
+
+func (q *Queries) InsertCollectionLog(ctx context.Context, arg InsertCollectionLogParams) error {
+	_, err := q.db.Exec(ctx, insertCollectionLog,
+		arg.TermCollectionHistoryID,
+		arg.SchoolID,
+		arg.TermCollectionID,
+		arg.CompressedLogs,
+		arg.UncompressedBytes,
+		arg.Lines,
+		arg.Truncated,
+	)
+	return err
+}
+
+const insertQuarantinedCollection = `-- name: InsertQuarantinedCollection :exec
+INSERT INTO quarantined_collections
+    (term_collection_history_id, term_collection_id, school_id, live_sections, deleted_sections)
+VALUES
+    ($1, $2, $3, $4, $5)
+`
+
+type InsertQuarantinedCollectionParams struct {
+	TermCollectionHistoryID int32  `json:"term_collection_history_id"`
+	TermCollectionID        string `json:"term_collection_id"`
+	SchoolID                string `json:"school_id"`
+	LiveSections            int32  `json:"live_sections"`
+	DeletedSections         int32  `json:"deleted_sections"`
+}
+
+func (q *Queries) InsertQuarantinedCollection(ctx context.Context, arg InsertQuarantinedCollectionParams) error {
+	_, err := q.db.Exec(ctx, insertQuarantinedCollection,
+		arg.TermCollectionHistoryID,
+		arg.TermCollectionID,
+		arg.SchoolID,
+		arg.LiveSections,
+		arg.DeletedSections,
+	)
+	return err
+}
+
 const insertTermCollectionHistory = `-- name: InsertTermCollectionHistory :one
 INSERT INTO term_collection_history
-    (term_collection_id, school_id, is_full)
-VALUES ($1, $2, $3)
+    (term_collection_id, school_id, is_full, service_name, status)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING id
 `
 
 type InsertTermCollectionHistoryParams struct {
-	TermCollectionID string `json:"term_collection_id"`
-	SchoolID         string `json:"school_id"`
-	IsFull           bool   `json:"is_full"`
+	TermCollectionID string                   `json:"term_collection_id"`
+	SchoolID         string                   `json:"school_id"`
+	IsFull           bool                     `json:"is_full"`
+	ServiceName      pgtype.Text              `json:"service_name"`
+	Status           TermCollectionStatusEnum `json:"status"`
 }
 
 func (q *Queries) InsertTermCollectionHistory(ctx context.Context, arg InsertTermCollectionHistoryParams) (int32, error) {
-	row := q.db.QueryRow(ctx, insertTermCollectionHistory, arg.TermCollectionID, arg.SchoolID, arg.IsFull)
+	row := q.db.QueryRow(ctx, insertTermCollectionHistory,
+		arg.TermCollectionID,
+		arg.SchoolID,
+		arg.IsFull,
+		arg.ServiceName,
+		arg.Status,
+	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
@@ -312,6 +687,230 @@ func (q *Queries) MoveStagedSections(ctx context.Context, termCollectionHistoryI
 	return err
 }
 
+const reapOrphanedTermCollectionHistory = `-- name: ReapOrphanedTermCollectionHistory :many
+UPDATE term_collection_history SET
+    status = CASE WHEN status = 'DryRun' THEN 'Cancelled' ELSE 'Failure' END::term_collection_status_enum,
+    failure_reason = $1::TEXT,
+    end_time = now()
+WHERE status IN ('Active', 'DryRun')
+      AND COALESCE(heartbeat_at, start_time) < now() - make_interval(secs => $2::INT)
+RETURNING id, term_collection_id, school_id
+`
+
+type ReapOrphanedTermCollectionHistoryParams struct {
+	FailureReason string `json:"failure_reason"`
+	StaleSeconds  int32  `json:"stale_seconds"`
+}
+
+type ReapOrphanedTermCollectionHistoryRow struct {
+	ID               int32  `json:"id"`
+	TermCollectionID string `json:"term_collection_id"`
+	SchoolID         string `json:"school_id"`
+}
+
+// fails the active collections which have not had a heartbeat in a while
+//
+//	their process is assumed to be gone
+//	orphaned dry runs are cancelled since they say nothing about the service
+func (q *Queries) ReapOrphanedTermCollectionHistory(ctx context.Context, arg ReapOrphanedTermCollectionHistoryParams) ([]ReapOrphanedTermCollectionHistoryRow, error) {
+	rows, err := q.db.Query(ctx, reapOrphanedTermCollectionHistory, arg.FailureReason, arg.StaleSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReapOrphanedTermCollectionHistoryRow
+	for rows.Next() {
+		var i ReapOrphanedTermCollectionHistoryRow
+		if err := rows.Scan(&i.ID, &i.TermCollectionID, &i.SchoolID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordBackwardsMeetingTimes = `-- name: RecordBackwardsMeetingTimes :execrows
+INSERT INTO collection_violations
+    (term_collection_history_id, school_id, rule, severity, table_name, record_key, message)
+SELECT
+    mt.term_collection_history_id, mt.school_id, $1::TEXT, $2::violation_severity_enum, 'meeting_times',
+    jsonb_build_object(
+        'sequence', mt.sequence, 'section_sequence', mt.section_sequence,
+        'subject_code', mt.subject_code, 'course_number', mt.course_number
+    ),
+    CASE WHEN mt.end_minutes < mt.start_minutes
+        THEN format('ends at %s before it starts at %s', mt.end_minutes, mt.start_minutes)
+        ELSE format('ends on %s before it starts on %s', mt.end_date::DATE, mt.start_date::DATE)
+    END
+FROM staging_meeting_times mt
+WHERE mt.term_collection_history_id = $3
+  AND (mt.end_minutes < mt.start_minutes OR mt.end_date < mt.start_date)
+`
+
+type RecordBackwardsMeetingTimesParams struct {
+	Rule                    string                `json:"rule"`
+	Severity                ViolationSeverityEnum `json:"severity"`
+	TermCollectionHistoryID int32                 `json:"term_collection_history_id"`
+}
+
+// meeting times which end before they start
+func (q *Queries) RecordBackwardsMeetingTimes(ctx context.Context, arg RecordBackwardsMeetingTimesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, recordBackwardsMeetingTimes, arg.Rule, arg.Severity, arg.TermCollectionHistoryID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordCreditHoursOutOfRange = `-- name: RecordCreditHoursOutOfRange :execrows
+INSERT INTO collection_violations
+    (term_collection_history_id, school_id, rule, severity, table_name, record_key, message)
+SELECT
+    c.term_collection_history_id, c.school_id, $1::TEXT, $2::violation_severity_enum, 'courses',
+    jsonb_build_object('subject_code', c.subject_code, 'number', c.number),
+    format('is worth %s credit hours', c.credit_hours)
+FROM staging_courses c
+WHERE c.term_collection_history_id = $3
+  AND (c.credit_hours < $4::REAL OR c.credit_hours > $5::REAL)
+`
+
+type RecordCreditHoursOutOfRangeParams struct {
+	Rule                    string                `json:"rule"`
+	Severity                ViolationSeverityEnum `json:"severity"`
+	TermCollectionHistoryID int32                 `json:"term_collection_history_id"`
+	MinCreditHours          float32               `json:"min_credit_hours"`
+	MaxCreditHours          float32               `json:"max_credit_hours"`
+}
+
+// courses worth less or more credit hours than any course should be
+func (q *Queries) RecordCreditHoursOutOfRange(ctx context.Context, arg RecordCreditHoursOutOfRangeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, recordCreditHoursOutOfRange,
+		arg.Rule,
+		arg.Severity,
+		arg.TermCollectionHistoryID,
+		arg.MinCreditHours,
+		arg.MaxCreditHours,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordDuplicateMeetingSequences = `-- name: RecordDuplicateMeetingSequences :execrows
+INSERT INTO collection_violations
+    (term_collection_history_id, school_id, rule, severity, table_name, record_key, message)
+SELECT
+    mt.term_collection_history_id, mt.school_id, $1::TEXT, $2::violation_severity_enum, 'meeting_times',
+    jsonb_build_object(
+        'sequence', mt.sequence, 'section_sequence', mt.section_sequence,
+        'subject_code', mt.subject_code, 'course_number', mt.course_number
+    ),
+    format('was staged %s times', COUNT(*))
+FROM staging_meeting_times mt
+WHERE mt.term_collection_history_id = $3
+GROUP BY mt.term_collection_history_id, mt.school_id, mt.term_collection_id,
+    mt.sequence, mt.section_sequence, mt.subject_code, mt.course_number
+HAVING COUNT(*) > 1
+`
+
+type RecordDuplicateMeetingSequencesParams struct {
+	Rule                    string                `json:"rule"`
+	Severity                ViolationSeverityEnum `json:"severity"`
+	TermCollectionHistoryID int32                 `json:"term_collection_history_id"`
+}
+
+// only one of the duplicated meetings is moved so the others are lost
+func (q *Queries) RecordDuplicateMeetingSequences(ctx context.Context, arg RecordDuplicateMeetingSequencesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, recordDuplicateMeetingSequences, arg.Rule, arg.Severity, arg.TermCollectionHistoryID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordEnrollmentOverflows = `-- name: RecordEnrollmentOverflows :execrows
+INSERT INTO collection_violations
+    (term_collection_history_id, school_id, rule, severity, table_name, record_key, message)
+SELECT
+    s.term_collection_history_id, s.school_id, $1::TEXT, $2::violation_severity_enum, 'sections',
+    jsonb_build_object(
+        'sequence', s.sequence, 'subject_code', s.subject_code, 'course_number', s.course_number
+    ),
+    format('has %s students enrolled in %s seats', s.enrollment, s.max_enrollment)
+FROM staging_sections s
+WHERE s.term_collection_history_id = $3
+  AND s.max_enrollment > 0
+  AND s.enrollment > s.max_enrollment * $4::REAL
+`
+
+type RecordEnrollmentOverflowsParams struct {
+	Rule                    string                `json:"rule"`
+	Severity                ViolationSeverityEnum `json:"severity"`
+	TermCollectionHistoryID int32                 `json:"term_collection_history_id"`
+	MaxOverflowRatio        float32               `json:"max_overflow_ratio"`
+}
+
+// sections with so many more students than seats the numbers are likely wrong
+func (q *Queries) RecordEnrollmentOverflows(ctx context.Context, arg RecordEnrollmentOverflowsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, recordEnrollmentOverflows,
+		arg.Rule,
+		arg.Severity,
+		arg.TermCollectionHistoryID,
+		arg.MaxOverflowRatio,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordMissingSectionCourses = `-- name: RecordMissingSectionCourses :execrows
+INSERT INTO collection_violations
+    (term_collection_history_id, school_id, rule, severity, table_name, record_key, message)
+SELECT
+    s.term_collection_history_id, s.school_id, $1::TEXT, $2::violation_severity_enum, 'sections',
+    jsonb_build_object(
+        'sequence', s.sequence, 'subject_code', s.subject_code, 'course_number', s.course_number
+    ),
+    format('references the missing course %s %s', s.subject_code, s.course_number)
+FROM staging_sections s
+WHERE s.term_collection_history_id = $3
+  AND NOT EXISTS (
+      SELECT 1
+      FROM staging_courses sc
+      WHERE sc.term_collection_history_id = s.term_collection_history_id
+        AND sc.school_id = s.school_id
+        AND sc.subject_code = s.subject_code
+        AND sc.number = s.course_number
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM courses c
+      WHERE c.school_id = s.school_id
+        AND c.subject_code = s.subject_code
+        AND c.number = s.course_number
+  )
+`
+
+type RecordMissingSectionCoursesParams struct {
+	Rule                    string                `json:"rule"`
+	Severity                ViolationSeverityEnum `json:"severity"`
+	TermCollectionHistoryID int32                 `json:"term_collection_history_id"`
+}
+
+// sections whose course is neither staged nor already known
+func (q *Queries) RecordMissingSectionCourses(ctx context.Context, arg RecordMissingSectionCoursesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, recordMissingSectionCourses, arg.Rule, arg.Severity, arg.TermCollectionHistoryID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const removeUnstagedMeetings = `-- name: RemoveUnstagedMeetings :exec
 DELETE FROM meeting_times mt
 WHERE mt.term_collection_id = $1
@@ -325,16 +924,18 @@ WHERE mt.term_collection_id = $1
       AND smt.course_number = mt.course_number
       AND smt.school_id = mt.school_id
       AND smt.section_sequence = mt.section_sequence
+      AND smt.term_collection_history_id = $3
   )
 `
 
 type RemoveUnstagedMeetingsParams struct {
-	TermCollectionID string `json:"term_collection_id"`
-	SchoolID         string `json:"school_id"`
+	TermCollectionID        string `json:"term_collection_id"`
+	SchoolID                string `json:"school_id"`
+	TermCollectionHistoryID int32  `json:"term_collection_history_id"`
 }
 
 func (q *Queries) RemoveUnstagedMeetings(ctx context.Context, arg RemoveUnstagedMeetingsParams) error {
-	_, err := q.db.Exec(ctx, removeUnstagedMeetings, arg.TermCollectionID, arg.SchoolID)
+	_, err := q.db.Exec(ctx, removeUnstagedMeetings, arg.TermCollectionID, arg.SchoolID, arg.TermCollectionHistoryID)
 	return err
 }
 
@@ -350,16 +951,18 @@ WHERE s.term_collection_id = $1
       AND ss.subject_code = s.subject_code
       AND ss.course_number = s.course_number
       AND ss.school_id = s.school_id
+      AND ss.term_collection_history_id = $3
   )
 `
 
 type RemoveUnstagedSectionsParams struct {
-	TermCollectionID string `json:"term_collection_id"`
-	SchoolID         string `json:"school_id"`
+	TermCollectionID        string `json:"term_collection_id"`
+	SchoolID                string `json:"school_id"`
+	TermCollectionHistoryID int32  `json:"term_collection_history_id"`
 }
 
 func (q *Queries) RemoveUnstagedSections(ctx context.Context, arg RemoveUnstagedSectionsParams) error {
-	_, err := q.db.Exec(ctx, removeUnstagedSections, arg.TermCollectionID, arg.SchoolID)
+	_, err := q.db.Exec(ctx, removeUnstagedSections, arg.TermCollectionID, arg.SchoolID, arg.TermCollectionHistoryID)
 	return err
 }
 
@@ -424,6 +1027,101 @@ type StageSectionsParams struct {
 	Other                   []byte      `json:"other"`
 }
 
+const snapshotSectionEnrollment = `-- name: SnapshotSectionEnrollment :exec
+WITH latest AS (
+    SELECT DISTINCT ON (snap.subject_code, snap.course_number, snap.section_sequence)
+        snap.subject_code, snap.course_number, snap.section_sequence,
+        snap.enrollment, snap.max_enrollment, snap.is_removed
+    FROM section_enrollment_snapshots snap
+    WHERE snap.school_id = $1
+          AND snap.term_collection_id = $2
+    ORDER BY snap.subject_code, snap.course_number, snap.section_sequence,
+        snap.term_collection_history_id DESC
+)
+INSERT INTO section_enrollment_snapshots
+    (term_collection_history_id, section_sequence, term_collection_id,
+        subject_code, course_number, school_id,
+        enrollment, max_enrollment, is_removed)
+SELECT $3::INT, s."sequence", s.term_collection_id,
+        s.subject_code, s.course_number, s.school_id,
+        s.enrollment, s.max_enrollment, FALSE
+FROM sections s
+LEFT JOIN latest l ON l.subject_code = s.subject_code
+                   AND l.course_number = s.course_number
+                   AND l.section_sequence = s."sequence"
+WHERE s.school_id = $1
+      AND s.term_collection_id = $2
+      AND (l.section_sequence IS NULL
+           OR l.is_removed
+           OR l.enrollment IS DISTINCT FROM s.enrollment
+           OR l.max_enrollment IS DISTINCT FROM s.max_enrollment)
+UNION ALL
+SELECT $3::INT, l.section_sequence, $2,
+        l.subject_code, l.course_number, $1,
+        NULL, NULL, TRUE
+FROM latest l
+WHERE NOT l.is_removed
+      AND NOT EXISTS (
+          SELECT 1
+          FROM sections s
+          WHERE s.school_id = $1
+                AND s.term_collection_id = $2
+                AND s.subject_code = l.subject_code
+                AND s.course_number = l.course_number
+                AND s."sequence" = l.section_sequence
+      )
+`
+
+type SnapshotSectionEnrollmentParams struct {
+	SchoolID                string `json:"school_id"`
+	TermCollectionID        string `json:"term_collection_id"`
+	TermCollectionHistoryID int32  `json:"term_collection_history_id"`
+}
+
+// only the sections whose enrollment changed since their latest snapshot are snapshotted
+func (q *Queries) SnapshotSectionEnrollment(ctx context.Context, arg SnapshotSectionEnrollmentParams) error {
+	_, err := q.db.Exec(ctx, snapshotSectionEnrollment, arg.SchoolID, arg.TermCollectionID, arg.TermCollectionHistoryID)
+	return err
+}
+
+const supersedeQuarantinedCollections = `-- name: SupersedeQuarantinedCollections :many
+UPDATE quarantined_collections SET
+    status = 'Superseded',
+    decided_at = now()
+WHERE school_id = $1
+      AND term_collection_id = $2
+      AND term_collection_history_id < $3
+      AND status = 'Pending'
+RETURNING term_collection_history_id
+`
+
+type SupersedeQuarantinedCollectionsParams struct {
+	SchoolID                string `json:"school_id"`
+	TermCollectionID        string `json:"term_collection_id"`
+	TermCollectionHistoryID int32  `json:"term_collection_history_id"`
+}
+
+// older pending collections of the term would overwrite newer data if they were approved
+func (q *Queries) SupersedeQuarantinedCollections(ctx context.Context, arg SupersedeQuarantinedCollectionsParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, supersedeQuarantinedCollections, arg.SchoolID, arg.TermCollectionID, arg.TermCollectionHistoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var term_collection_history_id int32
+		if err := rows.Scan(&term_collection_history_id); err != nil {
+			return nil, err
+		}
+		items = append(items, term_collection_history_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const upsertSchool = `-- name: UpsertSchool :exec
 INSERT INTO schools
     (id, name)
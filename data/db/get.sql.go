@@ -37,6 +37,86 @@ func (q *Queries) CourseExists(ctx context.Context, arg CourseExistsParams) (boo
 	return column_1, err
 }
 
+const getCourseFillRate = `-- name: GetCourseFillRate :many
+SELECT h.id AS term_collection_history_id, h.end_time AS collected_at,
+       COUNT(*) FILTER (WHERE NOT latest.is_removed) AS sections_count,
+       COUNT(*) FILTER (WHERE latest.enrollment >= latest.max_enrollment) AS full_sections_count,
+       COALESCE(SUM(latest.enrollment), 0)::INT AS enrollment,
+       COALESCE(SUM(latest.max_enrollment), 0)::INT AS max_enrollment
+FROM term_collection_history h
+CROSS JOIN LATERAL (
+    SELECT DISTINCT ON (s.section_sequence) s.enrollment, s.max_enrollment, s.is_removed
+    FROM section_enrollment_snapshots s
+    WHERE s.school_id = $1
+          AND s.term_collection_id = $2
+          AND s.subject_code = $3
+          AND s.course_number = $4
+          AND s.term_collection_history_id <= h.id
+    ORDER BY s.section_sequence, s.term_collection_history_id DESC
+) latest
+WHERE h.id IN (
+    SELECT s.term_collection_history_id
+    FROM section_enrollment_snapshots s
+    WHERE s.school_id = $1
+          AND s.term_collection_id = $2
+          AND s.subject_code = $3
+          AND s.course_number = $4
+)
+GROUP BY h.id, h.end_time
+ORDER BY h.end_time, h.id
+`
+
+type GetCourseFillRateParams struct {
+	SchoolID         string `json:"school_id"`
+	TermCollectionID string `json:"term_collection_id"`
+	SubjectCode      string `json:"subject_code"`
+	CourseNumber     string `json:"course_number"`
+}
+
+type GetCourseFillRateRow struct {
+	TermCollectionHistoryID int32              `json:"term_collection_history_id"`
+	CollectedAt             pgtype.Timestamptz `json:"collected_at"`
+	SectionsCount           int64              `json:"sections_count"`
+	FullSectionsCount       int64              `json:"full_sections_count"`
+	Enrollment              int32              `json:"enrollment"`
+	MaxEnrollment           int32              `json:"max_enrollment"`
+}
+
+// the enrollment of all of the sections of a course summed for each collection which changed it
+//
+//	a section's enrollment as of a collection is its latest snapshot at that collection
+func (q *Queries) GetCourseFillRate(ctx context.Context, arg GetCourseFillRateParams) ([]GetCourseFillRateRow, error) {
+	rows, err := q.db.Query(ctx, getCourseFillRate,
+		arg.SchoolID,
+		arg.TermCollectionID,
+		arg.SubjectCode,
+		arg.CourseNumber,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCourseFillRateRow
+	for rows.Next() {
+		var i GetCourseFillRateRow
+		if err := rows.Scan(
+			&i.TermCollectionHistoryID,
+			&i.CollectedAt,
+			&i.SectionsCount,
+			&i.FullSectionsCount,
+			&i.Enrollment,
+			&i.MaxEnrollment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getCourseWithHueristics = `-- name: GetCourseWithHueristics :one
 SELECT c.school_id, c.subject_code, c.number, c.subject_description, c.title, c.description, c.credit_hours, c.prerequisites, c.corequisites, c.other, ch.previous_terms, ch.previous_professors
 FROM courses c
@@ -228,17 +308,70 @@ JOIN sections ON sections."sequence"           = section_meetings."sequence"
               AND sections.school_id           = section_meetings.school_id
 WHERE sections.school_id = $1
       AND sections.term_collection_id = $2
+      AND ($3::TEXT IS NULL OR sections.subject_code = $3)
+      AND ($4::TEXT IS NULL OR sections.campus = $4)
+      AND ($5::TEXT IS NULL OR sections.instruction_method = $5)
+      AND ($6::TEXT IS NULL OR sections.primary_professor_id = $6)
+      AND (NOT $7::BOOL OR sections.enrollment < sections.max_enrollment)
+      AND ($8::TEXT IS NULL OR EXISTS (
+          SELECT 1 FROM meeting_times mt
+          WHERE mt.school_id = sections.school_id
+                AND mt.term_collection_id = sections.term_collection_id
+                AND mt.subject_code = sections.subject_code
+                AND mt.course_number = sections.course_number
+                AND mt.section_sequence = sections."sequence"
+                AND mt.meeting_type = $8
+      ))
+      -- every meeting of the section must fit in the requested days and time windows
+      AND NOT EXISTS (
+          SELECT 1 FROM meeting_times mt
+          WHERE mt.school_id = sections.school_id
+                AND mt.term_collection_id = sections.term_collection_id
+                AND mt.subject_code = sections.subject_code
+                AND mt.course_number = sections.course_number
+                AND mt.section_sequence = sections."sequence"
+                AND (
+                    ($9::BOOL AND mt.is_monday)
+                    OR ($10::BOOL AND mt.is_tuesday)
+                    OR ($11::BOOL AND mt.is_wednesday)
+                    OR ($12::BOOL AND mt.is_thursday)
+                    OR ($13::BOOL AND mt.is_friday)
+                    OR ($14::BOOL AND mt.is_saturday)
+                    OR ($15::BOOL AND mt.is_sunday)
+                    OR mt.start_minutes < $16::TIME
+                    OR mt.start_minutes > $17::TIME
+                    OR mt.end_minutes < $18::TIME
+                    OR mt.end_minutes > $19::TIME
+                )
+      )
 ORDER BY sections."sequence", sections.subject_code, sections.course_number, 
     sections.school_id, sections.term_collection_id
-LIMIT $4+ 1
-OFFSET $3
+LIMIT $21+ 1
+OFFSET $20
 `
 
 type GetSchoolsClassesForTermOrderedBySectionParams struct {
-	SchoolID         string `json:"school_id"`
-	TermCollectionID string `json:"term_collection_id"`
-	Offsetvalue      int32  `json:"offsetvalue"`
-	Limitvalue       int32  `json:"+limitvalue"`
+	SchoolID           string      `json:"school_id"`
+	TermCollectionID   string      `json:"term_collection_id"`
+	SubjectCode        pgtype.Text `json:"subject_code"`
+	Campus             pgtype.Text `json:"campus"`
+	InstructionMethod  pgtype.Text `json:"instruction_method"`
+	PrimaryProfessorID pgtype.Text `json:"primary_professor_id"`
+	HasOpenSeats       bool        `json:"has_open_seats"`
+	MeetingType        pgtype.Text `json:"meeting_type"`
+	ExcludeMonday      bool        `json:"exclude_monday"`
+	ExcludeTuesday     bool        `json:"exclude_tuesday"`
+	ExcludeWednesday   bool        `json:"exclude_wednesday"`
+	ExcludeThursday    bool        `json:"exclude_thursday"`
+	ExcludeFriday      bool        `json:"exclude_friday"`
+	ExcludeSaturday    bool        `json:"exclude_saturday"`
+	ExcludeSunday      bool        `json:"exclude_sunday"`
+	StartAfter         pgtype.Time `json:"start_after"`
+	StartBefore        pgtype.Time `json:"start_before"`
+	EndAfter           pgtype.Time `json:"end_after"`
+	EndBefore          pgtype.Time `json:"end_before"`
+	Offsetvalue        int32       `json:"offsetvalue"`
+	Limitvalue         int32       `json:"+limitvalue"`
 }
 
 type GetSchoolsClassesForTermOrderedBySectionRow struct {
@@ -246,10 +379,28 @@ type GetSchoolsClassesForTermOrderedBySectionRow struct {
 	MeetingTimes []PartialMeetingTime `json:"meeting_times"`
 }
 
+// null filters are ignored
 func (q *Queries) GetSchoolsClassesForTermOrderedBySection(ctx context.Context, arg GetSchoolsClassesForTermOrderedBySectionParams) ([]GetSchoolsClassesForTermOrderedBySectionRow, error) {
 	rows, err := q.db.Query(ctx, getSchoolsClassesForTermOrderedBySection,
 		arg.SchoolID,
 		arg.TermCollectionID,
+		arg.SubjectCode,
+		arg.Campus,
+		arg.InstructionMethod,
+		arg.PrimaryProfessorID,
+		arg.HasOpenSeats,
+		arg.MeetingType,
+		arg.ExcludeMonday,
+		arg.ExcludeTuesday,
+		arg.ExcludeWednesday,
+		arg.ExcludeThursday,
+		arg.ExcludeFriday,
+		arg.ExcludeSaturday,
+		arg.ExcludeSunday,
+		arg.StartAfter,
+		arg.StartBefore,
+		arg.EndAfter,
+		arg.EndBefore,
 		arg.Offsetvalue,
 		arg.Limitvalue,
 	)
@@ -284,6 +435,243 @@ func (q *Queries) GetSchoolsClassesForTermOrderedBySection(ctx context.Context,
 	return items, nil
 }
 
+const getSectionEnrollmentHistory = `-- name: GetSectionEnrollmentHistory :many
+SELECT h.id AS term_collection_history_id, h.end_time AS collected_at,
+       s.enrollment, s.max_enrollment
+FROM section_enrollment_snapshots s
+JOIN term_collection_history h ON h.id = s.term_collection_history_id
+WHERE s.school_id = $1
+      AND s.term_collection_id = $2
+      AND s.subject_code = $3
+      AND s.course_number = $4
+      AND s.section_sequence = $5
+      AND NOT s.is_removed
+ORDER BY h.end_time, h.id
+LIMIT $7+ 1
+OFFSET $6
+`
+
+type GetSectionEnrollmentHistoryParams struct {
+	SchoolID         string `json:"school_id"`
+	TermCollectionID string `json:"term_collection_id"`
+	SubjectCode      string `json:"subject_code"`
+	CourseNumber     string `json:"course_number"`
+	SectionSequence  string `json:"section_sequence"`
+	Offsetvalue      int32  `json:"offsetvalue"`
+	Limitvalue       int32  `json:"+limitvalue"`
+}
+
+type GetSectionEnrollmentHistoryRow struct {
+	TermCollectionHistoryID int32              `json:"term_collection_history_id"`
+	CollectedAt             pgtype.Timestamptz `json:"collected_at"`
+	Enrollment              pgtype.Int4        `json:"enrollment"`
+	MaxEnrollment           pgtype.Int4        `json:"max_enrollment"`
+}
+
+// only the collections which changed the section's enrollment
+func (q *Queries) GetSectionEnrollmentHistory(ctx context.Context, arg GetSectionEnrollmentHistoryParams) ([]GetSectionEnrollmentHistoryRow, error) {
+	rows, err := q.db.Query(ctx, getSectionEnrollmentHistory,
+		arg.SchoolID,
+		arg.TermCollectionID,
+		arg.SubjectCode,
+		arg.CourseNumber,
+		arg.SectionSequence,
+		arg.Offsetvalue,
+		arg.Limitvalue,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSectionEnrollmentHistoryRow
+	for rows.Next() {
+		var i GetSectionEnrollmentHistoryRow
+		if err := rows.Scan(
+			&i.TermCollectionHistoryID,
+			&i.CollectedAt,
+			&i.Enrollment,
+			&i.MaxEnrollment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSectionMeetingTimesForCalendar = `-- name: GetSectionMeetingTimesForCalendar :many
+SELECT mt."sequence", mt.section_sequence, mt.subject_code, mt.course_number,
+       mt.start_date, mt.end_date, mt.meeting_type, mt.start_minutes, mt.end_minutes,
+       mt.is_monday, mt.is_tuesday, mt.is_wednesday, mt.is_thursday, mt.is_friday,
+       mt.is_saturday, mt.is_sunday,
+       c.title, s.campus, p.name AS professor_name
+FROM UNNEST($1::TEXT[], $2::TEXT[], $3::TEXT[])
+    AS requested(subject_code, course_number, "sequence")
+JOIN sections s ON s.school_id = $4
+                AND s.term_collection_id = $5
+                AND s.subject_code = requested.subject_code
+                AND s.course_number = requested.course_number
+                AND s."sequence" = requested."sequence"
+JOIN meeting_times mt ON mt.school_id = s.school_id
+                      AND mt.term_collection_id = s.term_collection_id
+                      AND mt.subject_code = s.subject_code
+                      AND mt.course_number = s.course_number
+                      AND mt.section_sequence = s."sequence"
+JOIN courses c ON c.school_id = s.school_id
+               AND c.subject_code = s.subject_code
+               AND c.number = s.course_number
+LEFT JOIN professors p ON p.school_id = s.school_id
+                       AND p.id = s.primary_professor_id
+ORDER BY mt.subject_code, mt.course_number, mt.section_sequence, mt."sequence"
+`
+
+type GetSectionMeetingTimesForCalendarParams struct {
+	SubjectCodes     []string `json:"subject_codes"`
+	CourseNumbers    []string `json:"course_numbers"`
+	Sequences        []string `json:"sequences"`
+	SchoolID         string   `json:"school_id"`
+	TermCollectionID string   `json:"term_collection_id"`
+}
+
+type GetSectionMeetingTimesForCalendarRow struct {
+	Sequence        int32            `json:"sequence"`
+	SectionSequence string           `json:"section_sequence"`
+	SubjectCode     string           `json:"subject_code"`
+	CourseNumber    string           `json:"course_number"`
+	StartDate       pgtype.Timestamp `json:"start_date"`
+	EndDate         pgtype.Timestamp `json:"end_date"`
+	MeetingType     pgtype.Text      `json:"meeting_type"`
+	StartMinutes    pgtype.Time      `json:"start_minutes"`
+	EndMinutes      pgtype.Time      `json:"end_minutes"`
+	IsMonday        bool             `json:"is_monday"`
+	IsTuesday       bool             `json:"is_tuesday"`
+	IsWednesday     bool             `json:"is_wednesday"`
+	IsThursday      bool             `json:"is_thursday"`
+	IsFriday        bool             `json:"is_friday"`
+	IsSaturday      bool             `json:"is_saturday"`
+	IsSunday        bool             `json:"is_sunday"`
+	Title           pgtype.Text      `json:"title"`
+	Campus          pgtype.Text      `json:"campus"`
+	ProfessorName   pgtype.Text      `json:"professor_name"`
+}
+
+// sections are given as parallel arrays of their keys
+func (q *Queries) GetSectionMeetingTimesForCalendar(ctx context.Context, arg GetSectionMeetingTimesForCalendarParams) ([]GetSectionMeetingTimesForCalendarRow, error) {
+	rows, err := q.db.Query(ctx, getSectionMeetingTimesForCalendar,
+		arg.SubjectCodes,
+		arg.CourseNumbers,
+		arg.Sequences,
+		arg.SchoolID,
+		arg.TermCollectionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSectionMeetingTimesForCalendarRow
+	for rows.Next() {
+		var i GetSectionMeetingTimesForCalendarRow
+		if err := rows.Scan(
+			&i.Sequence,
+			&i.SectionSequence,
+			&i.SubjectCode,
+			&i.CourseNumber,
+			&i.StartDate,
+			&i.EndDate,
+			&i.MeetingType,
+			&i.StartMinutes,
+			&i.EndMinutes,
+			&i.IsMonday,
+			&i.IsTuesday,
+			&i.IsWednesday,
+			&i.IsThursday,
+			&i.IsFriday,
+			&i.IsSaturday,
+			&i.IsSunday,
+			&i.Title,
+			&i.Campus,
+			&i.ProfessorName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSectionsForCourses = `-- name: GetSectionsForCourses :many
+SELECT sections.sequence, sections.term_collection_id, sections.subject_code, sections.course_number, sections.school_id, sections.max_enrollment, sections.instruction_method, sections.campus, sections.enrollment, sections.primary_professor_id, sections.other, section_meetings.meeting_times
+FROM UNNEST($1::TEXT[], $2::TEXT[])
+    AS requested(subject_code, course_number)
+JOIN sections ON sections.school_id = $3
+              AND sections.term_collection_id = $4
+              AND sections.subject_code = requested.subject_code
+              AND sections.course_number = requested.course_number
+LEFT JOIN section_meetings ON sections."sequence"           = section_meetings."sequence"
+                           AND sections.term_collection_id  = section_meetings.term_collection_id
+                           AND sections.subject_code        = section_meetings.subject_code
+                           AND sections.course_number       = section_meetings.course_number
+                           AND sections.school_id           = section_meetings.school_id
+ORDER BY sections.subject_code, sections.course_number, sections."sequence"
+`
+
+type GetSectionsForCoursesParams struct {
+	SubjectCodes     []string `json:"subject_codes"`
+	CourseNumbers    []string `json:"course_numbers"`
+	SchoolID         string   `json:"school_id"`
+	TermCollectionID string   `json:"term_collection_id"`
+}
+
+type GetSectionsForCoursesRow struct {
+	Section      Section              `json:"section"`
+	MeetingTimes []PartialMeetingTime `json:"meeting_times"`
+}
+
+// courses are given as parallel arrays of their keys
+func (q *Queries) GetSectionsForCourses(ctx context.Context, arg GetSectionsForCoursesParams) ([]GetSectionsForCoursesRow, error) {
+	rows, err := q.db.Query(ctx, getSectionsForCourses,
+		arg.SubjectCodes,
+		arg.CourseNumbers,
+		arg.SchoolID,
+		arg.TermCollectionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSectionsForCoursesRow
+	for rows.Next() {
+		var i GetSectionsForCoursesRow
+		if err := rows.Scan(
+			&i.Section.Sequence,
+			&i.Section.TermCollectionID,
+			&i.Section.SubjectCode,
+			&i.Section.CourseNumber,
+			&i.Section.SchoolID,
+			&i.Section.MaxEnrollment,
+			&i.Section.InstructionMethod,
+			&i.Section.Campus,
+			&i.Section.Enrollment,
+			&i.Section.PrimaryProfessorID,
+			&i.Section.Other,
+			&i.MeetingTimes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTermCollectionsForSchool = `-- name: GetTermCollectionsForSchool :many
 SELECT term_collections.id, term_collections.school_id, term_collections.year, term_collections.season, term_collections.name, term_collections.still_collecting
 FROM term_collections 
@@ -438,6 +826,195 @@ func (q *Queries) SchoolExists(ctx context.Context, schoolID string) (bool, erro
 	return column_1, err
 }
 
+const searchCourseFacets = `-- name: SearchCourseFacets :many
+WITH matched_courses AS (
+    SELECT c.school_id, c.subject_code, c.number, c.subject_description, c.title, c.description, c.credit_hours, c.prerequisites, c.corequisites, c.other
+    FROM search_courses($1, $2) sc
+    JOIN courses c ON c.school_id = $1
+                   AND c.subject_code = sc.subject_code
+                   AND c.number = sc.number
+    WHERE ($3::TEXT IS NULL OR c.subject_code = $3)
+          AND ($4::REAL IS NULL OR c.credit_hours = $4)
+          AND ($5::TEXT IS NULL OR EXISTS (
+              SELECT 1 FROM sections s
+              WHERE s.school_id = c.school_id
+                    AND s.subject_code = c.subject_code
+                    AND s.course_number = c.number
+                    AND s.campus = $5
+          ))
+          AND ($6::TEXT IS NULL OR EXISTS (
+              SELECT 1 FROM sections s
+              WHERE s.school_id = c.school_id
+                    AND s.subject_code = c.subject_code
+                    AND s.course_number = c.number
+                    AND s.instruction_method = $6
+          ))
+),
+matched_sections AS (
+    SELECT s.sequence, s.term_collection_id, s.subject_code, s.course_number, s.school_id, s.max_enrollment, s.instruction_method, s.campus, s.enrollment, s.primary_professor_id, s.other
+    FROM matched_courses mc
+    JOIN sections s ON s.school_id = mc.school_id
+                    AND s.subject_code = mc.subject_code
+                    AND s.course_number = mc.number
+)
+SELECT 'subject'::TEXT AS facet, mc.subject_code::TEXT AS value, COUNT(*) AS count
+FROM matched_courses mc
+GROUP BY mc.subject_code
+UNION ALL
+SELECT 'credit_hours'::TEXT AS facet, mc.credit_hours::TEXT AS value, COUNT(*) AS count
+FROM matched_courses mc
+GROUP BY mc.credit_hours
+UNION ALL
+SELECT 'campus'::TEXT AS facet, ms.campus::TEXT AS value,
+    COUNT(DISTINCT (ms.subject_code, ms.course_number)) AS count
+FROM matched_sections ms
+WHERE ms.campus IS NOT NULL
+GROUP BY ms.campus
+UNION ALL
+SELECT 'instruction_method'::TEXT AS facet, ms.instruction_method::TEXT AS value,
+    COUNT(DISTINCT (ms.subject_code, ms.course_number)) AS count
+FROM matched_sections ms
+WHERE ms.instruction_method IS NOT NULL
+GROUP BY ms.instruction_method
+ORDER BY facet, count DESC, value
+`
+
+type SearchCourseFacetsParams struct {
+	SchoolID          string        `json:"school_id"`
+	SearchText        string        `json:"search_text"`
+	SubjectCode       pgtype.Text   `json:"subject_code"`
+	CreditHours       pgtype.Float4 `json:"credit_hours"`
+	Campus            pgtype.Text   `json:"campus"`
+	InstructionMethod pgtype.Text   `json:"instruction_method"`
+}
+
+type SearchCourseFacetsRow struct {
+	Facet string `json:"facet"`
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// facet counts are the number of matching courses for each value
+func (q *Queries) SearchCourseFacets(ctx context.Context, arg SearchCourseFacetsParams) ([]SearchCourseFacetsRow, error) {
+	rows, err := q.db.Query(ctx, searchCourseFacets,
+		arg.SchoolID,
+		arg.SearchText,
+		arg.SubjectCode,
+		arg.CreditHours,
+		arg.Campus,
+		arg.InstructionMethod,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchCourseFacetsRow
+	for rows.Next() {
+		var i SearchCourseFacetsRow
+		if err := rows.Scan(&i.Facet, &i.Value, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchCourses = `-- name: SearchCourses :many
+SELECT c.school_id, c.subject_code, c.number, c.subject_description, c.title, c.description, c.credit_hours, c.prerequisites, c.corequisites, c.other, sc.rank
+FROM search_courses($1, $2) sc
+JOIN courses c ON c.school_id = $1
+               AND c.subject_code = sc.subject_code
+               AND c.number = sc.number
+WHERE ($3::TEXT IS NULL OR c.subject_code = $3)
+      AND ($4::REAL IS NULL OR c.credit_hours = $4)
+      AND ($5::TEXT IS NULL OR EXISTS (
+          SELECT 1 FROM sections s
+          WHERE s.school_id = c.school_id
+                AND s.subject_code = c.subject_code
+                AND s.course_number = c.number
+                AND s.campus = $5
+      ))
+      AND ($6::TEXT IS NULL OR EXISTS (
+          SELECT 1 FROM sections s
+          WHERE s.school_id = c.school_id
+                AND s.subject_code = c.subject_code
+                AND s.course_number = c.number
+                AND s.instruction_method = $6
+      ))
+ORDER BY sc.rank DESC, c.subject_code, c.number
+LIMIT $8+ 1
+OFFSET $7
+`
+
+type SearchCoursesParams struct {
+	SchoolID          string        `json:"school_id"`
+	SearchText        string        `json:"search_text"`
+	SubjectCode       pgtype.Text   `json:"subject_code"`
+	CreditHours       pgtype.Float4 `json:"credit_hours"`
+	Campus            pgtype.Text   `json:"campus"`
+	InstructionMethod pgtype.Text   `json:"instruction_method"`
+	Offsetvalue       int32         `json:"offsetvalue"`
+	Limitvalue        int32         `json:"+limitvalue"`
+}
+
+type SearchCoursesRow struct {
+	SchoolID           string      `json:"school_id"`
+	SubjectCode        string      `json:"subject_code"`
+	Number             string      `json:"number"`
+	SubjectDescription pgtype.Text `json:"subject_description"`
+	Title              pgtype.Text `json:"title"`
+	Description        pgtype.Text `json:"description"`
+	CreditHours        float32     `json:"credit_hours"`
+	Prerequisites      pgtype.Text `json:"prerequisites"`
+	Corequisites       pgtype.Text `json:"corequisites"`
+	Other              []byte      `json:"other"`
+	Rank               float32     `json:"rank"`
+}
+
+func (q *Queries) SearchCourses(ctx context.Context, arg SearchCoursesParams) ([]SearchCoursesRow, error) {
+	rows, err := q.db.Query(ctx, searchCourses,
+		arg.SchoolID,
+		arg.SearchText,
+		arg.SubjectCode,
+		arg.CreditHours,
+		arg.Campus,
+		arg.InstructionMethod,
+		arg.Offsetvalue,
+		arg.Limitvalue,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchCoursesRow
+	for rows.Next() {
+		var i SearchCoursesRow
+		if err := rows.Scan(
+			&i.SchoolID,
+			&i.SubjectCode,
+			&i.Number,
+			&i.SubjectDescription,
+			&i.Title,
+			&i.Description,
+			&i.CreditHours,
+			&i.Prerequisites,
+			&i.Corequisites,
+			&i.Other,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const termCollectionExists = `-- name: TermCollectionExists :one
 SELECT CASE 
         WHEN EXISTS (
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+)
+
+// advisory locks belong to the session so these must be ran on a connection which is held
+// https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-ADVISORY-LOCKS
+const tryAdvisoryLock = `SELECT pg_try_advisory_lock($1::bigint)`
+
+const advisoryUnlock = `SELECT pg_advisory_unlock($1::bigint)`
+
+// false when another session holds the lock
+func (q *Queries) TryAdvisoryLock(ctx context.Context, key int64) (bool, error) {
+	row := q.db.QueryRow(ctx, tryAdvisoryLock, key)
+	var acquired bool
+	err := row.Scan(&acquired)
+	return acquired, err
+}
+
+// false when this session did not hold the lock
+func (q *Queries) AdvisoryUnlock(ctx context.Context, key int64) (bool, error) {
+	row := q.db.QueryRow(ctx, advisoryUnlock, key)
+	var released bool
+	err := row.Scan(&released)
+	return released, err
+}
@@ -7,17 +7,334 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const getPreviousCollections = `-- name: GetPreviousCollections :exec
-SELECT id, name FROM schools
+const claimCollectionSchedule = `-- name: ClaimCollectionSchedule :execrows
+UPDATE collection_schedules
+SET last_planned_at = $1
+WHERE id = $2
+      AND last_planned_at IS NOT DISTINCT FROM $3
+`
+
+type ClaimCollectionScheduleParams struct {
+	PlannedAt         pgtype.Timestamptz `json:"planned_at"`
+	ID                int32              `json:"id"`
+	PreviousPlannedAt pgtype.Timestamptz `json:"previous_planned_at"`
+}
+
+// only one planner gets to plan a schedule's run
+func (q *Queries) ClaimCollectionSchedule(ctx context.Context, arg ClaimCollectionScheduleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, claimCollectionSchedule, arg.PlannedAt, arg.ID, arg.PreviousPlannedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const decideQuarantinedCollection = `-- name: DecideQuarantinedCollection :execrows
+UPDATE quarantined_collections SET
+    status = $1,
+    decided_at = now()
+WHERE term_collection_history_id = $2
+      AND status = 'Pending'
 `
 
-func (q *Queries) GetPreviousCollections(ctx context.Context) error {
-	_, err := q.db.Exec(ctx, getPreviousCollections)
+type DecideQuarantinedCollectionParams struct {
+	Status                  QuarantineStatusEnum `json:"status"`
+	TermCollectionHistoryID int32                `json:"term_collection_history_id"`
+}
+
+func (q *Queries) DecideQuarantinedCollection(ctx context.Context, arg DecideQuarantinedCollectionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, decideQuarantinedCollection, arg.Status, arg.TermCollectionHistoryID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteCollectionSchedule = `-- name: DeleteCollectionSchedule :execrows
+DELETE FROM collection_schedules
+WHERE id = $1
+`
+
+func (q *Queries) DeleteCollectionSchedule(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteCollectionSchedule, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSchoolRegistryEntry = `-- name: DeleteSchoolRegistryEntry :execrows
+DELETE FROM school_registry
+WHERE school_id = $1 AND service_name = $2
+`
+
+type DeleteSchoolRegistryEntryParams struct {
+	SchoolID    string `json:"school_id"`
+	ServiceName string `json:"service_name"`
+}
+
+func (q *Queries) DeleteSchoolRegistryEntry(ctx context.Context, arg DeleteSchoolRegistryEntryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSchoolRegistryEntry, arg.SchoolID, arg.ServiceName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteWorkerPoolMetrics = `-- name: DeleteWorkerPoolMetrics :exec
+DELETE FROM worker_pool_metrics
+WHERE worker_pool_id = $1
+`
+
+func (q *Queries) DeleteWorkerPoolMetrics(ctx context.Context, workerPoolID string) error {
+	_, err := q.db.Exec(ctx, deleteWorkerPoolMetrics, workerPoolID)
 	return err
 }
 
+const getCollectionChanges = `-- name: GetCollectionChanges :many
+SELECT sequence, school_id, table_name, composite_hash, input_at, pk_fields, sync_action, relevant_fields, term_collection_history_id FROM historic_class_information
+WHERE term_collection_history_id = $1::INT
+ORDER BY sequence
+LIMIT $3+ 1
+OFFSET $2
+`
+
+type GetCollectionChangesParams struct {
+	TermCollectionHistoryID int32 `json:"term_collection_history_id"`
+	Offsetvalue             int32 `json:"offsetvalue"`
+	Limitvalue              int32 `json:"+limitvalue"`
+}
+
+// what the collection changed in the order the triggers logged it
+func (q *Queries) GetCollectionChanges(ctx context.Context, arg GetCollectionChangesParams) ([]HistoricClassInformation, error) {
+	rows, err := q.db.Query(ctx, getCollectionChanges, arg.TermCollectionHistoryID, arg.Offsetvalue, arg.Limitvalue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []HistoricClassInformation
+	for rows.Next() {
+		var i HistoricClassInformation
+		if err := rows.Scan(
+			&i.Sequence,
+			&i.SchoolID,
+			&i.TableName,
+			&i.CompositeHash,
+			&i.InputAt,
+			&i.PkFields,
+			&i.SyncAction,
+			&i.RelevantFields,
+			&i.TermCollectionHistoryID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCollectionLog = `-- name: GetCollectionLog :one
+SELECT term_collection_history_id, school_id, term_collection_id, compressed_logs, uncompressed_bytes, lines, truncated, created_at FROM collection_logs
+WHERE term_collection_history_id = $1
+`
+
+func (q *Queries) GetCollectionLog(ctx context.Context, termCollectionHistoryID int32) (CollectionLog, error) {
+	row := q.db.QueryRow(ctx, getCollectionLog, termCollectionHistoryID)
+	var i CollectionLog
+	err := row.Scan(
+		&i.TermCollectionHistoryID,
+		&i.SchoolID,
+		&i.TermCollectionID,
+		&i.CompressedLogs,
+		&i.UncompressedBytes,
+		&i.Lines,
+		&i.Truncated,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getEnabledCollectionSchedules = `-- name: GetEnabledCollectionSchedules :many
+SELECT id, school_id, term_collection_id, cron, timezone, is_full_collection, service_name, enabled, last_planned_at, created_at FROM collection_schedules
+WHERE enabled
+ORDER BY id
+`
+
+func (q *Queries) GetEnabledCollectionSchedules(ctx context.Context) ([]CollectionSchedule, error) {
+	rows, err := q.db.Query(ctx, getEnabledCollectionSchedules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CollectionSchedule
+	for rows.Next() {
+		var i CollectionSchedule
+		if err := rows.Scan(
+			&i.ID,
+			&i.SchoolID,
+			&i.TermCollectionID,
+			&i.Cron,
+			&i.Timezone,
+			&i.IsFullCollection,
+			&i.ServiceName,
+			&i.Enabled,
+			&i.LastPlannedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPreviousCollections = `-- name: GetPreviousCollections :many
+SELECT id, status, term_collection_id, school_id, start_time, is_full, end_time, deleted_records_count, updated_records_count, inserted_records_count, service_name, is_incorrect_assumption, failure_reason, heartbeat_at FROM term_collection_history
+WHERE ($1::TEXT IS NULL OR school_id = $1)
+      AND ($2::TEXT IS NULL OR term_collection_id = $2)
+ORDER BY id DESC
+LIMIT $4+ 1
+OFFSET $3
+`
+
+type GetPreviousCollectionsParams struct {
+	SchoolID         pgtype.Text `json:"school_id"`
+	TermCollectionID pgtype.Text `json:"term_collection_id"`
+	Offsetvalue      int32       `json:"offsetvalue"`
+	Limitvalue       int32       `json:"+limitvalue"`
+}
+
+// null filters are ignored
+func (q *Queries) GetPreviousCollections(ctx context.Context, arg GetPreviousCollectionsParams) ([]TermCollectionHistory, error) {
+	rows, err := q.db.Query(ctx, getPreviousCollections,
+		arg.SchoolID,
+		arg.TermCollectionID,
+		arg.Offsetvalue,
+		arg.Limitvalue,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TermCollectionHistory
+	for rows.Next() {
+		var i TermCollectionHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.TermCollectionID,
+			&i.SchoolID,
+			&i.StartTime,
+			&i.IsFull,
+			&i.EndTime,
+			&i.DeletedRecordsCount,
+			&i.UpdatedRecordsCount,
+			&i.InsertedRecordsCount,
+			&i.ServiceName,
+			&i.IsIncorrectAssumption,
+			&i.FailureReason,
+			&i.HeartbeatAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getQuarantinedCollectionForUpdate = `-- name: GetQuarantinedCollectionForUpdate :one
+SELECT term_collection_history_id, term_collection_id, school_id, live_sections, deleted_sections, status, created_at, decided_at FROM quarantined_collections
+WHERE term_collection_history_id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetQuarantinedCollectionForUpdate(ctx context.Context, termCollectionHistoryID int32) (QuarantinedCollection, error) {
+	row := q.db.QueryRow(ctx, getQuarantinedCollectionForUpdate, termCollectionHistoryID)
+	var i QuarantinedCollection
+	err := row.Scan(
+		&i.TermCollectionHistoryID,
+		&i.TermCollectionID,
+		&i.SchoolID,
+		&i.LiveSections,
+		&i.DeletedSections,
+		&i.Status,
+		&i.CreatedAt,
+		&i.DecidedAt,
+	)
+	return i, err
+}
+
+const getSchoolRegistryEntry = `-- name: GetSchoolRegistryEntry :one
+SELECT school_id, school_name, service_name, config, priority, enabled, updated_at FROM school_registry
+WHERE school_id = $1 AND service_name = $2
+`
+
+type GetSchoolRegistryEntryParams struct {
+	SchoolID    string `json:"school_id"`
+	ServiceName string `json:"service_name"`
+}
+
+func (q *Queries) GetSchoolRegistryEntry(ctx context.Context, arg GetSchoolRegistryEntryParams) (SchoolRegistry, error) {
+	row := q.db.QueryRow(ctx, getSchoolRegistryEntry, arg.SchoolID, arg.ServiceName)
+	var i SchoolRegistry
+	err := row.Scan(
+		&i.SchoolID,
+		&i.SchoolName,
+		&i.ServiceName,
+		&i.Config,
+		&i.Priority,
+		&i.Enabled,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getStillCollectingTermCollections = `-- name: GetStillCollectingTermCollections :many
+SELECT id, school_id, year, season, name, still_collecting FROM term_collections
+WHERE school_id = $1 AND still_collecting
+ORDER BY year, season
+`
+
+func (q *Queries) GetStillCollectingTermCollections(ctx context.Context, schoolID string) ([]TermCollection, error) {
+	rows, err := q.db.Query(ctx, getStillCollectingTermCollections, schoolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TermCollection
+	for rows.Next() {
+		var i TermCollection
+		if err := rows.Scan(
+			&i.ID,
+			&i.SchoolID,
+			&i.Year,
+			&i.Season,
+			&i.Name,
+			&i.StillCollecting,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTermCollection = `-- name: GetTermCollection :one
 SELECT id, school_id, year, season, name, still_collecting FROM  term_collections
 WHERE term_collections.id = $1
@@ -42,3 +359,460 @@ func (q *Queries) GetTermCollection(ctx context.Context, arg GetTermCollectionPa
 	)
 	return i, err
 }
+
+const getTermCollectionHistory = `-- name: GetTermCollectionHistory :one
+SELECT id, status, term_collection_id, school_id, start_time, is_full, end_time, deleted_records_count, updated_records_count, inserted_records_count, service_name, is_incorrect_assumption, failure_reason, heartbeat_at FROM term_collection_history
+WHERE id = $1
+`
+
+func (q *Queries) GetTermCollectionHistory(ctx context.Context, termCollectionHistoryID int32) (TermCollectionHistory, error) {
+	row := q.db.QueryRow(ctx, getTermCollectionHistory, termCollectionHistoryID)
+	var i TermCollectionHistory
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.TermCollectionID,
+		&i.SchoolID,
+		&i.StartTime,
+		&i.IsFull,
+		&i.EndTime,
+		&i.DeletedRecordsCount,
+		&i.UpdatedRecordsCount,
+		&i.InsertedRecordsCount,
+		&i.ServiceName,
+		&i.IsIncorrectAssumption,
+		&i.FailureReason,
+		&i.HeartbeatAt,
+	)
+	return i, err
+}
+
+const insertCollectionSchedule = `-- name: InsertCollectionSchedule :one
+INSERT INTO collection_schedules
+    (school_id, term_collection_id, cron, timezone, is_full_collection, service_name, enabled)
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, school_id, term_collection_id, cron, timezone, is_full_collection, service_name, enabled, last_planned_at, created_at
+`
+
+type InsertCollectionScheduleParams struct {
+	SchoolID         string      `json:"school_id"`
+	TermCollectionID pgtype.Text `json:"term_collection_id"`
+	Cron             string      `json:"cron"`
+	Timezone         string      `json:"timezone"`
+	IsFullCollection bool        `json:"is_full_collection"`
+	ServiceName      pgtype.Text `json:"service_name"`
+	Enabled          bool        `json:"enabled"`
+}
+
+func (q *Queries) InsertCollectionSchedule(ctx context.Context, arg InsertCollectionScheduleParams) (CollectionSchedule, error) {
+	row := q.db.QueryRow(ctx, insertCollectionSchedule,
+		arg.SchoolID,
+		arg.TermCollectionID,
+		arg.Cron,
+		arg.Timezone,
+		arg.IsFullCollection,
+		arg.ServiceName,
+		arg.Enabled,
+	)
+	var i CollectionSchedule
+	err := row.Scan(
+		&i.ID,
+		&i.SchoolID,
+		&i.TermCollectionID,
+		&i.Cron,
+		&i.Timezone,
+		&i.IsFullCollection,
+		&i.ServiceName,
+		&i.Enabled,
+		&i.LastPlannedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listCollectionSchedules = `-- name: ListCollectionSchedules :many
+SELECT id, school_id, term_collection_id, cron, timezone, is_full_collection, service_name, enabled, last_planned_at, created_at FROM collection_schedules
+ORDER BY school_id, id
+`
+
+func (q *Queries) ListCollectionSchedules(ctx context.Context) ([]CollectionSchedule, error) {
+	rows, err := q.db.Query(ctx, listCollectionSchedules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CollectionSchedule
+	for rows.Next() {
+		var i CollectionSchedule
+		if err := rows.Scan(
+			&i.ID,
+			&i.SchoolID,
+			&i.TermCollectionID,
+			&i.Cron,
+			&i.Timezone,
+			&i.IsFullCollection,
+			&i.ServiceName,
+			&i.Enabled,
+			&i.LastPlannedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCollectionViolations = `-- name: ListCollectionViolations :many
+SELECT id, term_collection_history_id, school_id, rule, severity, table_name, record_key, message, created_at FROM collection_violations
+WHERE term_collection_history_id = $1
+ORDER BY severity DESC, rule, id
+LIMIT $2
+`
+
+type ListCollectionViolationsParams struct {
+	TermCollectionHistoryID int32 `json:"term_collection_history_id"`
+	LimitCount              int32 `json:"limit_count"`
+}
+
+func (q *Queries) ListCollectionViolations(ctx context.Context, arg ListCollectionViolationsParams) ([]CollectionViolation, error) {
+	rows, err := q.db.Query(ctx, listCollectionViolations, arg.TermCollectionHistoryID, arg.LimitCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CollectionViolation
+	for rows.Next() {
+		var i CollectionViolation
+		if err := rows.Scan(
+			&i.ID,
+			&i.TermCollectionHistoryID,
+			&i.SchoolID,
+			&i.Rule,
+			&i.Severity,
+			&i.TableName,
+			&i.RecordKey,
+			&i.Message,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingQuarantinedCollections = `-- name: ListPendingQuarantinedCollections :many
+SELECT term_collection_history_id, term_collection_id, school_id, live_sections, deleted_sections, status, created_at, decided_at FROM quarantined_collections
+WHERE status = 'Pending'
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListPendingQuarantinedCollections(ctx context.Context) ([]QuarantinedCollection, error) {
+	rows, err := q.db.Query(ctx, listPendingQuarantinedCollections)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QuarantinedCollection
+	for rows.Next() {
+		var i QuarantinedCollection
+		if err := rows.Scan(
+			&i.TermCollectionHistoryID,
+			&i.TermCollectionID,
+			&i.SchoolID,
+			&i.LiveSections,
+			&i.DeletedSections,
+			&i.Status,
+			&i.CreatedAt,
+			&i.DecidedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentCollectionViolations = `-- name: ListRecentCollectionViolations :many
+WITH recent AS (
+    -- each collection has at least one row so only that many of the newest collections are grouped
+    SELECT DISTINCT term_collection_history_id
+    FROM collection_violations
+    ORDER BY term_collection_history_id DESC
+    LIMIT $1
+)
+SELECT
+    cv.term_collection_history_id,
+    cv.school_id,
+    tch.term_collection_id,
+    cv.rule,
+    cv.severity,
+    COUNT(*) AS violations,
+    (array_agg(format('%s %s', cv.record_key, cv.message) ORDER BY cv.id))[1]::TEXT AS example
+FROM recent r
+JOIN collection_violations cv ON cv.term_collection_history_id = r.term_collection_history_id
+JOIN term_collection_history tch ON tch.id = cv.term_collection_history_id
+GROUP BY cv.term_collection_history_id, cv.school_id, tch.term_collection_id, cv.rule, cv.severity
+ORDER BY cv.term_collection_history_id DESC, cv.severity DESC, cv.rule
+LIMIT $1
+`
+
+type ListRecentCollectionViolationsRow struct {
+	TermCollectionHistoryID int32                 `json:"term_collection_history_id"`
+	SchoolID                string                `json:"school_id"`
+	TermCollectionID        string                `json:"term_collection_id"`
+	Rule                    string                `json:"rule"`
+	Severity                ViolationSeverityEnum `json:"severity"`
+	Violations              int64                 `json:"violations"`
+	Example                 string                `json:"example"`
+}
+
+// the rules the most recent collections broke with one of the records that broke them
+func (q *Queries) ListRecentCollectionViolations(ctx context.Context, limitCount int32) ([]ListRecentCollectionViolationsRow, error) {
+	rows, err := q.db.Query(ctx, listRecentCollectionViolations, limitCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRecentCollectionViolationsRow
+	for rows.Next() {
+		var i ListRecentCollectionViolationsRow
+		if err := rows.Scan(
+			&i.TermCollectionHistoryID,
+			&i.SchoolID,
+			&i.TermCollectionID,
+			&i.Rule,
+			&i.Severity,
+			&i.Violations,
+			&i.Example,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentWorkerPoolMetrics = `-- name: ListRecentWorkerPoolMetrics :many
+SELECT worker_pool_id, hostname, workers, busy_workers, utilization, completed_jobs, failed_jobs, deferred_jobs, started_at, updated_at FROM worker_pool_metrics
+WHERE updated_at > CURRENT_TIMESTAMP - make_interval(secs => $1::int)
+ORDER BY started_at
+`
+
+func (q *Queries) ListRecentWorkerPoolMetrics(ctx context.Context, maxAgeSeconds int32) ([]WorkerPoolMetric, error) {
+	rows, err := q.db.Query(ctx, listRecentWorkerPoolMetrics, maxAgeSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WorkerPoolMetric
+	for rows.Next() {
+		var i WorkerPoolMetric
+		if err := rows.Scan(
+			&i.WorkerPoolID,
+			&i.Hostname,
+			&i.Workers,
+			&i.BusyWorkers,
+			&i.Utilization,
+			&i.CompletedJobs,
+			&i.FailedJobs,
+			&i.DeferredJobs,
+			&i.StartedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSchoolRegistry = `-- name: ListSchoolRegistry :many
+SELECT school_id, school_name, service_name, config, priority, enabled, updated_at FROM school_registry
+ORDER BY school_id, priority, service_name
+`
+
+func (q *Queries) ListSchoolRegistry(ctx context.Context) ([]SchoolRegistry, error) {
+	rows, err := q.db.Query(ctx, listSchoolRegistry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SchoolRegistry
+	for rows.Next() {
+		var i SchoolRegistry
+		if err := rows.Scan(
+			&i.SchoolID,
+			&i.SchoolName,
+			&i.ServiceName,
+			&i.Config,
+			&i.Priority,
+			&i.Enabled,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchCollectionLogs = `-- name: SearchCollectionLogs :many
+SELECT term_collection_history_id, school_id, term_collection_id, compressed_logs, uncompressed_bytes, lines, truncated, created_at FROM collection_logs
+WHERE ($1::TEXT IS NULL OR school_id = $1)
+      AND ($2::TEXT IS NULL OR term_collection_id = $2)
+ORDER BY term_collection_history_id DESC
+LIMIT $3
+`
+
+type SearchCollectionLogsParams struct {
+	SchoolID         pgtype.Text `json:"school_id"`
+	TermCollectionID pgtype.Text `json:"term_collection_id"`
+	LimitCount       int32       `json:"limit_count"`
+}
+
+// the most recent logs to search through null filters are ignored
+func (q *Queries) SearchCollectionLogs(ctx context.Context, arg SearchCollectionLogsParams) ([]CollectionLog, error) {
+	rows, err := q.db.Query(ctx, searchCollectionLogs, arg.SchoolID, arg.TermCollectionID, arg.LimitCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CollectionLog
+	for rows.Next() {
+		var i CollectionLog
+		if err := rows.Scan(
+			&i.TermCollectionHistoryID,
+			&i.SchoolID,
+			&i.TermCollectionID,
+			&i.CompressedLogs,
+			&i.UncompressedBytes,
+			&i.Lines,
+			&i.Truncated,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setCollectionScheduleEnabled = `-- name: SetCollectionScheduleEnabled :execrows
+UPDATE collection_schedules
+SET enabled = $1
+WHERE id = $2
+`
+
+type SetCollectionScheduleEnabledParams struct {
+	Enabled bool  `json:"enabled"`
+	ID      int32 `json:"id"`
+}
+
+func (q *Queries) SetCollectionScheduleEnabled(ctx context.Context, arg SetCollectionScheduleEnabledParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setCollectionScheduleEnabled, arg.Enabled, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertSchoolRegistryEntry = `-- name: UpsertSchoolRegistryEntry :exec
+INSERT INTO school_registry
+    (school_id, school_name, service_name, config, priority, enabled)
+VALUES
+    ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (school_id, service_name) DO UPDATE
+SET school_name = EXCLUDED.school_name,
+    config = EXCLUDED.config,
+    priority = EXCLUDED.priority,
+    enabled = EXCLUDED.enabled,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertSchoolRegistryEntryParams struct {
+	SchoolID    string `json:"school_id"`
+	SchoolName  string `json:"school_name"`
+	ServiceName string `json:"service_name"`
+	Config      []byte `json:"config"`
+	Priority    int32  `json:"priority"`
+	Enabled     bool   `json:"enabled"`
+}
+
+func (q *Queries) UpsertSchoolRegistryEntry(ctx context.Context, arg UpsertSchoolRegistryEntryParams) error {
+	_, err := q.db.Exec(ctx, upsertSchoolRegistryEntry,
+		arg.SchoolID,
+		arg.SchoolName,
+		arg.ServiceName,
+		arg.Config,
+		arg.Priority,
+		arg.Enabled,
+	)
+	return err
+}
+
+const upsertWorkerPoolMetrics = `-- name: UpsertWorkerPoolMetrics :exec
+INSERT INTO worker_pool_metrics
+    (worker_pool_id, hostname, workers, busy_workers, utilization,
+     completed_jobs, failed_jobs, deferred_jobs, started_at)
+VALUES
+    ($1, $2, $3, $4, $5,
+     $6, $7, $8, $9)
+ON CONFLICT (worker_pool_id) DO UPDATE
+SET busy_workers = EXCLUDED.busy_workers,
+    utilization = EXCLUDED.utilization,
+    completed_jobs = EXCLUDED.completed_jobs,
+    failed_jobs = EXCLUDED.failed_jobs,
+    deferred_jobs = EXCLUDED.deferred_jobs,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertWorkerPoolMetricsParams struct {
+	WorkerPoolID  string             `json:"worker_pool_id"`
+	Hostname      string             `json:"hostname"`
+	Workers       int32              `json:"workers"`
+	BusyWorkers   int32              `json:"busy_workers"`
+	Utilization   float32            `json:"utilization"`
+	CompletedJobs int64              `json:"completed_jobs"`
+	FailedJobs    int64              `json:"failed_jobs"`
+	DeferredJobs  int64              `json:"deferred_jobs"`
+	StartedAt     pgtype.Timestamptz `json:"started_at"`
+}
+
+func (q *Queries) UpsertWorkerPoolMetrics(ctx context.Context, arg UpsertWorkerPoolMetricsParams) error {
+	_, err := q.db.Exec(ctx, upsertWorkerPoolMetrics,
+		arg.WorkerPoolID,
+		arg.Hostname,
+		arg.Workers,
+		arg.BusyWorkers,
+		arg.Utilization,
+		arg.CompletedJobs,
+		arg.FailedJobs,
+		arg.DeferredJobs,
+		arg.StartedAt,
+	)
+	return err
+}
@@ -11,6 +11,50 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type QuarantineStatusEnum string
+
+const (
+	QuarantineStatusEnumPending    QuarantineStatusEnum = "Pending"
+	QuarantineStatusEnumApproved   QuarantineStatusEnum = "Approved"
+	QuarantineStatusEnumRejected   QuarantineStatusEnum = "Rejected"
+	QuarantineStatusEnumSuperseded QuarantineStatusEnum = "Superseded"
+)
+
+func (e *QuarantineStatusEnum) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = QuarantineStatusEnum(s)
+	case string:
+		*e = QuarantineStatusEnum(s)
+	default:
+		return fmt.Errorf("unsupported scan type for QuarantineStatusEnum: %T", src)
+	}
+	return nil
+}
+
+type NullQuarantineStatusEnum struct {
+	QuarantineStatusEnum QuarantineStatusEnum `json:"quarantine_status_enum"`
+	Valid                bool                 `json:"valid"` // Valid is true if QuarantineStatusEnum is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullQuarantineStatusEnum) Scan(value interface{}) error {
+	if value == nil {
+		ns.QuarantineStatusEnum, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.QuarantineStatusEnum.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullQuarantineStatusEnum) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.QuarantineStatusEnum), nil
+}
+
 type SeasonEnum string
 
 const (
@@ -101,9 +145,12 @@ func (ns NullSyncKind) Value() (driver.Value, error) {
 type TermCollectionStatusEnum string
 
 const (
-	TermCollectionStatusEnumActive  TermCollectionStatusEnum = "Active"
-	TermCollectionStatusEnumSuccess TermCollectionStatusEnum = "Success"
-	TermCollectionStatusEnumFailure TermCollectionStatusEnum = "Failure"
+	TermCollectionStatusEnumActive      TermCollectionStatusEnum = "Active"
+	TermCollectionStatusEnumSuccess     TermCollectionStatusEnum = "Success"
+	TermCollectionStatusEnumFailure     TermCollectionStatusEnum = "Failure"
+	TermCollectionStatusEnumCancelled   TermCollectionStatusEnum = "Cancelled"
+	TermCollectionStatusEnumDryRun      TermCollectionStatusEnum = "DryRun"
+	TermCollectionStatusEnumQuarantined TermCollectionStatusEnum = "Quarantined"
 )
 
 func (e *TermCollectionStatusEnum) Scan(src interface{}) error {
@@ -141,6 +188,93 @@ func (ns NullTermCollectionStatusEnum) Value() (driver.Value, error) {
 	return string(ns.TermCollectionStatusEnum), nil
 }
 
+type ViolationSeverityEnum string
+
+const (
+	ViolationSeverityEnumWarning  ViolationSeverityEnum = "Warning"
+	ViolationSeverityEnumBlocking ViolationSeverityEnum = "Blocking"
+)
+
+func (e *ViolationSeverityEnum) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ViolationSeverityEnum(s)
+	case string:
+		*e = ViolationSeverityEnum(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ViolationSeverityEnum: %T", src)
+	}
+	return nil
+}
+
+type NullViolationSeverityEnum struct {
+	ViolationSeverityEnum ViolationSeverityEnum `json:"violation_severity_enum"`
+	Valid                 bool                  `json:"valid"` // Valid is true if ViolationSeverityEnum is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullViolationSeverityEnum) Scan(value interface{}) error {
+	if value == nil {
+		ns.ViolationSeverityEnum, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ViolationSeverityEnum.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullViolationSeverityEnum) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ViolationSeverityEnum), nil
+}
+
+type CollectionLog struct {
+	TermCollectionHistoryID int32              `json:"term_collection_history_id"`
+	SchoolID                string             `json:"school_id"`
+	TermCollectionID        string             `json:"term_collection_id"`
+	CompressedLogs          []byte             `json:"compressed_logs"`
+	UncompressedBytes       int32              `json:"uncompressed_bytes"`
+	Lines                   int32              `json:"lines"`
+	Truncated               bool               `json:"truncated"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+}
+
+type CollectionSchedule struct {
+	ID               int32              `json:"id"`
+	SchoolID         string             `json:"school_id"`
+	TermCollectionID pgtype.Text        `json:"term_collection_id"`
+	Cron             string             `json:"cron"`
+	Timezone         string             `json:"timezone"`
+	IsFullCollection bool               `json:"is_full_collection"`
+	ServiceName      pgtype.Text        `json:"service_name"`
+	Enabled          bool               `json:"enabled"`
+	LastPlannedAt    pgtype.Timestamptz `json:"last_planned_at"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+}
+
+type CollectionViolation struct {
+	ID                      int32                 `json:"id"`
+	TermCollectionHistoryID int32                 `json:"term_collection_history_id"`
+	SchoolID                string                `json:"school_id"`
+	Rule                    string                `json:"rule"`
+	Severity                ViolationSeverityEnum `json:"severity"`
+	TableName               string                `json:"table_name"`
+	RecordKey               []byte                `json:"record_key"`
+	Message                 string                `json:"message"`
+	CreatedAt               pgtype.Timestamptz    `json:"created_at"`
+}
+
+type CollectionWebhook struct {
+	ID          int32              `json:"id"`
+	CallbackUrl string             `json:"callback_url"`
+	Secret      string             `json:"secret"`
+	SchoolID    pgtype.Text        `json:"school_id"`
+	Events      []string           `json:"events"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
 type Course struct {
 	SchoolID           string      `json:"school_id"`
 	SubjectCode        string      `json:"subject_code"`
@@ -219,11 +353,45 @@ type Professor struct {
 	Other        []byte      `json:"other"`
 }
 
+type QuarantinedCollection struct {
+	TermCollectionHistoryID int32                `json:"term_collection_history_id"`
+	TermCollectionID        string               `json:"term_collection_id"`
+	SchoolID                string               `json:"school_id"`
+	LiveSections            int32                `json:"live_sections"`
+	DeletedSections         int32                `json:"deleted_sections"`
+	Status                  QuarantineStatusEnum `json:"status"`
+	CreatedAt               pgtype.Timestamptz   `json:"created_at"`
+	DecidedAt               pgtype.Timestamptz   `json:"decided_at"`
+}
+
 type School struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 }
 
+type SchoolRegistry struct {
+	SchoolID    string             `json:"school_id"`
+	SchoolName  string             `json:"school_name"`
+	ServiceName string             `json:"service_name"`
+	Config      []byte             `json:"config"`
+	Priority    int32              `json:"priority"`
+	Enabled     bool               `json:"enabled"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type SeatSubscription struct {
+	ID               int32              `json:"id"`
+	SectionSequence  string             `json:"section_sequence"`
+	TermCollectionID string             `json:"term_collection_id"`
+	SubjectCode      string             `json:"subject_code"`
+	CourseNumber     string             `json:"course_number"`
+	SchoolID         string             `json:"school_id"`
+	CallbackUrl      string             `json:"callback_url"`
+	CallbackHost     string             `json:"callback_host"`
+	Secret           string             `json:"secret"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+}
+
 type Section struct {
 	Sequence           string      `json:"sequence"`
 	TermCollectionID   string      `json:"term_collection_id"`
@@ -238,6 +406,18 @@ type Section struct {
 	Other              []byte      `json:"other"`
 }
 
+type SectionEnrollmentSnapshot struct {
+	TermCollectionHistoryID int32       `json:"term_collection_history_id"`
+	SectionSequence         string      `json:"section_sequence"`
+	TermCollectionID        string      `json:"term_collection_id"`
+	SubjectCode             string      `json:"subject_code"`
+	CourseNumber            string      `json:"course_number"`
+	SchoolID                string      `json:"school_id"`
+	Enrollment              pgtype.Int4 `json:"enrollment"`
+	MaxEnrollment           pgtype.Int4 `json:"max_enrollment"`
+	IsRemoved               bool        `json:"is_removed"`
+}
+
 type SectionMeeting struct {
 	Sequence         string               `json:"sequence"`
 	TermCollectionID string               `json:"term_collection_id"`
@@ -325,14 +505,40 @@ type TermCollection struct {
 }
 
 type TermCollectionHistory struct {
-	ID                   int32                    `json:"id"`
-	Status               TermCollectionStatusEnum `json:"status"`
-	TermCollectionID     string                   `json:"term_collection_id"`
-	SchoolID             string                   `json:"school_id"`
-	StartTime            pgtype.Timestamptz       `json:"start_time"`
-	IsFull               bool                     `json:"is_full"`
-	EndTime              pgtype.Timestamptz       `json:"end_time"`
-	DeletedRecordsCount  int32                    `json:"deleted_records_count"`
-	UpdatedRecordsCount  int32                    `json:"updated_records_count"`
-	InsertedRecordsCount int32                    `json:"inserted_records_count"`
+	ID                    int32                    `json:"id"`
+	Status                TermCollectionStatusEnum `json:"status"`
+	TermCollectionID      string                   `json:"term_collection_id"`
+	SchoolID              string                   `json:"school_id"`
+	StartTime             pgtype.Timestamptz       `json:"start_time"`
+	IsFull                bool                     `json:"is_full"`
+	EndTime               pgtype.Timestamptz       `json:"end_time"`
+	DeletedRecordsCount   int32                    `json:"deleted_records_count"`
+	UpdatedRecordsCount   int32                    `json:"updated_records_count"`
+	InsertedRecordsCount  int32                    `json:"inserted_records_count"`
+	ServiceName           pgtype.Text              `json:"service_name"`
+	IsIncorrectAssumption bool                     `json:"is_incorrect_assumption"`
+	FailureReason         pgtype.Text              `json:"failure_reason"`
+	HeartbeatAt           pgtype.Timestamptz       `json:"heartbeat_at"`
+}
+
+type TermRegistrationCalendar struct {
+	TermCollectionID  string             `json:"term_collection_id"`
+	SchoolID          string             `json:"school_id"`
+	RegistrationStart pgtype.Timestamptz `json:"registration_start"`
+	ClassesStart      pgtype.Timestamptz `json:"classes_start"`
+	AddDropEnd        pgtype.Timestamptz `json:"add_drop_end"`
+	TermEnd           pgtype.Timestamptz `json:"term_end"`
+}
+
+type WorkerPoolMetric struct {
+	WorkerPoolID  string             `json:"worker_pool_id"`
+	Hostname      string             `json:"hostname"`
+	Workers       int32              `json:"workers"`
+	BusyWorkers   int32              `json:"busy_workers"`
+	Utilization   float32            `json:"utilization"`
+	CompletedJobs int64              `json:"completed_jobs"`
+	FailedJobs    int64              `json:"failed_jobs"`
+	DeferredJobs  int64              `json:"deferred_jobs"`
+	StartedAt     pgtype.Timestamptz `json:"started_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
 }
@@ -10,7 +10,7 @@ import (
 // pgmq docs:
 // https://github.com/pgmq/pgmq/blob/main/docs/api/sql/functions.md
 const readPollingMessages = `
-SELECT FROM pgmq.read_with_poll(
+SELECT msg_id, read_ct, enqueued_at, vt, message FROM pgmq.read_with_poll(
 			queue_name       => $1::text,
 			vt               => $2::int,
 			qty              => $3::int,
@@ -22,6 +22,12 @@ SELECT FROM pgmq.read_with_poll(
 const viewMessages = `SELECT msg_id, read_ct, enqueued_at, vt, message
 	FROM pgmq.q_collection_jobs ORDER BY vt LIMIT $1::int`
 
+const viewDeadMessages = `SELECT msg_id, read_ct, enqueued_at, vt, message
+	FROM pgmq.q_collection_jobs_dead ORDER BY enqueued_at DESC LIMIT $1::int`
+
+const getDeadMessage = `SELECT msg_id, read_ct, enqueued_at, vt, message
+	FROM pgmq.q_collection_jobs_dead WHERE msg_id = $1::bigint`
+
 type ReadPollingParams struct {
 	QueueName                   string `json:"queue_name"`
 	SecondsUntilRescheduled     int32  `json:"seconds_until_rescheduled"`
@@ -99,6 +105,46 @@ func (q *Queries) ViewQueue(ctx context.Context, limit int32) ([]QueueRow, error
 	return items, nil
 }
 
+// dead collection jobs are never read so they are only viewed
+func (q *Queries) ViewDeadQueue(ctx context.Context, limit int32) ([]QueueRow, error) {
+	rows, err := q.db.Query(ctx, viewDeadMessages, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QueueRow
+	for rows.Next() {
+		var i QueueRow
+		if err := rows.Scan(
+			&i.MessageID,
+			&i.ReadAmount,
+			&i.EnquededAt,
+			&i.VisibleAt,
+			&i.Message,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (q *Queries) GetDeadQueueMessage(ctx context.Context, messageID int32) (QueueRow, error) {
+	row := q.db.QueryRow(ctx, getDeadMessage, messageID)
+	var i QueueRow
+	err := row.Scan(
+		&i.MessageID,
+		&i.ReadAmount,
+		&i.EnquededAt,
+		&i.VisibleAt,
+		&i.Message,
+	)
+	return i, err
+}
+
 type DeleteFromQueueParams struct {
 	QueueName string `json:"queue_name"`
 	MessageID int32  `json:"msg_id"`
@@ -141,3 +187,96 @@ func (q *Queries) AddToQueue(ctx context.Context, arg AddToQueueParams) error {
 	)
 	return err
 }
+
+type SetQueueVisibilityParams struct {
+	QueueName             string `json:"queue_name"`
+	MessageID             int32  `json:"msg_id"`
+	SecondsUntilAvailable int    `json:"vt_offset"`
+}
+
+const setQueueVisibility = `
+SELECT msg_id FROM pgmq.set_vt(
+			queue_name       => $1::text,
+			msg_id           => $2::bigint,
+			vt_offset        => $3::int
+)
+`
+
+// https://github.com/pgmq/pgmq/blob/main/docs/api/sql/functions.md#set_vt
+func (q *Queries) SetQueueVisibility(ctx context.Context, arg SetQueueVisibilityParams) error {
+	_, err := q.db.Exec(ctx, setQueueVisibility,
+		arg.QueueName,
+		arg.MessageID,
+		arg.SecondsUntilAvailable,
+	)
+	return err
+}
+
+type ArchiveFromQueueParams struct {
+	QueueName string `json:"queue_name"`
+	MessageID int32  `json:"msg_id"`
+}
+
+const archiveFromQueue = `
+SELECT * FROM pgmq.archive(
+			queue_name       => $1::text,
+			msg_id           => $2::bigint
+)
+`
+
+// moves the message to the queue's archive table so it is kept but never read again
+// https://github.com/pgmq/pgmq/blob/main/docs/api/sql/functions.md#archive-single
+func (q *Queries) ArchiveFromQueue(ctx context.Context, arg ArchiveFromQueueParams) error {
+	_, err := q.db.Exec(ctx, archiveFromQueue,
+		arg.QueueName,
+		arg.MessageID,
+	)
+	return err
+}
+
+type ReleaseCollectionJobParams struct {
+	MessageID             int32 `json:"msg_id"`
+	SecondsUntilAvailable int   `json:"vt_offset"`
+}
+
+// pgmq does not have a way to give back a message so the queue table is changed directly
+const releaseCollectionJob = `
+UPDATE pgmq.q_collection_jobs
+SET vt = clock_timestamp() + make_interval(secs => $2::int),
+    read_ct = GREATEST(read_ct - 1, 0)
+WHERE msg_id = $1::bigint
+`
+
+// makes a read collection job visible again without counting the read as an attempt
+func (q *Queries) ReleaseCollectionJob(ctx context.Context, arg ReleaseCollectionJobParams) error {
+	_, err := q.db.Exec(ctx, releaseCollectionJob,
+		arg.MessageID,
+		arg.SecondsUntilAvailable,
+	)
+	return err
+}
+
+const collectionQueueDepth = `
+SELECT
+    (SELECT count(*) FROM pgmq.q_collection_jobs WHERE vt <= clock_timestamp()),
+    (SELECT count(*) FROM pgmq.q_collection_jobs),
+    (SELECT count(*) FROM pgmq.q_collection_jobs_dead)
+`
+
+type CollectionQueueDepth struct {
+	// jobs which can be read right now
+	Visible int64 `json:"visible"`
+	Total   int64 `json:"total"`
+	Dead    int64 `json:"dead"`
+}
+
+func (q *Queries) GetCollectionQueueDepth(ctx context.Context) (CollectionQueueDepth, error) {
+	row := q.db.QueryRow(ctx, collectionQueueDepth)
+	var i CollectionQueueDepth
+	err := row.Scan(
+		&i.Visible,
+		&i.Total,
+		&i.Dead,
+	)
+	return i, err
+}
@@ -83,6 +83,7 @@ WHERE s.term_collection_id = @term_collection_id
       AND ss.subject_code = s.subject_code
       AND ss.course_number = s.course_number
       AND ss.school_id = s.school_id
+      AND ss.term_collection_history_id = @term_collection_history_id
   );
 
 -- name: MoveStagedSections :exec
@@ -128,6 +129,7 @@ WHERE mt.term_collection_id = @term_collection_id
       AND smt.course_number = mt.course_number
       AND smt.school_id = mt.school_id
       AND smt.section_sequence = mt.section_sequence
+      AND smt.term_collection_history_id = @term_collection_history_id
   )
 ;
 
@@ -226,8 +228,8 @@ SET subject_description = COALESCE(EXCLUDED.subject_description, courses.subject
 
 -- name: InsertTermCollectionHistory :one
 INSERT INTO term_collection_history
-    (term_collection_id, school_id, is_full)
-VALUES (@term_collection_id, @school_id, @is_full)
+    (term_collection_id, school_id, is_full, service_name, status)
+VALUES (@term_collection_id, @school_id, @is_full, @service_name, @status)
 RETURNING id;
 
 -- name: FinishTermCollectionHistory :exec
@@ -236,10 +238,28 @@ UPDATE term_collection_history SET
     deleted_records_count = @deleted_records_count,
     updated_records_count = @updated_records_count,
     inserted_records_count = @inserted_records_count,
+    is_incorrect_assumption = @is_incorrect_assumption,
+    failure_reason = @failure_reason,
     end_time = now()
 WHERE id = @term_collection_history_id
 ;
 
+-- name: BeatTermCollectionHistory :exec
+UPDATE term_collection_history SET heartbeat_at = now()
+WHERE id = @term_collection_history_id AND status IN ('Active', 'DryRun');
+
+-- name: ReapOrphanedTermCollectionHistory :many
+-- fails the active collections which have not had a heartbeat in a while
+--    their process is assumed to be gone
+--    orphaned dry runs are cancelled since they say nothing about the service
+UPDATE term_collection_history SET
+    status = CASE WHEN status = 'DryRun' THEN 'Cancelled' ELSE 'Failure' END::term_collection_status_enum,
+    failure_reason = @failure_reason::TEXT,
+    end_time = now()
+WHERE status IN ('Active', 'DryRun')
+      AND COALESCE(heartbeat_at, start_time) < now() - make_interval(secs => @stale_seconds::INT)
+RETURNING id, term_collection_id, school_id;
+
 -- name: GetActiveTermCollections :many
 SELECT tc.*
 FROM term_collections tc
@@ -262,6 +282,44 @@ WHERE t.id = @term_collection_history_id::INTEGER
 GROUP BY (t.id, t.end_time, t.start_time)
 ;
 
+-- name: GetServiceHealth :many
+-- how the most recent finished collections of each school's services went
+WITH recent AS (
+    -- walks term_collection_history_service_health for each service so only the window is read
+    SELECT r.school_id, r.service_name, h.status, h.is_incorrect_assumption, h.start_time, h.end_time,
+        ROW_NUMBER() OVER (PARTITION BY r.school_id, r.service_name ORDER BY h.start_time DESC) AS recency
+    FROM school_registry r
+    CROSS JOIN LATERAL (
+        SELECT status, is_incorrect_assumption, start_time, end_time
+        FROM term_collection_history
+        WHERE school_id = r.school_id AND service_name = r.service_name
+            -- cancelled collections say nothing about the service
+            AND status IN ('Success', 'Failure')
+        ORDER BY start_time DESC
+        LIMIT @window_size::INTEGER
+    ) h
+)
+SELECT school_id, service_name::TEXT AS service_name,
+    COUNT(*) FILTER (WHERE status = 'Success')::INTEGER AS success_count,
+    COUNT(*) FILTER (WHERE status = 'Failure')::INTEGER AS failure_count,
+    COUNT(*) FILTER (WHERE is_incorrect_assumption)::INTEGER AS incorrect_assumption_count,
+    -- failures since the last success
+    COALESCE(MIN(recency) FILTER (WHERE status = 'Success') - 1, COUNT(*))::INTEGER AS consecutive_failure_count,
+    COALESCE(
+        AVG(EXTRACT(EPOCH FROM end_time - start_time)) FILTER (WHERE status = 'Success'),
+        0
+    )::FLOAT8 AS average_success_seconds,
+    MAX(end_time) FILTER (WHERE status = 'Failure')::TIMESTAMPTZ AS last_failure_time
+FROM recent
+GROUP BY school_id, service_name
+;
+
+-- name: DeleteTermCollectionHistory :exec
+-- dry runs do not leave any history behind
+DELETE FROM term_collection_history
+WHERE id = @term_collection_history_id
+;
+
 -- name: DeleteStagingCourses :exec
 DELETE FROM staging_courses
 WHERE term_collection_history_id = @term_collection_history_id
@@ -281,3 +339,243 @@ WHERE term_collection_history_id = @term_collection_history_id
 DELETE FROM staging_meeting_times
 WHERE term_collection_history_id = @term_collection_history_id
 ;
+
+-- name: SnapshotSectionEnrollment :exec
+-- only the sections whose enrollment changed since their latest snapshot are snapshotted
+WITH latest AS (
+    SELECT DISTINCT ON (snap.subject_code, snap.course_number, snap.section_sequence)
+        snap.subject_code, snap.course_number, snap.section_sequence,
+        snap.enrollment, snap.max_enrollment, snap.is_removed
+    FROM section_enrollment_snapshots snap
+    WHERE snap.school_id = @school_id
+          AND snap.term_collection_id = @term_collection_id
+    ORDER BY snap.subject_code, snap.course_number, snap.section_sequence,
+        snap.term_collection_history_id DESC
+)
+INSERT INTO section_enrollment_snapshots
+    (term_collection_history_id, section_sequence, term_collection_id,
+        subject_code, course_number, school_id,
+        enrollment, max_enrollment, is_removed)
+SELECT @term_collection_history_id::INT, s."sequence", s.term_collection_id,
+        s.subject_code, s.course_number, s.school_id,
+        s.enrollment, s.max_enrollment, FALSE
+FROM sections s
+LEFT JOIN latest l ON l.subject_code = s.subject_code
+                   AND l.course_number = s.course_number
+                   AND l.section_sequence = s."sequence"
+WHERE s.school_id = @school_id
+      AND s.term_collection_id = @term_collection_id
+      AND (l.section_sequence IS NULL
+           OR l.is_removed
+           OR l.enrollment IS DISTINCT FROM s.enrollment
+           OR l.max_enrollment IS DISTINCT FROM s.max_enrollment)
+UNION ALL
+SELECT @term_collection_history_id::INT, l.section_sequence, @term_collection_id,
+        l.subject_code, l.course_number, @school_id,
+        NULL, NULL, TRUE
+FROM latest l
+WHERE NOT l.is_removed
+      AND NOT EXISTS (
+          SELECT 1
+          FROM sections s
+          WHERE s.school_id = @school_id
+                AND s.term_collection_id = @term_collection_id
+                AND s.subject_code = l.subject_code
+                AND s.course_number = l.course_number
+                AND s."sequence" = l.section_sequence
+      );
+
+-- name: DeleteExpiredEnrollmentSnapshots :execrows
+-- the latest snapshot of a section is kept while the section exists since it is still its enrollment
+DELETE FROM section_enrollment_snapshots snap
+USING term_collection_history h
+WHERE h.id = snap.term_collection_history_id
+      AND h.start_time < CURRENT_TIMESTAMP - make_interval(secs => @max_age_seconds::int)
+      AND (snap.is_removed OR EXISTS (
+          SELECT 1
+          FROM section_enrollment_snapshots newer
+          WHERE newer.school_id = snap.school_id
+                AND newer.term_collection_id = snap.term_collection_id
+                AND newer.subject_code = snap.subject_code
+                AND newer.course_number = snap.course_number
+                AND newer.section_sequence = snap.section_sequence
+                AND newer.term_collection_history_id > snap.term_collection_history_id
+      ));
+
+-- name: CountUnstagedSections :one
+-- how many of the term's sections moving the collection's staged sections would delete
+SELECT
+    COUNT(*) AS live_sections,
+    COUNT(*) FILTER (WHERE NOT EXISTS (
+        SELECT 1
+        FROM staging_sections ss
+        WHERE ss.sequence = s.sequence
+          AND ss.term_collection_id = s.term_collection_id
+          AND ss.subject_code = s.subject_code
+          AND ss.course_number = s.course_number
+          AND ss.school_id = s.school_id
+          AND ss.term_collection_history_id = @term_collection_history_id
+    )) AS unstaged_sections
+FROM sections s
+WHERE s.term_collection_id = @term_collection_id
+  AND s.school_id = @school_id;
+
+-- name: RecordBackwardsMeetingTimes :execrows
+-- meeting times which end before they start
+INSERT INTO collection_violations
+    (term_collection_history_id, school_id, rule, severity, table_name, record_key, message)
+SELECT
+    mt.term_collection_history_id, mt.school_id, @rule::TEXT, @severity::violation_severity_enum, 'meeting_times',
+    jsonb_build_object(
+        'sequence', mt.sequence, 'section_sequence', mt.section_sequence,
+        'subject_code', mt.subject_code, 'course_number', mt.course_number
+    ),
+    CASE WHEN mt.end_minutes < mt.start_minutes
+        THEN format('ends at %s before it starts at %s', mt.end_minutes, mt.start_minutes)
+        ELSE format('ends on %s before it starts on %s', mt.end_date::DATE, mt.start_date::DATE)
+    END
+FROM staging_meeting_times mt
+WHERE mt.term_collection_history_id = @term_collection_history_id
+  AND (mt.end_minutes < mt.start_minutes OR mt.end_date < mt.start_date);
+
+-- name: RecordCreditHoursOutOfRange :execrows
+-- courses worth less or more credit hours than any course should be
+INSERT INTO collection_violations
+    (term_collection_history_id, school_id, rule, severity, table_name, record_key, message)
+SELECT
+    c.term_collection_history_id, c.school_id, @rule::TEXT, @severity::violation_severity_enum, 'courses',
+    jsonb_build_object('subject_code', c.subject_code, 'number', c.number),
+    format('is worth %s credit hours', c.credit_hours)
+FROM staging_courses c
+WHERE c.term_collection_history_id = @term_collection_history_id
+  AND (c.credit_hours < @min_credit_hours::REAL OR c.credit_hours > @max_credit_hours::REAL);
+
+-- name: RecordDuplicateMeetingSequences :execrows
+-- only one of the duplicated meetings is moved so the others are lost
+INSERT INTO collection_violations
+    (term_collection_history_id, school_id, rule, severity, table_name, record_key, message)
+SELECT
+    mt.term_collection_history_id, mt.school_id, @rule::TEXT, @severity::violation_severity_enum, 'meeting_times',
+    jsonb_build_object(
+        'sequence', mt.sequence, 'section_sequence', mt.section_sequence,
+        'subject_code', mt.subject_code, 'course_number', mt.course_number
+    ),
+    format('was staged %s times', COUNT(*))
+FROM staging_meeting_times mt
+WHERE mt.term_collection_history_id = @term_collection_history_id
+GROUP BY mt.term_collection_history_id, mt.school_id, mt.term_collection_id,
+    mt.sequence, mt.section_sequence, mt.subject_code, mt.course_number
+HAVING COUNT(*) > 1;
+
+-- name: RecordEnrollmentOverflows :execrows
+-- sections with so many more students than seats the numbers are likely wrong
+INSERT INTO collection_violations
+    (term_collection_history_id, school_id, rule, severity, table_name, record_key, message)
+SELECT
+    s.term_collection_history_id, s.school_id, @rule::TEXT, @severity::violation_severity_enum, 'sections',
+    jsonb_build_object(
+        'sequence', s.sequence, 'subject_code', s.subject_code, 'course_number', s.course_number
+    ),
+    format('has %s students enrolled in %s seats', s.enrollment, s.max_enrollment)
+FROM staging_sections s
+WHERE s.term_collection_history_id = @term_collection_history_id
+  AND s.max_enrollment > 0
+  AND s.enrollment > s.max_enrollment * @max_overflow_ratio::REAL;
+
+-- name: RecordMissingSectionCourses :execrows
+-- sections whose course is neither staged nor already known
+INSERT INTO collection_violations
+    (term_collection_history_id, school_id, rule, severity, table_name, record_key, message)
+SELECT
+    s.term_collection_history_id, s.school_id, @rule::TEXT, @severity::violation_severity_enum, 'sections',
+    jsonb_build_object(
+        'sequence', s.sequence, 'subject_code', s.subject_code, 'course_number', s.course_number
+    ),
+    format('references the missing course %s %s', s.subject_code, s.course_number)
+FROM staging_sections s
+WHERE s.term_collection_history_id = @term_collection_history_id
+  AND NOT EXISTS (
+      SELECT 1
+      FROM staging_courses sc
+      WHERE sc.term_collection_history_id = s.term_collection_history_id
+        AND sc.school_id = s.school_id
+        AND sc.subject_code = s.subject_code
+        AND sc.number = s.course_number
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM courses c
+      WHERE c.school_id = s.school_id
+        AND c.subject_code = s.subject_code
+        AND c.number = s.course_number
+  );
+
+-- name: InsertCollectionLog :exec
+INSERT INTO collection_logs
+    (term_collection_history_id, school_id, term_collection_id,
+        compressed_logs, uncompressed_bytes, lines, truncated)
+VALUES
+    (@term_collection_history_id, @school_id, @term_collection_id,
+        @compressed_logs, @uncompressed_bytes, @lines, @truncated);
+
+-- name: DeleteExpiredCollectionLogs :execrows
+DELETE FROM collection_logs
+WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => @max_age_seconds::int);
+
+-- name: DeleteExpiredCollectionViolations :execrows
+DELETE FROM collection_violations
+WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => @max_age_seconds::int);
+
+-- name: DeleteExcessCollectionLogs :execrows
+-- only the most recent logs of each term are kept
+DELETE FROM collection_logs
+WHERE term_collection_history_id IN (
+    SELECT ranked.term_collection_history_id
+    FROM (
+        SELECT term_collection_history_id,
+            row_number() OVER (
+                PARTITION BY school_id, term_collection_id
+                ORDER BY term_collection_history_id DESC
+            ) AS recency
+        FROM collection_logs
+    ) ranked
+    WHERE ranked.recency > @keep_count::int
+);
+
+-- name: InsertQuarantinedCollection :exec
+INSERT INTO quarantined_collections
+    (term_collection_history_id, term_collection_id, school_id, live_sections, deleted_sections)
+VALUES
+    (@term_collection_history_id, @term_collection_id, @school_id, @live_sections, @deleted_sections);
+
+-- name: SupersedeQuarantinedCollections :many
+-- older pending collections of the term would overwrite newer data if they were approved
+UPDATE quarantined_collections SET
+    status = 'Superseded',
+    decided_at = now()
+WHERE school_id = @school_id
+      AND term_collection_id = @term_collection_id
+      AND term_collection_history_id < @term_collection_history_id
+      AND status = 'Pending'
+RETURNING term_collection_history_id;
+
+-- name: HasPendingQuarantinedCollection :one
+-- scheduled collections wait for the term's quarantined collection to be decided
+SELECT EXISTS (
+    SELECT 1 FROM quarantined_collections
+    WHERE school_id = @school_id
+          AND term_collection_id = @term_collection_id
+          AND status = 'Pending'
+)::BOOL AS is_pending;
+
+-- name: GetRecentTermCollectionHistory :many
+SELECT * FROM term_collection_history
+WHERE school_id = @school_id
+      AND term_collection_id = @term_collection_id
+      AND status NOT IN ('Active', 'DryRun')
+ORDER BY start_time DESC
+LIMIT @limit_count;
+
+-- name: GetTermRegistrationCalendar :one
+SELECT * FROM term_registration_calendars
+WHERE school_id = @school_id AND term_collection_id = @term_collection_id;
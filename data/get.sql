@@ -62,6 +62,48 @@ LIMIT @limitValue + 1
 OFFSET @offsetValue
 ;
 
+-- name: GetSectionMeetingTimesForCalendar :many
+-- sections are given as parallel arrays of their keys
+SELECT mt."sequence", mt.section_sequence, mt.subject_code, mt.course_number,
+       mt.start_date, mt.end_date, mt.meeting_type, mt.start_minutes, mt.end_minutes,
+       mt.is_monday, mt.is_tuesday, mt.is_wednesday, mt.is_thursday, mt.is_friday,
+       mt.is_saturday, mt.is_sunday,
+       c.title, s.campus, p.name AS professor_name
+FROM UNNEST(@subject_codes::TEXT[], @course_numbers::TEXT[], @sequences::TEXT[])
+    AS requested(subject_code, course_number, "sequence")
+JOIN sections s ON s.school_id = @school_id
+                AND s.term_collection_id = @term_collection_id
+                AND s.subject_code = requested.subject_code
+                AND s.course_number = requested.course_number
+                AND s."sequence" = requested."sequence"
+JOIN meeting_times mt ON mt.school_id = s.school_id
+                      AND mt.term_collection_id = s.term_collection_id
+                      AND mt.subject_code = s.subject_code
+                      AND mt.course_number = s.course_number
+                      AND mt.section_sequence = s."sequence"
+JOIN courses c ON c.school_id = s.school_id
+               AND c.subject_code = s.subject_code
+               AND c.number = s.course_number
+LEFT JOIN professors p ON p.school_id = s.school_id
+                       AND p.id = s.primary_professor_id
+ORDER BY mt.subject_code, mt.course_number, mt.section_sequence, mt."sequence";
+
+-- name: GetSectionsForCourses :many
+-- courses are given as parallel arrays of their keys
+SELECT sqlc.embed(sections), section_meetings.meeting_times
+FROM UNNEST(@subject_codes::TEXT[], @course_numbers::TEXT[])
+    AS requested(subject_code, course_number)
+JOIN sections ON sections.school_id = @school_id
+              AND sections.term_collection_id = @term_collection_id
+              AND sections.subject_code = requested.subject_code
+              AND sections.course_number = requested.course_number
+LEFT JOIN section_meetings ON sections."sequence"           = section_meetings."sequence"
+                           AND sections.term_collection_id  = section_meetings.term_collection_id
+                           AND sections.subject_code        = section_meetings.subject_code
+                           AND sections.course_number       = section_meetings.course_number
+                           AND sections.school_id           = section_meetings.school_id
+ORDER BY sections.subject_code, sections.course_number, sections."sequence";
+
 -- name: GetTermCollectionsForSchool :many
 SELECT term_collections.*
 FROM term_collections 
@@ -106,6 +148,7 @@ FROM term_collections t
 WHERE t.school_id = @school_id AND t.id = @term_collection_id;
 
 -- name: GetSchoolsClassesForTermOrderedBySection :many
+-- null filters are ignored
 SELECT sqlc.embed(sections), section_meetings.meeting_times
 FROM section_meetings
 JOIN sections ON sections."sequence"           = section_meetings."sequence"
@@ -115,8 +158,171 @@ JOIN sections ON sections."sequence"           = section_meetings."sequence"
               AND sections.school_id           = section_meetings.school_id
 WHERE sections.school_id = @school_id
       AND sections.term_collection_id = @term_collection_id
+      AND (sqlc.narg(subject_code)::TEXT IS NULL OR sections.subject_code = sqlc.narg(subject_code))
+      AND (sqlc.narg(campus)::TEXT IS NULL OR sections.campus = sqlc.narg(campus))
+      AND (sqlc.narg(instruction_method)::TEXT IS NULL OR sections.instruction_method = sqlc.narg(instruction_method))
+      AND (sqlc.narg(primary_professor_id)::TEXT IS NULL OR sections.primary_professor_id = sqlc.narg(primary_professor_id))
+      AND (NOT @has_open_seats::BOOL OR sections.enrollment < sections.max_enrollment)
+      AND (sqlc.narg(meeting_type)::TEXT IS NULL OR EXISTS (
+          SELECT 1 FROM meeting_times mt
+          WHERE mt.school_id = sections.school_id
+                AND mt.term_collection_id = sections.term_collection_id
+                AND mt.subject_code = sections.subject_code
+                AND mt.course_number = sections.course_number
+                AND mt.section_sequence = sections."sequence"
+                AND mt.meeting_type = sqlc.narg(meeting_type)
+      ))
+      -- every meeting of the section must fit in the requested days and time windows
+      AND NOT EXISTS (
+          SELECT 1 FROM meeting_times mt
+          WHERE mt.school_id = sections.school_id
+                AND mt.term_collection_id = sections.term_collection_id
+                AND mt.subject_code = sections.subject_code
+                AND mt.course_number = sections.course_number
+                AND mt.section_sequence = sections."sequence"
+                AND (
+                    (@exclude_monday::BOOL AND mt.is_monday)
+                    OR (@exclude_tuesday::BOOL AND mt.is_tuesday)
+                    OR (@exclude_wednesday::BOOL AND mt.is_wednesday)
+                    OR (@exclude_thursday::BOOL AND mt.is_thursday)
+                    OR (@exclude_friday::BOOL AND mt.is_friday)
+                    OR (@exclude_saturday::BOOL AND mt.is_saturday)
+                    OR (@exclude_sunday::BOOL AND mt.is_sunday)
+                    OR mt.start_minutes < sqlc.narg(start_after)::TIME
+                    OR mt.start_minutes > sqlc.narg(start_before)::TIME
+                    OR mt.end_minutes < sqlc.narg(end_after)::TIME
+                    OR mt.end_minutes > sqlc.narg(end_before)::TIME
+                )
+      )
 ORDER BY sections."sequence", sections.subject_code, sections.course_number, 
     sections.school_id, sections.term_collection_id
 LIMIT @limitValue + 1
 OFFSET @offsetValue
 ;
+
+-- name: SearchCourses :many
+SELECT c.*, sc.rank
+FROM search_courses(@school_id, @search_text) sc
+JOIN courses c ON c.school_id = @school_id
+               AND c.subject_code = sc.subject_code
+               AND c.number = sc.number
+WHERE (sqlc.narg(subject_code)::TEXT IS NULL OR c.subject_code = sqlc.narg(subject_code))
+      AND (sqlc.narg(credit_hours)::REAL IS NULL OR c.credit_hours = sqlc.narg(credit_hours))
+      AND (sqlc.narg(campus)::TEXT IS NULL OR EXISTS (
+          SELECT 1 FROM sections s
+          WHERE s.school_id = c.school_id
+                AND s.subject_code = c.subject_code
+                AND s.course_number = c.number
+                AND s.campus = sqlc.narg(campus)
+      ))
+      AND (sqlc.narg(instruction_method)::TEXT IS NULL OR EXISTS (
+          SELECT 1 FROM sections s
+          WHERE s.school_id = c.school_id
+                AND s.subject_code = c.subject_code
+                AND s.course_number = c.number
+                AND s.instruction_method = sqlc.narg(instruction_method)
+      ))
+ORDER BY sc.rank DESC, c.subject_code, c.number
+LIMIT @limitValue + 1
+OFFSET @offsetValue
+;
+
+-- name: SearchCourseFacets :many
+-- facet counts are the number of matching courses for each value
+WITH matched_courses AS (
+    SELECT c.*
+    FROM search_courses(@school_id, @search_text) sc
+    JOIN courses c ON c.school_id = @school_id
+                   AND c.subject_code = sc.subject_code
+                   AND c.number = sc.number
+    WHERE (sqlc.narg(subject_code)::TEXT IS NULL OR c.subject_code = sqlc.narg(subject_code))
+          AND (sqlc.narg(credit_hours)::REAL IS NULL OR c.credit_hours = sqlc.narg(credit_hours))
+          AND (sqlc.narg(campus)::TEXT IS NULL OR EXISTS (
+              SELECT 1 FROM sections s
+              WHERE s.school_id = c.school_id
+                    AND s.subject_code = c.subject_code
+                    AND s.course_number = c.number
+                    AND s.campus = sqlc.narg(campus)
+          ))
+          AND (sqlc.narg(instruction_method)::TEXT IS NULL OR EXISTS (
+              SELECT 1 FROM sections s
+              WHERE s.school_id = c.school_id
+                    AND s.subject_code = c.subject_code
+                    AND s.course_number = c.number
+                    AND s.instruction_method = sqlc.narg(instruction_method)
+          ))
+),
+matched_sections AS (
+    SELECT s.*
+    FROM matched_courses mc
+    JOIN sections s ON s.school_id = mc.school_id
+                    AND s.subject_code = mc.subject_code
+                    AND s.course_number = mc.number
+)
+SELECT 'subject'::TEXT AS facet, mc.subject_code::TEXT AS value, COUNT(*) AS count
+FROM matched_courses mc
+GROUP BY mc.subject_code
+UNION ALL
+SELECT 'credit_hours'::TEXT AS facet, mc.credit_hours::TEXT AS value, COUNT(*) AS count
+FROM matched_courses mc
+GROUP BY mc.credit_hours
+UNION ALL
+SELECT 'campus'::TEXT AS facet, ms.campus::TEXT AS value,
+    COUNT(DISTINCT (ms.subject_code, ms.course_number)) AS count
+FROM matched_sections ms
+WHERE ms.campus IS NOT NULL
+GROUP BY ms.campus
+UNION ALL
+SELECT 'instruction_method'::TEXT AS facet, ms.instruction_method::TEXT AS value,
+    COUNT(DISTINCT (ms.subject_code, ms.course_number)) AS count
+FROM matched_sections ms
+WHERE ms.instruction_method IS NOT NULL
+GROUP BY ms.instruction_method
+ORDER BY facet, count DESC, value
+;
+
+-- name: GetSectionEnrollmentHistory :many
+-- only the collections which changed the section's enrollment
+SELECT h.id AS term_collection_history_id, h.end_time AS collected_at,
+       s.enrollment, s.max_enrollment
+FROM section_enrollment_snapshots s
+JOIN term_collection_history h ON h.id = s.term_collection_history_id
+WHERE s.school_id = @school_id
+      AND s.term_collection_id = @term_collection_id
+      AND s.subject_code = @subject_code
+      AND s.course_number = @course_number
+      AND s.section_sequence = @section_sequence
+      AND NOT s.is_removed
+ORDER BY h.end_time, h.id
+LIMIT @limitValue + 1
+OFFSET @offsetValue;
+
+-- name: GetCourseFillRate :many
+-- the enrollment of all of the sections of a course summed for each collection which changed it
+--    a section's enrollment as of a collection is its latest snapshot at that collection
+SELECT h.id AS term_collection_history_id, h.end_time AS collected_at,
+       COUNT(*) FILTER (WHERE NOT latest.is_removed) AS sections_count,
+       COUNT(*) FILTER (WHERE latest.enrollment >= latest.max_enrollment) AS full_sections_count,
+       COALESCE(SUM(latest.enrollment), 0)::INT AS enrollment,
+       COALESCE(SUM(latest.max_enrollment), 0)::INT AS max_enrollment
+FROM term_collection_history h
+CROSS JOIN LATERAL (
+    SELECT DISTINCT ON (s.section_sequence) s.enrollment, s.max_enrollment, s.is_removed
+    FROM section_enrollment_snapshots s
+    WHERE s.school_id = @school_id
+          AND s.term_collection_id = @term_collection_id
+          AND s.subject_code = @subject_code
+          AND s.course_number = @course_number
+          AND s.term_collection_history_id <= h.id
+    ORDER BY s.section_sequence, s.term_collection_history_id DESC
+) latest
+WHERE h.id IN (
+    SELECT s.term_collection_history_id
+    FROM section_enrollment_snapshots s
+    WHERE s.school_id = @school_id
+          AND s.term_collection_id = @term_collection_id
+          AND s.subject_code = @subject_code
+          AND s.course_number = @course_number
+)
+GROUP BY h.id, h.end_time
+ORDER BY h.end_time, h.id;
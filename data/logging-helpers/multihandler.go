@@ -50,10 +50,13 @@ func (h *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	return false
 }
 
-// always calls each handlers returning all errors wrapped
+// calls each handler which is enabled for the record's level returning all errors wrapped
 func (h *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
 	var errs error
 	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
 		errs = errors.Join(errs, handler.Handle(ctx, r))
 	}
 	return errs
@@ -1,8 +1,170 @@
--- name: GetPreviousCollections :exec
-SELECT * FROM schools;
+-- name: GetPreviousCollections :many
+-- null filters are ignored
+SELECT * FROM term_collection_history
+WHERE (sqlc.narg(school_id)::TEXT IS NULL OR school_id = sqlc.narg(school_id))
+      AND (sqlc.narg(term_collection_id)::TEXT IS NULL OR term_collection_id = sqlc.narg(term_collection_id))
+ORDER BY id DESC
+LIMIT @limitValue + 1
+OFFSET @offsetValue;
+
+-- name: GetTermCollectionHistory :one
+SELECT * FROM term_collection_history
+WHERE id = @term_collection_history_id;
+
+-- name: GetCollectionChanges :many
+-- what the collection changed in the order the triggers logged it
+SELECT * FROM historic_class_information
+WHERE term_collection_history_id = @term_collection_history_id::INT
+ORDER BY sequence
+LIMIT @limitValue + 1
+OFFSET @offsetValue;
+
+-- name: GetCollectionLog :one
+SELECT * FROM collection_logs
+WHERE term_collection_history_id = @term_collection_history_id;
+
+-- name: SearchCollectionLogs :many
+-- the most recent logs to search through null filters are ignored
+SELECT * FROM collection_logs
+WHERE (sqlc.narg(school_id)::TEXT IS NULL OR school_id = sqlc.narg(school_id))
+      AND (sqlc.narg(term_collection_id)::TEXT IS NULL OR term_collection_id = sqlc.narg(term_collection_id))
+ORDER BY term_collection_history_id DESC
+LIMIT @limit_count;
+
+-- name: ListCollectionViolations :many
+SELECT * FROM collection_violations
+WHERE term_collection_history_id = @term_collection_history_id
+ORDER BY severity DESC, rule, id
+LIMIT @limit_count;
 
 -- name: GetTermCollection :one
 SELECT * FROM  term_collections
 WHERE term_collections.id = @id
       AND term_collections.school_id = @school_id;
 
+
+-- name: ListPendingQuarantinedCollections :many
+SELECT * FROM quarantined_collections
+WHERE status = 'Pending'
+ORDER BY created_at DESC;
+
+-- name: ListRecentCollectionViolations :many
+-- the rules the most recent collections broke with one of the records that broke them
+WITH recent AS (
+    -- each collection has at least one row so only that many of the newest collections are grouped
+    SELECT DISTINCT term_collection_history_id
+    FROM collection_violations
+    ORDER BY term_collection_history_id DESC
+    LIMIT @limit_count
+)
+SELECT
+    cv.term_collection_history_id,
+    cv.school_id,
+    tch.term_collection_id,
+    cv.rule,
+    cv.severity,
+    COUNT(*) AS violations,
+    (array_agg(format('%s %s', cv.record_key, cv.message) ORDER BY cv.id))[1]::TEXT AS example
+FROM recent r
+JOIN collection_violations cv ON cv.term_collection_history_id = r.term_collection_history_id
+JOIN term_collection_history tch ON tch.id = cv.term_collection_history_id
+GROUP BY cv.term_collection_history_id, cv.school_id, tch.term_collection_id, cv.rule, cv.severity
+ORDER BY cv.term_collection_history_id DESC, cv.severity DESC, cv.rule
+LIMIT @limit_count;
+
+-- name: GetQuarantinedCollectionForUpdate :one
+SELECT * FROM quarantined_collections
+WHERE term_collection_history_id = @term_collection_history_id
+FOR UPDATE;
+
+-- name: DecideQuarantinedCollection :execrows
+UPDATE quarantined_collections SET
+    status = @status,
+    decided_at = now()
+WHERE term_collection_history_id = @term_collection_history_id
+      AND status = 'Pending';
+
+-- name: ListSchoolRegistry :many
+SELECT * FROM school_registry
+ORDER BY school_id, priority, service_name;
+
+-- name: GetSchoolRegistryEntry :one
+SELECT * FROM school_registry
+WHERE school_id = @school_id AND service_name = @service_name;
+
+-- name: UpsertSchoolRegistryEntry :exec
+INSERT INTO school_registry
+    (school_id, school_name, service_name, config, priority, enabled)
+VALUES
+    (@school_id, @school_name, @service_name, @config, @priority, @enabled)
+ON CONFLICT (school_id, service_name) DO UPDATE
+SET school_name = EXCLUDED.school_name,
+    config = EXCLUDED.config,
+    priority = EXCLUDED.priority,
+    enabled = EXCLUDED.enabled,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteSchoolRegistryEntry :execrows
+DELETE FROM school_registry
+WHERE school_id = @school_id AND service_name = @service_name;
+
+-- name: ListCollectionSchedules :many
+SELECT * FROM collection_schedules
+ORDER BY school_id, id;
+
+-- name: GetEnabledCollectionSchedules :many
+SELECT * FROM collection_schedules
+WHERE enabled
+ORDER BY id;
+
+-- name: InsertCollectionSchedule :one
+INSERT INTO collection_schedules
+    (school_id, term_collection_id, cron, timezone, is_full_collection, service_name, enabled)
+VALUES
+    (@school_id, sqlc.narg(term_collection_id), @cron, @timezone, @is_full_collection, sqlc.narg(service_name), @enabled)
+RETURNING *;
+
+-- name: SetCollectionScheduleEnabled :execrows
+UPDATE collection_schedules
+SET enabled = @enabled
+WHERE id = @id;
+
+-- name: DeleteCollectionSchedule :execrows
+DELETE FROM collection_schedules
+WHERE id = @id;
+
+-- name: ClaimCollectionSchedule :execrows
+-- only one planner gets to plan a schedule's run
+UPDATE collection_schedules
+SET last_planned_at = @planned_at
+WHERE id = @id
+      AND last_planned_at IS NOT DISTINCT FROM sqlc.narg(previous_planned_at);
+
+-- name: GetStillCollectingTermCollections :many
+SELECT * FROM term_collections
+WHERE school_id = @school_id AND still_collecting
+ORDER BY year, season;
+
+-- name: UpsertWorkerPoolMetrics :exec
+INSERT INTO worker_pool_metrics
+    (worker_pool_id, hostname, workers, busy_workers, utilization,
+     completed_jobs, failed_jobs, deferred_jobs, started_at)
+VALUES
+    (@worker_pool_id, @hostname, @workers, @busy_workers, @utilization,
+     @completed_jobs, @failed_jobs, @deferred_jobs, @started_at)
+ON CONFLICT (worker_pool_id) DO UPDATE
+SET busy_workers = EXCLUDED.busy_workers,
+    utilization = EXCLUDED.utilization,
+    completed_jobs = EXCLUDED.completed_jobs,
+    failed_jobs = EXCLUDED.failed_jobs,
+    deferred_jobs = EXCLUDED.deferred_jobs,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: ListRecentWorkerPoolMetrics :many
+SELECT * FROM worker_pool_metrics
+WHERE updated_at > CURRENT_TIMESTAMP - make_interval(secs => @max_age_seconds::int)
+ORDER BY started_at;
+
+-- name: DeleteWorkerPoolMetrics :exec
+DELETE FROM worker_pool_metrics
+WHERE worker_pool_id = @worker_pool_id;
@@ -0,0 +1,93 @@
+-- name: InsertSeatSubscription :one
+INSERT INTO seat_subscriptions
+    (school_id, term_collection_id, subject_code, course_number, section_sequence,
+        callback_url, callback_host, secret)
+VALUES
+    (@school_id, @term_collection_id, @subject_code, @course_number, @section_sequence,
+        @callback_url, @callback_host, @secret)
+RETURNING *;
+
+-- name: GetSeatSubscription :one
+SELECT * FROM seat_subscriptions
+WHERE id = @id;
+
+-- name: DeleteSeatSubscription :execrows
+DELETE FROM seat_subscriptions
+WHERE id = @id AND secret = @secret;
+
+-- name: GetSeatOpenings :many
+-- subscribed sections which are open now but were full as of the last collection
+SELECT sub.id AS subscription_id, s.school_id, s.term_collection_id,
+       s.subject_code, s.course_number, s."sequence",
+       s.enrollment, s.max_enrollment
+FROM sections s
+JOIN seat_subscriptions sub ON sub.school_id = s.school_id
+                            AND sub.term_collection_id = s.term_collection_id
+                            AND sub.subject_code = s.subject_code
+                            AND sub.course_number = s.course_number
+                            AND sub.section_sequence = s."sequence"
+JOIN LATERAL (
+    SELECT snap.enrollment, snap.max_enrollment
+    FROM section_enrollment_snapshots snap
+    WHERE snap.school_id = s.school_id
+          AND snap.term_collection_id = s.term_collection_id
+          AND snap.subject_code = s.subject_code
+          AND snap.course_number = s.course_number
+          AND snap.section_sequence = s."sequence"
+          AND snap.term_collection_history_id < @term_collection_history_id
+    ORDER BY snap.term_collection_history_id DESC
+    LIMIT 1
+) previous ON TRUE
+WHERE s.school_id = @school_id
+      AND s.term_collection_id = @term_collection_id
+      AND s.enrollment < s.max_enrollment
+      AND previous.enrollment >= previous.max_enrollment;
+
+-- name: InsertCollectionWebhook :one
+INSERT INTO collection_webhooks
+    (callback_url, secret, school_id, events)
+VALUES
+    (@callback_url, @secret, sqlc.narg(school_id), @events)
+RETURNING *;
+
+-- name: ListCollectionWebhooks :many
+SELECT * FROM collection_webhooks
+ORDER BY id;
+
+-- name: GetCollectionWebhook :one
+SELECT * FROM collection_webhooks
+WHERE id = @id;
+
+-- name: GetCollectionWebhooksForEvent :many
+SELECT * FROM collection_webhooks
+WHERE (school_id IS NULL OR school_id = @school_id)
+      AND (cardinality(events) = 0 OR @event::TEXT = ANY(events))
+ORDER BY id;
+
+-- name: DeleteCollectionWebhook :execrows
+DELETE FROM collection_webhooks
+WHERE id = @id;
+
+-- name: SectionExists :one
+SELECT EXISTS (
+    SELECT 1
+    FROM sections
+    WHERE school_id = @school_id
+          AND term_collection_id = @term_collection_id
+          AND subject_code = @subject_code
+          AND course_number = @course_number
+          AND "sequence" = @section_sequence
+);
+
+-- name: CountSeatSubscriptions :one
+SELECT COUNT(*) FROM seat_subscriptions
+WHERE school_id = @school_id
+      AND term_collection_id = @term_collection_id
+      AND subject_code = @subject_code
+      AND course_number = @course_number
+      AND section_sequence = @section_sequence;
+
+-- name: CountRecentSeatSubscriptionsOfHost :one
+SELECT COUNT(*) FROM seat_subscriptions
+WHERE callback_host = @callback_host
+      AND created_at > CURRENT_TIMESTAMP - make_interval(secs => @window_seconds::int);
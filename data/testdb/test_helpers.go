@@ -41,7 +41,7 @@ func ReloadDb() error {
 	if err != nil {
 		return err
 	}
-	m.Force(6)
+	m.Force(22)
 	err = m.Down()
 	if err != nil {
 		return err
@@ -0,0 +1,5 @@
+DROP FUNCTION IF EXISTS search_courses(TEXT, TEXT);
+DROP INDEX IF EXISTS professors_search_idx;
+DROP INDEX IF EXISTS courses_search_idx;
+DROP FUNCTION IF EXISTS professor_search_document(TEXT);
+DROP FUNCTION IF EXISTS course_search_document(TEXT, TEXT, TEXT, TEXT, TEXT);
@@ -0,0 +1,72 @@
+-- full text search over courses
+-- expression indexes are used instead of generated columns because the historic
+--    triggers log every column of a row and the search documents should never be synced
+
+CREATE OR REPLACE FUNCTION course_search_document(
+    subject_code TEXT,
+    number TEXT,
+    title TEXT,
+    subject_description TEXT,
+    description TEXT
+)
+RETURNS tsvector
+AS $$
+    SELECT setweight(to_tsvector('simple', COALESCE(subject_code, '') || ' ' || COALESCE(number, '')), 'A')
+        || setweight(to_tsvector('english', COALESCE(title, '')), 'A')
+        || setweight(to_tsvector('english', COALESCE(subject_description, '')), 'B')
+        || setweight(to_tsvector('english', COALESCE(description, '')), 'C');
+$$ LANGUAGE sql IMMUTABLE;
+
+-- names are not stemmed
+CREATE OR REPLACE FUNCTION professor_search_document(name TEXT)
+RETURNS tsvector
+AS $$
+    SELECT to_tsvector('simple', COALESCE(name, ''));
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE INDEX courses_search_idx ON courses
+USING GIN (course_search_document(subject_code, number, title, subject_description, description));
+
+CREATE INDEX professors_search_idx ON professors
+USING GIN (professor_search_document(name));
+
+-- a course matches if its own text matches or if it has a section taught by a matching professor
+-- the rank of both matches are added together so a course matching on both shows up first
+CREATE OR REPLACE FUNCTION search_courses(_school_id TEXT, _search_text TEXT)
+RETURNS TABLE (subject_code TEXT, number TEXT, rank REAL)
+AS $$
+    WITH search AS (
+        SELECT websearch_to_tsquery('english', _search_text) AS course_query,
+               websearch_to_tsquery('simple', _search_text) AS professor_query
+    ),
+    course_matches AS (
+        SELECT c.subject_code, c.number,
+               ts_rank(
+                   course_search_document(c.subject_code, c.number, c.title, c.subject_description, c.description),
+                   search.course_query
+               ) AS rank
+        FROM courses c
+        CROSS JOIN search
+        WHERE c.school_id = _school_id
+              AND course_search_document(c.subject_code, c.number, c.title, c.subject_description, c.description)
+                  @@ search.course_query
+    ),
+    professor_matches AS (
+        SELECT s.subject_code, s.course_number AS number,
+               MAX(ts_rank(professor_search_document(p.name), search.professor_query)) AS rank
+        FROM professors p
+        CROSS JOIN search
+        JOIN sections s ON s.school_id = p.school_id
+                        AND s.primary_professor_id = p.id
+        WHERE p.school_id = _school_id
+              AND professor_search_document(p.name) @@ search.professor_query
+        GROUP BY s.subject_code, s.course_number
+    )
+    SELECT m.subject_code, m.number, SUM(m.rank)::REAL AS rank
+    FROM (
+        SELECT * FROM course_matches
+        UNION ALL
+        SELECT * FROM professor_matches
+    ) m
+    GROUP BY m.subject_code, m.number;
+$$ LANGUAGE sql STABLE;
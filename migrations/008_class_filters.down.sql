@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS meeting_times_time_filter_idx;
+DROP INDEX IF EXISTS meeting_times_section_idx;
+DROP INDEX IF EXISTS sections_open_seats_filter_idx;
+DROP INDEX IF EXISTS sections_professor_filter_idx;
+DROP INDEX IF EXISTS sections_subject_filter_idx;
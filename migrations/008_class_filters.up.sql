@@ -0,0 +1,13 @@
+-- supports the optional filters on the term classes endpoint
+CREATE INDEX sections_subject_filter_idx ON sections
+    (school_id, term_collection_id, subject_code);
+CREATE INDEX sections_professor_filter_idx ON sections
+    (school_id, term_collection_id, primary_professor_id);
+CREATE INDEX sections_open_seats_filter_idx ON sections
+    (school_id, term_collection_id)
+    WHERE enrollment < max_enrollment;
+
+CREATE INDEX meeting_times_section_idx ON meeting_times
+    (school_id, term_collection_id, subject_code, course_number, section_sequence);
+CREATE INDEX meeting_times_time_filter_idx ON meeting_times
+    (school_id, term_collection_id, start_minutes, end_minutes);
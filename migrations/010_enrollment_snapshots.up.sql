@@ -0,0 +1,25 @@
+-- the enrollment of every section as of each successful collection
+--    sections only keeps the latest numbers and the historic table only has
+--    the changed json so this makes time series of enrollment cheap to query
+-- snapshots are only taken of sections whose enrollment changed so the latest snapshot
+--    of a section is its enrollment until the next one
+--    sections which are no longer in the term get a removed snapshot so they stop counting
+CREATE TABLE section_enrollment_snapshots (
+    term_collection_history_id INT NOT NULL,
+    section_sequence TEXT NOT NULL,
+    term_collection_id TEXT NOT NULL,
+    subject_code TEXT NOT NULL,
+    course_number TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+
+    enrollment INTEGER,
+    max_enrollment INTEGER,
+    is_removed BOOLEAN NOT NULL DEFAULT FALSE,
+
+    FOREIGN KEY (term_collection_history_id) REFERENCES term_collection_history(id) ON DELETE CASCADE,
+    PRIMARY KEY (term_collection_history_id, section_sequence, term_collection_id, subject_code, course_number, school_id)
+);
+
+-- every collection looks up the latest snapshot of each of its term's sections
+CREATE INDEX section_enrollment_snapshots_section_idx ON section_enrollment_snapshots
+    (school_id, term_collection_id, subject_code, course_number, section_sequence, term_collection_history_id DESC);
@@ -0,0 +1,3 @@
+SELECT pgmq.drop_queue('webhook_deliveries');
+
+DROP TABLE IF EXISTS seat_subscriptions;
@@ -0,0 +1,25 @@
+-- webhooks which get a signed payload when a full section opens up
+CREATE TABLE seat_subscriptions (
+    id SERIAL PRIMARY KEY,
+    section_sequence TEXT NOT NULL,
+    term_collection_id TEXT NOT NULL,
+    subject_code TEXT NOT NULL,
+    course_number TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+
+    callback_url TEXT NOT NULL,
+    -- subscribing is limited per callback host across every server
+    callback_host TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (term_collection_id, school_id) REFERENCES term_collections(id, school_id)
+);
+
+CREATE INDEX seat_subscriptions_section_idx ON seat_subscriptions
+    (school_id, term_collection_id, subject_code, course_number, section_sequence);
+
+CREATE INDEX seat_subscriptions_callback_host_idx ON seat_subscriptions (callback_host, created_at);
+
+-- every outgoing webhook goes through this queue so failed deliveries can be retried
+SELECT pgmq.create('webhook_deliveries');
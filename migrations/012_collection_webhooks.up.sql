@@ -0,0 +1,13 @@
+-- webhooks which get a signed payload when collections start and finish
+CREATE TABLE collection_webhooks (
+    id SERIAL PRIMARY KEY,
+    callback_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    -- null gets the events of every school
+    school_id TEXT,
+    -- empty gets every kind of event
+    events TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (school_id) REFERENCES schools(id) ON DELETE CASCADE
+);
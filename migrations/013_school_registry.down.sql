@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS school_registry_changed ON school_registry;
+DROP FUNCTION IF EXISTS notify_school_registry;
+DROP TABLE IF EXISTS school_registry;
@@ -0,0 +1,51 @@
+-- the schools each service collects and the settings it collects them with
+--    running orchestrators reload their schools when this changes
+CREATE TABLE school_registry (
+    school_id TEXT NOT NULL,
+    school_name TEXT NOT NULL,
+    service_name TEXT NOT NULL,
+    -- the shape depends on the service ex: base_url, semaphores, rate_limit
+    config JSONB NOT NULL DEFAULT '{}',
+    -- when a school has multiple services the lowest priority is used by default
+    priority INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (school_id, service_name)
+);
+
+CREATE OR REPLACE FUNCTION notify_school_registry()
+RETURNS TRIGGER AS $$
+BEGIN
+    -- listeners reload the whole registry so there is nothing to send
+    PERFORM pg_notify('school_registry', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER school_registry_changed
+AFTER INSERT OR UPDATE OR DELETE ON school_registry
+FOR EACH STATEMENT EXECUTE FUNCTION notify_school_registry();
+
+-- the schools which used to be in the banner service's code
+INSERT INTO school_registry (school_id, school_name, service_name, config) VALUES
+    ('marist', 'Marist University', 'Banner', '{
+        "base_url": "https://ssb1-reg.banner.marist.edu",
+        "full_collection_section_semaphore": 3,
+        "full_collection_course_semaphore": 35,
+        "regular_collection_section_semaphore": 5,
+        "max_term_count": 100,
+        "max_section_page_count": 200,
+        "request_retry_count": 3,
+        "rate_limit": {"interval": "250ms", "burst": 5, "max_increase": "500ms"}
+    }'),
+    ('temple', 'Temple University', 'Banner', '{
+        "base_url": "https://prd-xereg.temple.edu",
+        "full_collection_section_semaphore": 2,
+        "full_collection_course_semaphore": 20,
+        "regular_collection_section_semaphore": 5,
+        "max_term_count": 100,
+        "max_section_page_count": 200,
+        "request_retry_count": 3,
+        "rate_limit": {"interval": "25ms", "burst": 5, "max_increase": "50ms"}
+    }');
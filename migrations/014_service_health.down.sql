@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS term_collection_history_service_health;
+ALTER TABLE term_collection_history DROP COLUMN IF EXISTS is_incorrect_assumption;
+ALTER TABLE term_collection_history DROP COLUMN IF EXISTS service_name;
@@ -0,0 +1,7 @@
+-- which service did the collection so service managers can tell how healthy each service is
+--    collections from before this are not counted towards any service
+ALTER TABLE term_collection_history ADD COLUMN service_name TEXT;
+ALTER TABLE term_collection_history ADD COLUMN is_incorrect_assumption BOOL NOT NULL DEFAULT FALSE;
+
+CREATE INDEX term_collection_history_service_health
+ON term_collection_history (school_id, service_name, start_time DESC);
@@ -0,0 +1,18 @@
+-- when a term's registration happens so collections can be scheduled around it
+--    terms without a calendar have one estimated from their season
+CREATE TABLE term_registration_calendars (
+    term_collection_id TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+    registration_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    classes_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    add_drop_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    term_end TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    FOREIGN KEY (term_collection_id, school_id) REFERENCES term_collections(id, school_id) ON DELETE CASCADE,
+    PRIMARY KEY (term_collection_id, school_id),
+    CONSTRAINT ordered_calendar CHECK (
+        registration_start <= classes_start
+        AND classes_start <= add_drop_end
+        AND add_drop_end <= term_end
+    )
+);
@@ -0,0 +1 @@
+SELECT pgmq.drop_queue('collection_jobs_dead');
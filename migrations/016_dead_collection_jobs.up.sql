@@ -0,0 +1,2 @@
+-- collections which failed too many times or need manual changes to the service
+SELECT pgmq.create('collection_jobs_dead');
@@ -0,0 +1,22 @@
+-- recurring collections which the planner turns into collection jobs
+CREATE TABLE collection_schedules (
+    id SERIAL PRIMARY KEY,
+    school_id TEXT NOT NULL,
+    -- every still collecting term of the school when null
+    term_collection_id TEXT,
+    -- minute hour day-of-month month day-of-week ex: */15 7-21 * * *
+    cron TEXT NOT NULL,
+    -- the cron is read in this time zone
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    is_full_collection BOOLEAN NOT NULL DEFAULT FALSE,
+    -- the school's default service when null
+    service_name TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    -- the last time jobs were made for this schedule
+    last_planned_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (term_collection_id, school_id) REFERENCES term_collections(id, school_id) ON DELETE CASCADE
+);
+
+CREATE INDEX collection_schedules_school_idx ON collection_schedules (school_id);
@@ -0,0 +1,15 @@
+-- the latest metrics each running scheduler worker pool reported
+CREATE TABLE worker_pool_metrics (
+    worker_pool_id TEXT PRIMARY KEY,
+    hostname TEXT NOT NULL,
+    workers INTEGER NOT NULL,
+    busy_workers INTEGER NOT NULL,
+    -- fraction of the workers' time spent collecting since the last report
+    utilization REAL NOT NULL,
+    completed_jobs BIGINT NOT NULL,
+    failed_jobs BIGINT NOT NULL,
+    -- jobs given back because their school or service was at its limit
+    deferred_jobs BIGINT NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,20 @@
+-- enum values cannot be dropped so the type is made again without them
+DELETE FROM term_collection_history WHERE status = 'DryRun';
+UPDATE term_collection_history SET status = 'Failure' WHERE status = 'Cancelled';
+
+ALTER TYPE term_collection_status_enum RENAME TO term_collection_status_enum_old;
+CREATE TYPE term_collection_status_enum AS ENUM ('Active', 'Success', 'Failure');
+
+DROP INDEX IF EXISTS ensure_unqiue_active_collection;
+ALTER TABLE term_collection_history ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE term_collection_history ALTER COLUMN status TYPE term_collection_status_enum
+    USING status::TEXT::term_collection_status_enum;
+ALTER TABLE term_collection_history ALTER COLUMN status SET DEFAULT 'Active';
+CREATE UNIQUE INDEX ensure_unqiue_active_collection
+ON term_collection_history (term_collection_id, school_id)
+WHERE status = 'Active';
+
+DROP TYPE term_collection_status_enum_old;
+
+ALTER TABLE term_collection_history DROP COLUMN IF EXISTS heartbeat_at;
+ALTER TABLE term_collection_history DROP COLUMN IF EXISTS failure_reason;
@@ -0,0 +1,10 @@
+-- why a collection failed so interrupted collections can be told apart from broken ones
+ALTER TABLE term_collection_history ADD COLUMN failure_reason TEXT;
+-- running collections beat so ones whose process died can be found and released
+--    collections from before this fall back to their start time
+ALTER TABLE term_collection_history ADD COLUMN heartbeat_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+-- collections stopped by a shutdown or a timeout are not the fault of their service
+ALTER TYPE term_collection_status_enum ADD VALUE IF NOT EXISTS 'Cancelled';
+-- dry runs beat like active collections but do not block the term's other collections
+ALTER TYPE term_collection_status_enum ADD VALUE IF NOT EXISTS 'DryRun';
@@ -0,0 +1,24 @@
+CREATE TYPE quarantine_status_enum AS ENUM ('Pending', 'Approved', 'Rejected', 'Superseded');
+
+-- collections which would have deleted too much of their term
+--    their staged data is kept until an operator approves or rejects the move
+CREATE TABLE quarantined_collections (
+    term_collection_history_id INTEGER PRIMARY KEY,
+    term_collection_id TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+    live_sections INTEGER NOT NULL,
+    deleted_sections INTEGER NOT NULL,
+    status quarantine_status_enum NOT NULL DEFAULT 'Pending',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    decided_at TIMESTAMP WITH TIME ZONE,
+
+    FOREIGN KEY (term_collection_history_id) REFERENCES term_collection_history(id) ON DELETE CASCADE,
+    FOREIGN KEY (term_collection_id, school_id) REFERENCES term_collections(id, school_id) ON DELETE CASCADE
+);
+
+CREATE INDEX quarantined_collections_pending
+ON quarantined_collections (school_id, term_collection_id)
+WHERE status = 'Pending';
+
+-- quarantined collections wait on an operator instead of failing
+ALTER TYPE term_collection_status_enum ADD VALUE IF NOT EXISTS 'Quarantined';
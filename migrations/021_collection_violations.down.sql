@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS collection_violations_created;
+DROP INDEX IF EXISTS collection_violations_history;
+DROP TABLE IF EXISTS collection_violations;
+DROP TYPE IF EXISTS violation_severity_enum;
@@ -0,0 +1,24 @@
+CREATE TYPE violation_severity_enum AS ENUM ('Warning', 'Blocking');
+
+-- staged records which broke a validation rule
+--    blocking violations fail the collection before anything is moved
+CREATE TABLE collection_violations (
+    id SERIAL PRIMARY KEY,
+    term_collection_history_id INTEGER NOT NULL,
+    school_id TEXT NOT NULL,
+    rule TEXT NOT NULL,
+    severity violation_severity_enum NOT NULL,
+    table_name TEXT NOT NULL,
+    record_key JSONB NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (term_collection_history_id) REFERENCES term_collection_history(id) ON DELETE CASCADE
+);
+
+CREATE INDEX collection_violations_history
+ON collection_violations (term_collection_history_id);
+
+-- the reaper deletes violations past their retention
+CREATE INDEX collection_violations_created
+ON collection_violations (created_at);
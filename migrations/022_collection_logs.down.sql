@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS collection_logs_created_at;
+DROP INDEX IF EXISTS collection_logs_term;
+DROP TABLE IF EXISTS collection_logs;
@@ -0,0 +1,20 @@
+-- gzipped logs of each collection so they can be looked at after the run
+--    old logs are pruned by the leader so they do not baloon in size
+CREATE TABLE collection_logs (
+    term_collection_history_id INTEGER PRIMARY KEY,
+    school_id TEXT NOT NULL,
+    term_collection_id TEXT NOT NULL,
+    compressed_logs BYTEA NOT NULL,
+    uncompressed_bytes INTEGER NOT NULL,
+    lines INTEGER NOT NULL,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (term_collection_history_id) REFERENCES term_collection_history(id) ON DELETE CASCADE
+);
+
+CREATE INDEX collection_logs_term
+ON collection_logs (school_id, term_collection_id, term_collection_history_id DESC);
+
+CREATE INDEX collection_logs_created_at
+ON collection_logs (created_at);
@@ -0,0 +1,257 @@
+package serverget
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/go-chi/chi/v5"
+)
+
+// how often calendar clients should refetch the feed
+//
+//	keeping this reasonably short means a later collection that moves a
+//	meeting time shows up in subscribed calendars
+const CALENDAR_REFRESH_INTERVAL = 12 * time.Hour
+
+// the most octets allowed on a content line before it has to be folded (RFC 5545 3.1)
+const icalLineLimit = 75
+
+type sectionKey struct {
+	subjectCode  string
+	courseNumber string
+	sequence     string
+}
+
+// section keys are given as SUBJECT-NUMBER-SEQUENCE separated by commas
+//
+//	(subject codes, numbers and sequences are alphanumeric so `-` is a safe separator)
+func parseSectionKeys(value string) ([]sectionKey, error) {
+	keys := []sectionKey{}
+	for _, rawKey := range strings.Split(value, ",") {
+		rawKey = strings.TrimSpace(rawKey)
+		if rawKey == "" {
+			continue
+		}
+		parts := strings.Split(rawKey, "-")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("Invalid section `%s` (expected SUBJECT-NUMBER-SEQUENCE)", rawKey)
+		}
+		keys = append(keys, sectionKey{
+			subjectCode:  parts[0],
+			courseNumber: parts[1],
+			sequence:     parts[2],
+		})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("Missing query sections param")
+	}
+	return keys, nil
+}
+
+func (h *getHandler) getCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := db.New(h.dbPool)
+	schoolID := chi.URLParam(r, "schoolID")
+	termCollectionID := chi.URLParam(r, "termCollectionID")
+
+	keys, err := parseSectionKeys(r.URL.Query().Get("sections"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := db.GetSectionMeetingTimesForCalendarParams{
+		SubjectCodes:     make([]string, len(keys)),
+		CourseNumbers:    make([]string, len(keys)),
+		Sequences:        make([]string, len(keys)),
+		SchoolID:         schoolID,
+		TermCollectionID: termCollectionID,
+	}
+	for i, key := range keys {
+		params.SubjectCodes[i] = key.subjectCode
+		params.CourseNumbers[i] = key.courseNumber
+		params.Sequences[i] = key.sequence
+	}
+
+	meetingRows, err := q.GetSectionMeetingTimesForCalendar(ctx, params)
+	if err != nil {
+		h.logger.Error("Could not get calendar meeting rows", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("inline; filename=\"%s-%s.ics\"", schoolID, termCollectionID))
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(CALENDAR_REFRESH_INTERVAL.Seconds())))
+	err = writeCalendar(w, schoolID, termCollectionID, meetingRows, time.Now())
+	if err != nil {
+		h.logger.Error("Could not write calendar", "err", err)
+	}
+}
+
+type icalWriter struct {
+	w   io.Writer
+	err error
+}
+
+// writes a single content line folding it when it is too long
+func (iw *icalWriter) line(name string, value string) {
+	if iw.err != nil {
+		return
+	}
+	content := name + ":" + value
+	var folded strings.Builder
+	lineLength := 0
+	for _, r := range content {
+		runeLength := utf8.RuneLen(r)
+		if lineLength+runeLength > icalLineLimit {
+			folded.WriteString("\r\n ")
+			// the leading space counts towards the next line
+			lineLength = 1
+		}
+		folded.WriteRune(r)
+		lineLength += runeLength
+	}
+	folded.WriteString("\r\n")
+	_, iw.err = io.WriteString(iw.w, folded.String())
+}
+
+func escapeIcalText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+// times are written floating (without a zone) so they display as the school's local time
+const icalLocalTimeFormat = "20060102T150405"
+
+func writeCalendar(
+	w io.Writer,
+	schoolID string,
+	termCollectionID string,
+	meetingRows []db.GetSectionMeetingTimesForCalendarRow,
+	now time.Time,
+) error {
+	iw := icalWriter{w: w}
+	refreshInterval := fmt.Sprintf("PT%dH", int(CALENDAR_REFRESH_INTERVAL.Hours()))
+
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", "-//classy//classes//EN")
+	iw.line("CALSCALE", "GREGORIAN")
+	iw.line("METHOD", "PUBLISH")
+	iw.line("X-WR-CALNAME", escapeIcalText(fmt.Sprintf("%s %s classes", schoolID, termCollectionID)))
+	iw.line("REFRESH-INTERVAL;VALUE=DURATION", refreshInterval)
+	iw.line("X-PUBLISHED-TTL", refreshInterval)
+	for _, meeting := range meetingRows {
+		writeMeetingEvent(&iw, schoolID, termCollectionID, meeting, now)
+	}
+	iw.line("END", "VCALENDAR")
+
+	return iw.err
+}
+
+var icalWeekdays = [...]string{
+	time.Sunday:    "SU",
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+}
+
+func meetingWeekdays(meeting db.GetSectionMeetingTimesForCalendarRow) map[time.Weekday]bool {
+	return map[time.Weekday]bool{
+		time.Sunday:    meeting.IsSunday,
+		time.Monday:    meeting.IsMonday,
+		time.Tuesday:   meeting.IsTuesday,
+		time.Wednesday: meeting.IsWednesday,
+		time.Thursday:  meeting.IsThursday,
+		time.Friday:    meeting.IsFriday,
+		time.Saturday:  meeting.IsSaturday,
+	}
+}
+
+// meetings which are missing dates, times or days cannot be put on a calendar and are skipped
+func writeMeetingEvent(
+	iw *icalWriter,
+	schoolID string,
+	termCollectionID string,
+	meeting db.GetSectionMeetingTimesForCalendarRow,
+	now time.Time,
+) {
+	if !meeting.StartDate.Valid || !meeting.EndDate.Valid ||
+		!meeting.StartMinutes.Valid || !meeting.EndMinutes.Valid {
+		return
+	}
+	weekdays := meetingWeekdays(meeting)
+	byDay := []string{}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if weekdays[day] {
+			byDay = append(byDay, icalWeekdays[day])
+		}
+	}
+	if len(byDay) == 0 {
+		return
+	}
+
+	// the first event has to fall on one of the meeting days
+	startDate := meeting.StartDate.Time
+	firstDay := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	for !weekdays[firstDay.Weekday()] {
+		firstDay = firstDay.AddDate(0, 0, 1)
+	}
+	endDate := meeting.EndDate.Time
+	lastDay := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, time.UTC)
+	if firstDay.After(lastDay) {
+		return
+	}
+	start := firstDay.Add(time.Duration(meeting.StartMinutes.Microseconds) * time.Microsecond)
+	end := firstDay.Add(time.Duration(meeting.EndMinutes.Microseconds) * time.Microsecond)
+
+	summary := fmt.Sprintf("%s %s", meeting.SubjectCode, meeting.CourseNumber)
+	if meeting.Title.Valid && meeting.Title.String != "" {
+		summary += " - " + meeting.Title.String
+	}
+	description := []string{"Section: " + meeting.SectionSequence}
+	if meeting.ProfessorName.Valid {
+		description = append(description, "Professor: "+meeting.ProfessorName.String)
+	}
+	if meeting.MeetingType.Valid {
+		description = append(description, "Meeting type: "+meeting.MeetingType.String)
+	}
+
+	iw.line("BEGIN", "VEVENT")
+	// uids only depend on the keys so calendars update the event in place
+	iw.line("UID", fmt.Sprintf("%s-%s-%s-%s-%s-%d@classy",
+		schoolID,
+		termCollectionID,
+		meeting.SubjectCode,
+		meeting.CourseNumber,
+		meeting.SectionSequence,
+		meeting.Sequence,
+	))
+	iw.line("DTSTAMP", now.UTC().Format("20060102T150405Z"))
+	iw.line("DTSTART", start.Format(icalLocalTimeFormat))
+	iw.line("DTEND", end.Format(icalLocalTimeFormat))
+	iw.line("RRULE", fmt.Sprintf("FREQ=WEEKLY;BYDAY=%s;UNTIL=%s",
+		strings.Join(byDay, ","),
+		lastDay.Format(icalLocalTimeFormat),
+	))
+	iw.line("SUMMARY", escapeIcalText(summary))
+	if meeting.Campus.Valid {
+		iw.line("LOCATION", escapeIcalText(meeting.Campus.String))
+	}
+	iw.line("DESCRIPTION", escapeIcalText(strings.Join(description, "\n")))
+	iw.line("END", "VEVENT")
+}
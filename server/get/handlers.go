@@ -4,12 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"log/slog"
 
 	"github.com/Pjt727/classy/data/db"
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -208,14 +212,16 @@ func (h *getHandler) getClasses(w http.ResponseWriter, r *http.Request) {
 	q := db.New(h.dbPool)
 	limit := ctx.Value(LimitKey).(int32)
 	offset := ctx.Value(OffsetKey).(int32)
-	classRows, err := q.GetSchoolsClassesForTermOrderedBySection(ctx,
-		db.GetSchoolsClassesForTermOrderedBySectionParams{
-			SchoolID:         chi.URLParam(r, "schoolID"),
-			TermCollectionID: chi.URLParam(r, "termCollectionID"),
-			Offsetvalue:      offset,
-			Limitvalue:       limit,
-		},
-	)
+	params, err := classFilterParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params.SchoolID = chi.URLParam(r, "schoolID")
+	params.TermCollectionID = chi.URLParam(r, "termCollectionID")
+	params.Offsetvalue = offset
+	params.Limitvalue = limit
+	classRows, err := q.GetSchoolsClassesForTermOrderedBySection(ctx, params)
 	if err != nil {
 		h.logger.Error("Could not get class rows", "err", err)
 		http.Error(w, http.StatusText(500), 500)
@@ -236,6 +242,236 @@ func (h *getHandler) getClasses(w http.ResponseWriter, r *http.Request) {
 	w.Write(classRowsJSON)
 }
 
+func (h *getHandler) getSectionEnrollmentHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := db.New(h.dbPool)
+	limit := ctx.Value(LimitKey).(int32)
+	offset := ctx.Value(OffsetKey).(int32)
+	keys, err := parseSectionKeys(chi.URLParam(r, "section"))
+	if err != nil || len(keys) != 1 {
+		http.Error(w, "Invalid section (expected SUBJECT-NUMBER-SEQUENCE)", http.StatusBadRequest)
+		return
+	}
+	historyRows, err := q.GetSectionEnrollmentHistory(ctx, db.GetSectionEnrollmentHistoryParams{
+		SchoolID:         chi.URLParam(r, "schoolID"),
+		TermCollectionID: chi.URLParam(r, "termCollectionID"),
+		SubjectCode:      keys[0].subjectCode,
+		CourseNumber:     keys[0].courseNumber,
+		SectionSequence:  keys[0].sequence,
+		Offsetvalue:      offset,
+		Limitvalue:       limit,
+	})
+	if err != nil {
+		h.logger.Error("Could not get section enrollment history rows", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	historyRows, didLimit := normalizeLimits(historyRows, limit)
+	addPaginationLinks(&w, r, offset, limit, didLimit)
+
+	historyRowsJSON, err := json.Marshal(historyRows)
+	if err != nil {
+		h.logger.Error("Could not marshal section enrollment history rows", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(historyRowsJSON)
+}
+
+type fillRatePoint struct {
+	db.GetCourseFillRateRow
+	// null when none of the sections have a max enrollment
+	FillRate *float64 `json:"fill_rate"`
+}
+
+func (h *getHandler) getCourseFillRate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := db.New(h.dbPool)
+	fillRateRows, err := q.GetCourseFillRate(ctx, db.GetCourseFillRateParams{
+		SchoolID:         chi.URLParam(r, "schoolID"),
+		TermCollectionID: chi.URLParam(r, "termCollectionID"),
+		SubjectCode:      chi.URLParam(r, "subjectCode"),
+		CourseNumber:     chi.URLParam(r, "courseNumber"),
+	})
+	if err != nil {
+		h.logger.Error("Could not get course fill rate rows", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	fillRate := make([]fillRatePoint, len(fillRateRows))
+	for i, fillRateRow := range fillRateRows {
+		fillRate[i] = fillRatePoint{GetCourseFillRateRow: fillRateRow}
+		if fillRateRow.MaxEnrollment > 0 {
+			rate := float64(fillRateRow.Enrollment) / float64(fillRateRow.MaxEnrollment)
+			fillRate[i].FillRate = &rate
+		}
+	}
+
+	fillRateJSON, err := json.Marshal(fillRate)
+	if err != nil {
+		h.logger.Error("Could not marshal course fill rate rows", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(fillRateJSON)
+}
+
+// builds the section filters from the query params
+// the filtering itself is done in the query so it can use the indexes
+func classFilterParams(queryParams url.Values) (db.GetSchoolsClassesForTermOrderedBySectionParams, error) {
+	params := db.GetSchoolsClassesForTermOrderedBySectionParams{
+		SubjectCode:        optionalText(queryParams.Get("subject")),
+		Campus:             optionalText(queryParams.Get("campus")),
+		InstructionMethod:  optionalText(queryParams.Get("instructionMethod")),
+		PrimaryProfessorID: optionalText(queryParams.Get("professorId")),
+		MeetingType:        optionalText(queryParams.Get("meetingType")),
+	}
+
+	if hasOpenSeats := queryParams.Get("hasOpenSeats"); hasOpenSeats != "" {
+		parsedHasOpenSeats, err := strconv.ParseBool(hasOpenSeats)
+		if err != nil {
+			return params, fmt.Errorf("Invalid query hasOpenSeats param")
+		}
+		params.HasOpenSeats = parsedHasOpenSeats
+	}
+
+	// sections which meet on a day that was not asked for are excluded
+	if days := queryParams.Get("days"); days != "" {
+		allowedDays := make(map[string]bool)
+		for _, day := range strings.Split(days, ",") {
+			day = strings.ToLower(strings.TrimSpace(day))
+			switch day {
+			case "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday":
+				allowedDays[day] = true
+			default:
+				return params, fmt.Errorf("Invalid day `%s` in query days param", day)
+			}
+		}
+		params.ExcludeMonday = !allowedDays["monday"]
+		params.ExcludeTuesday = !allowedDays["tuesday"]
+		params.ExcludeWednesday = !allowedDays["wednesday"]
+		params.ExcludeThursday = !allowedDays["thursday"]
+		params.ExcludeFriday = !allowedDays["friday"]
+		params.ExcludeSaturday = !allowedDays["saturday"]
+		params.ExcludeSunday = !allowedDays["sunday"]
+	}
+
+	var err error
+	if params.StartAfter, err = optionalTime(queryParams.Get("startAfter")); err != nil {
+		return params, fmt.Errorf("Invalid query startAfter param (expected HH:MM)")
+	}
+	if params.StartBefore, err = optionalTime(queryParams.Get("startBefore")); err != nil {
+		return params, fmt.Errorf("Invalid query startBefore param (expected HH:MM)")
+	}
+	if params.EndAfter, err = optionalTime(queryParams.Get("endAfter")); err != nil {
+		return params, fmt.Errorf("Invalid query endAfter param (expected HH:MM)")
+	}
+	if params.EndBefore, err = optionalTime(queryParams.Get("endBefore")); err != nil {
+		return params, fmt.Errorf("Invalid query endBefore param (expected HH:MM)")
+	}
+
+	return params, nil
+}
+
+type searchFacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type searchResult struct {
+	Courses []db.SearchCoursesRow         `json:"courses"`
+	Facets  map[string][]searchFacetValue `json:"facets"`
+}
+
+func (h *getHandler) searchCourses(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := db.New(h.dbPool)
+	limit := ctx.Value(LimitKey).(int32)
+	offset := ctx.Value(OffsetKey).(int32)
+
+	queryParams := r.URL.Query()
+	searchText := queryParams.Get("q")
+	if searchText == "" {
+		http.Error(w, "Missing search query param `q`", http.StatusBadRequest)
+		return
+	}
+	var creditHours pgtype.Float4
+	if queryCreditHours := queryParams.Get("creditHours"); queryCreditHours != "" {
+		parsedCreditHours, err := strconv.ParseFloat(queryCreditHours, 32)
+		if err != nil {
+			http.Error(w, "Invalid query creditHours param", http.StatusBadRequest)
+			return
+		}
+		creditHours = pgtype.Float4{Float32: float32(parsedCreditHours), Valid: true}
+	}
+	schoolID := chi.URLParam(r, "schoolID")
+	subjectCode := optionalText(queryParams.Get("subject"))
+	campus := optionalText(queryParams.Get("campus"))
+	instructionMethod := optionalText(queryParams.Get("instructionMethod"))
+
+	courseRows, err := q.SearchCourses(ctx, db.SearchCoursesParams{
+		SchoolID:          schoolID,
+		SearchText:        searchText,
+		SubjectCode:       subjectCode,
+		CreditHours:       creditHours,
+		Campus:            campus,
+		InstructionMethod: instructionMethod,
+		Offsetvalue:       offset,
+		Limitvalue:        limit,
+	})
+	if err != nil {
+		h.logger.Error("Could not get course search rows", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	facetRows, err := q.SearchCourseFacets(ctx, db.SearchCourseFacetsParams{
+		SchoolID:          schoolID,
+		SearchText:        searchText,
+		SubjectCode:       subjectCode,
+		CreditHours:       creditHours,
+		Campus:            campus,
+		InstructionMethod: instructionMethod,
+	})
+	if err != nil {
+		h.logger.Error("Could not get course search facet rows", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	courseRows, didLimit := normalizeLimits(courseRows, limit)
+	addPaginationLinks(&w, r, offset, limit, didLimit)
+
+	result := searchResult{
+		Courses: courseRows,
+		Facets:  make(map[string][]searchFacetValue),
+	}
+	if result.Courses == nil {
+		result.Courses = []db.SearchCoursesRow{}
+	}
+	for _, facetRow := range facetRows {
+		result.Facets[facetRow.Facet] = append(result.Facets[facetRow.Facet], searchFacetValue{
+			Value: facetRow.Value,
+			Count: facetRow.Count,
+		})
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		h.logger.Error("Could not marshal course search rows", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resultJSON)
+}
+
 func (h *getHandler) verifyCourse(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -330,3 +566,24 @@ func addPaginationLinks(w *http.ResponseWriter, req *http.Request, currentOffset
 
 	(*w).Header().Set("Link", linkHeader)
 }
+
+// empty query params are treated as not given
+func optionalText(value string) pgtype.Text {
+	if value == "" {
+		return pgtype.Text{String: "", Valid: false}
+	}
+	return pgtype.Text{String: value, Valid: true}
+}
+
+// parses a 24 hour HH:MM time of day
+func optionalTime(value string) (pgtype.Time, error) {
+	if value == "" {
+		return pgtype.Time{Microseconds: 0, Valid: false}, nil
+	}
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return pgtype.Time{}, err
+	}
+	microseconds := (int64(parsed.Hour())*60 + int64(parsed.Minute())) * int64(time.Minute/time.Microsecond)
+	return pgtype.Time{Microseconds: microseconds, Valid: true}, nil
+}
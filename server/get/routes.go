@@ -3,6 +3,7 @@ package serverget
 import (
 	"context"
 	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -22,6 +23,7 @@ func PopulateGetRoutes(r *chi.Router, pool *pgxpool.Pool, logger slog.Logger) {
 	(*r).Route("/{schoolID}", func(r chi.Router) {
 		r.Use(getHandler.verifySchool)
 		r.Get("/", getHandler.getSchoolTerms)
+		r.Get("/search", getHandler.searchCourses)
 
 		r.Route("/courses", func(r chi.Router) {
 			r.Get("/", getHandler.getCourses)
@@ -38,19 +40,31 @@ func PopulateGetRoutes(r *chi.Router, pool *pgxpool.Pool, logger slog.Logger) {
 			r.Use(getHandler.verifyTermCollection)
 			r.Get("/", getHandler.getTermHueristics)
 			r.Get("/classes", getHandler.getClasses)
+			r.Get("/classes/{section}/enrollment-history", getHandler.getSectionEnrollmentHistory)
+			r.Get("/courses/{subjectCode}/{courseNumber}/fill-rate", getHandler.getCourseFillRate)
+			r.Get("/calendar.ics", getHandler.getCalendar)
+			r.Get("/schedules", getHandler.getSchedules)
 		})
 	})
 }
 
+const DEFAULT_LIMIT = 200
+
+// larger limits are lowered to this
+const MAX_LIMIT = 1000
+
+// offsets past this are rejected so that offset + limit stays within an int32
+const MAX_OFFSET = math.MaxInt32 - MAX_LIMIT - 1
+
 func populatePagnation(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		offset := 0
-		limit := 200
+		limit := DEFAULT_LIMIT
 		queryOffset := r.URL.Query().Get("offset")
 		if queryOffset != "" {
 			newOffset, err := strconv.Atoi(queryOffset)
-			if err != nil {
+			if err != nil || newOffset < 0 || newOffset > MAX_OFFSET {
 				http.Error(w, "Invalid query offset param", http.StatusBadRequest)
 				return
 			}
@@ -59,11 +73,11 @@ func populatePagnation(next http.Handler) http.Handler {
 		queryLimit := r.URL.Query().Get("limit")
 		if queryLimit != "" {
 			setLimit, err := strconv.Atoi(queryLimit)
-			if err != nil {
+			if err != nil || setLimit < 0 {
 				http.Error(w, "Invalid query limit param", http.StatusBadRequest)
 				return
 			}
-			limit = setLimit
+			limit = min(setLimit, MAX_LIMIT)
 		}
 		ctx = context.WithValue(ctx, OffsetKey, int32(offset))
 		ctx = context.WithValue(ctx, LimitKey, int32(limit))
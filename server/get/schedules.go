@@ -0,0 +1,613 @@
+package serverget
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// the number of courses that can be put into one schedule
+//
+//	the amount of combinations grows very quickly so this is kept small
+const MAX_SCHEDULE_COURSES = 10
+
+type courseKey struct {
+	subjectCode string
+	number      string
+}
+
+func (c courseKey) String() string {
+	return c.subjectCode + "-" + c.number
+}
+
+func (s sectionKey) String() string {
+	return s.subjectCode + "-" + s.courseNumber + "-" + s.sequence
+}
+
+// course keys are given as SUBJECT-NUMBER separated by commas
+func parseCourseKeys(value string) ([]courseKey, error) {
+	keys := []courseKey{}
+	for _, rawKey := range strings.Split(value, ",") {
+		rawKey = strings.TrimSpace(rawKey)
+		if rawKey == "" {
+			continue
+		}
+		parts := strings.Split(rawKey, "-")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid course `%s` (expected SUBJECT-NUMBER)", rawKey)
+		}
+		key := courseKey{subjectCode: parts[0], number: parts[1]}
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("Missing query courses param")
+	}
+	return keys, nil
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "sunday":
+		return time.Sunday, nil
+	case "monday":
+		return time.Monday, nil
+	case "tuesday":
+		return time.Tuesday, nil
+	case "wednesday":
+		return time.Wednesday, nil
+	case "thursday":
+		return time.Thursday, nil
+	case "friday":
+		return time.Friday, nil
+	case "saturday":
+		return time.Saturday, nil
+	}
+	return time.Sunday, fmt.Errorf("Invalid day `%s`", value)
+}
+
+// a meeting time reduced to what is needed to compare it with others
+type scheduleMeeting struct {
+	days      [7]bool // indexed by time.Weekday
+	hasTime   bool
+	start     int // minutes from midnight
+	end       int
+	startDate pgtype.Timestamp
+	endDate   pgtype.Timestamp
+}
+
+type scheduleSection struct {
+	key      sectionKey
+	row      db.GetSectionsForCoursesRow
+	meetings []scheduleMeeting
+}
+
+// times come out of the json aggregate as HH:MM:SS
+func parseClockMinutes(value string) (int, error) {
+	parsed, err := time.Parse("15:04:05", value)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+func newScheduleSection(row db.GetSectionsForCoursesRow) (scheduleSection, error) {
+	section := scheduleSection{
+		key: sectionKey{
+			subjectCode:  row.Section.SubjectCode,
+			courseNumber: row.Section.CourseNumber,
+			sequence:     row.Section.Sequence,
+		},
+		row:      row,
+		meetings: make([]scheduleMeeting, 0, len(row.MeetingTimes)),
+	}
+	for _, meetingTime := range row.MeetingTimes {
+		meeting := scheduleMeeting{
+			startDate: meetingTime.StartDate,
+			endDate:   meetingTime.EndDate,
+		}
+		meeting.days[time.Sunday] = meetingTime.IsSunday
+		meeting.days[time.Monday] = meetingTime.IsMonday
+		meeting.days[time.Tuesday] = meetingTime.IsTuesday
+		meeting.days[time.Wednesday] = meetingTime.IsWednesday
+		meeting.days[time.Thursday] = meetingTime.IsThursday
+		meeting.days[time.Friday] = meetingTime.IsFriday
+		meeting.days[time.Saturday] = meetingTime.IsSaturday
+		if meetingTime.StartMinutes.Valid && meetingTime.EndMinutes.Valid {
+			var err error
+			if meeting.start, err = parseClockMinutes(meetingTime.StartMinutes.String); err != nil {
+				return section, err
+			}
+			if meeting.end, err = parseClockMinutes(meetingTime.EndMinutes.String); err != nil {
+				return section, err
+			}
+			meeting.hasTime = true
+		}
+		section.meetings = append(section.meetings, meeting)
+	}
+	return section, nil
+}
+
+// unknown dates are assumed to span the whole term
+func datesOverlap(a scheduleMeeting, b scheduleMeeting) bool {
+	if a.startDate.Valid && b.endDate.Valid && a.startDate.Time.After(b.endDate.Time) {
+		return false
+	}
+	if b.startDate.Valid && a.endDate.Valid && b.startDate.Time.After(a.endDate.Time) {
+		return false
+	}
+	return true
+}
+
+// meetings without times (like asynchronous online classes) never conflict
+func meetingsConflict(a scheduleMeeting, b scheduleMeeting) bool {
+	if !a.hasTime || !b.hasTime {
+		return false
+	}
+	if a.start >= b.end || b.start >= a.end {
+		return false
+	}
+	sharesDay := false
+	for day := range a.days {
+		if a.days[day] && b.days[day] {
+			sharesDay = true
+			break
+		}
+	}
+	return sharesDay && datesOverlap(a, b)
+}
+
+func sectionsConflict(a scheduleSection, b scheduleSection) bool {
+	for _, aMeeting := range a.meetings {
+		for _, bMeeting := range b.meetings {
+			if meetingsConflict(aMeeting, bMeeting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// constraints on the schedules that get generated
+//
+//	a negative value means the constraint was not given
+type scheduleConstraints struct {
+	earliestStart int
+	latestEnd     int
+	maxGap        int
+	daysOff       [7]bool
+	hasOpenSeats  bool
+}
+
+func scheduleConstraintsFromQuery(queryParams url.Values) (scheduleConstraints, error) {
+	constraints := scheduleConstraints{
+		earliestStart: -1,
+		latestEnd:     -1,
+		maxGap:        -1,
+	}
+	var err error
+	if earliestStart := queryParams.Get("earliestStart"); earliestStart != "" {
+		if constraints.earliestStart, err = parseQueryClockMinutes(earliestStart); err != nil {
+			return constraints, fmt.Errorf("Invalid query earliestStart param (expected HH:MM)")
+		}
+	}
+	if latestEnd := queryParams.Get("latestEnd"); latestEnd != "" {
+		if constraints.latestEnd, err = parseQueryClockMinutes(latestEnd); err != nil {
+			return constraints, fmt.Errorf("Invalid query latestEnd param (expected HH:MM)")
+		}
+	}
+	if maxGap := queryParams.Get("maxGap"); maxGap != "" {
+		if constraints.maxGap, err = strconv.Atoi(maxGap); err != nil || constraints.maxGap < 0 {
+			return constraints, fmt.Errorf("Invalid query maxGap param (expected minutes)")
+		}
+	}
+	if daysOff := queryParams.Get("daysOff"); daysOff != "" {
+		for _, rawDay := range strings.Split(daysOff, ",") {
+			day, err := parseWeekday(rawDay)
+			if err != nil {
+				return constraints, fmt.Errorf("Invalid query daysOff param: %w", err)
+			}
+			constraints.daysOff[day] = true
+		}
+	}
+	if hasOpenSeats := queryParams.Get("hasOpenSeats"); hasOpenSeats != "" {
+		if constraints.hasOpenSeats, err = strconv.ParseBool(hasOpenSeats); err != nil {
+			return constraints, fmt.Errorf("Invalid query hasOpenSeats param")
+		}
+	}
+	return constraints, nil
+}
+
+func parseQueryClockMinutes(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// the constraints which only depend on a single section
+func (c scheduleConstraints) allowsSection(section scheduleSection) bool {
+	if c.hasOpenSeats {
+		enrollment := section.row.Section.Enrollment
+		maxEnrollment := section.row.Section.MaxEnrollment
+		if enrollment.Valid && maxEnrollment.Valid && enrollment.Int32 >= maxEnrollment.Int32 {
+			return false
+		}
+	}
+	for _, meeting := range section.meetings {
+		if !meeting.hasTime {
+			continue
+		}
+		for day, meets := range meeting.days {
+			if meets && c.daysOff[day] {
+				return false
+			}
+		}
+		if c.earliestStart >= 0 && meeting.start < c.earliestStart {
+			return false
+		}
+		if c.latestEnd >= 0 && meeting.end > c.latestEnd {
+			return false
+		}
+	}
+	return true
+}
+
+type clockInterval struct {
+	start int
+	end   int
+}
+
+// the timed meetings of the sections on each day sorted by start
+func dayIntervals(sections ...[]scheduleSection) [7][]clockInterval {
+	var days [7][]clockInterval
+	for _, group := range sections {
+		for _, section := range group {
+			for _, meeting := range section.meetings {
+				if !meeting.hasTime {
+					continue
+				}
+				for day, meets := range meeting.days {
+					if meets {
+						days[day] = append(days[day], clockInterval{start: meeting.start, end: meeting.end})
+					}
+				}
+			}
+		}
+	}
+	for day := range days {
+		slices.SortFunc(days[day], func(a, b clockInterval) int { return a.start - b.start })
+	}
+	return days
+}
+
+// the longest part of start to end which none of the intervals cover
+func largestGap(start int, end int, intervals []clockInterval) int {
+	largest := 0
+	covered := start
+	for _, interval := range intervals {
+		if interval.start >= end {
+			break
+		}
+		if interval.start > covered {
+			largest = max(largest, interval.start-covered)
+		}
+		covered = max(covered, interval.end)
+	}
+	return max(largest, end-covered)
+}
+
+// the constraints which depend on the whole schedule
+//
+//	gaps between chosen meetings can only shrink as more meetings are added so a partial schedule is
+//	rejected when one of its gaps stays too large even with the meetings of every remaining section
+func (c scheduleConstraints) allowsSchedule(chosen [7][]clockInterval, remaining [7][]clockInterval) bool {
+	if c.maxGap < 0 {
+		return true
+	}
+	for day, meetings := range chosen {
+		latestEnd := 0
+		for i := 1; i < len(meetings); i++ {
+			latestEnd = max(latestEnd, meetings[i-1].end)
+			if meetings[i].start-latestEnd <= c.maxGap {
+				continue
+			}
+			if largestGap(latestEnd, meetings[i].start, remaining[day]) > c.maxGap {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// the most sections that are tried in a single search
+//
+//	when few schedules fit the constraints the search would otherwise try nearly every combination
+const MAX_SCHEDULE_SEARCH_STEPS = 100_000
+
+// enumerates combinations of one section per course which do not overlap
+//
+//	stops after maxSchedules have been found or MAX_SCHEDULE_SEARCH_STEPS sections were tried
+//	in which case the schedules are not complete
+func generateSchedules(
+	courseSections [][]scheduleSection,
+	constraints scheduleConstraints,
+	maxSchedules int,
+) ([][]scheduleSection, bool) {
+	schedules := [][]scheduleSection{}
+	if len(courseSections) == 0 {
+		return schedules, true
+	}
+
+	// courses with the fewest options are tried first to prune the search early
+	order := make([]int, len(courseSections))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return len(courseSections[a]) - len(courseSections[b])
+	})
+
+	// the meetings that could still be added after each depth
+	remaining := make([][7][]clockInterval, len(order)+1)
+	if constraints.maxGap >= 0 {
+		for depth := range order {
+			remainingSections := make([][]scheduleSection, 0, len(order)-depth)
+			for _, courseIndex := range order[depth:] {
+				remainingSections = append(remainingSections, courseSections[courseIndex])
+			}
+			remaining[depth] = dayIntervals(remainingSections...)
+		}
+	}
+
+	chosen := make([]scheduleSection, len(courseSections))
+	chosenInOrder := make([]scheduleSection, 0, len(courseSections))
+	steps := 0
+	complete := true
+	var search func(depth int) bool
+	search = func(depth int) bool {
+		if depth == len(order) {
+			schedules = append(schedules, slices.Clone(chosen))
+			return len(schedules) < maxSchedules
+		}
+		courseIndex := order[depth]
+		for _, candidate := range courseSections[courseIndex] {
+			steps++
+			if steps > MAX_SCHEDULE_SEARCH_STEPS {
+				complete = false
+				return false
+			}
+			conflicts := false
+			for _, previous := range chosenInOrder {
+				if sectionsConflict(candidate, previous) {
+					conflicts = true
+					break
+				}
+			}
+			if conflicts {
+				continue
+			}
+			chosenInOrder = append(chosenInOrder, candidate)
+			allowed := constraints.maxGap < 0 ||
+				constraints.allowsSchedule(dayIntervals(chosenInOrder), remaining[depth+1])
+			if allowed {
+				chosen[courseIndex] = candidate
+				if !search(depth + 1) {
+					return false
+				}
+			}
+			chosenInOrder = chosenInOrder[:depth]
+		}
+		return true
+	}
+	search(0)
+
+	return schedules, complete
+}
+
+type sectionConflict struct {
+	Sections [2]string `json:"sections"`
+}
+
+type conflictCheckResult struct {
+	HasConflicts    bool              `json:"has_conflicts"`
+	Conflicts       []sectionConflict `json:"conflicts"`
+	MissingSections []string          `json:"missing_sections"`
+}
+
+type scheduleResult struct {
+	Schedules          [][]db.GetSectionsForCoursesRow `json:"schedules"`
+	UnavailableCourses []string                        `json:"unavailable_courses"`
+	// the search was stopped early so there may be schedules that were not found
+	IsIncomplete bool `json:"is_incomplete"`
+}
+
+// generates schedules from the `courses` query param or
+// checks the sections from the `sections` query param for conflicts
+func (h *getHandler) getSchedules(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	hasCourses := queryParams.Get("courses") != ""
+	hasSections := queryParams.Get("sections") != ""
+	if hasCourses == hasSections {
+		http.Error(w, "Exactly one of the query params `courses` or `sections` must be given", http.StatusBadRequest)
+		return
+	}
+	if hasSections {
+		h.checkSectionConflicts(w, r)
+		return
+	}
+	h.generateSchedules(w, r)
+}
+
+func (h *getHandler) getScheduleSections(r *http.Request, courses []courseKey) ([]scheduleSection, error) {
+	ctx := r.Context()
+	q := db.New(h.dbPool)
+	params := db.GetSectionsForCoursesParams{
+		SubjectCodes:     make([]string, len(courses)),
+		CourseNumbers:    make([]string, len(courses)),
+		SchoolID:         chi.URLParam(r, "schoolID"),
+		TermCollectionID: chi.URLParam(r, "termCollectionID"),
+	}
+	for i, course := range courses {
+		params.SubjectCodes[i] = course.subjectCode
+		params.CourseNumbers[i] = course.number
+	}
+	sectionRows, err := q.GetSectionsForCourses(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	sections := make([]scheduleSection, 0, len(sectionRows))
+	for _, sectionRow := range sectionRows {
+		section, err := newScheduleSection(sectionRow)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+func (h *getHandler) generateSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	limit := ctx.Value(LimitKey).(int32)
+	offset := ctx.Value(OffsetKey).(int32)
+	queryParams := r.URL.Query()
+
+	courses, err := parseCourseKeys(queryParams.Get("courses"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(courses) > MAX_SCHEDULE_COURSES {
+		http.Error(w,
+			fmt.Sprintf("At most %d courses can be put into a schedule", MAX_SCHEDULE_COURSES),
+			http.StatusBadRequest,
+		)
+		return
+	}
+	constraints, err := scheduleConstraintsFromQuery(queryParams)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sections, err := h.getScheduleSections(r, courses)
+	if err != nil {
+		h.logger.Error("Could not get schedule sections", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	result := scheduleResult{
+		Schedules:          [][]db.GetSectionsForCoursesRow{},
+		UnavailableCourses: []string{},
+	}
+	courseSections := make([][]scheduleSection, len(courses))
+	for _, section := range sections {
+		if !constraints.allowsSection(section) {
+			continue
+		}
+		courseIndex := slices.Index(courses, courseKey{
+			subjectCode: section.key.subjectCode,
+			number:      section.key.courseNumber,
+		})
+		courseSections[courseIndex] = append(courseSections[courseIndex], section)
+	}
+	for i, course := range courses {
+		if len(courseSections[i]) == 0 {
+			result.UnavailableCourses = append(result.UnavailableCourses, course.String())
+		}
+	}
+
+	if len(result.UnavailableCourses) == 0 {
+		schedules, complete := generateSchedules(courseSections, constraints, int(offset)+int(limit)+1)
+		result.IsIncomplete = !complete
+		schedules = schedules[min(int(offset), len(schedules)):]
+		schedules, didLimit := normalizeLimits(schedules, limit)
+		addPaginationLinks(&w, r, offset, limit, didLimit)
+		for _, schedule := range schedules {
+			scheduleRows := make([]db.GetSectionsForCoursesRow, len(schedule))
+			for i, section := range schedule {
+				scheduleRows[i] = section.row
+			}
+			result.Schedules = append(result.Schedules, scheduleRows)
+		}
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		h.logger.Error("Could not marshal schedules", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resultJSON)
+}
+
+func (h *getHandler) checkSectionConflicts(w http.ResponseWriter, r *http.Request) {
+	requestedKeys, err := parseSectionKeys(r.URL.Query().Get("sections"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	courses := []courseKey{}
+	for _, key := range requestedKeys {
+		course := courseKey{subjectCode: key.subjectCode, number: key.courseNumber}
+		if !slices.Contains(courses, course) {
+			courses = append(courses, course)
+		}
+	}
+
+	courseSections, err := h.getScheduleSections(r, courses)
+	if err != nil {
+		h.logger.Error("Could not get schedule sections", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	result := conflictCheckResult{
+		Conflicts:       []sectionConflict{},
+		MissingSections: []string{},
+	}
+	requestedSections := []scheduleSection{}
+	for _, key := range requestedKeys {
+		i := slices.IndexFunc(courseSections, func(s scheduleSection) bool { return s.key == key })
+		if i == -1 {
+			result.MissingSections = append(result.MissingSections, key.String())
+			continue
+		}
+		requestedSections = append(requestedSections, courseSections[i])
+	}
+	for i, a := range requestedSections {
+		for _, b := range requestedSections[i+1:] {
+			if sectionsConflict(a, b) {
+				result.Conflicts = append(result.Conflicts, sectionConflict{
+					Sections: [2]string{a.key.String(), b.key.String()},
+				})
+			}
+		}
+	}
+	result.HasConflicts = len(result.Conflicts) > 0
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		h.logger.Error("Could not marshal section conflicts", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resultJSON)
+}
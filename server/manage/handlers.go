@@ -1,19 +1,25 @@
 package servermanage
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"log/slog"
 
 	"github.com/Pjt727/classy/collection"
+	"github.com/Pjt727/classy/collection/services/banner"
 	test_banner "github.com/Pjt727/classy/collection/services/banner/testbanner"
+	"github.com/Pjt727/classy/collection/services/fileimport"
 	"github.com/Pjt727/classy/data/db"
 	logginghelpers "github.com/Pjt727/classy/data/logging-helpers"
 	dbhelpers "github.com/Pjt727/classy/data/testdb"
@@ -35,6 +41,9 @@ const (
 const DEFAULT_SCHEDULING_LIMIT = 15
 const DEFAULT_TOKEN_EXPIRY = 5 * time.Minute
 
+// rules broken by each collection are shown in a single row
+const MAX_DASHBOARD_VIOLATIONS = 25
+
 // auth for management is in memory as the expected number of users authenticated
 // is tiny
 const UserCookieName = "user_token"
@@ -129,7 +138,12 @@ var loggingOptions = &logginghelpers.Options{
 	NoColor:   false,
 }
 
-func getManageHandler(pool *pgxpool.Pool, testPool *pgxpool.Pool, logger *slog.Logger) *manageHandler {
+func getManageHandler(
+	defaultOrchestrator *collection.Orchestrator,
+	pool *pgxpool.Pool,
+	testPool *pgxpool.Pool,
+	logger *slog.Logger,
+) *manageHandler {
 
 	testServices := make([]collection.Service, 0)
 
@@ -170,8 +184,6 @@ func getManageHandler(pool *pgxpool.Pool, testPool *pgxpool.Pool, logger *slog.L
 		baseLogger:            baseLogger,
 		testBaseLogger:        testBaseLogger,
 	}
-	defaultOrchestrator := collection.GetDefaultOrchestrator(pool)
-
 	testOrchestrator, err := collection.CreateOrchestrator(
 		testServices,
 		slog.New(frameLogger),
@@ -181,7 +193,7 @@ func getManageHandler(pool *pgxpool.Pool, testPool *pgxpool.Pool, logger *slog.L
 		slog.Warn("Testing orchestrator could not be made", "err", err)
 	}
 	managementOrchestrator := &components.ManagementOrchestrator{
-		O:     &defaultOrchestrator,
+		O:     defaultOrchestrator,
 		Name:  "Default Orch",
 		Label: h.lastOrchestratorLabel,
 	}
@@ -251,6 +263,34 @@ func getJobCollections(ctx context.Context, q *db.Queries) ([]*components.QueueC
 	return queueMessages, nil
 }
 
+func getDeadCollectionJobs(ctx context.Context, q *db.Queries) ([]*components.DeadCollectionMessage, error) {
+	rows, err := q.ViewDeadQueue(ctx, DEFAULT_SCHEDULING_LIMIT)
+	deadMessages := make([]*components.DeadCollectionMessage, len(rows))
+	if err != nil {
+		return deadMessages, err
+	}
+	for i, row := range rows {
+		var message collection.DeadCollectionMessage
+		err := json.Unmarshal(row.Message, &message)
+		if err != nil {
+			return deadMessages, err
+		}
+
+		deadMessages[i] = &components.DeadCollectionMessage{
+			DeadJobID:             row.MessageID,
+			TermCollectionID:      message.Collection.TermCollectionID,
+			SchoolID:              message.Collection.SchoolID,
+			ServiceName:           message.Collection.ServiceName,
+			Error:                 message.Error,
+			Attempts:              message.Attempts,
+			IsIncorrectAssumption: message.IsIncorrectAssumption,
+			DiedAt:                message.DiedAt,
+		}
+	}
+
+	return deadMessages, nil
+}
+
 func (h *manageHandler) loginView(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	err := components.Login().Render(r.Context(), w)
@@ -342,7 +382,69 @@ func (h *manageHandler) dashboardHome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = components.Dashboard(managementOrchs, queueMessages).Render(ctx, w)
+	deadMessages, err := getDeadCollectionJobs(ctx, q)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get dead collection jobs", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	var workerStatus components.WorkerStatus
+	workerStatus.QueueDepth, err = q.GetCollectionQueueDepth(ctx)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get collection queue depth", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	// pools which stopped without cleaning up stop reporting
+	workerStatus.Pools, err = q.ListRecentWorkerPoolMetrics(ctx, int32(3*collection.WORKER_METRICS_INTERVAL.Seconds()))
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get worker pool metrics", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	quarantinedCollections, err := q.ListPendingQuarantinedCollections(ctx)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get quarantined collections", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	violations, err := q.ListRecentCollectionViolations(ctx, MAX_DASHBOARD_VIOLATIONS)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get collection violations", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	importTargets, err := h.getImportTargets()
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get file import targets", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	// just using the first orchestrator maybe change
+	var serviceDecisions []collection.SchoolServiceDecision
+	if o, ok := h.orchestrators[0]; ok {
+		// collections are usually done by other orchestrators
+		if err := o.data.O.RefreshServiceHealth(ctx); err != nil {
+			h.baseLogger.ErrorContext(ctx, "Could not refresh service health", "error", err)
+		}
+		serviceDecisions = o.data.O.GetServiceDecisions()
+	}
+
+	err = components.Dashboard(
+		managementOrchs,
+		queueMessages,
+		deadMessages,
+		workerStatus,
+		quarantinedCollections,
+		violations,
+		importTargets,
+		serviceDecisions,
+	).Render(ctx, w)
 
 	if err != nil {
 		h.baseLogger.ErrorContext(ctx, "Could not render dashboard home component err", "error", err)
@@ -538,6 +640,58 @@ func (h *manageHandler) deleteCollectionJob(w http.ResponseWriter, r *http.Reque
 	notify(w, r, components.NotifySuccess, fmt.Sprintf("Canceled colection job: %s", collectionJobIdStr))
 }
 
+func (h *manageHandler) requeueDeadCollectionJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	err := r.ParseForm()
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not parse form: "+err.Error())
+		return
+	}
+
+	deadJobId, err := strconv.Atoi(r.Form.Get("deadJobId"))
+	if err != nil {
+		notify(w, r, components.NotifyError, "Dead job Id is not an integer: "+err.Error())
+		return
+	}
+
+	message, err := collection.RequeueDeadCollectionJob(ctx, h.DbPool, int32(deadJobId))
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not requeue dead collection job", "error", err)
+		notify(w, r, components.NotifyError, "Could not requeue dead collection job")
+		return
+	}
+
+	q := db.New(h.DbPool)
+	queueMessages, err := getJobCollections(ctx, q)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get collection job queue", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	deadMessages, err := getDeadCollectionJobs(ctx, q)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get dead collection jobs", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	err = components.ManageScheduling(queueMessages).Render(ctx, w)
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not render scheduling component"+err.Error())
+		return
+	}
+	err = components.ManageDeadCollections(deadMessages).Render(ctx, w)
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not render dead collections component"+err.Error())
+		return
+	}
+
+	notify(w, r, components.NotifySuccess, fmt.Sprintf("Requeued collection for %s %s", message.SchoolID, message.TermCollectionID))
+}
+
 func (h *manageHandler) validateOrchestrator(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -615,7 +769,8 @@ func (h *manageHandler) collectTerm(w http.ResponseWriter, r *http.Request) {
 	schoolID := r.FormValue("schoolID")
 	termID := r.FormValue("termID")
 	isFullCollection := r.FormValue("isFullCollection") == "on"
-	h.baseLogger.InfoContext(ctx, "Is full collection: ", "isFullCollection", isFullCollection)
+	isDryRun := r.FormValue("isDryRun") == "on"
+	h.baseLogger.InfoContext(ctx, "Is full collection: ", "isFullCollection", isFullCollection, "isDryRun", isDryRun)
 	orchestrator := h.orchestrators[label]
 
 	school, ok := orchestrator.data.O.GetSchoolById(schoolID)
@@ -705,7 +860,8 @@ func (h *manageHandler) collectTerm(w http.ResponseWriter, r *http.Request) {
 			collection.DefualtUpdateSectionsConfig().
 				SetLogger(oneOffLogger).
 				SetServiceName(serviceName).
-				SetFullCollection(isFullCollection),
+				SetFullCollection(isFullCollection).
+				SetDryRun(isDryRun),
 		)
 		if err != nil {
 			oneOffLogger.ErrorContext(ctx, "Failed collection", "error", err)
@@ -722,7 +878,14 @@ func (h *manageHandler) collectTerm(w http.ResponseWriter, r *http.Request) {
 			results.Deleted,
 			"duration",
 			results.Duration,
+			"warnings",
+			results.Warnings,
 		)
+		if results.Diff != nil {
+			if err := webWriter.sendDiff(ctx, *results.Diff); err != nil {
+				h.baseLogger.ErrorContext(ctx, "Could not send dry run diff", "error", err)
+			}
+		}
 
 		err = webWriter.finish(ctx, components.JobSuccess)
 		if err != nil {
@@ -771,3 +934,306 @@ func (h *manageHandler) resetDatabase(w http.ResponseWriter, r *http.Request) {
 		message,
 	)
 }
+
+// upload files up to this size from the dashboard
+const MAX_IMPORT_FILE_SIZE = 32 << 20
+
+// the file import service of the default orchestrator
+type fileImporter interface {
+	SchoolMappings() (map[string]fileimport.SchoolMapping, error)
+	SaveUpload(schoolID string, termCollectionID string, fileName string, file io.Reader) (int, error)
+}
+
+func (h *manageHandler) getFileImporter() (fileImporter, bool) {
+	o, ok := h.orchestrators[0]
+	if !ok {
+		return nil, false
+	}
+	service, ok := o.data.O.GetService(fileimport.SERVICE_NAME)
+	if !ok {
+		return nil, false
+	}
+	importer, ok := service.(fileImporter)
+	return importer, ok
+}
+
+func (h *manageHandler) getImportTargets() ([]components.ImportTarget, error) {
+	importer, ok := h.getFileImporter()
+	if !ok {
+		return nil, nil
+	}
+	mappings, err := importer.SchoolMappings()
+	if err != nil {
+		return nil, err
+	}
+	var importTargets []components.ImportTarget
+	for schoolID, mapping := range mappings {
+		for _, term := range mapping.Terms {
+			termName := term.Name
+			if termName == "" {
+				termName = fmt.Sprintf("%s %d", term.Season, term.Year)
+			}
+			importTargets = append(importTargets, components.ImportTarget{
+				SchoolID:         schoolID,
+				SchoolName:       mapping.Name,
+				TermCollectionID: term.ID,
+				TermName:         termName,
+			})
+		}
+	}
+	slices.SortFunc(importTargets, func(a, b components.ImportTarget) int {
+		return strings.Compare(a.SchoolID+"/"+a.TermCollectionID, b.SchoolID+"/"+b.TermCollectionID)
+	})
+	return importTargets, nil
+}
+
+// the import watcher schedules the collection once the file is saved
+func (h *manageHandler) uploadImportFile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	importer, ok := h.getFileImporter()
+	if !ok {
+		notify(w, r, components.NotifyError, "File imports are not enabled")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_IMPORT_FILE_SIZE)
+	err := r.ParseMultipartForm(MAX_IMPORT_FILE_SIZE)
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not parse form: "+err.Error())
+		return
+	}
+
+	schoolID, termCollectionID, ok := strings.Cut(r.PostForm.Get("importTarget"), "/")
+	if !ok {
+		notify(w, r, components.NotifyError, "Invalid term to import")
+		return
+	}
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not read file: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	sectionCount, err := importer.SaveUpload(schoolID, termCollectionID, fileHeader.Filename, file)
+	if err != nil {
+		h.baseLogger.WarnContext(ctx, "Rejected import file", "file", fileHeader.Filename, "error", err)
+		notify(w, r, components.NotifyError, "Could not import file: "+err.Error())
+		return
+	}
+
+	notify(w, r, components.NotifySuccess, fmt.Sprintf(
+		"Saved %s with %d sections for %s %s",
+		fileHeader.Filename,
+		sectionCount,
+		schoolID,
+		termCollectionID,
+	))
+}
+
+func getRegistryEntries(ctx context.Context, q *db.Queries) ([]components.RegistryEntry, error) {
+	rows, err := q.ListSchoolRegistry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]components.RegistryEntry, len(rows))
+	for i, row := range rows {
+		var configText bytes.Buffer
+		if err := json.Indent(&configText, row.Config, "", "  "); err != nil {
+			return nil, err
+		}
+		entries[i] = components.RegistryEntry{
+			SchoolRegistry: row,
+			ConfigText:     configText.String(),
+		}
+	}
+	return entries, nil
+}
+
+func (h *manageHandler) schoolRegistryView(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	entries, err := getRegistryEntries(ctx, db.New(h.DbPool))
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get school registry", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	err = components.SchoolRegistry(entries, collection.RegistryServiceNames()).Render(ctx, w)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not render school registry component", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+}
+
+// orchestrators are told about the change by the registry's trigger
+func (h *manageHandler) upsertSchoolRegistryEntry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	err := r.ParseForm()
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not parse form: "+err.Error())
+		return
+	}
+
+	config := []byte(strings.TrimSpace(r.PostForm.Get("config")))
+	if len(config) == 0 {
+		config = []byte("{}")
+	}
+	var compactConfig bytes.Buffer
+	if err := json.Compact(&compactConfig, config); err != nil {
+		notify(w, r, components.NotifyError, "Config is not valid json: "+err.Error())
+		return
+	}
+	priority, err := strconv.Atoi(r.PostForm.Get("priority"))
+	if err != nil {
+		notify(w, r, components.NotifyError, "Priority is not an integer: "+err.Error())
+		return
+	}
+	entry := db.SchoolRegistry{
+		SchoolID:    strings.TrimSpace(r.PostForm.Get("schoolId")),
+		SchoolName:  strings.TrimSpace(r.PostForm.Get("schoolName")),
+		ServiceName: r.PostForm.Get("serviceName"),
+		Config:      compactConfig.Bytes(),
+		Priority:    int32(priority),
+		Enabled:     r.PostForm.Get("enabled") == "on",
+	}
+	if err := collection.ValidateRegistryEntry(entry); err != nil {
+		notify(w, r, components.NotifyError, "Invalid registry entry: "+err.Error())
+		return
+	}
+
+	q := db.New(h.DbPool)
+	err = q.UpsertSchoolRegistryEntry(ctx, db.UpsertSchoolRegistryEntryParams{
+		SchoolID:    entry.SchoolID,
+		SchoolName:  entry.SchoolName,
+		ServiceName: entry.ServiceName,
+		Config:      entry.Config,
+		Priority:    entry.Priority,
+		Enabled:     entry.Enabled,
+	})
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not save school registry entry", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	entries, err := getRegistryEntries(ctx, q)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get school registry", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	err = components.SchoolRegistryTable(entries).Render(ctx, w)
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not render school registry component"+err.Error())
+		return
+	}
+
+	notify(w, r, components.NotifySuccess, fmt.Sprintf("Saved %s for %s", entry.ServiceName, entry.SchoolID))
+}
+
+func (h *manageHandler) deleteSchoolRegistryEntry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	err := r.ParseForm()
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not parse form: "+err.Error())
+		return
+	}
+
+	schoolID := r.Form.Get("schoolId")
+	serviceName := r.Form.Get("serviceName")
+	q := db.New(h.DbPool)
+	deleted, err := q.DeleteSchoolRegistryEntry(ctx, db.DeleteSchoolRegistryEntryParams{
+		SchoolID:    schoolID,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not delete school registry entry", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	if deleted == 0 {
+		notify(w, r, components.NotifyError, fmt.Sprintf("There is no %s entry for %s", serviceName, schoolID))
+		return
+	}
+
+	entries, err := getRegistryEntries(ctx, q)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get school registry", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	err = components.SchoolRegistryTable(entries).Render(ctx, w)
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not render school registry component"+err.Error())
+		return
+	}
+
+	notify(w, r, components.NotifySuccess, fmt.Sprintf("Deleted %s for %s", serviceName, schoolID))
+}
+
+// fills in a new registry entry with what was found about the banner host
+func (h *manageHandler) discoverBannerSchool(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	err := r.ParseForm()
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not parse form: "+err.Error())
+		return
+	}
+
+	discovery, err := banner.Discover(ctx, *h.baseLogger, r.PostForm.Get("host"))
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not discover banner: "+err.Error())
+		return
+	}
+	config, err := json.MarshalIndent(discovery.Config, "", "  ")
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not marshal discovered config", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	err = components.BannerDiscoveryResult(
+		components.DiscoveredSchool{
+			BaseURL:   discovery.BaseURL,
+			TermCount: discovery.TermCount,
+			SampleTerm: db.TermCollection{
+				ID:   discovery.SampleTerm.ID,
+				Name: discovery.SampleTerm.Name,
+			},
+			SectionCount: discovery.SectionCount,
+			ResponseTime: discovery.ResponseTime.Round(time.Millisecond).String(),
+		},
+		collection.RegistryServiceNames(),
+		components.RegistryEntry{
+			SchoolRegistry: db.SchoolRegistry{
+				SchoolID:    r.PostForm.Get("schoolId"),
+				SchoolName:  r.PostForm.Get("schoolName"),
+				ServiceName: banner.SERVICE_NAME,
+				Enabled:     true,
+			},
+			ConfigText: string(config),
+		},
+	).Render(ctx, w)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not render banner discovery component", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+}
@@ -0,0 +1,365 @@
+package servermanage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pjt727/classy/collection"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/Pjt727/classy/server/components"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/robert-nix/ansihtml"
+)
+
+const DEFAULT_HISTORY_LIMIT = 50
+const MAX_HISTORY_LIMIT = 500
+
+// a broken rule can be violated by every staged record of a collection
+const MAX_DETAIL_VIOLATIONS = 200
+
+// the history and json api use the same offset and limit query params as the get api
+func parseHistoryPagination(r *http.Request) (offset int32, limit int32, err error) {
+	limit = DEFAULT_HISTORY_LIMIT
+	query := r.URL.Query()
+	if queryOffset := query.Get("offset"); queryOffset != "" {
+		newOffset, err := strconv.Atoi(queryOffset)
+		if err != nil || newOffset < 0 {
+			return 0, 0, errors.New("Invalid query offset param")
+		}
+		offset = int32(newOffset)
+	}
+	if queryLimit := query.Get("limit"); queryLimit != "" {
+		newLimit, err := strconv.Atoi(queryLimit)
+		if err != nil || newLimit <= 0 {
+			return 0, 0, errors.New("Invalid query limit param")
+		}
+		limit = int32(min(newLimit, MAX_HISTORY_LIMIT))
+	}
+	return offset, limit, nil
+}
+
+// queries fetch one extra row to know whether there is a next page
+func historyPageLinks(r *http.Request, offset int32, limit int32, hasNext bool) components.PageLinks {
+	var links components.PageLinks
+	pageURL := func(newOffset int32) string {
+		u := url.URL{Path: r.URL.Path}
+		q := r.URL.Query()
+		q.Set("offset", strconv.Itoa(int(newOffset)))
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	if offset > 0 {
+		links.Previous = pageURL(max(offset-limit, 0))
+	}
+	if hasNext {
+		links.Next = pageURL(offset + limit)
+	}
+	return links
+}
+
+func setLinkHeader(w http.ResponseWriter, links components.PageLinks) {
+	linkHeader := ""
+	if links.Previous != "" {
+		linkHeader += fmt.Sprintf("<%s>; rel=\"prev\"", links.Previous)
+	}
+	if links.Next != "" {
+		if linkHeader != "" {
+			linkHeader += ","
+		}
+		linkHeader += fmt.Sprintf("<%s>; rel=\"next\"", links.Next)
+	}
+	if linkHeader != "" {
+		w.Header().Set("Link", linkHeader)
+	}
+}
+
+func optionalQueryText(r *http.Request, key string) pgtype.Text {
+	value := r.URL.Query().Get(key)
+	return pgtype.Text{String: value, Valid: value != ""}
+}
+
+func (h *manageHandler) getPreviousCollections(r *http.Request) ([]db.TermCollectionHistory, components.PageLinks, error) {
+	offset, limit, err := parseHistoryPagination(r)
+	if err != nil {
+		return nil, components.PageLinks{}, err
+	}
+	history, err := db.New(h.DbPool).GetPreviousCollections(r.Context(), db.GetPreviousCollectionsParams{
+		SchoolID:         optionalQueryText(r, "schoolID"),
+		TermCollectionID: optionalQueryText(r, "termCollectionID"),
+		Offsetvalue:      offset,
+		Limitvalue:       limit,
+	})
+	if err != nil {
+		return nil, components.PageLinks{}, fmt.Errorf("Could not get previous collections %w", err)
+	}
+	hasNext := len(history) > int(limit)
+	if hasNext {
+		history = history[:limit]
+	}
+	return history, historyPageLinks(r, offset, limit, hasNext), nil
+}
+
+type collectionHistoryDetail struct {
+	History    db.TermCollectionHistory
+	Violations []db.CollectionViolation
+	Changes    []db.HistoricClassInformation
+	Links      components.PageLinks
+	// nil when the logs were not kept or already pruned
+	Log *components.RunLog
+}
+
+var errNoCollectionHistory = errors.New("There is no collection with that id")
+
+func (h *manageHandler) getCollectionHistoryDetail(r *http.Request) (collectionHistoryDetail, error) {
+	ctx := r.Context()
+	termCollectionHistoryID, err := strconv.Atoi(chi.URLParam(r, "termCollectionHistoryID"))
+	if err != nil {
+		return collectionHistoryDetail{}, errNoCollectionHistory
+	}
+	offset, limit, err := parseHistoryPagination(r)
+	if err != nil {
+		return collectionHistoryDetail{}, err
+	}
+
+	q := db.New(h.DbPool)
+	var detail collectionHistoryDetail
+	detail.History, err = q.GetTermCollectionHistory(ctx, int32(termCollectionHistoryID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return collectionHistoryDetail{}, errNoCollectionHistory
+	} else if err != nil {
+		return collectionHistoryDetail{}, fmt.Errorf("Could not get collection %w", err)
+	}
+	detail.Violations, err = q.ListCollectionViolations(ctx, db.ListCollectionViolationsParams{
+		TermCollectionHistoryID: detail.History.ID,
+		LimitCount:              MAX_DETAIL_VIOLATIONS,
+	})
+	if err != nil {
+		return collectionHistoryDetail{}, fmt.Errorf("Could not get collection violations %w", err)
+	}
+	detail.Changes, err = q.GetCollectionChanges(ctx, db.GetCollectionChangesParams{
+		TermCollectionHistoryID: detail.History.ID,
+		Offsetvalue:             offset,
+		Limitvalue:              limit,
+	})
+	if err != nil {
+		return collectionHistoryDetail{}, fmt.Errorf("Could not get collection changes %w", err)
+	}
+	hasNext := len(detail.Changes) > int(limit)
+	if hasNext {
+		detail.Changes = detail.Changes[:limit]
+	}
+	detail.Links = historyPageLinks(r, offset, limit, hasNext)
+
+	runLog, err := q.GetCollectionLog(ctx, detail.History.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return detail, nil
+	} else if err != nil {
+		return collectionHistoryDetail{}, fmt.Errorf("Could not get collection logs %w", err)
+	}
+	logs, err := collection.ReadRunLog(runLog)
+	if err != nil {
+		return collectionHistoryDetail{}, err
+	}
+	detail.Log = &components.RunLog{
+		HTML:      string(ansihtml.ConvertToHTML(logs)),
+		Lines:     runLog.Lines,
+		Truncated: runLog.Truncated,
+	}
+	return detail, nil
+}
+
+func (h *manageHandler) collectionHistoryView(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	ctx := r.Context()
+
+	history, links, err := h.getPreviousCollections(r)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get collection history", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := components.HistoryFilter{
+		SchoolID:         r.URL.Query().Get("schoolID"),
+		TermCollectionID: r.URL.Query().Get("termCollectionID"),
+	}
+	err = components.CollectionHistory(filter, history, links).Render(ctx, w)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not render collection history", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+}
+
+func (h *manageHandler) collectionHistoryDetailView(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	ctx := r.Context()
+
+	detail, err := h.getCollectionHistoryDetail(r)
+	if errors.Is(err, errNoCollectionHistory) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get collection history detail", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	err = components.CollectionHistoryDetail(
+		detail.History,
+		detail.Violations,
+		detail.Changes,
+		detail.Links,
+		detail.Log,
+	).Render(ctx, w)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not render collection history detail", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+}
+
+// the jsonb columns are bytes in the db models which would be encoded as base64
+type collectionViolationJson struct {
+	Rule      string                   `json:"rule"`
+	Severity  db.ViolationSeverityEnum `json:"severity"`
+	TableName string                   `json:"table_name"`
+	RecordKey json.RawMessage          `json:"record_key"`
+	Message   string                   `json:"message"`
+}
+
+type collectionChangeJson struct {
+	Sequence       int32           `json:"sequence"`
+	TableName      string          `json:"table_name"`
+	SyncAction     db.SyncKind     `json:"sync_action"`
+	PkFields       json.RawMessage `json:"pk_fields"`
+	RelevantFields json.RawMessage `json:"relevant_fields"`
+	InputAt        time.Time       `json:"input_at"`
+}
+
+type collectionHistoryDetailJson struct {
+	History    db.TermCollectionHistory  `json:"history"`
+	Violations []collectionViolationJson `json:"violations"`
+	Changes    []collectionChangeJson    `json:"changes"`
+}
+
+func (h *manageHandler) getCollectionHistoryJson(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	history, links, err := h.getPreviousCollections(r)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get collection history", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if history == nil {
+		history = []db.TermCollectionHistory{}
+	}
+
+	historyJson, err := json.Marshal(history)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not marshal collection history", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	setLinkHeader(w, links)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(historyJson)
+}
+
+func (h *manageHandler) getCollectionHistoryDetailJson(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	detail, err := h.getCollectionHistoryDetail(r)
+	if errors.Is(err, errNoCollectionHistory) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get collection history detail", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	response := collectionHistoryDetailJson{
+		History:    detail.History,
+		Violations: make([]collectionViolationJson, len(detail.Violations)),
+		Changes:    make([]collectionChangeJson, len(detail.Changes)),
+	}
+	for i, violation := range detail.Violations {
+		response.Violations[i] = collectionViolationJson{
+			Rule:      violation.Rule,
+			Severity:  violation.Severity,
+			TableName: violation.TableName,
+			RecordKey: violation.RecordKey,
+			Message:   violation.Message,
+		}
+	}
+	for i, change := range detail.Changes {
+		response.Changes[i] = collectionChangeJson{
+			Sequence:       change.Sequence,
+			TableName:      change.TableName,
+			SyncAction:     change.SyncAction,
+			PkFields:       change.PkFields,
+			RelevantFields: change.RelevantFields,
+			InputAt:        change.InputAt.Time,
+		}
+	}
+
+	detailJson, err := json.Marshal(response)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not marshal collection history detail", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	setLinkHeader(w, detail.Links)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(detailJson)
+}
+
+func (h *manageHandler) collectionLogsView(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	params := collection.SearchRunLogsParams{
+		SchoolID:         query.Get("schoolID"),
+		TermCollectionID: query.Get("termCollectionID"),
+		Text:             query.Get("text"),
+	}
+	var matches []components.RunLogMatch
+	if params.Text != "" {
+		runLogMatches, err := collection.SearchRunLogs(ctx, db.New(h.DbPool), params)
+		if err != nil {
+			h.baseLogger.ErrorContext(ctx, "Could not search collection logs", "error", err)
+			http.Error(w, http.StatusText(500), 500)
+			return
+		}
+		matches = make([]components.RunLogMatch, len(runLogMatches))
+		for i, match := range runLogMatches {
+			matches[i] = components.RunLogMatch{
+				Log:       match.Log,
+				HTML:      string(ansihtml.ConvertToHTML([]byte(strings.Join(match.Lines, "\n")))),
+				MoreLines: match.MoreLines,
+			}
+		}
+	}
+
+	filter := components.HistoryFilter{
+		SchoolID:         params.SchoolID,
+		TermCollectionID: params.TermCollectionID,
+	}
+	err := components.CollectionLogs(filter, params.Text, matches).Render(ctx, w)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not render collection logs", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+}
@@ -0,0 +1,96 @@
+package servermanage
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Pjt727/classy/data/db"
+	"github.com/Pjt727/classy/server/components"
+)
+
+func (h *manageHandler) renderQuarantinedCollections(w http.ResponseWriter, r *http.Request, message string) {
+	ctx := r.Context()
+	quarantinedCollections, err := db.New(h.DbPool).ListPendingQuarantinedCollections(ctx)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get quarantined collections", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	err = components.ManageQuarantinedCollections(quarantinedCollections).Render(ctx, w)
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not render quarantined collections component"+err.Error())
+		return
+	}
+	notify(w, r, components.NotifySuccess, message)
+}
+
+func parseTermCollectionHistoryID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	err := r.ParseForm()
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not parse form: "+err.Error())
+		return 0, false
+	}
+	termCollectionHistoryID, err := strconv.Atoi(r.Form.Get("termCollectionHistoryId"))
+	if err != nil {
+		notify(w, r, components.NotifyError, "Term collection history Id is not an integer: "+err.Error())
+		return 0, false
+	}
+	return int32(termCollectionHistoryID), true
+}
+
+func (h *manageHandler) approveQuarantinedCollection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	termCollectionHistoryID, ok := parseTermCollectionHistoryID(w, r)
+	if !ok {
+		return
+	}
+
+	// the default orchestrator is the one on the main db
+	orchestrator, ok := h.orchestrators[0]
+	if !ok {
+		notify(w, r, components.NotifyError, "There is no default orchestrator")
+		return
+	}
+	result, err := orchestrator.data.O.ApproveQuarantinedCollection(ctx, termCollectionHistoryID)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not approve quarantined collection", "error", err)
+		notify(w, r, components.NotifyError, "Could not approve the collection: "+err.Error())
+		return
+	}
+
+	h.renderQuarantinedCollections(w, r, fmt.Sprintf(
+		"Approved collection %d: %d inserted, %d updated, %d deleted",
+		termCollectionHistoryID,
+		result.Inserted,
+		result.Updated,
+		result.Deleted,
+	))
+}
+
+func (h *manageHandler) rejectQuarantinedCollection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	termCollectionHistoryID, ok := parseTermCollectionHistoryID(w, r)
+	if !ok {
+		return
+	}
+
+	orchestrator, ok := h.orchestrators[0]
+	if !ok {
+		notify(w, r, components.NotifyError, "There is no default orchestrator")
+		return
+	}
+	if err := orchestrator.data.O.RejectQuarantinedCollection(ctx, termCollectionHistoryID); err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not reject quarantined collection", "error", err)
+		notify(w, r, components.NotifyError, "Could not reject the collection: "+err.Error())
+		return
+	}
+
+	h.renderQuarantinedCollections(w, r, fmt.Sprintf("Rejected collection %d", termCollectionHistoryID))
+}
@@ -4,13 +4,21 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/Pjt727/classy/collection"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func PopulateManagementRoutes(r *chi.Router, pool *pgxpool.Pool, testPool *pgxpool.Pool, logger slog.Logger) error {
-	h := getManageHandler(pool, testPool, &logger)
+// the orchestrator is the default one whose school registry is watched by the server
+func PopulateManagementRoutes(
+	r *chi.Router,
+	defaultOrchestrator *collection.Orchestrator,
+	pool *pgxpool.Pool,
+	testPool *pgxpool.Pool,
+	logger slog.Logger,
+) error {
+	h := getManageHandler(defaultOrchestrator, pool, testPool, &logger)
 	(*r).Use(
 		middleware.AllowContentType("application/x-www-form-urlencoded", "multipart/form-data"),
 	)
@@ -29,11 +37,44 @@ func PopulateManagementRoutes(r *chi.Router, pool *pgxpool.Pool, testPool *pgxpo
 
 		r.Get("/", h.dashboardHome)
 		r.Delete("/db", h.resetDatabase)
+		r.Post("/imports", h.uploadImportFile)
+
+		r.Route("/registry", func(r chi.Router) {
+			r.Get("/", h.schoolRegistryView)
+			r.Post("/", h.upsertSchoolRegistryEntry)
+			r.Delete("/", h.deleteSchoolRegistryEntry)
+			r.Post("/discover", h.discoverBannerSchool)
+		})
 
 		r.Route("/schedule", func(r chi.Router) {
 			r.Get("/", h.getScheduleCollectionForm)
 			r.Delete("/", h.deleteCollectionJob)
 			r.Post("/", h.scheduleCollectionForm)
+			r.Post("/dead", h.requeueDeadCollectionJob)
+		})
+
+		r.Route("/quarantine", func(r chi.Router) {
+			r.Post("/", h.approveQuarantinedCollection)
+			r.Delete("/", h.rejectQuarantinedCollection)
+		})
+
+		r.Route("/history", func(r chi.Router) {
+			r.Get("/", h.collectionHistoryView)
+			r.Get("/{termCollectionHistoryID}", h.collectionHistoryDetailView)
+		})
+
+		r.Get("/logs", h.collectionLogsView)
+
+		r.Route("/api/history", func(r chi.Router) {
+			r.Get("/", h.getCollectionHistoryJson)
+			r.Get("/{termCollectionHistoryID}", h.getCollectionHistoryDetailJson)
+		})
+
+		r.Route("/recurring", func(r chi.Router) {
+			r.Get("/", h.collectionSchedulesView)
+			r.Post("/", h.addCollectionSchedule)
+			r.Patch("/", h.setCollectionScheduleEnabled)
+			r.Delete("/", h.deleteCollectionSchedule)
 		})
 
 		r.Route("/{orchestratorLabel}", func(r chi.Router) {
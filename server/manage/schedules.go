@@ -0,0 +1,202 @@
+package servermanage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pjt727/classy/collection"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/Pjt727/classy/server/components"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func getCollectionSchedules(ctx context.Context, q *db.Queries) ([]components.CollectionScheduleRow, error) {
+	schedules, err := q.ListCollectionSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	rows := make([]components.CollectionScheduleRow, len(schedules))
+	for i, schedule := range schedules {
+		rows[i] = components.CollectionScheduleRow{CollectionSchedule: schedule}
+		if !schedule.Enabled {
+			rows[i].NextCollection = "Disabled"
+			continue
+		}
+		next, err := collection.NextScheduledCollection(schedule, now)
+		switch {
+		case err != nil:
+			rows[i].NextCollection = err.Error()
+		case next.IsZero():
+			rows[i].NextCollection = "Never"
+		default:
+			rows[i].NextCollection = next.Format("2006-01-02 15:04 MST")
+		}
+	}
+	return rows, nil
+}
+
+func (h *manageHandler) getServiceNames() []string {
+	// just using the first orchestrator maybe change
+	o, ok := h.orchestrators[0]
+	if !ok {
+		return nil
+	}
+	return o.data.O.GetServices()
+}
+
+func (h *manageHandler) renderCollectionSchedules(w http.ResponseWriter, r *http.Request, q *db.Queries, message string) {
+	ctx := r.Context()
+	schedules, err := getCollectionSchedules(ctx, q)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get collection schedules", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	err = components.CollectionSchedulesTable(schedules).Render(ctx, w)
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not render collection schedules component"+err.Error())
+		return
+	}
+	notify(w, r, components.NotifySuccess, message)
+}
+
+func (h *manageHandler) collectionSchedulesView(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	schedules, err := getCollectionSchedules(ctx, db.New(h.DbPool))
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not get collection schedules", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	err = components.CollectionSchedules(schedules, h.getServiceNames()).Render(ctx, w)
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not render collection schedules component", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+}
+
+func (h *manageHandler) addCollectionSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	err := r.ParseForm()
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not parse form: "+err.Error())
+		return
+	}
+
+	schoolID := strings.TrimSpace(r.PostForm.Get("schoolId"))
+	termCollectionID := strings.TrimSpace(r.PostForm.Get("termCollectionId"))
+	serviceName := r.PostForm.Get("serviceName")
+	cron := strings.TrimSpace(r.PostForm.Get("cron"))
+	timezone := strings.TrimSpace(r.PostForm.Get("timezone"))
+	if schoolID == "" {
+		notify(w, r, components.NotifyError, "School id is required")
+		return
+	}
+	if err := collection.ValidateCollectionSchedule(cron, timezone); err != nil {
+		notify(w, r, components.NotifyError, "Invalid schedule: "+err.Error())
+		return
+	}
+
+	q := db.New(h.DbPool)
+	schedule, err := q.InsertCollectionSchedule(ctx, db.InsertCollectionScheduleParams{
+		SchoolID:         schoolID,
+		TermCollectionID: pgtype.Text{String: termCollectionID, Valid: termCollectionID != ""},
+		Cron:             cron,
+		Timezone:         timezone,
+		IsFullCollection: r.PostForm.Get("isFullCollection") == "on",
+		ServiceName:      pgtype.Text{String: serviceName, Valid: serviceName != ""},
+		Enabled:          r.PostForm.Get("enabled") == "on",
+	})
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not add collection schedule", "error", err)
+		notify(w, r, components.NotifyError, "Could not add the schedule, make sure the term collection exists")
+		return
+	}
+
+	h.renderCollectionSchedules(w, r, q, fmt.Sprintf("Added schedule %d for %s", schedule.ID, schedule.SchoolID))
+}
+
+func (h *manageHandler) setCollectionScheduleEnabled(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	err := r.ParseForm()
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not parse form: "+err.Error())
+		return
+	}
+
+	scheduleID, err := strconv.Atoi(r.Form.Get("scheduleId"))
+	if err != nil {
+		notify(w, r, components.NotifyError, "Schedule Id is not an integer: "+err.Error())
+		return
+	}
+	enabled := r.Form.Get("enabled") == "true"
+
+	q := db.New(h.DbPool)
+	changed, err := q.SetCollectionScheduleEnabled(ctx, db.SetCollectionScheduleEnabledParams{
+		Enabled: enabled,
+		ID:      int32(scheduleID),
+	})
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not change collection schedule", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	if changed == 0 {
+		notify(w, r, components.NotifyError, fmt.Sprintf("There is no schedule %d", scheduleID))
+		return
+	}
+
+	message := fmt.Sprintf("Disabled schedule %d", scheduleID)
+	if enabled {
+		message = fmt.Sprintf("Enabled schedule %d", scheduleID)
+	}
+	h.renderCollectionSchedules(w, r, q, message)
+}
+
+func (h *manageHandler) deleteCollectionSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	ctx := r.Context()
+
+	err := r.ParseForm()
+	if err != nil {
+		notify(w, r, components.NotifyError, "Could not parse form: "+err.Error())
+		return
+	}
+
+	scheduleID, err := strconv.Atoi(r.Form.Get("scheduleId"))
+	if err != nil {
+		notify(w, r, components.NotifyError, "Schedule Id is not an integer: "+err.Error())
+		return
+	}
+
+	q := db.New(h.DbPool)
+	deleted, err := q.DeleteCollectionSchedule(ctx, int32(scheduleID))
+	if err != nil {
+		h.baseLogger.ErrorContext(ctx, "Could not delete collection schedule", "error", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	if deleted == 0 {
+		notify(w, r, components.NotifyError, fmt.Sprintf("There is no schedule %d", scheduleID))
+		return
+	}
+
+	h.renderCollectionSchedules(w, r, q, fmt.Sprintf("Deleted schedule %d", scheduleID))
+}
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"slices"
 
+	"github.com/Pjt727/classy/collection"
 	"github.com/Pjt727/classy/data/db"
 	"github.com/Pjt727/classy/server/components"
 	"github.com/gorilla/websocket"
@@ -138,6 +139,32 @@ func (w *websocketLoggingWriter) finish(ctx context.Context, status components.J
 	return nil
 }
 
+// shows what a dry run would have changed under its logs
+func (w *websocketLoggingWriter) sendDiff(ctx context.Context, diff collection.CollectionDiff) error {
+	wsConn, ok := w.h.orchestrators[w.orchestratorLabel]
+
+	// it is completely fine if the diff does not get sent
+	if !ok {
+		slog.Warn("Could not find the orch")
+		return nil
+	}
+
+	var buf bytes.Buffer
+	err := components.CollectionDiffLog(w.termCollection, diff).Render(ctx, &buf)
+	if err != nil {
+		slog.Error("Could not render the dry run diff", "err", err)
+		return err
+	}
+
+	for _, c := range wsConn.connections {
+		if c == nil || c.send == nil {
+			continue
+		}
+		c.send <- buf.Bytes()
+	}
+	return nil
+}
+
 type WebSocketConnection struct {
 	conn              *websocket.Conn
 	orchestratorLabel int
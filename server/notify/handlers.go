@@ -0,0 +1,177 @@
+package servernotify
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"log/slog"
+
+	"github.com/Pjt727/classy/collection/webhooks"
+	"github.com/Pjt727/classy/data/db"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// the secret given back when subscribing must be sent to unsubscribe
+const SECRET_HEADER = "X-Classy-Secret"
+
+// a full section opening up is delivered to every subscription so there can only be so many
+const MAX_SECTION_SUBSCRIPTIONS = 200
+
+type notifyHandler struct {
+	dbPool          *pgxpool.Pool
+	logger          *slog.Logger
+	subscribeLimits *addressLimiter
+}
+
+type seatSubscriptionRequest struct {
+	SchoolID         string `json:"school_id"`
+	TermCollectionID string `json:"term_collection_id"`
+	// SUBJECT-NUMBER-SEQUENCE
+	Section string `json:"section"`
+	URL     string `json:"url"`
+}
+
+// the only time the secret is given out
+type seatSubscriptionResult struct {
+	ID     int32  `json:"id"`
+	Secret string `json:"secret"`
+}
+
+func (h *notifyHandler) subscribeToSeats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := db.New(h.dbPool)
+
+	if !h.subscribeLimits.allow(r) {
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+
+	var request seatSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Request has incorrect shape: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	sectionParts := strings.Split(request.Section, "-")
+	if len(sectionParts) != 3 || sectionParts[0] == "" || sectionParts[1] == "" || sectionParts[2] == "" {
+		http.Error(w, "Invalid section (expected SUBJECT-NUMBER-SEQUENCE)", http.StatusBadRequest)
+		return
+	}
+	callbackURL, err := webhooks.ValidateCallbackURL(ctx, request.URL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	callbackHost := strings.ToLower(callbackURL.Hostname())
+	hostSubscriptions, err := q.CountRecentSeatSubscriptionsOfHost(ctx, db.CountRecentSeatSubscriptionsOfHostParams{
+		CallbackHost:  callbackHost,
+		WindowSeconds: int32(HOST_SUBSCRIPTION_WINDOW.Seconds()),
+	})
+	if err != nil {
+		h.logger.Error("Could not count the callback host's subscriptions", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	if hostSubscriptions >= MAX_HOST_SUBSCRIPTIONS {
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+
+	sectionExists, err := q.SectionExists(ctx, db.SectionExistsParams{
+		SchoolID:         request.SchoolID,
+		TermCollectionID: request.TermCollectionID,
+		SubjectCode:      sectionParts[0],
+		CourseNumber:     sectionParts[1],
+		SectionSequence:  sectionParts[2],
+	})
+	if err != nil {
+		h.logger.Error("Could not check section", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	if !sectionExists {
+		http.Error(w, "Section does not exist", http.StatusNotFound)
+		return
+	}
+	subscriptions, err := q.CountSeatSubscriptions(ctx, db.CountSeatSubscriptionsParams{
+		SchoolID:         request.SchoolID,
+		TermCollectionID: request.TermCollectionID,
+		SubjectCode:      sectionParts[0],
+		CourseNumber:     sectionParts[1],
+		SectionSequence:  sectionParts[2],
+	})
+	if err != nil {
+		h.logger.Error("Could not count seat subscriptions", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	if subscriptions >= MAX_SECTION_SUBSCRIPTIONS {
+		http.Error(w, "Section has too many subscriptions", http.StatusConflict)
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		h.logger.Error("Could not make subscription secret", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	subscription, err := q.InsertSeatSubscription(ctx, db.InsertSeatSubscriptionParams{
+		SchoolID:         request.SchoolID,
+		TermCollectionID: request.TermCollectionID,
+		SubjectCode:      sectionParts[0],
+		CourseNumber:     sectionParts[1],
+		SectionSequence:  sectionParts[2],
+		CallbackUrl:      callbackURL.String(),
+		CallbackHost:     callbackHost,
+		Secret:           secret,
+	})
+	if err != nil {
+		h.logger.Error("Could not insert seat subscription", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	resultJSON, err := json.Marshal(seatSubscriptionResult{
+		ID:     subscription.ID,
+		Secret: subscription.Secret,
+	})
+	if err != nil {
+		h.logger.Error("Could not marshal seat subscription", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resultJSON)
+}
+
+func (h *notifyHandler) unsubscribeFromSeats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := db.New(h.dbPool)
+
+	subscriptionID, err := strconv.Atoi(chi.URLParam(r, "subscriptionID"))
+	if err != nil {
+		http.Error(w, "Invalid subscription id", http.StatusBadRequest)
+		return
+	}
+	deleted, err := q.DeleteSeatSubscription(ctx, db.DeleteSeatSubscriptionParams{
+		ID:     int32(subscriptionID),
+		Secret: r.Header.Get(SECRET_HEADER),
+	})
+	if err != nil {
+		h.logger.Error("Could not delete seat subscription", "err", err)
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	// a wrong secret looks the same as a missing subscription
+	if deleted == 0 {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
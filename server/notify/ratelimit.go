@@ -0,0 +1,135 @@
+package servernotify
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// subscribing is public so each address gets a burst of subscriptions and then one a minute
+const SUBSCRIBE_BURST = 10
+
+var SUBSCRIBE_RATE = rate.Every(time.Minute)
+
+// addresses which have not subscribed in this long have a full burst again so they are forgotten
+const FORGET_ADDRESS_AFTER = SUBSCRIBE_BURST * time.Minute
+
+// one callback host can only be subscribed so many times an hour no matter which replica or address asks
+const MAX_HOST_SUBSCRIPTIONS = 60
+
+const HOST_SUBSCRIPTION_WINDOW = time.Hour
+
+type addressLimit struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// each replica keeps its own buckets so the limit across replicas comes from the subscriptions table
+//
+//	see MAX_HOST_SUBSCRIPTIONS
+type addressLimiter struct {
+	// the reverse proxies whose forwarded headers are believed
+	trustedProxies []netip.Prefix
+
+	mu        sync.Mutex
+	addresses map[string]*addressLimit
+	lastSweep time.Time
+}
+
+func newAddressLimiter(trustedProxies []netip.Prefix) *addressLimiter {
+	return &addressLimiter{
+		trustedProxies: trustedProxies,
+		addresses:      make(map[string]*addressLimit),
+		lastSweep:      time.Now(),
+	}
+}
+
+// ex: 172.28.0.0/16,10.0.0.5
+func parseTrustedProxies(logger *slog.Logger, proxies string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, proxy := range strings.Split(proxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		address, err := netip.ParseAddr(proxy)
+		if err != nil {
+			logger.Warn("Ignoring invalid trusted proxy", "proxy", proxy, "error", err)
+			continue
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(address.Unmap(), address.Unmap().BitLen()))
+	}
+	return prefixes
+}
+
+func (l *addressLimiter) isTrusted(address string) bool {
+	parsed, err := netip.ParseAddr(address)
+	if err != nil {
+		return false
+	}
+	parsed = parsed.Unmap()
+	for _, prefix := range l.trustedProxies {
+		if prefix.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwarded headers can be set by anyone so they are only believed when a trusted proxy sent them
+//
+//	the proxy appends who connected to it so the rightmost untrusted address is the client
+func (l *addressLimiter) clientAddress(r *http.Request) string {
+	address, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		address = r.RemoteAddr
+	}
+	if !l.isTrusted(address) {
+		return address
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		forwardedAddress := strings.TrimSpace(forwarded[i])
+		if _, err := netip.ParseAddr(forwardedAddress); err != nil {
+			break
+		}
+		if !l.isTrusted(forwardedAddress) {
+			return forwardedAddress
+		}
+	}
+	return address
+}
+
+func (l *addressLimiter) allow(r *http.Request) bool {
+	address := l.clientAddress(r)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) > FORGET_ADDRESS_AFTER {
+		for otherAddress, limit := range l.addresses {
+			if now.Sub(limit.lastSeen) > FORGET_ADDRESS_AFTER {
+				delete(l.addresses, otherAddress)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	limit, ok := l.addresses[address]
+	if !ok {
+		limit = &addressLimit{limiter: rate.NewLimiter(SUBSCRIBE_RATE, SUBSCRIBE_BURST)}
+		l.addresses[address] = limit
+	}
+	limit.lastSeen = now
+	return limit.limiter.Allow()
+}
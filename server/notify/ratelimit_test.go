@@ -0,0 +1,57 @@
+package servernotify
+
+import (
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientAddress(t *testing.T) {
+	limiter := newAddressLimiter(parseTrustedProxies(slog.Default(), "172.28.0.0/16, 10.0.0.5, not-an-address"))
+	tests := []struct {
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}{
+		{"203.0.113.7:5000", nil, "203.0.113.7"},
+		// anyone can send a forwarded header
+		{"203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"172.28.0.3:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		// the client's own forwarded header is before what the proxy appended
+		{"172.28.0.3:5000", []string{"192.0.2.9, 198.51.100.1"}, "198.51.100.1"},
+		{"172.28.0.3:5000", []string{"198.51.100.1", "10.0.0.5"}, "198.51.100.1"},
+		{"172.28.0.3:5000", []string{"garbage"}, "172.28.0.3"},
+		{"172.28.0.3:5000", nil, "172.28.0.3"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/seats", nil)
+		r.RemoteAddr = test.remoteAddr
+		for _, forwarded := range test.forwarded {
+			r.Header.Add("X-Forwarded-For", forwarded)
+		}
+		if address := limiter.clientAddress(r); address != test.expected {
+			t.Errorf("%s forwarding %v expected %s got %s", test.remoteAddr, test.forwarded, test.expected, address)
+		}
+	}
+}
+
+func TestAddressLimiter(t *testing.T) {
+	limiter := newAddressLimiter(parseTrustedProxies(slog.Default(), "172.28.0.3"))
+	request := func(forwarded string) bool {
+		r := httptest.NewRequest("POST", "/seats", nil)
+		r.RemoteAddr = "172.28.0.3:5000"
+		r.Header.Set("X-Forwarded-For", forwarded)
+		return limiter.allow(r)
+	}
+	for i := range SUBSCRIBE_BURST {
+		if !request("198.51.100.1") {
+			t.Fatalf("expected subscription %d of the burst to be allowed", i)
+		}
+	}
+	if request("198.51.100.1") {
+		t.Error("expected the client to be limited after its burst")
+	}
+	if !request("198.51.100.2") {
+		t.Error("expected another client behind the same proxy to have its own burst")
+	}
+}
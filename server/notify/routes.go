@@ -0,0 +1,23 @@
+package servernotify
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func PopulateNotifyRoutes(r *chi.Router, pool *pgxpool.Pool, logger slog.Logger) {
+	notifyHandler := notifyHandler{
+		dbPool:          pool,
+		logger:          &logger,
+		subscribeLimits: newAddressLimiter(parseTrustedProxies(&logger, os.Getenv("TRUSTED_PROXIES"))),
+	}
+
+	(*r).Route("/seats", func(r chi.Router) {
+		r.Post("/", notifyHandler.subscribeToSeats)
+		r.Delete("/{subscriptionID}", notifyHandler.unsubscribeFromSeats)
+	})
+}
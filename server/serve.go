@@ -2,27 +2,37 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"log/slog"
 
 	"github.com/Pjt727/classy/collection"
 	"github.com/Pjt727/classy/collection/projectpath"
+	"github.com/Pjt727/classy/collection/webhooks"
 	"github.com/Pjt727/classy/data"
 	logginghelpers "github.com/Pjt727/classy/data/logging-helpers"
 	serverget "github.com/Pjt727/classy/server/get"
 	servermanage "github.com/Pjt727/classy/server/manage"
+	servernotify "github.com/Pjt727/classy/server/notify"
 	serversync "github.com/Pjt727/classy/server/sync"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 )
 
-func Serve() {
+// how long open requests get to finish when shutting down
+const SHUTDOWN_TIMEOUT = 10 * time.Second
+
+// serves until the context is done
+//
+//	when withWorker is false collections are left to separate `classy worker` processes
+func Serve(ctx context.Context, withWorker bool) {
 	r := chi.NewRouter()
 	cors := cors.New(cors.Options{
 		// Allow the github page to make to make requests for when running locally
@@ -36,11 +46,16 @@ func Serve() {
 	r.Use(cors.Handler)
 	r.Use(middleware.Logger)
 
-	dbPool, err := data.NewPool(context.Background(), false)
+	dbPool, err := data.NewPool(ctx, false)
 	if err != nil {
 		slog.Error("Fatal cannot connect to main db", "err", err)
 		return
 	}
+	// a school which cannot be loaded would silently never be collected
+	if err := collection.ValidateSchoolRegistry(ctx, dbPool); err != nil {
+		slog.Error("Fatal invalid school registry", "err", err)
+		return
+	}
 
 	baseLogger := slog.New(logginghelpers.NewHandler(os.Stdout, &logginghelpers.Options{
 		AddSource: false,
@@ -52,34 +67,55 @@ func Serve() {
 		serverget.PopulateGetRoutes(&r, dbPool, *baseLogger)
 	})
 	r.Route("/sync", func(r chi.Router) {
-		serversync.PopulateSyncRoutes(&r, dbPool, *baseLogger)
+		serversync.PopulateSyncRoutes(ctx, &r, dbPool, *baseLogger)
+	})
+	r.Route("/notify", func(r chi.Router) {
+		servernotify.PopulateNotifyRoutes(&r, dbPool, *baseLogger)
 	})
 
 	fileServer(r, "/static", http.Dir(filepath.Join(projectpath.Root, "server", "static")))
 
-	dbTestPool, err := data.NewPool(context.Background(), true)
+	dbTestPool, err := data.NewPool(ctx, true)
 	if err != nil {
 		panic(fmt.Sprintf("Cannot connect to test db %v", err))
 	}
+	// one orchestrator is shared by the management routes and watching so only one follows the registry
+	orchestrator := collection.GetDefaultOrchestrator(dbPool)
+	go orchestrator.WatchSchoolRegistry(ctx)
 	r.Route("/manage", func(r chi.Router) {
-		servermanage.PopulateManagementRoutes(&r, dbPool, dbTestPool, *baseLogger)
+		servermanage.PopulateManagementRoutes(&r, &orchestrator, dbPool, dbTestPool, *baseLogger)
 	})
-	port := 3000
-	slog.Info("Running server on", "port", port)
-	http.ListenAndServe(fmt.Sprintf(":%d", port), r)
+	// send out queued webhooks
+	deliverer := webhooks.NewDeliverer(dbPool, baseLogger)
+	go deliverer.Run(ctx)
+	// running collections are given their grace period before the process exits
+	workerDone := make(chan struct{})
+	if withWorker {
+		// continously look for collections to collect, plan the recurring ones and watch for new data
+		go func() {
+			defer close(workerDone)
+			collection.RunCollectionWorker(ctx, dbPool, collection.DefaultWorkerPoolConfig(), baseLogger)
+		}()
+	} else {
+		close(workerDone)
+	}
 
-	// continously look for collections to collect
-	scheduler := collection.NewScheduler(dbPool)
+	port := 3000
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: r}
 	go func() {
-		for {
-			// the poll function takes care of waiting to not overwhelm the db
-			_, err := scheduler.PollForCollections(context.Background())
-			if err != nil {
-				baseLogger.Error("Scheduled collection error", "error", err)
-			}
+		<-ctx.Done()
+		slog.Info("Shutting down server")
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), SHUTDOWN_TIMEOUT)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Could not shut down server", "err", err)
 		}
 	}()
-
+	slog.Info("Running server on", "port", port)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Stopped serving", "err", err)
+	}
+	<-workerDone
 }
 
 // https://github.com/go-chi/chi/blob/master/_examples/fileserver/main.go
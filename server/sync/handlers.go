@@ -27,8 +27,11 @@ type syncChange struct {
 }
 
 type syncHandler struct {
-	dbPool *pgxpool.Pool
-	logger *slog.Logger
+	// streams are hijacked so the server does not wait for them when shutting down
+	serverCtx context.Context
+	dbPool    *pgxpool.Pool
+	logger    *slog.Logger
+	notifier  *changeNotifier
 }
 
 // all syncs
@@ -191,18 +194,18 @@ type syncTermsResult struct {
 	AnyHasMore       bool           `json:"any_has_more"`
 }
 
-func (h *syncHandler) syncSchoolTerms(w http.ResponseWriter, r *http.Request) {
-
-	// reuse this input at return updating values in return without a separate qwuery
-	var syncData selectSchoolEntry
-	err := json.NewDecoder(r.Body).Decode(&syncData)
-	if err != nil {
-		http.Error(w, "Request has incorrect shape: "+err.Error(), http.StatusBadRequest)
-		return
-	}
-	ctx := r.Context()
-	q := db.New(h.dbPool)
+// the validated school / term sequences of a sync request
+type schoolSelection struct {
+	schoolToSequence      map[string]uint32
+	schoolToTermSequences map[string]map[string]uint32
+	termExclusions        map[string]map[string]uint32
+	maxRecordsPerRequest  uint32
+}
 
+// process the full request to ensure its validity before making any calls to the db
+// this is import bc if rate limiting is done at a different layer based on bandwith
+// the db could get slammed with requests while still only sending back the error
+func parseSchoolSelection(syncData selectSchoolEntry) (schoolSelection, error) {
 	// TODO: fix this pontential attack
 	// it is a little odd that limiting has to be per school bc there is not a single sql query for everything
 	// overloading this endpoint with difficult requests from bad actors is far too easy
@@ -214,27 +217,29 @@ func (h *syncHandler) syncSchoolTerms(w http.ResponseWriter, r *http.Request) {
 		maxRequestsPerRequest = DEFAULT_MAX_RECORDS
 	}
 
-	// process the full request to ensure its validity before making any calls to the db
-	// this is import bc if rate limiting is done at a different layer based on bandwith
-	// the db could get slammed with requests while still only sending back the error
-	schoolToSequence := make(map[string]uint32)
-	schoolToTermSequences := make(map[string]map[string]uint32)
+	selection := schoolSelection{
+		schoolToSequence:      make(map[string]uint32),
+		schoolToTermSequences: make(map[string]map[string]uint32),
+		termExclusions:        syncData.TermExclusions,
+		maxRecordsPerRequest:  maxRequestsPerRequest,
+	}
+	if selection.termExclusions == nil {
+		selection.termExclusions = make(map[string]map[string]uint32)
+	}
 	for schoolID, sequenceOrTermMap := range syncData.Schools {
 		switch schoolChoice := sequenceOrTermMap.(type) {
 		case float64: // this school last sequence
 			if schoolChoice < 0 {
-				http.Error(w, fmt.Sprintf("Invalid sequence number: %f", schoolChoice), http.StatusBadRequest)
-				return
+				return selection, fmt.Errorf("Invalid sequence number: %f", schoolChoice)
 			}
-			schoolToSequence[schoolID] = uint32(schoolChoice)
+			selection.schoolToSequence[schoolID] = uint32(schoolChoice)
 		case map[string]any: // this is a term mapping
 			termMap := make(map[string]uint32)
 			termsExclusions, checkTermExclusion := syncData.TermExclusions[schoolID]
 			for term, seq := range schoolChoice {
 				seqFloat, ok := seq.(float64)
 				if !ok || seqFloat < 0 {
-					http.Error(w, fmt.Sprintf("Invalid term sequence: %s", schoolChoice), http.StatusBadRequest)
-					return
+					return selection, fmt.Errorf("Invalid term sequence: %s", schoolChoice)
 				}
 				termMap[term] = uint32(seqFloat)
 
@@ -242,30 +247,57 @@ func (h *syncHandler) syncSchoolTerms(w http.ResponseWriter, r *http.Request) {
 				if checkTermExclusion {
 					_, ok = termsExclusions[term]
 					if ok {
-						http.Error(w, fmt.Sprintf("Term collect `%s` requested sync but it is also excluded", term), http.StatusBadRequest)
-						return
+						return selection, fmt.Errorf("Term collect `%s` requested sync but it is also excluded", term)
 					}
 				}
 			}
 
-			schoolToTermSequences[schoolID] = termMap
+			selection.schoolToTermSequences[schoolID] = termMap
 
 		default: // invalid type
-			http.Error(w, "Invalid body, schools must map to a sequence or term mapping", http.StatusBadRequest)
-			return
+			return selection, fmt.Errorf("Invalid body, schools must map to a sequence or term mapping")
 		}
 	}
+	return selection, nil
+}
+
+// the sequences in the same shape as the request's schools
+func (s *schoolSelection) syncSequences() map[string]any {
+	sequences := make(map[string]any)
+	for schoolID, sequence := range s.schoolToSequence {
+		sequences[schoolID] = sequence
+	}
+	for schoolID, termSequences := range s.schoolToTermSequences {
+		sequences[schoolID] = termSequences
+	}
+	return sequences
+}
+
+func (s *schoolSelection) schoolIDs() []string {
+	schoolIDs := make([]string, 0, len(s.schoolToSequence)+len(s.schoolToTermSequences))
+	for schoolID := range s.schoolToSequence {
+		schoolIDs = append(schoolIDs, schoolID)
+	}
+	for schoolID := range s.schoolToTermSequences {
+		schoolIDs = append(schoolIDs, schoolID)
+	}
+	return schoolIDs
+}
 
+// gets the changes past the selection's sequences and moves the sequences forward
+func (h *syncHandler) getSelectionChanges(ctx context.Context, selection *schoolSelection) ([]syncChange, bool, error) {
+	q := db.New(h.dbPool)
 	syncChanges := make([]syncChange, 0)
+	newSchoolSequences := make(map[string]uint32)
 	var mu sync.Mutex
 	syncGroup, syncCtx := errgroup.WithContext(ctx)
 
 	anyHasMore := false
 
 	// sync requests for all terms of a school
-	for schoolID, sequence := range schoolToSequence {
+	for schoolID, sequence := range selection.schoolToSequence {
 		syncGroup.Go(func() error {
-			newSyncChanges, hasMore, err := syncSchoolBasedOffSchool(q, syncCtx, schoolID, uint32(sequence), maxRequestsPerRequest)
+			newSyncChanges, hasMore, err := syncSchoolBasedOffSchool(q, syncCtx, schoolID, uint32(sequence), selection.maxRecordsPerRequest)
 			if err != nil {
 				return fmt.Errorf("Could not get school=`%s` sequence=`%d` %w", schoolID, sequence, err)
 			}
@@ -273,7 +305,7 @@ func (h *syncHandler) syncSchoolTerms(w http.ResponseWriter, r *http.Request) {
 			defer mu.Unlock()
 			if len(newSyncChanges) > 0 {
 				newLastestSync := newSyncChanges[len(newSyncChanges)-1].Sequence
-				syncData.Schools[schoolID] = max(newLastestSync, uint32(sequence))
+				newSchoolSequences[schoolID] = max(newLastestSync, uint32(sequence))
 			}
 			anyHasMore = anyHasMore || hasMore
 			syncChanges = append(syncChanges, newSyncChanges...)
@@ -282,13 +314,13 @@ func (h *syncHandler) syncSchoolTerms(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// sync requests for select terms of a school
-	for schoolID, termSequences := range schoolToTermSequences {
+	for schoolID, termSequences := range selection.schoolToTermSequences {
 		syncGroup.Go(func() error {
-			termExclusions, ok := syncData.TermExclusions[schoolID]
+			termExclusions, ok := selection.termExclusions[schoolID]
 			if !ok {
 				termExclusions = make(map[string]uint32)
 			}
-			newSyncChanges, hasMore, err := getTerms(q, ctx, schoolID, termSequences, maxRequestsPerRequest, termExclusions)
+			newSyncChanges, hasMore, err := getTerms(q, ctx, schoolID, termSequences, selection.maxRecordsPerRequest, termExclusions)
 			h.logger.Info("term results", "hasMore", hasMore)
 			if err != nil {
 				return err
@@ -300,7 +332,6 @@ func (h *syncHandler) syncSchoolTerms(w http.ResponseWriter, r *http.Request) {
 				for term, seq := range termSequences {
 					termSequences[term] = max(newLastestSync, seq)
 				}
-				syncData.Schools[schoolID] = termSequences
 			}
 			anyHasMore = anyHasMore || hasMore
 			syncChanges = append(syncChanges, newSyncChanges...)
@@ -309,12 +340,39 @@ func (h *syncHandler) syncSchoolTerms(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := syncGroup.Wait(); err != nil {
+		return syncChanges, anyHasMore, err
+	}
+	for schoolID, sequence := range newSchoolSequences {
+		selection.schoolToSequence[schoolID] = sequence
+	}
+
+	return syncChanges, anyHasMore, nil
+}
+
+func (h *syncHandler) syncSchoolTerms(w http.ResponseWriter, r *http.Request) {
+
+	var syncData selectSchoolEntry
+	err := json.NewDecoder(r.Body).Decode(&syncData)
+	if err != nil {
+		http.Error(w, "Request has incorrect shape: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+
+	selection, err := parseSchoolSelection(syncData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	syncChanges, anyHasMore, err := h.getSelectionChanges(ctx, &selection)
+	if err != nil {
 		h.logger.Error("Could not get term/school sync rows", "err", err)
 		http.Error(w, "Problem getting the sync changes", http.StatusInternalServerError)
 	}
 
 	result := syncTermsResult{
-		NewSyncSequences: syncData.Schools,
+		NewSyncSequences: selection.syncSequences(),
 		SyncData:         syncChanges,
 		AnyHasMore:       anyHasMore,
 	}
@@ -1,6 +1,7 @@
 package serversync
 
 import (
+	"context"
 	"log/slog"
 
 	"github.com/go-chi/chi/v5"
@@ -8,14 +9,19 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func PopulateSyncRoutes(r *chi.Router, pool *pgxpool.Pool, logger slog.Logger) error {
+// the change notifier and open streams are stopped when the context is done
+func PopulateSyncRoutes(ctx context.Context, r *chi.Router, pool *pgxpool.Pool, logger slog.Logger) error {
 	syncHandler := syncHandler{
-		dbPool: pool,
-		logger: &logger,
+		serverCtx: ctx,
+		dbPool:    pool,
+		logger:    &logger,
+		notifier:  newChangeNotifier(pool, &logger),
 	}
+	go syncHandler.notifier.run(ctx)
 
 	(*r).Get("/all", syncHandler.syncAll)
 	(*r).Post("/schools", syncHandler.syncSchoolTerms)
+	(*r).Get("/stream", syncHandler.syncStream)
 
 	return nil
 }
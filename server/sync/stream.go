@@ -0,0 +1,226 @@
+package serversync
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// the channel the historic trigger notifies with the school id of the changes
+const HISTORIC_CHANGES_CHANNEL = "historic_class_information"
+
+// how long to wait before listening again after losing the listening connection
+const LISTEN_RETRY_INTERVAL = 5 * time.Second
+
+// how often streams are pinged to keep idle connections from being closed
+const STREAM_PING_INTERVAL = 30 * time.Second
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true // sync data is public
+	},
+}
+
+type changeSubscription struct {
+	schoolIDs map[string]bool
+	// only ever needs to hold one wake up because the stream
+	//    gets everything past its last sequence when it wakes
+	wake chan struct{}
+}
+
+// holds one connection listening for new historic changes and
+// wakes the streams which care about the notified school
+type changeNotifier struct {
+	dbPool        *pgxpool.Pool
+	logger        *slog.Logger
+	mu            sync.Mutex
+	subscriptions map[*changeSubscription]struct{}
+}
+
+func newChangeNotifier(dbPool *pgxpool.Pool, logger *slog.Logger) *changeNotifier {
+	return &changeNotifier{
+		dbPool:        dbPool,
+		logger:        logger,
+		subscriptions: make(map[*changeSubscription]struct{}),
+	}
+}
+
+func (n *changeNotifier) subscribe(schoolIDs []string) *changeSubscription {
+	subscription := &changeSubscription{
+		schoolIDs: make(map[string]bool),
+		wake:      make(chan struct{}, 1),
+	}
+	for _, schoolID := range schoolIDs {
+		subscription.schoolIDs[schoolID] = true
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.subscriptions[subscription] = struct{}{}
+	return subscription
+}
+
+func (n *changeNotifier) unsubscribe(subscription *changeSubscription) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.subscriptions, subscription)
+}
+
+// an empty school id wakes every subscription
+func (n *changeNotifier) wake(schoolID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for subscription := range n.subscriptions {
+		if schoolID != "" && !subscription.schoolIDs[schoolID] {
+			continue
+		}
+		select {
+		case subscription.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// listens until the context is done reconnecting when the connection is lost
+func (n *changeNotifier) run(ctx context.Context) {
+	for {
+		err := n.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		n.logger.Error("Lost listening connection for sync changes", "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(LISTEN_RETRY_INTERVAL):
+		}
+	}
+}
+
+func (n *changeNotifier) listen(ctx context.Context) error {
+	poolConn, err := n.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the connection has a LISTEN registered on it so it is not given back to the pool
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+HISTORIC_CHANGES_CHANNEL)
+	if err != nil {
+		return err
+	}
+	// notifications could have been missed while not listening
+	n.wake("")
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		n.wake(notification.Payload)
+	}
+}
+
+// streams sync changes as they are committed over a websocket
+//
+//	the first message from the client is the same body `/sync/schools` takes
+//	each message back is the same shape `/sync/schools` returns
+func (h *syncHandler) syncStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Info("Could not upgrade", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	var syncData selectSchoolEntry
+	if err := conn.ReadJSON(&syncData); err != nil {
+		conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseUnsupportedData, "Request has incorrect shape"))
+		return
+	}
+	selection, err := parseSchoolSelection(syncData)
+	if err != nil {
+		conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stopOnShutdown := context.AfterFunc(h.serverCtx, cancel)
+	defer stopOnShutdown()
+	// the client is not expected to send anything else but reading is needed to notice the close
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	subscription := h.notifier.subscribe(selection.schoolIDs())
+	defer h.notifier.unsubscribe(subscription)
+
+	pingTicker := time.NewTicker(STREAM_PING_INTERVAL)
+	defer pingTicker.Stop()
+
+	for {
+		syncChanges, anyHasMore, err := h.getSelectionChanges(ctx, &selection)
+		if err != nil {
+			if ctx.Err() == nil {
+				h.logger.Error("Could not get streamed sync rows", "err", err)
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "Problem getting the sync changes"))
+			}
+			return
+		}
+
+		if len(syncChanges) > 0 {
+			resultJson, err := json.Marshal(syncTermsResult{
+				NewSyncSequences: selection.syncSequences(),
+				SyncData:         syncChanges,
+				AnyHasMore:       anyHasMore,
+			})
+			if err != nil {
+				h.logger.Error("Could not marshal streamed sync rows", "err", err)
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, resultJson); err != nil {
+				return
+			}
+		}
+
+		// keep sending until the client is caught up
+		if anyHasMore {
+			continue
+		}
+
+	waiting:
+		for {
+			select {
+			case <-ctx.Done():
+				if h.serverCtx.Err() != nil {
+					conn.WriteMessage(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server is shutting down"))
+				}
+				return
+			case <-subscription.wake:
+				break waiting
+			case <-pingTicker.C:
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			}
+		}
+	}
+}